@@ -13,7 +13,7 @@ cat << EOF
     agents:
       suite: "activedirectory"
 EOF
-elif [[ "${SUITE_NAME}" = "HighAvailability" ]]; then
+elif [[ "${SUITE_NAME}" = "HighAvailability" ]] || [[ "${SUITE_NAME}" = "HighAvailabilityCluster" ]]; then
 cat << EOF
     agents:
       suite: "highavailability"
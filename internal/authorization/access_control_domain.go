@@ -2,6 +2,7 @@ package authorization
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/authelia/authelia/v4/internal/utils"
@@ -10,6 +11,7 @@ import (
 // AccessControlDomain represents an ACL domain.
 type AccessControlDomain struct {
 	Name          string
+	Pattern       *regexp.Regexp
 	Wildcard      bool
 	UserWildcard  bool
 	GroupWildcard bool
@@ -18,6 +20,8 @@ type AccessControlDomain struct {
 // IsMatch returns true if the ACL domain matches the object domain.
 func (acd AccessControlDomain) IsMatch(subject Subject, object Object) (match bool) {
 	switch {
+	case acd.Pattern != nil:
+		return isMatchForPattern(acd.Pattern, object.Domain, subject)
 	case acd.Wildcard:
 		return strings.HasSuffix(object.Domain, acd.Name)
 	case acd.UserWildcard:
@@ -0,0 +1,53 @@
+package authorization
+
+import (
+	"strings"
+	"text/template"
+)
+
+// AccessControlHeader represents a header of an ACL which is added to the authorized responses.
+type AccessControlHeader struct {
+	Name  string
+	Value *template.Template
+}
+
+// Render returns the value of the header for the data.
+func (ach AccessControlHeader) Render(data HeaderTemplateData) (value string, err error) {
+	builder := &strings.Builder{}
+
+	if err = ach.Value.Execute(builder, data); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+// HeaderTemplateData is the data the header templates are rendered with.
+type HeaderTemplateData struct {
+	Username    string
+	DisplayName string
+	Email       string
+	Emails      HeaderTemplateValues
+	Groups      HeaderTemplateValues
+
+	// Captures are the named subexpressions of the domain and resource patterns which matched the object.
+	Captures map[string]string
+}
+
+// HeaderTemplateValues is a list of values of the header template data.
+type HeaderTemplateValues []string
+
+// Join returns the values separated by sep.
+func (v HeaderTemplateValues) Join(sep string) string {
+	return strings.Join(v, sep)
+}
+
+// String returns the values separated by a comma which is the format of the Remote-* headers.
+func (v HeaderTemplateValues) String() string {
+	return v.Join(",")
+}
+
+// NewHeaderTemplate parses the value of a header. The missing captures are rendered as empty strings.
+func NewHeaderTemplate(name, value string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Parse(value)
+}
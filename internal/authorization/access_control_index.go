@@ -0,0 +1,148 @@
+package authorization
+
+import (
+	"sort"
+	"strings"
+)
+
+// accessControlIndex finds the rules which may match the domain of an object without evaluating every rule. The rules
+// returned by the index are a superset of the rules which match the domain, so they must still be evaluated with
+// AccessControlRule.IsMatch in the order they are returned to keep the first match semantics.
+type accessControlIndex struct {
+	// exact contains the positions of the rules with a domain which must be equal to the object domain.
+	exact map[string][]int
+
+	// suffixes contains the positions of the rules with a domain which must be a subdomain of the trie path, this
+	// includes the wildcard, user wildcard, and group wildcard domains.
+	suffixes *domainTrieNode
+
+	// unindexed contains the positions of the rules which have to be evaluated for every domain such as rules with a
+	// domain regex or without a domain.
+	unindexed []int
+}
+
+// domainTrieNode is a node of a trie where each edge is a domain label starting from the top level domain.
+type domainTrieNode struct {
+	children map[string]*domainTrieNode
+	rules    []int
+}
+
+func newAccessControlIndex(rules []*AccessControlRule) (index *accessControlIndex) {
+	index = &accessControlIndex{
+		exact:    map[string][]int{},
+		suffixes: &domainTrieNode{},
+	}
+
+	for i, rule := range rules {
+		if len(rule.Domains) == 0 {
+			index.unindexed = append(index.unindexed, i)
+
+			continue
+		}
+
+		for _, domain := range rule.Domains {
+			switch {
+			case domain.Pattern != nil:
+				index.unindexed = appendPosition(index.unindexed, i)
+			case domain.Wildcard:
+				// The wildcard domain name includes the leading period.
+				index.suffixes.insert(domain.Name[1:], i)
+			case domain.UserWildcard:
+				index.suffixes.insert(domain.Name, i)
+			case domain.GroupWildcard:
+				index.suffixes.insert(domain.Name, i)
+
+				// A domain without a period is entirely considered the suffix when matching a group wildcard.
+				index.exact[domain.Name] = appendPosition(index.exact[domain.Name], i)
+			default:
+				index.exact[domain.Name] = appendPosition(index.exact[domain.Name], i)
+			}
+		}
+	}
+
+	return index
+}
+
+// Candidates returns the ordered positions of the rules which may match the domain.
+func (i *accessControlIndex) Candidates(domain string) (positions []int) {
+	positions = append(positions, i.unindexed...)
+	positions = append(positions, i.exact[domain]...)
+	positions = i.suffixes.collect(domain, positions)
+
+	if len(positions) < 2 {
+		return positions
+	}
+
+	sort.Ints(positions)
+
+	// Remove the duplicates of rules with several domains matching the same object domain.
+	n := 1
+
+	for j := 1; j < len(positions); j++ {
+		if positions[j] != positions[n-1] {
+			positions[n] = positions[j]
+			n++
+		}
+	}
+
+	return positions[:n]
+}
+
+func (n *domainTrieNode) insert(suffix string, position int) {
+	node := n
+	labels := strings.Split(suffix, ".")
+
+	for j := len(labels) - 1; j >= 0; j-- {
+		if node.children == nil {
+			node.children = map[string]*domainTrieNode{}
+		}
+
+		child, ok := node.children[labels[j]]
+		if !ok {
+			child = &domainTrieNode{}
+			node.children[labels[j]] = child
+		}
+
+		node = child
+	}
+
+	node.rules = appendPosition(node.rules, position)
+}
+
+// collect appends the rules of every node along the path of the domain labels which still has at least one label left
+// in the domain, i.e. the domain is a subdomain of the path to the node.
+func (n *domainTrieNode) collect(domain string, positions []int) []int {
+	node := n
+	end := len(domain)
+
+	for end >= 0 {
+		start := strings.LastIndexByte(domain[:end], '.')
+
+		// The label is the first label of the domain so none of the following nodes could be a suffix of it.
+		if start == -1 {
+			break
+		}
+
+		child, ok := node.children[domain[start+1:end]]
+		if !ok {
+			break
+		}
+
+		node = child
+		end = start
+
+		positions = append(positions, node.rules...)
+	}
+
+	return positions
+}
+
+// appendPosition appends the position to the positions if it's not already the last position, which avoids duplicates
+// for rules with several domains as positions are appended in order.
+func appendPosition(positions []int, position int) []int {
+	if len(positions) != 0 && positions[len(positions)-1] == position {
+		return positions
+	}
+
+	return append(positions, position)
+}
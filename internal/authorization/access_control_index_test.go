@@ -0,0 +1,221 @@
+package authorization
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// getRequiredLevelLinear evaluates every rule in order and is the reference the index must agree with.
+func getRequiredLevelLinear(ac *accessControl, subject Subject, object Object) Level {
+	for _, rule := range ac.rules {
+		if rule.IsMatch(subject, object) {
+			return rule.Policy
+		}
+	}
+
+	return ac.defaultPolicy
+}
+
+func TestShouldReturnIndexCandidates(t *testing.T) {
+	rules := NewAccessControlRules(schema.AccessControlConfiguration{
+		Rules: []schema.ACLRule{
+			{Domains: []string{"example.com"}},
+			{Domains: []string{"*.example.com"}},
+			{Domains: []string{"{user}.example.com"}},
+			{Domains: []string{"{group}.example.com"}},
+			{DomainsRegex: []string{`^api\.`}},
+			{Domains: []string{"app.example.com", "*.app.example.com", "*.example.com"}},
+			{Domains: []string{"*.com"}},
+			{Domains: []string{"example.org"}},
+		},
+	})
+
+	index := newAccessControlIndex(rules)
+
+	assert.Equal(t, []int{0, 3, 4, 6}, index.Candidates("example.com"))
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, index.Candidates("app.example.com"))
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, index.Candidates("a.b.app.example.com"))
+	assert.Equal(t, []int{4, 7}, index.Candidates("example.org"))
+	assert.Equal(t, []int{4}, index.Candidates("com"))
+	assert.Equal(t, []int{4}, index.Candidates(""))
+}
+
+func TestShouldMatchLinearEvaluationWithIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(42)) //nolint:gosec // Deterministic values are required to reproduce failures.
+
+	for i := 0; i < 200; i++ {
+		config := randomAccessControlConfiguration(r, 1+r.Intn(30))
+
+		authorizer := NewAuthorizer(&schema.Configuration{AccessControl: config}, utils.RealClock{})
+		ac := authorizer.load()
+
+		for j := 0; j < 200; j++ {
+			subject, object := randomSubject(r), randomObject(r)
+
+			expected := getRequiredLevelLinear(ac, subject, object)
+			actual := authorizer.GetRequiredLevel(subject, object)
+
+			require.Equal(t, expected, actual, "configuration %d request %d: subject %s and object %s (method %s) with rules %+v",
+				i, j, subject, object, object.Method, config.Rules)
+		}
+	}
+}
+
+var (
+	randomRuleDomains = []string{
+		"example.com", "*.example.com", "{user}.example.com", "{group}.example.com", "app.example.com",
+		"*.app.example.com", "*.com", "example.org", "*.example.org", "{user}.example.org", "a.b.example.com",
+	}
+	randomRuleDomainsRegex = []string{`^(?P<User>\w+)\.example\.com$`, `^app\.`, `example`, `^(?P<Group>\w+)\.example\.org$`}
+	randomRuleSubjects     = [][][]string{{{"user:john"}}, {{"group:admins"}}, {{"group:dev", "user:harry"}}, {{"user:bob"}, {"group:dev"}}}
+	randomRuleResources    = [][]string{{"^/api.*"}, {`^/(?P<User>\w+)/.*`}, {"^/$", "^/admin"}}
+	randomRuleMethods      = [][]string{{"GET"}, {"POST", "PUT"}}
+	randomRuleNetworks     = [][]string{{"10.0.0.0/8"}, {"192.168.1.0/24", "127.0.0.1"}}
+	randomRulePolicies     = []string{bypass, oneFactor, twoFactor, deny}
+
+	randomObjectDomains = []string{
+		"example.com", "app.example.com", "x.app.example.com", "john.example.com", "john.doe.example.com",
+		"harry.example.org", "admins.example.com", "dev.example.org", "example.org", "a.b.example.com", "com", "example",
+		".example.com", "app.example.net",
+	}
+	randomObjectPaths   = []string{"/", "/api/users", "/john/files", "/harry/", "/admin/panel"}
+	randomObjectMethods = []string{"GET", "POST", "PUT", "DELETE"}
+
+	randomSubjects = []Subject{
+		{},
+		{Username: "john", Groups: []string{"admins"}},
+		{Username: "john.doe", Groups: []string{"dev"}},
+		{Username: "harry", Groups: []string{"admins", "dev"}},
+		{Username: "bob"},
+	}
+	randomSubjectIPs = []string{"10.1.1.1", "192.168.1.5", "127.0.0.1", "172.16.0.1"}
+)
+
+func randomAccessControlConfiguration(r *rand.Rand, count int) (config schema.AccessControlConfiguration) {
+	config.DefaultPolicy = randomRulePolicies[r.Intn(len(randomRulePolicies))]
+
+	for i := 0; i < count; i++ {
+		rule := schema.ACLRule{
+			Policy: randomRulePolicies[r.Intn(len(randomRulePolicies))],
+		}
+
+		for j := r.Intn(3); j >= 0; j-- {
+			rule.Domains = append(rule.Domains, randomRuleDomains[r.Intn(len(randomRuleDomains))])
+		}
+
+		if r.Intn(5) == 0 {
+			rule.DomainsRegex = append(rule.DomainsRegex, randomRuleDomainsRegex[r.Intn(len(randomRuleDomainsRegex))])
+		}
+
+		if r.Intn(3) == 0 {
+			rule.Subjects = randomRuleSubjects[r.Intn(len(randomRuleSubjects))]
+		}
+
+		if r.Intn(3) == 0 {
+			rule.Resources = randomRuleResources[r.Intn(len(randomRuleResources))]
+		}
+
+		if r.Intn(3) == 0 {
+			rule.Methods = randomRuleMethods[r.Intn(len(randomRuleMethods))]
+		}
+
+		if r.Intn(4) == 0 {
+			rule.Networks = randomRuleNetworks[r.Intn(len(randomRuleNetworks))]
+		}
+
+		config.Rules = append(config.Rules, rule)
+	}
+
+	return config
+}
+
+func randomSubject(r *rand.Rand) (subject Subject) {
+	subject = randomSubjects[r.Intn(len(randomSubjects))]
+	subject.IP = net.ParseIP(randomSubjectIPs[r.Intn(len(randomSubjectIPs))])
+
+	return subject
+}
+
+func randomObject(r *rand.Rand) (object Object) {
+	return Object{
+		Scheme: "https",
+		Domain: randomObjectDomains[r.Intn(len(randomObjectDomains))],
+		Path:   randomObjectPaths[r.Intn(len(randomObjectPaths))],
+		Method: randomObjectMethods[r.Intn(len(randomObjectMethods))],
+	}
+}
+
+// benchmarkAccessControlConfiguration generates a configuration similar to a large deployment where most rules apply
+// to a single application.
+func benchmarkAccessControlConfiguration(count int) (config schema.AccessControlConfiguration) {
+	config.DefaultPolicy = deny
+
+	for i := 0; i < count; i++ {
+		rule := schema.ACLRule{
+			Domains: []string{fmt.Sprintf("app%d.example.com", i)},
+			Policy:  oneFactor,
+		}
+
+		switch i % 4 {
+		case 1:
+			rule.Domains = []string{fmt.Sprintf("*.team%d.example.com", i)}
+			rule.Policy = twoFactor
+		case 2:
+			rule.Subjects = [][]string{{"group:admins"}, {fmt.Sprintf("user:user%d", i)}}
+			rule.Resources = []string{"^/admin([/?].*)?$"}
+			rule.Policy = twoFactor
+		case 3:
+			rule.Networks = []string{"10.0.0.0/8"}
+			rule.Methods = []string{"GET", "HEAD"}
+			rule.Policy = bypass
+		}
+
+		config.Rules = append(config.Rules, rule)
+	}
+
+	return config
+}
+
+func benchmarkGetRequiredLevel(b *testing.B, linear bool) {
+	config := benchmarkAccessControlConfiguration(600)
+
+	authorizer := NewAuthorizer(&schema.Configuration{AccessControl: config}, utils.RealClock{})
+	ac := authorizer.load()
+
+	subject := Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP("192.168.1.5")}
+	objects := []Object{
+		{Scheme: "https", Domain: "app4.example.com", Path: "/", Method: "GET"},
+		{Scheme: "https", Domain: "app300.example.com", Path: "/", Method: "GET"},
+		{Scheme: "https", Domain: "grafana.team597.example.com", Path: "/dashboards", Method: "GET"},
+		{Scheme: "https", Domain: "unknown.example.com", Path: "/", Method: "POST"},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		object := objects[i%len(objects)]
+
+		if linear {
+			getRequiredLevelLinear(ac, subject, object)
+		} else {
+			authorizer.GetRequiredLevel(subject, object)
+		}
+	}
+}
+
+func BenchmarkGetRequiredLevelLinear(b *testing.B) {
+	benchmarkGetRequiredLevel(b, true)
+}
+
+func BenchmarkGetRequiredLevelIndexed(b *testing.B) {
+	benchmarkGetRequiredLevel(b, false)
+}
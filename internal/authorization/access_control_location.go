@@ -0,0 +1,18 @@
+package authorization
+
+// AccessControlLocation represents the countries and autonomous systems of an ACL network group.
+type AccessControlLocation struct {
+	Countries []string
+	ASNs      []uint
+}
+
+// IsMatch returns true if the country or the autonomous system of the subject is one of the location.
+func (acl AccessControlLocation) IsMatch(subject Subject) (match bool) {
+	for _, country := range acl.Countries {
+		if subject.Country == country {
+			return true
+		}
+	}
+
+	return subject.ASN != 0 && isUintInSlice(subject.ASN, acl.ASNs)
+}
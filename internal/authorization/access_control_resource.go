@@ -10,6 +10,6 @@ type AccessControlResource struct {
 }
 
 // IsMatch returns true if the ACL resource match the object path.
-func (acr AccessControlResource) IsMatch(object Object) (match bool) {
-	return acr.Pattern.MatchString(object.Path)
+func (acr AccessControlResource) IsMatch(subject Subject, object Object) (match bool) {
+	return isMatchForPattern(acr.Pattern, object.Path, subject)
 }
@@ -2,6 +2,7 @@ package authorization
 
 import (
 	"net"
+	"time"
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
 	"github.com/authelia/authelia/v4/internal/utils"
@@ -10,24 +11,36 @@ import (
 // NewAccessControlRules converts a schema.AccessControlConfiguration into an AccessControlRule slice.
 func NewAccessControlRules(config schema.AccessControlConfiguration) (rules []*AccessControlRule) {
 	networksMap, networksCacheMap := parseSchemaNetworks(config.Networks)
+	locationsMap := parseSchemaNetworkLocations(config.Networks)
 
 	for i, schemaRule := range config.Rules {
-		rules = append(rules, NewAccessControlRule(i+1, schemaRule, networksMap, networksCacheMap))
+		rules = append(rules, NewAccessControlRule(i+1, schemaRule, networksMap, networksCacheMap, locationsMap))
 	}
 
 	return rules
 }
 
 // NewAccessControlRule parses a schema ACL and generates an internal ACL.
-func NewAccessControlRule(pos int, rule schema.ACLRule, networksMap map[string][]*net.IPNet, networksCacheMap map[string]*net.IPNet) *AccessControlRule {
+func NewAccessControlRule(pos int, rule schema.ACLRule, networksMap map[string][]*net.IPNet, networksCacheMap map[string]*net.IPNet,
+	locationsMap map[string]AccessControlLocation) *AccessControlRule {
 	return &AccessControlRule{
 		Position:  pos,
-		Domains:   schemaDomainsToACL(rule.Domains),
+		Domains:   schemaDomainsToACL(rule.Domains, rule.DomainsRegex),
 		Resources: schemaResourcesToACL(rule.Resources),
 		Methods:   schemaMethodsToACL(rule.Methods),
 		Networks:  schemaNetworksToACL(rule.Networks, networksMap, networksCacheMap),
+		Countries: schemaCountriesToACL(rule.Countries),
+		ASNs:      rule.ASNs,
+		ClientIDs: rule.ClientIDs,
+		Scopes:    rule.Scopes,
 		Subjects:  schemaSubjectsToACL(rule.Subjects),
+		Schedule:  schemaScheduleToACL(rule.Schedule),
 		Policy:    PolicyToLevel(rule.Policy),
+
+		NetworkLocations: schemaNetworkLocationsToACL(rule.Networks, locationsMap),
+
+		MaxAuthenticationAge: schemaMaxAuthenticationAgeToACL(rule.MaxAuthenticationAge),
+		Headers:              schemaHeadersToACL(rule.Headers),
 	}
 }
 
@@ -38,8 +51,17 @@ type AccessControlRule struct {
 	Resources []AccessControlResource
 	Methods   []string
 	Networks  []*net.IPNet
+	Countries []string
+	ASNs      []uint
+	ClientIDs []string
+	Scopes    []string
 	Subjects  []AccessControlSubjects
+	Schedule  *AccessControlSchedule
 	Policy    Level
+
+	NetworkLocations     []AccessControlLocation
+	MaxAuthenticationAge MaxAuthenticationAge
+	Headers              []AccessControlHeader
 }
 
 // IsMatch returns true if all elements of an AccessControlRule match the object and subject.
@@ -48,7 +70,7 @@ func (acr *AccessControlRule) IsMatch(subject Subject, object Object) (match boo
 		return false
 	}
 
-	if !isMatchForResources(object, acr) {
+	if !isMatchForResources(subject, object, acr) {
 		return false
 	}
 
@@ -60,13 +82,49 @@ func (acr *AccessControlRule) IsMatch(subject Subject, object Object) (match boo
 		return false
 	}
 
+	if !isMatchForLocation(subject, acr) {
+		return false
+	}
+
 	if !isMatchForSubjects(subject, acr) {
 		return false
 	}
 
+	if !isMatchForClient(subject, acr) {
+		return false
+	}
+
 	return true
 }
 
+// IsActive returns true if the time is within the AccessControlRule schedule or the rule has no schedule. It is
+// separate from IsMatch as a rule which matches outside of its schedule denies access rather than falling through.
+func (acr *AccessControlRule) IsActive(now time.Time) (active bool) {
+	if acr.Schedule == nil {
+		return true
+	}
+
+	return acr.Schedule.IsActive(now)
+}
+
+// Captures returns the named subexpressions of the domain and resource patterns of the AccessControlRule which match
+// the object. When several patterns have a subexpression with the same name the value of the last one is returned.
+func (acr *AccessControlRule) Captures(object Object) (captures map[string]string) {
+	captures = map[string]string{}
+
+	for _, domain := range acr.Domains {
+		if domain.Pattern != nil {
+			addPatternCaptures(captures, domain.Pattern, object.Domain)
+		}
+	}
+
+	for _, resource := range acr.Resources {
+		addPatternCaptures(captures, resource.Pattern, object.Path)
+	}
+
+	return captures
+}
+
 func isMatchForDomains(subject Subject, object Object, acl *AccessControlRule) (match bool) {
 	// If there are no domains in this rule then the domain condition is a match.
 	if len(acl.Domains) == 0 {
@@ -83,7 +141,7 @@ func isMatchForDomains(subject Subject, object Object, acl *AccessControlRule) (
 	return false
 }
 
-func isMatchForResources(object Object, acl *AccessControlRule) (match bool) {
+func isMatchForResources(subject Subject, object Object, acl *AccessControlRule) (match bool) {
 	// If there are no resources in this rule then the resource condition is a match.
 	if len(acl.Resources) == 0 {
 		return true
@@ -91,7 +149,7 @@ func isMatchForResources(object Object, acl *AccessControlRule) (match bool) {
 
 	// Iterate over the resources until we find a match (return true) or until we exit the loop (return false).
 	for _, resource := range acl.Resources {
-		if resource.IsMatch(object) {
+		if resource.IsMatch(subject, object) {
 			return true
 		}
 	}
@@ -110,7 +168,7 @@ func isMatchForMethods(object Object, acl *AccessControlRule) (match bool) {
 
 func isMatchForNetworks(subject Subject, acl *AccessControlRule) (match bool) {
 	// If there are no networks in this rule then the network condition is a match.
-	if len(acl.Networks) == 0 {
+	if len(acl.Networks) == 0 && len(acl.NetworkLocations) == 0 {
 		return true
 	}
 
@@ -121,9 +179,31 @@ func isMatchForNetworks(subject Subject, acl *AccessControlRule) (match bool) {
 		}
 	}
 
+	// Iterate over the locations of the network groups until we find a match (return true) or until we exit the loop
+	// (return false).
+	for _, location := range acl.NetworkLocations {
+		if location.IsMatch(subject) {
+			return true
+		}
+	}
+
 	return false
 }
 
+func isMatchForLocation(subject Subject, acl *AccessControlRule) (match bool) {
+	// If there are no countries in this rule then the country condition is a match.
+	if len(acl.Countries) != 0 && !utils.IsStringInSlice(subject.Country, acl.Countries) {
+		return false
+	}
+
+	// If there are no asns in this rule then the asn condition is a match.
+	if len(acl.ASNs) != 0 && !isUintInSlice(subject.ASN, acl.ASNs) {
+		return false
+	}
+
+	return true
+}
+
 func isMatchForSubjects(subject Subject, acl *AccessControlRule) (match bool) {
 	// If there are no subjects in this rule then the subject condition is a match.
 	if len(acl.Subjects) == 0 || subject.IsAnonymous() {
@@ -139,3 +219,30 @@ func isMatchForSubjects(subject Subject, acl *AccessControlRule) (match bool) {
 
 	return false
 }
+
+func isMatchForClient(subject Subject, acl *AccessControlRule) (match bool) {
+	// Anonymous subjects match like they do for subjects so they are asked to authenticate rather than falling through
+	// to the next rule.
+	if subject.IsAnonymous() {
+		return true
+	}
+
+	// Access tokens are only accepted by the rules which opt in by allowing their client, the rules without client ids
+	// only match the other subjects unless they bypass authentication.
+	if len(acl.ClientIDs) == 0 {
+		return subject.ClientID == "" || acl.Policy == Bypass
+	}
+
+	if !utils.IsStringInSlice(subject.ClientID, acl.ClientIDs) {
+		return false
+	}
+
+	// Every scope of the rule must have been granted to the access token.
+	for _, scope := range acl.Scopes {
+		if !utils.IsStringInSlice(scope, subject.Scopes) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,82 @@
+package authorization
+
+import (
+	"time"
+)
+
+// AccessControlSchedule represents the time window conditions of an ACL.
+type AccessControlSchedule struct {
+	Location *time.Location
+	Weekdays []time.Weekday
+	Times    []AccessControlTimeRange
+	Start    time.Time
+	End      time.Time
+}
+
+// AccessControlTimeRange represents a time of day range as offsets since midnight.
+type AccessControlTimeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// IsActive returns true if the time is within the ACL schedule.
+func (acs AccessControlSchedule) IsActive(now time.Time) (active bool) {
+	now = now.In(acs.Location)
+
+	if !acs.Start.IsZero() && now.Before(acs.Start) {
+		return false
+	}
+
+	if !acs.End.IsZero() && !now.Before(acs.End) {
+		return false
+	}
+
+	if !isActiveForWeekdays(now, acs.Weekdays) {
+		return false
+	}
+
+	return isActiveForTimes(now, acs.Times)
+}
+
+// IsMatch returns true if the time of day is within the ACL time range.
+func (actr AccessControlTimeRange) IsMatch(offset time.Duration) (match bool) {
+	// If the end is before the start then the time range spans midnight.
+	if actr.End < actr.Start {
+		return offset >= actr.Start || offset < actr.End
+	}
+
+	return offset >= actr.Start && offset < actr.End
+}
+
+func isActiveForWeekdays(now time.Time, weekdays []time.Weekday) (active bool) {
+	// If there are no weekdays in this schedule then the weekday condition is active.
+	if len(weekdays) == 0 {
+		return true
+	}
+
+	for _, weekday := range weekdays {
+		if now.Weekday() == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isActiveForTimes(now time.Time, times []AccessControlTimeRange) (active bool) {
+	// If there are no time ranges in this schedule then the time condition is active.
+	if len(times) == 0 {
+		return true
+	}
+
+	// The offset is calculated from the wall clock so days with daylight saving transitions behave as expected.
+	offset := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+
+	for _, timeRange := range times {
+		if timeRange.IsMatch(offset) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,33 +1,115 @@
 package authorization
 
 import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/geoip"
 	"github.com/authelia/authelia/v4/internal/logging"
+	"github.com/authelia/authelia/v4/internal/utils"
 )
 
 // Authorizer the component in charge of checking whether a user can access a given resource.
 type Authorizer struct {
+	accessControl atomic.Value
+	configuration *schema.Configuration
+	clock         utils.Clock
+}
+
+// accessControl is the parsed access control configuration. It is never modified once it's stored in the Authorizer,
+// instead it is replaced as a whole so concurrent requests never evaluate a partially updated list of rules.
+type accessControl struct {
 	defaultPolicy Level
 	rules         []*AccessControlRule
-	configuration *schema.Configuration
+	index         *accessControlIndex
+	geoip         geoip.Provider
+
+	// headers are the lowercase names of the headers of the rules.
+	headers map[string]bool
+
+	// location is true if one of the rules requires the location of the subject.
+	location bool
 }
 
 // NewAuthorizer create an instance of authorizer with a given access control configuration.
-func NewAuthorizer(configuration *schema.Configuration) *Authorizer {
-	return &Authorizer{
-		defaultPolicy: PolicyToLevel(configuration.AccessControl.DefaultPolicy),
-		rules:         NewAccessControlRules(configuration.AccessControl),
+func NewAuthorizer(configuration *schema.Configuration, clock utils.Clock) *Authorizer {
+	authorizer := &Authorizer{
 		configuration: configuration,
+		clock:         clock,
+	}
+
+	authorizer.SetAccessControl(configuration.AccessControl, nil)
+
+	return authorizer
+}
+
+// SetAccessControl atomically replaces the access control default policy and rules, and the provider used to lookup
+// the location of the subject for the rules with country or asn criteria which may be nil. The configuration must be
+// validated before it's provided to this function.
+func (p *Authorizer) SetAccessControl(config schema.AccessControlConfiguration, provider geoip.Provider) {
+	rules := NewAccessControlRules(config)
+
+	p.accessControl.Store(&accessControl{
+		defaultPolicy: PolicyToLevel(config.DefaultPolicy),
+		rules:         rules,
+		index:         newAccessControlIndex(rules),
+		geoip:         provider,
+		headers:       ruleHeaderNames(rules),
+		location:      hasLocationCriteria(rules),
+	})
+}
+
+// GeoIP returns the provider currently used to lookup the location of the subject, it's nil if there is none.
+func (p *Authorizer) GeoIP() geoip.Provider {
+	return p.load().geoip
+}
+
+// lookupLocation returns the subject with the country and asn of its IP if one of the rules requires them.
+func (p *Authorizer) lookupLocation(ac *accessControl, subject Subject) Subject {
+	if !ac.location || ac.geoip == nil || subject.IP == nil || subject.Country != "" || subject.ASN != 0 {
+		return subject
+	}
+
+	record, err := ac.geoip.Lookup(subject.IP)
+
+	// The provider is closed once the access control is reloaded with another provider, which is used instead.
+	if errors.Is(err, geoip.ErrClosed) {
+		if current := p.load(); current != ac && current.geoip != nil {
+			record, err = current.geoip.Lookup(subject.IP)
+		}
 	}
+
+	if err != nil {
+		logging.Logger().Errorf("Unable to lookup the location of subject %s: %v", subject.String(), err)
+
+		return subject
+	}
+
+	subject.Country, subject.ASN = record.Country, record.ASN
+
+	return subject
+}
+
+// IsRuleHeader returns true if the header is set by one of the rules.
+func (p *Authorizer) IsRuleHeader(name string) bool {
+	return p.load().headers[strings.ToLower(name)]
+}
+
+func (p *Authorizer) load() *accessControl {
+	return p.accessControl.Load().(*accessControl)
 }
 
 // IsSecondFactorEnabled return true if at least one policy is set to second factor.
-func (p Authorizer) IsSecondFactorEnabled() bool {
-	if p.defaultPolicy == TwoFactor {
+func (p *Authorizer) IsSecondFactorEnabled() bool {
+	ac := p.load()
+
+	if ac.defaultPolicy == TwoFactor {
 		return true
 	}
 
-	for _, rule := range p.rules {
+	for _, rule := range ac.rules {
 		if rule.Policy == TwoFactor {
 			return true
 		}
@@ -45,24 +127,98 @@ func (p Authorizer) IsSecondFactorEnabled() bool {
 }
 
 // GetRequiredLevel retrieve the required level of authorization to access the object.
-func (p Authorizer) GetRequiredLevel(subject Subject, object Object) Level {
+func (p *Authorizer) GetRequiredLevel(subject Subject, object Object) Level {
+	return p.GetRequirements(subject, object).Level
+}
+
+// GetRequirements retrieve the required level of authorization, the maximum authentication age and the headers of the
+// rule granting access to the object.
+func (p *Authorizer) GetRequirements(subject Subject, object Object) (requirements Requirements) {
 	logger := logging.Logger()
 
+	ac := p.load()
+
+	subject = p.lookupLocation(ac, subject)
+
 	logger.Debugf("Check authorization of subject %s and object %s (method %s).",
 		subject.String(), object.String(), object.Method)
 
-	for _, rule := range p.rules {
+	// Only the rules which may match the domain of the object are evaluated, in the same order as they are configured.
+	for _, i := range ac.index.Candidates(object.Domain) {
+		rule := ac.rules[i]
+
 		if rule.IsMatch(subject, object) {
 			logger.Tracef(traceFmtACLHitMiss, "HIT", rule.Position, subject.String(), object.String(), object.Method)
 
-			return rule.Policy
+			if !rule.IsActive(p.clock.Now()) {
+				// The anonymous users are asked to log in, access is only denied once the subject is known.
+				if subject.IsAnonymous() {
+					logger.Debugf(debugFmtACLOutsideScheduleAnonymous, rule.Position, subject.String(), object.String())
+
+					return Requirements{Level: OneFactor}
+				}
+
+				logger.Debugf(debugFmtACLOutsideSchedule, rule.Position, subject.String(), object.String())
+
+				return Requirements{Level: Denied}
+			}
+
+			requirements = Requirements{Level: rule.Policy, MaxAuthenticationAge: rule.MaxAuthenticationAge}
+
+			if len(rule.Headers) != 0 {
+				requirements.Headers, requirements.Captures = rule.Headers, rule.Captures(object)
+			}
+
+			return requirements
 		}
 
 		logger.Tracef(traceFmtACLHitMiss, "MISS", rule.Position, subject.String(), object.String(), object.Method)
 	}
 
+	// The access tokens are only accepted by the rules allowing their client.
+	if subject.ClientID != "" && ac.defaultPolicy != Bypass {
+		logger.Debugf(debugFmtACLNoRuleForClient, subject.ClientID, subject.String(), object.String())
+
+		return Requirements{Level: Denied}
+	}
+
 	logger.Debugf("No matching rule for subject %s and url %s... Applying default policy.",
 		subject.String(), object.String())
 
-	return p.defaultPolicy
+	return Requirements{Level: ac.defaultPolicy}
+}
+
+// GetRuleMatchResults returns the match results of each rule for the subject and object. Rules after the first rule
+// that matches are marked as skipped as they would not be evaluated by GetRequiredLevel.
+func (p *Authorizer) GetRuleMatchResults(subject Subject, object Object) (results []RuleMatchResult) {
+	skipped := false
+	now := p.clock.Now()
+
+	ac := p.load()
+
+	subject = p.lookupLocation(ac, subject)
+
+	results = make([]RuleMatchResult, len(ac.rules))
+
+	for i, rule := range ac.rules {
+		results[i] = RuleMatchResult{
+			Rule:    rule,
+			Skipped: skipped,
+
+			MatchDomain:    isMatchForDomains(subject, object, rule),
+			MatchResources: isMatchForResources(subject, object, rule),
+			MatchMethods:   isMatchForMethods(object, rule),
+			MatchNetworks:  isMatchForNetworks(subject, rule),
+			MatchLocation:  isMatchForLocation(subject, rule),
+			MatchSubjects:  isMatchForSubjects(subject, rule),
+			MatchClient:    isMatchForClient(subject, rule),
+			MatchSchedule:  rule.IsActive(now),
+		}
+
+		if results[i].IsMatch() {
+			skipped = true
+		}
+	}
+
+	return results
 }
@@ -3,13 +3,17 @@ package authorization
 import (
 	"net"
 	"net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/geoip"
+	"github.com/authelia/authelia/v4/internal/utils"
 )
 
 type AuthorizerSuite struct {
@@ -20,13 +24,13 @@ type AuthorizerTester struct {
 	*Authorizer
 }
 
-func NewAuthorizerTester(config schema.AccessControlConfiguration) *AuthorizerTester {
+func NewAuthorizerTester(config schema.AccessControlConfiguration, clock utils.Clock) *AuthorizerTester {
 	fullConfig := &schema.Configuration{
 		AccessControl: config,
 	}
 
 	return &AuthorizerTester{
-		NewAuthorizer(fullConfig),
+		NewAuthorizer(fullConfig, clock),
 	}
 }
 
@@ -47,10 +51,13 @@ func (s *AuthorizerTester) CheckAuthorizations(t *testing.T, subject Subject, re
 
 type AuthorizerTesterBuilder struct {
 	config schema.AccessControlConfiguration
+	clock  utils.Clock
 }
 
 func NewAuthorizerBuilder() *AuthorizerTesterBuilder {
-	return &AuthorizerTesterBuilder{}
+	return &AuthorizerTesterBuilder{
+		clock: utils.RealClock{},
+	}
 }
 
 func (b *AuthorizerTesterBuilder) WithDefaultPolicy(policy string) *AuthorizerTesterBuilder {
@@ -63,8 +70,49 @@ func (b *AuthorizerTesterBuilder) WithRule(rule schema.ACLRule) *AuthorizerTeste
 	return b
 }
 
+func (b *AuthorizerTesterBuilder) WithClock(clock utils.Clock) *AuthorizerTesterBuilder {
+	b.clock = clock
+	return b
+}
+
 func (b *AuthorizerTesterBuilder) Build() *AuthorizerTester {
-	return NewAuthorizerTester(b.config)
+	return NewAuthorizerTester(b.config, b.clock)
+}
+
+type FakeGeoIP map[string]geoip.Record
+
+func (g FakeGeoIP) Lookup(ip net.IP) (record geoip.Record, err error) {
+	return g[ip.String()], nil
+}
+
+// ReloadedGeoIP is a provider which is closed by a reload of the access control with the next provider during its
+// first lookup.
+type ReloadedGeoIP struct {
+	authorizer *Authorizer
+	config     schema.AccessControlConfiguration
+	next       geoip.Provider
+}
+
+func (g ReloadedGeoIP) Lookup(_ net.IP) (record geoip.Record, err error) {
+	g.authorizer.SetAccessControl(g.config, g.next)
+
+	return record, geoip.ErrClosed
+}
+
+type FixedClock struct {
+	now time.Time
+}
+
+func (c *FixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c *FixedClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (c *FixedClock) Set(now time.Time) {
+	c.now = now
 }
 
 var AnonymousUser = Subject{
@@ -161,6 +209,63 @@ func (s *AuthorizerSuite) TestShouldCheckDynamicDomainRules() {
 	tester.CheckAuthorizations(s.T(), UserWithGroups, "https://othergroup.example.com/", "GET", Denied)
 }
 
+func (s *AuthorizerSuite) TestShouldCheckDomainRegexRules() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(deny).
+		WithRule(schema.ACLRule{
+			DomainsRegex: []string{`^(?P<User>\w+)\.home\.example\.com$`},
+			Policy:       oneFactor,
+		}).
+		WithRule(schema.ACLRule{
+			DomainsRegex: []string{`^(?P<Group>\w+)\.groups\.example\.com$`},
+			Policy:       twoFactor,
+		}).
+		WithRule(schema.ACLRule{
+			DomainsRegex: []string{`^(api|app)-\d+\.example\.com$`},
+			Policy:       bypass,
+		}).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), John, "https://john.home.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://JOHN.home.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://bob.home.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), Bob, "https://bob.home.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://bob.home.example.com/", "GET", OneFactor)
+
+	tester.CheckAuthorizations(s.T(), John, "https://dev.groups.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://admins.groups.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://ops.groups.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), Bob, "https://dev.groups.example.com/", "GET", Denied)
+
+	tester.CheckAuthorizations(s.T(), John, "https://api-1.example.com/", "GET", Bypass)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app-20.example.com/", "GET", Bypass)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://app.example.com/", "GET", Denied)
+}
+
+func (s *AuthorizerSuite) TestShouldCheckResourceNamedSubexpressionRules() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(deny).
+		WithRule(schema.ACLRule{
+			Domains:   []string{"files.example.com"},
+			Resources: []string{`^/users/(?P<User>\w+)/`},
+			Policy:    oneFactor,
+		}).
+		WithRule(schema.ACLRule{
+			Domains:   []string{"files.example.com"},
+			Resources: []string{`^/groups/(?P<Group>\w+)/`},
+			Policy:    twoFactor,
+		}).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), John, "https://files.example.com/users/john/file.txt", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://files.example.com/users/bob/file.txt", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), Bob, "https://files.example.com/users/bob/file.txt", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://files.example.com/users/bob/file.txt", "GET", OneFactor)
+
+	tester.CheckAuthorizations(s.T(), John, "https://files.example.com/groups/dev/file.txt", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), Bob, "https://files.example.com/groups/dev/file.txt", "GET", Denied)
+}
+
 func (s *AuthorizerSuite) TestShouldCheckMultipleDomainRule() {
 	tester := NewAuthorizerBuilder().
 		WithDefaultPolicy(deny).
@@ -483,6 +588,468 @@ func (s *AuthorizerSuite) TestShouldMatchResourceWithSubjectRules() {
 	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://private.example.com", "GET", TwoFactor)
 }
 
+func (s *AuthorizerSuite) TestShouldReturnMaxAuthenticationAgeRequirements() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(oneFactor).
+		WithRule(schema.ACLRule{
+			Domains: []string{"admin.example.com"},
+			Policy:  twoFactor,
+			MaxAuthenticationAge: &schema.ACLMaxAuthenticationAge{
+				OneFactor: "1d",
+				TwoFactor: "10m",
+			},
+		}).
+		WithRule(schema.ACLRule{
+			Domains: []string{"public.example.com"},
+			Policy:  bypass,
+		}).
+		Build()
+
+	requirements := tester.GetRequirements(John, Object{Scheme: "https", Domain: "admin.example.com", Path: "/"})
+	s.Assert().Equal(TwoFactor, requirements.Level)
+	s.Assert().Equal(24*time.Hour, requirements.MaxAuthenticationAge.OneFactor)
+	s.Assert().Equal(10*time.Minute, requirements.MaxAuthenticationAge.TwoFactor)
+	s.Assert().False(requirements.MaxAuthenticationAge.IsZero())
+
+	requirements = tester.GetRequirements(John, Object{Scheme: "https", Domain: "public.example.com", Path: "/"})
+	s.Assert().Equal(Bypass, requirements.Level)
+	s.Assert().True(requirements.MaxAuthenticationAge.IsZero())
+
+	requirements = tester.GetRequirements(John, Object{Scheme: "https", Domain: "example.com", Path: "/"})
+	s.Assert().Equal(OneFactor, requirements.Level)
+	s.Assert().True(requirements.MaxAuthenticationAge.IsZero())
+}
+
+func (s *AuthorizerSuite) TestShouldReturnHeadersRequirements() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(oneFactor).
+		WithRule(schema.ACLRule{
+			DomainsRegex: []string{`^(?P<tenant>[a-z]+)\.apps\.example\.com$`},
+			Resources:    []string{`^/(?P<section>[a-z]+)/`, `^/(?P<section>[a-z]+)/(?P<page>[a-z]+)$`},
+			Policy:       twoFactor,
+			Headers: []schema.ACLHeader{
+				{Name: "X-Tenant", Value: "{{ .Captures.tenant }}"},
+			},
+		}).
+		Build()
+
+	requirements := tester.GetRequirements(John, Object{Scheme: "https", Domain: "acme.apps.example.com", Path: "/billing/invoices"})
+	s.Assert().Equal(TwoFactor, requirements.Level)
+	s.Require().Len(requirements.Headers, 1)
+	s.Assert().Equal("X-Tenant", requirements.Headers[0].Name)
+	s.Assert().Equal(map[string]string{"tenant": "acme", "section": "billing", "page": "invoices"}, requirements.Captures)
+
+	value, err := requirements.Headers[0].Render(HeaderTemplateData{Captures: requirements.Captures})
+	s.Require().NoError(err)
+	s.Assert().Equal("acme", value)
+
+	requirements = tester.GetRequirements(John, Object{Scheme: "https", Domain: "example.com", Path: "/"})
+	s.Assert().Equal(OneFactor, requirements.Level)
+	s.Assert().Len(requirements.Headers, 0)
+	s.Assert().Nil(requirements.Captures)
+}
+
+func (s *AuthorizerSuite) TestShouldCheckLocationRules() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: deny,
+		Networks: []schema.ACLNetwork{
+			{
+				Name:      "eu",
+				Networks:  []string{"10.0.0.0/8"},
+				Countries: []string{"fr", "DE"},
+			},
+			{
+				Name: "hosting",
+				ASNs: []uint{64500},
+			},
+		},
+		Rules: []schema.ACLRule{
+			{
+				Domains:  []string{"admin.example.com"},
+				Policy:   twoFactor,
+				Networks: []string{"eu"},
+			},
+			{
+				Domains: []string{"admin.example.com"},
+				Policy:  deny,
+			},
+			{
+				Domains:   []string{"app.example.com"},
+				Policy:    oneFactor,
+				Countries: []string{"FR"},
+				ASNs:      []uint{64496},
+			},
+			{
+				Domains:  []string{"app.example.com"},
+				Policy:   deny,
+				Networks: []string{"hosting"},
+			},
+			{
+				Domains: []string{"app.example.com"},
+				Policy:  twoFactor,
+			},
+		},
+	}, utils.RealClock{})
+
+	tester.SetAccessControl(tester.configuration.AccessControl, FakeGeoIP{
+		"192.168.1.1": {Country: "FR", ASN: 64496},
+		"192.168.1.2": {Country: "DE", ASN: 64500},
+		"192.168.1.3": {Country: "US", ASN: 64496},
+	})
+
+	subject := func(ip string) Subject {
+		return Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP(ip)}
+	}
+
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.1"), "https://admin.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.2"), "https://admin.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), subject("10.0.0.1"), "https://admin.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.3"), "https://admin.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.4"), "https://admin.example.com/", "GET", Denied)
+
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.1"), "https://app.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.2"), "https://app.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.3"), "https://app.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), subject("192.168.1.4"), "https://app.example.com/", "GET", TwoFactor)
+
+	results := tester.GetRuleMatchResults(subject("192.168.1.3"), Object{Scheme: "https", Domain: "app.example.com", Path: "/"})
+
+	s.Require().Len(results, 5)
+	s.Assert().False(results[2].MatchLocation)
+	s.Assert().True(results[2].MatchNetworks)
+	s.Assert().False(results[3].MatchNetworks)
+	s.Assert().True(results[4].IsMatch())
+}
+
+func (s *AuthorizerSuite) TestShouldLookupLocationWithReloadedProvider() {
+	tester := NewAuthorizerTester(schema.AccessControlConfiguration{
+		DefaultPolicy: twoFactor,
+		Rules: []schema.ACLRule{
+			{
+				Domains:   []string{"admin.example.com"},
+				Policy:    deny,
+				Countries: []string{"FR"},
+			},
+		},
+	}, utils.RealClock{})
+
+	tester.SetAccessControl(tester.configuration.AccessControl, ReloadedGeoIP{
+		authorizer: tester.Authorizer,
+		config:     tester.configuration.AccessControl,
+		next:       FakeGeoIP{"192.168.1.1": {Country: "FR"}},
+	})
+
+	tester.CheckAuthorizations(s.T(), Subject{Username: "john", IP: net.ParseIP("192.168.1.1")}, "https://admin.example.com/", "GET", Denied)
+}
+
+func (s *AuthorizerSuite) TestShouldCheckScheduleRules() {
+	clock := &FixedClock{}
+
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(deny).
+		WithClock(clock).
+		WithRule(schema.ACLRule{
+			Domains:  []string{"admin.example.com"},
+			Policy:   oneFactor,
+			Subjects: [][]string{{"user:john"}},
+			Schedule: &schema.ACLSchedule{
+				Timezone: "Europe/Paris",
+				Weekdays: []string{"monday", "tue", "Wednesday", "thu", "fri"},
+				Times:    []string{"09:00-12:00", "13:00-18:00"},
+			},
+		}).
+		WithRule(schema.ACLRule{
+			Domains: []string{"admin.example.com"},
+			Policy:  oneFactor,
+		}).
+		WithRule(schema.ACLRule{
+			Domains: []string{"night.example.com"},
+			Policy:  twoFactor,
+			Schedule: &schema.ACLSchedule{
+				Timezone: "UTC",
+				Times:    []string{"22:00-06:00"},
+			},
+		}).
+		WithRule(schema.ACLRule{
+			Domains: []string{"contract.example.com"},
+			Policy:  twoFactor,
+			Schedule: &schema.ACLSchedule{
+				Timezone: "UTC",
+				Start:    "2021-01-01",
+				End:      "2021-06-30",
+			},
+		}).
+		Build()
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	s.Require().NoError(err)
+
+	// Monday 10:30 Paris time.
+	clock.Set(time.Date(2021, time.August, 2, 10, 30, 0, 0, paris))
+	tester.CheckAuthorizations(s.T(), John, "https://admin.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://admin.example.com/", "GET", OneFactor)
+
+	// Monday 12:30 Paris time (lunch break), John matches the subject so is denied instead of falling through.
+	clock.Set(time.Date(2021, time.August, 2, 12, 30, 0, 0, paris))
+	tester.CheckAuthorizations(s.T(), John, "https://admin.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), Bob, "https://admin.example.com/", "GET", OneFactor)
+
+	// Monday 08:30 UTC is 10:30 Paris time.
+	clock.Set(time.Date(2021, time.August, 2, 8, 30, 0, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://admin.example.com/", "GET", OneFactor)
+
+	// Saturday 10:30 Paris time.
+	clock.Set(time.Date(2021, time.August, 7, 10, 30, 0, 0, paris))
+	tester.CheckAuthorizations(s.T(), John, "https://admin.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), Bob, "https://admin.example.com/", "GET", OneFactor)
+
+	// Time ranges spanning midnight.
+	clock.Set(time.Date(2021, time.August, 7, 23, 0, 0, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://night.example.com/", "GET", TwoFactor)
+
+	clock.Set(time.Date(2021, time.August, 8, 5, 59, 59, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://night.example.com/", "GET", TwoFactor)
+
+	clock.Set(time.Date(2021, time.August, 8, 6, 0, 0, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://night.example.com/", "GET", Denied)
+
+	// The anonymous users are asked to log in instead of being denied outside of the schedule.
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://night.example.com/", "GET", OneFactor)
+
+	// Absolute dates with an inclusive end date.
+	clock.Set(time.Date(2020, time.December, 31, 23, 59, 59, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://contract.example.com/", "GET", Denied)
+
+	clock.Set(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://contract.example.com/", "GET", TwoFactor)
+
+	clock.Set(time.Date(2021, time.June, 30, 23, 59, 59, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://contract.example.com/", "GET", TwoFactor)
+
+	clock.Set(time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC))
+	tester.CheckAuthorizations(s.T(), John, "https://contract.example.com/", "GET", Denied)
+}
+
+func (s *AuthorizerSuite) TestShouldCheckClientRules() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(deny).
+		WithRule(schema.ACLRule{
+			Domains:   []string{"api.example.com"},
+			Resources: []string{"^/write$"},
+			Policy:    oneFactor,
+			ClientIDs: []string{"cli"},
+			Scopes:    []string{"api:read", "api:write"},
+		}).
+		WithRule(schema.ACLRule{
+			Domains:   []string{"api.example.com"},
+			Policy:    oneFactor,
+			ClientIDs: []string{"cli"},
+			Scopes:    []string{"api:read"},
+		}).
+		WithRule(schema.ACLRule{
+			Domains:   []string{"admin.example.com"},
+			Policy:    twoFactor,
+			ClientIDs: []string{"cli", "dashboard"},
+		}).
+		WithRule(schema.ACLRule{
+			Domains: []string{"admin.example.com"},
+			Policy:  oneFactor,
+		}).
+		WithRule(schema.ACLRule{
+			Domains: []string{"public.example.com"},
+			Policy:  bypass,
+		}).
+		Build()
+
+	client := func(id string, scopes ...string) Subject {
+		return Subject{Username: "john", Groups: []string{"dev"}, IP: net.ParseIP("10.0.0.8"), ClientID: id, Scopes: scopes}
+	}
+
+	tester.CheckAuthorizations(s.T(), client("cli", "api:read"), "https://api.example.com/read", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), client("cli", "api:read"), "https://api.example.com/write", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), client("cli", "api:read", "api:write"), "https://api.example.com/write", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), client("cli", "openid"), "https://api.example.com/read", "GET", Denied)
+
+	// Requests authenticated with a session have no client so never match client criteria.
+	tester.CheckAuthorizations(s.T(), John, "https://api.example.com/read", "GET", Denied)
+
+	// Anonymous users match the client criteria so they're asked to authenticate.
+	tester.CheckAuthorizations(s.T(), AnonymousUser, "https://api.example.com/read", "GET", OneFactor)
+
+	tester.CheckAuthorizations(s.T(), client("dashboard"), "https://admin.example.com/", "GET", TwoFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://admin.example.com/", "GET", OneFactor)
+
+	// Access tokens are only accepted by the rules allowing their client, or the rules bypassing authentication.
+	tester.CheckAuthorizations(s.T(), client("other"), "https://admin.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), client("other"), "https://public.example.com/", "GET", Bypass)
+	tester.CheckAuthorizations(s.T(), client("dashboard"), "https://unknown.example.com/", "GET", Denied)
+
+	results := tester.GetRuleMatchResults(client("cli", "api:read"), Object{Scheme: "https", Domain: "api.example.com", Path: "/write"})
+
+	s.Require().Len(results, 5)
+	s.Assert().False(results[0].MatchClient)
+	s.Assert().False(results[0].IsMatch())
+	s.Assert().True(results[1].MatchClient)
+	s.Assert().True(results[1].IsMatch())
+}
+
+func (s *AuthorizerSuite) TestShouldNotApplyDefaultPolicyToClients() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(oneFactor).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), Subject{Username: "john", ClientID: "cli"}, "https://app.example.com/", "GET", Denied)
+	tester.CheckAuthorizations(s.T(), John, "https://app.example.com/", "GET", OneFactor)
+
+	tester = NewAuthorizerBuilder().
+		WithDefaultPolicy(bypass).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), Subject{Username: "john", ClientID: "cli"}, "https://app.example.com/", "GET", Bypass)
+}
+
+func (s *AuthorizerSuite) TestShouldGetRuleMatchResults() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(deny).
+		WithRule(schema.ACLRule{
+			Domains: []string{"public.example.com"},
+			Policy:  bypass,
+		}).
+		WithRule(schema.ACLRule{
+			Domains:  []string{"*.example.com"},
+			Subjects: [][]string{{"group:admins"}},
+			Policy:   twoFactor,
+		}).
+		WithRule(schema.ACLRule{
+			Domains:  []string{"secure.example.com"},
+			Networks: []string{"10.0.0.0/8"},
+			Methods:  []string{"GET"},
+			Policy:   oneFactor,
+		}).
+		Build()
+
+	object := Object{Scheme: "https", Domain: "secure.example.com", Path: "/", Method: "GET"}
+
+	results := tester.GetRuleMatchResults(Bob, object)
+	s.Require().Len(results, 3)
+
+	s.Assert().False(results[0].MatchDomain)
+	s.Assert().False(results[0].IsMatch())
+	s.Assert().False(results[0].Skipped)
+
+	s.Assert().True(results[1].MatchDomain)
+	s.Assert().False(results[1].MatchSubjects)
+	s.Assert().False(results[1].IsMatch())
+	s.Assert().False(results[1].Skipped)
+
+	s.Assert().True(results[2].IsMatch())
+	s.Assert().False(results[2].Skipped)
+
+	results = tester.GetRuleMatchResults(AnonymousUser, object)
+	s.Require().Len(results, 3)
+
+	s.Assert().True(results[1].IsMatch())
+	s.Assert().True(results[1].IsPotentialMatch(AnonymousUser))
+	s.Assert().False(results[1].Skipped)
+
+	s.Assert().False(results[2].MatchNetworks)
+	s.Assert().True(results[2].Skipped)
+
+	results = tester.GetRuleMatchResults(John, object)
+	s.Require().Len(results, 3)
+
+	s.Assert().True(results[1].IsMatch())
+	s.Assert().False(results[1].IsPotentialMatch(John))
+	s.Assert().True(results[2].IsMatch())
+	s.Assert().True(results[2].Skipped)
+}
+
+func (s *AuthorizerSuite) TestShouldReplaceAccessControl() {
+	tester := NewAuthorizerBuilder().
+		WithDefaultPolicy(deny).
+		WithRule(schema.ACLRule{
+			Domains: []string{"public.example.com"},
+			Policy:  bypass,
+		}).
+		Build()
+
+	tester.CheckAuthorizations(s.T(), John, "https://public.example.com/", "GET", Bypass)
+	tester.CheckAuthorizations(s.T(), John, "https://secure.example.com/", "GET", Denied)
+	s.Assert().False(tester.IsSecondFactorEnabled())
+
+	tester.SetAccessControl(schema.AccessControlConfiguration{
+		DefaultPolicy: oneFactor,
+		Rules: []schema.ACLRule{
+			{
+				Domains: []string{"secure.example.com"},
+				Policy:  twoFactor,
+			},
+		},
+	}, nil)
+
+	tester.CheckAuthorizations(s.T(), John, "https://public.example.com/", "GET", OneFactor)
+	tester.CheckAuthorizations(s.T(), John, "https://secure.example.com/", "GET", TwoFactor)
+	s.Assert().True(tester.IsSecondFactorEnabled())
+}
+
+func (s *AuthorizerSuite) TestShouldReplaceAccessControlConcurrently() {
+	configs := []schema.AccessControlConfiguration{
+		{
+			DefaultPolicy: deny,
+			Rules: []schema.ACLRule{
+				{Domains: []string{"a.example.com"}, Policy: oneFactor},
+				{Domains: []string{"b.example.com"}, Policy: oneFactor},
+			},
+		},
+		{
+			DefaultPolicy: deny,
+			Rules: []schema.ACLRule{
+				{Domains: []string{"a.example.com"}, Policy: twoFactor},
+				{Domains: []string{"b.example.com"}, Policy: twoFactor},
+			},
+		},
+	}
+
+	tester := NewAuthorizerTester(configs[0], utils.RealClock{})
+
+	object := Object{Scheme: "https", Domain: "b.example.com", Path: "/", Method: "GET"}
+
+	wg := sync.WaitGroup{}
+	done := make(chan struct{})
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			tester.SetAccessControl(configs[i%2], nil)
+		}
+
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			wg.Wait()
+			return
+		default:
+			level := tester.GetRequiredLevel(John, object)
+			s.Require().True(level == OneFactor || level == TwoFactor, "unexpected level %d", level)
+		}
+	}
+}
+
+func (s *AuthorizerSuite) TestLevelToPolicy() {
+	s.Assert().Equal(bypass, LevelToPolicy(Bypass))
+	s.Assert().Equal(oneFactor, LevelToPolicy(OneFactor))
+	s.Assert().Equal(twoFactor, LevelToPolicy(TwoFactor))
+	s.Assert().Equal(deny, LevelToPolicy(Denied))
+
+	s.Assert().Equal(deny, LevelToPolicy(Level(99)))
+}
+
 func (s *AuthorizerSuite) TestPolicyToLevel() {
 	s.Assert().Equal(Bypass, PolicyToLevel(bypass))
 	s.Assert().Equal(OneFactor, PolicyToLevel(oneFactor))
@@ -518,16 +1085,16 @@ func TestNewAuthorizer(t *testing.T) {
 		},
 	}
 
-	authorizer := NewAuthorizer(config)
+	authorizer := NewAuthorizer(config, utils.RealClock{})
 
-	assert.Equal(t, Denied, authorizer.defaultPolicy)
-	assert.Equal(t, TwoFactor, authorizer.rules[0].Policy)
+	assert.Equal(t, Denied, authorizer.load().defaultPolicy)
+	assert.Equal(t, TwoFactor, authorizer.load().rules[0].Policy)
 
-	user, ok := authorizer.rules[0].Subjects[0].Subjects[0].(AccessControlUser)
+	user, ok := authorizer.load().rules[0].Subjects[0].Subjects[0].(AccessControlUser)
 	require.True(t, ok)
 	assert.Equal(t, "admin", user.Name)
 
-	group, ok := authorizer.rules[0].Subjects[1].Subjects[0].(AccessControlGroup)
+	group, ok := authorizer.load().rules[0].Subjects[1].Subjects[0].(AccessControlGroup)
 	require.True(t, ok)
 	assert.Equal(t, "admins", group.Name)
 }
@@ -545,10 +1112,10 @@ func TestAuthorizerIsSecondFactorEnabledRuleWithNoOIDC(t *testing.T) {
 		},
 	}
 
-	authorizer := NewAuthorizer(config)
+	authorizer := NewAuthorizer(config, utils.RealClock{})
 	assert.False(t, authorizer.IsSecondFactorEnabled())
 
-	authorizer.rules[0].Policy = TwoFactor
+	authorizer.load().rules[0].Policy = TwoFactor
 	assert.True(t, authorizer.IsSecondFactorEnabled())
 }
 
@@ -574,25 +1141,25 @@ func TestAuthorizerIsSecondFactorEnabledRuleWithOIDC(t *testing.T) {
 		},
 	}
 
-	authorizer := NewAuthorizer(config)
+	authorizer := NewAuthorizer(config, utils.RealClock{})
 	assert.False(t, authorizer.IsSecondFactorEnabled())
 
-	authorizer.rules[0].Policy = TwoFactor
+	authorizer.load().rules[0].Policy = TwoFactor
 	assert.True(t, authorizer.IsSecondFactorEnabled())
 
-	authorizer.rules[0].Policy = OneFactor
+	authorizer.load().rules[0].Policy = OneFactor
 	assert.False(t, authorizer.IsSecondFactorEnabled())
 
 	config.IdentityProviders.OIDC.Clients[0].Policy = twoFactor
 
 	assert.True(t, authorizer.IsSecondFactorEnabled())
 
-	authorizer.rules[0].Policy = OneFactor
+	authorizer.load().rules[0].Policy = OneFactor
 	config.IdentityProviders.OIDC.Clients[0].Policy = oneFactor
 
 	assert.False(t, authorizer.IsSecondFactorEnabled())
 
-	authorizer.defaultPolicy = TwoFactor
+	authorizer.load().defaultPolicy = TwoFactor
 
 	assert.True(t, authorizer.IsSecondFactorEnabled())
 }
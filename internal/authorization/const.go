@@ -17,9 +17,18 @@ const (
 const userPrefix = "user:"
 const groupPrefix = "group:"
 
+// Named subexpressions of domain and resource patterns which are compared to the subject.
+const subexpNameUser = "User"
+const subexpNameGroup = "Group"
+
 const bypass = "bypass"
 const oneFactor = "one_factor"
 const twoFactor = "two_factor"
 const deny = "deny"
 
 const traceFmtACLHitMiss = "ACL %s Position %d for subject %s and object %s (Method %s)"
+
+const debugFmtACLOutsideSchedule = "ACL Position %d for subject %s and object %s is outside of the rule schedule, denying access"
+const debugFmtACLOutsideScheduleAnonymous = "ACL Position %d for subject %s and object %s is outside of the rule schedule, requiring authentication"
+
+const debugFmtACLNoRuleForClient = "No ACL allows the client %s of the access token of subject %s for object %s, denying access"
@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Subject represents the identity of a user for the purposes of ACL matching.
@@ -12,6 +13,14 @@ type Subject struct {
 	Username string
 	Groups   []string
 	IP       net.IP
+
+	// Country and ASN are the location of the IP. They're looked up by the Authorizer when a rule requires them.
+	Country string
+	ASN     uint
+
+	// ClientID and Scopes are the OpenID Connect client and granted scopes of an access token used as credentials.
+	ClientID string
+	Scopes   []string
 }
 
 // String returns a string representation of the Subject.
@@ -58,3 +67,54 @@ func NewObject(targetURL *url.URL, method string) (object Object) {
 
 	return object
 }
+
+// Requirements represents what a subject must satisfy to access an object.
+type Requirements struct {
+	Level                Level
+	MaxAuthenticationAge MaxAuthenticationAge
+
+	// Headers are the headers of the matching rule and Captures the named subexpressions of its patterns.
+	Headers  []AccessControlHeader
+	Captures map[string]string
+}
+
+// MaxAuthenticationAge represents the maximum time since each authentication factor was performed. A zero value means
+// the factor has no maximum age.
+type MaxAuthenticationAge struct {
+	OneFactor time.Duration
+	TwoFactor time.Duration
+}
+
+// IsZero returns true if neither authentication factor has a maximum age.
+func (m MaxAuthenticationAge) IsZero() bool {
+	return m.OneFactor == 0 && m.TwoFactor == 0
+}
+
+// RuleMatchResult describes how each criteria of an AccessControlRule matched a Subject and Object.
+type RuleMatchResult struct {
+	Rule *AccessControlRule
+
+	Skipped bool
+
+	MatchDomain    bool
+	MatchResources bool
+	MatchMethods   bool
+	MatchNetworks  bool
+	MatchLocation  bool
+	MatchSubjects  bool
+	MatchClient    bool
+	MatchSchedule  bool
+}
+
+// IsMatch returns true if all criteria of the rule matched.
+func (r RuleMatchResult) IsMatch() (match bool) {
+	return r.MatchDomain && r.MatchResources && r.MatchMethods && r.MatchNetworks && r.MatchLocation && r.MatchSubjects &&
+		r.MatchClient
+}
+
+// IsPotentialMatch returns true if the rule is a match for an anonymous subject with subject or client criteria, i.e.
+// the rule may match once the subject is identified.
+func (r RuleMatchResult) IsPotentialMatch(subject Subject) (potential bool) {
+	return r.IsMatch() && subject.IsAnonymous() &&
+		(len(r.Rule.Subjects) != 0 || len(r.Rule.ClientIDs) != 0 || len(r.Rule.Scopes) != 0)
+}
@@ -4,9 +4,11 @@ import (
 	"net"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/utils"
 )
 
 // PolicyToLevel converts a string policy to int authorization level.
@@ -25,6 +27,20 @@ func PolicyToLevel(policy string) Level {
 	return Denied
 }
 
+// LevelToPolicy converts an int authorization level to a string policy.
+func LevelToPolicy(level Level) (policy string) {
+	switch level {
+	case Bypass:
+		return bypass
+	case OneFactor:
+		return oneFactor
+	case TwoFactor:
+		return twoFactor
+	}
+	// By default the deny policy applies.
+	return deny
+}
+
 func schemaSubjectToACLSubject(subjectRule string) (subject AccessControlSubject) {
 	if strings.HasPrefix(subjectRule, userPrefix) {
 		user := strings.Trim(subjectRule[len(userPrefix):], " ")
@@ -41,7 +57,7 @@ func schemaSubjectToACLSubject(subjectRule string) (subject AccessControlSubject
 	return nil
 }
 
-func schemaDomainsToACL(domainRules []string) (domains []AccessControlDomain) {
+func schemaDomainsToACL(domainRules, domainRegexRules []string) (domains []AccessControlDomain) {
 	for _, domainRule := range domainRules {
 		domain := AccessControlDomain{}
 
@@ -64,6 +80,10 @@ func schemaDomainsToACL(domainRules []string) (domains []AccessControlDomain) {
 		domains = append(domains, domain)
 	}
 
+	for _, domainRegexRule := range domainRegexRules {
+		domains = append(domains, AccessControlDomain{Pattern: regexp.MustCompile(domainRegexRule)})
+	}
+
 	return domains
 }
 
@@ -75,6 +95,19 @@ func schemaResourcesToACL(resourceRules []string) (resources []AccessControlReso
 	return resources
 }
 
+func schemaHeadersToACL(headerRules []schema.ACLHeader) (headers []AccessControlHeader) {
+	for _, headerRule := range headerRules {
+		value, err := NewHeaderTemplate(headerRule.Name, headerRule.Value)
+		if err != nil {
+			continue
+		}
+
+		headers = append(headers, AccessControlHeader{Name: headerRule.Name, Value: value})
+	}
+
+	return headers
+}
+
 func schemaMethodsToACL(methodRules []string) (methods []string) {
 	for _, method := range methodRules {
 		methods = append(methods, strings.ToUpper(method))
@@ -136,6 +169,75 @@ func parseSchemaNetworks(schemaNetworks []schema.ACLNetwork) (networksMap map[st
 	return networksMap, networksCacheMap
 }
 
+func parseSchemaNetworkLocations(schemaNetworks []schema.ACLNetwork) (locationsMap map[string]AccessControlLocation) {
+	locationsMap = map[string]AccessControlLocation{}
+
+	for _, aclNetwork := range schemaNetworks {
+		if len(aclNetwork.Countries) == 0 && len(aclNetwork.ASNs) == 0 {
+			continue
+		}
+
+		if _, ok := locationsMap[aclNetwork.Name]; !ok {
+			locationsMap[aclNetwork.Name] = AccessControlLocation{
+				Countries: schemaCountriesToACL(aclNetwork.Countries),
+				ASNs:      aclNetwork.ASNs,
+			}
+		}
+	}
+
+	return locationsMap
+}
+
+func schemaNetworkLocationsToACL(networkRules []string, locationsMap map[string]AccessControlLocation) (locations []AccessControlLocation) {
+	for _, network := range networkRules {
+		if location, ok := locationsMap[network]; ok {
+			locations = append(locations, location)
+		}
+	}
+
+	return locations
+}
+
+func schemaCountriesToACL(countryRules []string) (countries []string) {
+	for _, country := range countryRules {
+		countries = append(countries, strings.ToUpper(country))
+	}
+
+	return countries
+}
+
+func hasLocationCriteria(rules []*AccessControlRule) bool {
+	for _, rule := range rules {
+		if len(rule.Countries) != 0 || len(rule.ASNs) != 0 || len(rule.NetworkLocations) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ruleHeaderNames(rules []*AccessControlRule) (names map[string]bool) {
+	names = map[string]bool{}
+
+	for _, rule := range rules {
+		for _, header := range rule.Headers {
+			names[strings.ToLower(header.Name)] = true
+		}
+	}
+
+	return names
+}
+
+func isUintInSlice(needle uint, haystack []uint) (inSlice bool) {
+	for _, value := range haystack {
+		if value == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
 func parseNetwork(networkRule string) (cidr *net.IPNet, err error) {
 	if !strings.Contains(networkRule, "/") {
 		ip := net.ParseIP(networkRule)
@@ -167,6 +269,120 @@ func schemaSubjectsToACL(subjectRules [][]string) (subjects []AccessControlSubje
 	return subjects
 }
 
+func schemaScheduleToACL(scheduleRule *schema.ACLSchedule) (schedule *AccessControlSchedule) {
+	if scheduleRule == nil {
+		return nil
+	}
+
+	schedule = &AccessControlSchedule{
+		Location: time.Local,
+	}
+
+	if scheduleRule.Timezone != "" {
+		if location, err := time.LoadLocation(scheduleRule.Timezone); err == nil {
+			schedule.Location = location
+		}
+	}
+
+	for _, weekdayRule := range scheduleRule.Weekdays {
+		if weekday, err := utils.ParseWeekday(weekdayRule); err == nil {
+			schedule.Weekdays = append(schedule.Weekdays, weekday)
+		}
+	}
+
+	for _, timeRule := range scheduleRule.Times {
+		if start, end, err := utils.ParseTimeOfDayRange(timeRule); err == nil {
+			schedule.Times = append(schedule.Times, AccessControlTimeRange{Start: start, End: end})
+		}
+	}
+
+	if scheduleRule.Start != "" {
+		if start, _, err := utils.ParseDateTime(scheduleRule.Start, schedule.Location); err == nil {
+			schedule.Start = start
+		}
+	}
+
+	if scheduleRule.End != "" {
+		if end, dateOnly, err := utils.ParseDateTime(scheduleRule.End, schedule.Location); err == nil {
+			// A date without a time is inclusive of the whole day.
+			if dateOnly {
+				end = end.AddDate(0, 0, 1)
+			}
+
+			schedule.End = end
+		}
+	}
+
+	return schedule
+}
+
+func schemaMaxAuthenticationAgeToACL(maxAgeRule *schema.ACLMaxAuthenticationAge) (maxAge MaxAuthenticationAge) {
+	if maxAgeRule == nil {
+		return maxAge
+	}
+
+	if maxAgeRule.OneFactor != "" {
+		if duration, err := utils.ParseDurationString(maxAgeRule.OneFactor); err == nil {
+			maxAge.OneFactor = duration
+		}
+	}
+
+	if maxAgeRule.TwoFactor != "" {
+		if duration, err := utils.ParseDurationString(maxAgeRule.TwoFactor); err == nil {
+			maxAge.TwoFactor = duration
+		}
+	}
+
+	return maxAge
+}
+
+// isMatchForPattern returns true if the pattern matches the value and each of the User and Group named subexpressions
+// match the subject username or one of the subject groups. The named subexpressions are not compared for anonymous
+// subjects as their identity is not yet known, similar to the subject criteria.
+func isMatchForPattern(pattern *regexp.Regexp, value string, subject Subject) (match bool) {
+	matches := pattern.FindStringSubmatch(value)
+
+	if matches == nil {
+		return false
+	}
+
+	if subject.IsAnonymous() {
+		return true
+	}
+
+	for i, name := range pattern.SubexpNames() {
+		switch {
+		case i == 0 || name == "":
+			continue
+		case strings.EqualFold(name, subexpNameUser):
+			if !strings.EqualFold(matches[i], subject.Username) {
+				return false
+			}
+		case strings.EqualFold(name, subexpNameGroup):
+			if !utils.IsStringInSliceFold(matches[i], subject.Groups) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// addPatternCaptures adds the named subexpressions of the pattern to the captures if the pattern matches the value.
+func addPatternCaptures(captures map[string]string, pattern *regexp.Regexp, value string) {
+	matches := pattern.FindStringSubmatch(value)
+
+	if matches == nil {
+		return
+	}
+
+	for i, name := range pattern.SubexpNames() {
+		if i != 0 && name != "" {
+			captures[name] = matches[i]
+		}
+	}
+}
+
 func domainToPrefixSuffix(domain string) (prefix, suffix string) {
 	parts := strings.Split(domain, ".")
 
@@ -0,0 +1,292 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/v4/internal/authorization"
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/configuration/validator"
+	"github.com/authelia/authelia/v4/internal/geoip"
+	"github.com/authelia/authelia/v4/internal/logging"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// NewAccessControlCommand returns a new Access Control Cmd.
+func NewAccessControlCommand() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "access-control",
+		Short: "Helpers for the access control system",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newAccessControlCheckCommand(),
+	)
+
+	return cmd
+}
+
+func newAccessControlCheckCommand() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "check-policy",
+		Short:   "Checks a request against the access control rules to determine what policy would be applied",
+		Long:    accessControlPolicyCheckLong,
+		Example: accessControlPolicyCheckExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdAccessControlCheckRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	cmd.Flags().String("url", "", "the url of the object")
+	cmd.Flags().String("method", "GET", "the HTTP method of the object")
+	cmd.Flags().String("username", "", "the username of the subject")
+	cmd.Flags().StringSlice("groups", nil, "the groups of the subject")
+	cmd.Flags().String("ip", "", "the ip of the subject")
+	cmd.Flags().String("client-id", "", "the OpenID Connect client id of the access token of the subject")
+	cmd.Flags().StringSlice("scopes", nil, "the scopes granted to the access token of the subject")
+	cmd.Flags().Bool("json", false, "output the results as json")
+
+	return cmd
+}
+
+func cmdAccessControlCheckRun(cmd *cobra.Command, _ []string) {
+	logger := logging.Logger()
+
+	configs, _ := cmd.Flags().GetStringSlice("config")
+
+	accessControlConfig, err := loadAccessControlConfiguration(configs)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	subject, object, err := getSubjectAndObjectFromFlags(cmd)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	authorizer := authorization.NewAuthorizer(&schema.Configuration{AccessControl: *accessControlConfig}, utils.RealClock{})
+
+	if accessControlConfig.GeoIP != nil {
+		provider, err := geoip.NewMaxMindProvider(accessControlConfig.GeoIP)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		defer provider.Close()
+
+		authorizer.SetAccessControl(*accessControlConfig, provider)
+	}
+
+	results := authorizer.GetRuleMatchResults(subject, object)
+	level := authorizer.GetRequiredLevel(subject, object)
+
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if jsonOutput {
+		err = writeAccessControlCheckJSON(cmd, subject, object, results, level)
+	} else {
+		err = writeAccessControlCheckText(cmd, subject, object, results, level)
+	}
+
+	if err != nil {
+		logger.Fatalf("Error writing the results: %v", err)
+	}
+}
+
+func loadAccessControlConfiguration(configs []string) (accessControlConfig *schema.AccessControlConfiguration, err error) {
+	conf, err := loadConfiguration(configs, "access control", func(conf *schema.Configuration, val *schema.StructValidator) {
+		validator.ValidateAccessControl(&conf.AccessControl, val)
+		validator.ValidateRules(conf.AccessControl, val)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &conf.AccessControl, nil
+}
+
+func getSubjectAndObjectFromFlags(cmd *cobra.Command) (subject authorization.Subject, object authorization.Object, err error) {
+	rawURL, _ := cmd.Flags().GetString("url")
+	method, _ := cmd.Flags().GetString("method")
+	username, _ := cmd.Flags().GetString("username")
+	groups, _ := cmd.Flags().GetStringSlice("groups")
+	rawIP, _ := cmd.Flags().GetString("ip")
+	clientID, _ := cmd.Flags().GetString("client-id")
+	scopes, _ := cmd.Flags().GetStringSlice("scopes")
+
+	if rawURL == "" {
+		return subject, object, fmt.Errorf("the url flag is required")
+	}
+
+	targetURL, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return subject, object, fmt.Errorf("failed to parse the url: %w", err)
+	}
+
+	subject = authorization.Subject{
+		Username: username,
+		Groups:   groups,
+		ClientID: clientID,
+		Scopes:   scopes,
+	}
+
+	if rawIP != "" {
+		if subject.IP = net.ParseIP(rawIP); subject.IP == nil {
+			return subject, object, fmt.Errorf("failed to parse the ip '%s'", rawIP)
+		}
+	}
+
+	return subject, authorization.NewObject(targetURL, strings.ToUpper(method)), nil
+}
+
+func writeAccessControlCheckText(cmd *cobra.Command, subject authorization.Subject, object authorization.Object, results []authorization.RuleMatchResult, level authorization.Level) (err error) {
+	out := cmd.OutOrStdout()
+
+	fmt.Fprintf(out, "Performing policy check for request to '%s' method '%s' by subject '%s'.\n\n", object.String(), object.Method, subject.String())
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "  #\tDomain\tResources\tMethods\tNetworks\tLocation\tSubjects\tClient\tSchedule\tPolicy\tResult")
+
+	potential := false
+
+	for _, result := range results {
+		var status string
+
+		switch {
+		case result.Skipped && result.IsMatch():
+			status = "match (skipped)"
+		case result.Skipped:
+			status = "skipped"
+		case result.IsPotentialMatch(subject):
+			status = "potential match"
+			potential = true
+		case result.IsMatch():
+			status = "match"
+		default:
+			status = "miss"
+		}
+
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", result.Rule.Position,
+			hitMiss(result.MatchDomain), hitMiss(result.MatchResources), hitMiss(result.MatchMethods),
+			hitMiss(result.MatchNetworks), hitMiss(result.MatchLocation), hitMiss(result.MatchSubjects), hitMiss(result.MatchClient),
+			activeInactive(result.MatchSchedule),
+			authorization.LevelToPolicy(result.Rule.Policy), status)
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nThe policy '%s' will be applied to this request.\n", authorization.LevelToPolicy(level))
+
+	if potential {
+		fmt.Fprintln(out, "The subject is anonymous, a rule with subject or client criteria was matched so the subject will be asked to authenticate and the rules will be evaluated again.")
+	}
+
+	return nil
+}
+
+func hitMiss(match bool) string {
+	if match {
+		return "hit"
+	}
+
+	return "miss"
+}
+
+func activeInactive(active bool) string {
+	if active {
+		return "active"
+	}
+
+	return "inactive"
+}
+
+type accessControlCheckJSON struct {
+	Subject accessControlCheckSubjectJSON `json:"subject"`
+	Object  accessControlCheckObjectJSON  `json:"object"`
+	Rules   []accessControlCheckRuleJSON  `json:"rules"`
+	Policy  string                        `json:"policy"`
+}
+
+type accessControlCheckSubjectJSON struct {
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+	IP       string   `json:"ip"`
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+type accessControlCheckObjectJSON struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+}
+
+type accessControlCheckRuleJSON struct {
+	Position  int    `json:"position"`
+	Domain    bool   `json:"domain"`
+	Resources bool   `json:"resources"`
+	Methods   bool   `json:"methods"`
+	Networks  bool   `json:"networks"`
+	Location  bool   `json:"location"`
+	Subjects  bool   `json:"subjects"`
+	Client    bool   `json:"client"`
+	Schedule  bool   `json:"schedule"`
+	Match     bool   `json:"match"`
+	Potential bool   `json:"potential"`
+	Skipped   bool   `json:"skipped"`
+	Policy    string `json:"policy"`
+}
+
+func writeAccessControlCheckJSON(cmd *cobra.Command, subject authorization.Subject, object authorization.Object, results []authorization.RuleMatchResult, level authorization.Level) (err error) {
+	output := accessControlCheckJSON{
+		Subject: accessControlCheckSubjectJSON{
+			Username: subject.Username,
+			Groups:   subject.Groups,
+			ClientID: subject.ClientID,
+			Scopes:   subject.Scopes,
+		},
+		Object: accessControlCheckObjectJSON{
+			URL:    object.String(),
+			Method: object.Method,
+		},
+		Rules:  make([]accessControlCheckRuleJSON, len(results)),
+		Policy: authorization.LevelToPolicy(level),
+	}
+
+	if subject.IP != nil {
+		output.Subject.IP = subject.IP.String()
+	}
+
+	for i, result := range results {
+		output.Rules[i] = accessControlCheckRuleJSON{
+			Position:  result.Rule.Position,
+			Domain:    result.MatchDomain,
+			Resources: result.MatchResources,
+			Methods:   result.MatchMethods,
+			Networks:  result.MatchNetworks,
+			Location:  result.MatchLocation,
+			Subjects:  result.MatchSubjects,
+			Client:    result.MatchClient,
+			Schedule:  result.MatchSchedule,
+			Match:     result.IsMatch(),
+			Potential: result.IsPotentialMatch(subject),
+			Skipped:   result.Skipped,
+			Policy:    authorization.LevelToPolicy(result.Rule.Policy),
+		}
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(output)
+}
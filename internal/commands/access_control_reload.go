@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/knadh/koanf/providers/file"
+
+	"github.com/authelia/authelia/v4/internal/authorization"
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/configuration/validator"
+	"github.com/authelia/authelia/v4/internal/geoip"
+	"github.com/authelia/authelia/v4/internal/logging"
+)
+
+// accessControlReloader reloads the access control configuration of an authorization.Authorizer from the
+// configuration sources.
+type accessControlReloader struct {
+	sync.Mutex
+
+	configs    []string
+	authorizer *authorization.Authorizer
+
+	// geoipConfig is the GeoIP configuration of the provider currently used by the authorizer.
+	geoipConfig *schema.ACLGeoIP
+
+	// geoipWatches are the GeoIP database files which are watched. Each file is watched once and its changes reload
+	// the databases of the provider currently used by the authorizer.
+	geoipWatches map[string]bool
+}
+
+// startAccessControlReloader reloads the access control configuration when the process receives a SIGHUP signal or
+// when one of the configuration files changes.
+func startAccessControlReloader(configs []string, geoipConfig *schema.ACLGeoIP, authorizer *authorization.Authorizer) {
+	logger := logging.Logger()
+
+	reloader := &accessControlReloader{
+		configs:      configs,
+		authorizer:   authorizer,
+		geoipConfig:  geoipConfig,
+		geoipWatches: map[string]bool{},
+	}
+
+	reloader.watchGeoIP(geoipConfig)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			reloader.reload("SIGHUP signal")
+		}
+	}()
+
+	for _, path := range configs {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			logger.Debugf("Access control configuration changes to %s will not be automatically reloaded as it's not a file", path)
+
+			continue
+		}
+
+		p := path
+
+		err = file.Provider(p).Watch(func(_ interface{}, err error) {
+			if err != nil {
+				logger.Errorf("Access control configuration changes to %s will no longer be automatically reloaded: %v", p, err)
+
+				return
+			}
+
+			reloader.reload("change to " + p)
+		})
+
+		if err != nil {
+			logger.Errorf("Access control configuration changes to %s will not be automatically reloaded: %v", p, err)
+		}
+	}
+}
+
+// reload loads and validates the configuration and replaces the current rules and GeoIP provider if it's valid. If it's
+// not valid the current rules remain active. The whole configuration is validated like on startup since the rules
+// depend on other sections such as the OpenID Connect clients and the session domain.
+func (r *accessControlReloader) reload(reason string) {
+	r.Lock()
+	defer r.Unlock()
+
+	logger := logging.Logger()
+
+	logger.Infof("Reloading the access control configuration due to a %s", reason)
+
+	conf, err := loadConfiguration(r.configs, "reloaded", validator.ValidateConfiguration)
+	if err != nil {
+		logger.Errorf("Failed to reload the access control configuration, the existing rules remain active: %v", err)
+
+		return
+	}
+
+	previous := r.authorizer.GeoIP()
+
+	provider, err := r.loadGeoIP(conf.AccessControl.GeoIP, previous)
+	if err != nil {
+		logger.Errorf("Failed to reload the access control configuration, the existing rules remain active: %v", err)
+
+		return
+	}
+
+	r.authorizer.SetAccessControl(conf.AccessControl, provider)
+	r.geoipConfig = conf.AccessControl.GeoIP
+
+	r.watchGeoIP(r.geoipConfig)
+
+	if previous != nil && previous != provider {
+		if closer, ok := previous.(io.Closer); ok {
+			if err = closer.Close(); err != nil {
+				logger.Errorf("Error occurred closing the previous GeoIP databases: %v", err)
+			}
+		}
+	}
+
+	logger.Infof("Reloaded the access control configuration with %d rules", len(conf.AccessControl.Rules))
+}
+
+// loadGeoIP returns the GeoIP provider of the reloaded configuration. The current provider is kept if the GeoIP
+// configuration didn't change, otherwise the databases of the new configuration are opened.
+func (r *accessControlReloader) loadGeoIP(config *schema.ACLGeoIP, current geoip.Provider) (provider geoip.Provider, err error) {
+	switch {
+	case config == nil:
+		return nil, nil
+	case current != nil && r.geoipConfig != nil && *r.geoipConfig == *config:
+		return current, nil
+	}
+
+	return geoip.NewMaxMindProvider(config)
+}
+
+// watchGeoIP watches the GeoIP database files of the configuration which aren't watched yet.
+func (r *accessControlReloader) watchGeoIP(config *schema.ACLGeoIP) {
+	if config == nil {
+		return
+	}
+
+	logger := logging.Logger()
+
+	for _, path := range []string{config.Path, config.ASNPath} {
+		if path == "" || r.geoipWatches[path] {
+			continue
+		}
+
+		p := path
+
+		err := file.Provider(p).Watch(func(_ interface{}, err error) {
+			if err != nil {
+				logger.Errorf("GeoIP database changes to %s will no longer be automatically reloaded: %v", p, err)
+
+				return
+			}
+
+			r.reloadGeoIP(p)
+		})
+
+		if err != nil {
+			logger.Errorf("GeoIP database changes to %s will not be automatically reloaded: %v", p, err)
+
+			continue
+		}
+
+		r.geoipWatches[p] = true
+	}
+}
+
+// reloadGeoIP reloads the databases of the GeoIP provider currently used by the authorizer if it uses the changed
+// database file.
+func (r *accessControlReloader) reloadGeoIP(path string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.geoipConfig == nil || (r.geoipConfig.Path != path && r.geoipConfig.ASNPath != path) {
+		return
+	}
+
+	provider, ok := r.authorizer.GeoIP().(*geoip.MaxMindProvider)
+	if !ok {
+		return
+	}
+
+	logger := logging.Logger()
+
+	logger.Infof("Reloading the GeoIP databases due to a change to %s", path)
+
+	if err := provider.Reload(); err != nil {
+		logger.Errorf("Error occurred reloading the GeoIP databases, the previous databases remain in use: %v", err)
+	}
+}
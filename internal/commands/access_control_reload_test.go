@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/authorization"
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/geoip"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+type closerGeoIP struct {
+	closed bool
+}
+
+func (p *closerGeoIP) Lookup(_ net.IP) (record geoip.Record, err error) {
+	return record, nil
+}
+
+func (p *closerGeoIP) Close() error {
+	p.closed = true
+
+	return nil
+}
+
+func writeTestReloadConfiguration(t *testing.T, jwtSecret, accessControl string) string {
+	dir := t.TempDir()
+
+	return writeTestConfiguration(t, fmt.Sprintf(`
+jwt_secret: %s
+authentication_backend:
+  file:
+    path: %s
+session:
+  secret: a_session_secret
+  domain: example.com
+storage:
+  encryption_key: a_not_so_secure_encryption_key
+  local:
+    path: %s
+notifier:
+  filesystem:
+    filename: %s
+%s`, jwtSecret, filepath.Join(dir, "users.yml"), filepath.Join(dir, "db.sqlite3"), filepath.Join(dir, "notification.txt"), accessControl))
+}
+
+func newTestReloader(t *testing.T, path string) (reloader *accessControlReloader, provider *closerGeoIP) {
+	authorizer := authorization.NewAuthorizer(&schema.Configuration{
+		AccessControl: schema.AccessControlConfiguration{DefaultPolicy: "deny"},
+	}, utils.RealClock{})
+
+	geoipConfig := &schema.ACLGeoIP{Path: filepath.Join(t.TempDir(), "country.mmdb")}
+	provider = &closerGeoIP{}
+
+	authorizer.SetAccessControl(schema.AccessControlConfiguration{DefaultPolicy: "deny", GeoIP: geoipConfig}, provider)
+
+	return &accessControlReloader{configs: []string{path}, authorizer: authorizer, geoipConfig: geoipConfig, geoipWatches: map[string]bool{}}, provider
+}
+
+func requiredTestLevel(reloader *accessControlReloader) authorization.Level {
+	return reloader.authorizer.GetRequiredLevel(authorization.Subject{}, authorization.NewObject(&url.URL{Scheme: "https", Host: "app.example.com", Path: "/"}, "GET"))
+}
+
+func TestShouldReloadAccessControl(t *testing.T) {
+	path := writeTestReloadConfiguration(t, "a_jwt_secret", `
+access_control:
+  default_policy: one_factor
+`)
+
+	reloader, _ := newTestReloader(t, path)
+
+	reloader.reload("test")
+
+	assert.Equal(t, authorization.OneFactor, requiredTestLevel(reloader))
+}
+
+func TestShouldReloadAccessControlAndRemoveGeoIP(t *testing.T) {
+	path := writeTestReloadConfiguration(t, "a_jwt_secret", `
+access_control:
+  default_policy: one_factor
+`)
+
+	reloader, provider := newTestReloader(t, path)
+
+	reloader.reload("test")
+
+	assert.Equal(t, authorization.OneFactor, requiredTestLevel(reloader))
+	assert.Nil(t, reloader.authorizer.GeoIP())
+	assert.Nil(t, reloader.geoipConfig)
+	assert.True(t, provider.closed)
+}
+
+func TestShouldKeepGeoIPWhenReloadingWithTheSameGeoIPConfiguration(t *testing.T) {
+	path := writeTestReloadConfiguration(t, "a_jwt_secret", "")
+
+	reloader, provider := newTestReloader(t, path)
+
+	require.NoError(t, appendTestConfiguration(path, fmt.Sprintf(`
+access_control:
+  default_policy: one_factor
+  geoip:
+    path: %s
+`, reloader.geoipConfig.Path)))
+
+	reloader.reload("test")
+
+	assert.Equal(t, authorization.OneFactor, requiredTestLevel(reloader))
+	assert.Equal(t, provider, reloader.authorizer.GeoIP())
+	assert.False(t, provider.closed)
+
+	// The database is watched once whatever the number of reloads.
+	require.NoError(t, os.WriteFile(reloader.geoipConfig.Path, nil, 0600))
+
+	reloader.reload("test")
+	reloader.reload("test")
+
+	assert.Equal(t, map[string]bool{reloader.geoipConfig.Path: true}, reloader.geoipWatches)
+}
+
+func TestShouldNotReloadAccessControlWithMissingGeoIPDatabase(t *testing.T) {
+	path := writeTestReloadConfiguration(t, "a_jwt_secret", fmt.Sprintf(`
+access_control:
+  default_policy: one_factor
+  geoip:
+    path: %s
+`, filepath.Join(t.TempDir(), "missing.mmdb")))
+
+	reloader, provider := newTestReloader(t, path)
+
+	reloader.reload("test")
+
+	assert.Equal(t, authorization.Denied, requiredTestLevel(reloader))
+	assert.Equal(t, provider, reloader.authorizer.GeoIP())
+	assert.False(t, provider.closed)
+}
+
+func TestShouldNotReloadAccessControlWithInvalidConfiguration(t *testing.T) {
+	path := writeTestReloadConfiguration(t, "", `
+access_control:
+  default_policy: one_factor
+`)
+
+	reloader, provider := newTestReloader(t, path)
+
+	reloader.reload("test")
+
+	assert.Equal(t, authorization.Denied, requiredTestLevel(reloader))
+	assert.Equal(t, provider, reloader.authorizer.GeoIP())
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAccessControlConfiguration = `
+access_control:
+  default_policy: deny
+  rules:
+    - domain: public.example.com
+      policy: bypass
+    - domain: admin.example.com
+      subject: "group:admins"
+      policy: two_factor
+`
+
+func TestShouldCheckPolicyOfRequest(t *testing.T) {
+	path := writeTestConfiguration(t, testAccessControlConfiguration)
+
+	output, _, exited := executeTestCommand(t, newAccessControlCheckCommand(), "--config", path, "--url", "https://public.example.com/")
+
+	require.False(t, exited)
+	assert.Contains(t, output, "Performing policy check for request to 'https://public.example.com/' method 'GET'")
+	assert.Contains(t, output, "The policy 'bypass' will be applied to this request.")
+}
+
+func TestShouldCheckPolicyOfAnonymousRequestWithJSONOutput(t *testing.T) {
+	path := writeTestConfiguration(t, testAccessControlConfiguration)
+
+	output, _, exited := executeTestCommand(t, newAccessControlCheckCommand(), "--config", path, "--url", "https://admin.example.com/", "--json")
+
+	require.False(t, exited)
+
+	result := accessControlCheckJSON{}
+
+	require.NoError(t, json.Unmarshal([]byte(output), &result))
+
+	assert.Equal(t, "two_factor", result.Policy)
+	require.Len(t, result.Rules, 2)
+	assert.False(t, result.Rules[0].Domain)
+	assert.True(t, result.Rules[1].Domain)
+	assert.True(t, result.Rules[1].Potential)
+}
+
+func TestShouldCheckPolicyOfAuthenticatedRequest(t *testing.T) {
+	path := writeTestConfiguration(t, testAccessControlConfiguration)
+
+	output, _, exited := executeTestCommand(t, newAccessControlCheckCommand(), "--config", path, "--url", "https://admin.example.com/", "--username", "john", "--groups", "dev")
+
+	require.False(t, exited)
+	assert.Contains(t, output, "The policy 'deny' will be applied to this request.")
+}
+
+func TestShouldExitWhenCheckingPolicyWithoutURL(t *testing.T) {
+	path := writeTestConfiguration(t, testAccessControlConfiguration)
+
+	_, logged, exited := executeTestCommand(t, newAccessControlCheckCommand(), "--config", path)
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "the url flag is required")
+}
+
+func TestShouldExitWhenCheckingPolicyWithInvalidIP(t *testing.T) {
+	path := writeTestConfiguration(t, testAccessControlConfiguration)
+
+	_, logged, exited := executeTestCommand(t, newAccessControlCheckCommand(), "--config", path, "--url", "https://admin.example.com/", "--ip", "abc")
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "failed to parse the ip 'abc'")
+}
+
+func TestShouldExitWhenCheckingPolicyWithInvalidConfiguration(t *testing.T) {
+	path := writeTestConfiguration(t, `
+access_control:
+  default_policy: deny
+  rules:
+    - domain: public.example.com
+      policy: invalid
+`)
+
+	_, logged, exited := executeTestCommand(t, newAccessControlCheckCommand(), "--config", path, "--url", "https://public.example.com/")
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "errors occurred validating the access control configuration")
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/logging"
+)
+
+// fatalExit is the value the exit func of the logger panics with in the tests of the commands.
+type fatalExit int
+
+// writeTestConfiguration writes the configuration to a file in a temporary directory and returns its path.
+func writeTestConfiguration(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "configuration.yml")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
+
+// executeTestCommand executes the command with the args and returns what it wrote to the output, what it logged and
+// whether it exited through a fatal log entry.
+func executeTestCommand(t *testing.T, cmd *cobra.Command, args ...string) (output, logged string, exited bool) {
+	logger := logging.Logger()
+
+	out, log := &bytes.Buffer{}, &bytes.Buffer{}
+
+	logger.SetOutput(log)
+	logger.ExitFunc = func(code int) {
+		panic(fatalExit(code))
+	}
+
+	t.Cleanup(func() {
+		logger.SetOutput(os.Stderr)
+		logger.ExitFunc = os.Exit
+	})
+
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(fatalExit); !ok {
+					panic(r)
+				}
+
+				exited = true
+			}
+		}()
+
+		require.NoError(t, cmd.Execute())
+	}()
+
+	return out.String(), log.String(), exited
+}
+
+// appendTestConfiguration appends the content to the configuration file.
+func appendTestConfiguration(path, content string) (err error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+
+	return err
+}
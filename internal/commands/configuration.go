@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -73,3 +75,28 @@ func newCmdWithConfigPreRun(ensureConfigExists, validateKeys, validateConfigurat
 		}
 	}
 }
+
+// loadConfiguration loads the configuration from the configuration sources for the commands which only need a section
+// of it, the validate func validates the section and the errors are joined into a single error naming the section.
+func loadConfiguration(configs []string, section string, validate func(conf *schema.Configuration, val *schema.StructValidator)) (conf *schema.Configuration, err error) {
+	val := schema.NewStructValidator()
+
+	_, conf, err = configuration.Load(val, configuration.NewDefaultSources(configs, configuration.DefaultEnvPrefix, configuration.DefaultEnvDelimiter)...)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred loading configuration: %w", err)
+	}
+
+	validate(conf, val)
+
+	if errs := val.Errors(); len(errs) != 0 {
+		messages := make([]string, len(errs))
+
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+
+		return nil, fmt.Errorf("errors occurred validating the %s configuration: %s", section, strings.Join(messages, ", "))
+	}
+
+	return conf, nil
+}
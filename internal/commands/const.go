@@ -75,3 +75,111 @@ PowerShell:
   PS> authelia completion powershell > authelia.ps1
   # and source this file from your PowerShell profile.
 `
+
+const accessControlPolicyCheckLong = `Checks a request against the access control rules to determine what policy would be applied.
+
+Each rule is listed with the result of each of its criteria. Rules are evaluated in order and the
+first rule which matches is applied, subsequent rules are skipped. If no rule matches the default
+policy is applied.
+`
+
+const accessControlPolicyCheckExample = `authelia access-control check-policy --config config.yml --url https://example.com
+authelia access-control check-policy --config config.yml --url https://example.com --username john --groups admin,public
+authelia access-control check-policy --config config.yml --url https://example.com --method POST --ip 192.168.1.4 --json
+`
+
+const sessionsRevokeLong = `Revokes every session of a user, for example after the compromise of their account.
+
+The sessions are revoked in the Redis or SQL session providers so this command can't revoke the sessions
+stored in memory by the running Authelia process. These can be revoked by the administrators with
+the /api/admin/sessions/revoke endpoint instead.
+`
+
+const sessionsRevokeExample = `authelia sessions revoke john --config config.yml
+`
+
+const storageMigrateLong = `Migrates the schema of the storage provider up or down, lists the migrations and shows the
+history of the migrations applied to the database.
+
+Authelia migrates the schema up to the latest version on startup so these commands are mainly
+useful to preview a migration, to revert the schema before downgrading Authelia or to recover from
+a failed migration.
+`
+
+const storageMigrateUpExample = `authelia storage migrate up --config config.yml
+authelia storage migrate up --target 3 --config config.yml
+authelia storage migrate up --dry-run --config config.yml
+`
+
+const storageMigrateDownLong = `Migrates the schema down to the target version.
+
+Migrating down drops the tables and columns of the reverted migrations which destroys their data,
+the --destroy-data flag is required to confirm it unless the --dry-run flag is used. The statements
+altering the schema of MySQL are committed implicitly so a failed migration can't be rolled back on MySQL.
+`
+
+const storageMigrateDownExample = `authelia storage migrate down --target 2 --destroy-data --config config.yml
+authelia storage migrate down --target 2 --dry-run --config config.yml
+`
+
+const storageMigrateListExample = `authelia storage migrate list --config config.yml
+`
+
+const storageMigrateHistoryExample = `authelia storage migrate history --config config.yml
+`
+
+const storageEncryptionChangeKeyLong = `Re-encrypts the TOTP secrets and U2F devices of the database with a new encryption key.
+
+The data is decrypted with the storage.encryption_key of the configuration, or encrypted for the first
+time if the database isn't encrypted yet. All the rows are re-encrypted in a single transaction. Authelia
+must be stopped while the key is changed and the configuration updated with the new key afterwards.
+
+The new key is read from the file of the --new-encryption-key-file flag, or from the
+AUTHELIA_STORAGE_NEW_ENCRYPTION_KEY environment variable when the flag isn't set.
+`
+
+const storageEncryptionChangeKeyExample = `authelia storage encryption change-key --new-encryption-key-file /run/secrets/new_encryption_key --config config.yml
+`
+
+const envStorageNewEncryptionKey = "AUTHELIA_STORAGE_NEW_ENCRYPTION_KEY"
+
+const storageExportLong = `Exports the TOTP secrets, the U2F devices and the preferred 2FA methods of the users as a versioned
+YAML or JSON document, for example to move them to another storage provider with the import command.
+
+The export contains the secrets of the second factors of the users. They're encrypted in the export with
+a key derived from the key of the file of the --encryption-key-file flag, or of the
+AUTHELIA_STORAGE_EXPORT_ENCRYPTION_KEY environment variable when the flag isn't set. The same key is then
+required to import the export.
+`
+
+const storageExportExample = `authelia storage export --file export.yml --config config.yml
+authelia storage export --format json --encryption-key-file /run/secrets/export_key --config config.yml > export.json
+`
+
+const storageImportLong = `Imports the TOTP secrets, the U2F devices and the preferred 2FA methods of the users of an export.
+
+The users who already have a second factor or a preferred 2FA method abort the import by default. They
+can be skipped or overwritten with the --conflict flag, the overwritten users lose the second factors missing
+from the export. The whole export is checked before any user is imported and the users are imported in a
+single transaction.
+
+The key of an encrypted export is read like the key of the export command.
+`
+
+const storageImportExample = `authelia storage import export.yml --config config.yml
+authelia storage import export.json --conflict skip --encryption-key-file /run/secrets/export_key --config config.yml
+`
+
+const envStorageExportEncryptionKey = "AUTHELIA_STORAGE_EXPORT_ENCRYPTION_KEY"
+
+const storageAuthenticationLogsLong = `Lists the attempts of the authentication log, the latest first.
+
+The attempts are filtered by user and IP with the --username and --ip flags and by time range with the
+--from and --to flags in the RFC3339 format. At most 500 attempts are listed at once, the next attempts
+are listed with the --offset flag.
+`
+
+const storageAuthenticationLogsExample = `authelia storage authentication-logs --username john --config config.yml
+authelia storage authentication-logs --ip 192.168.0.10 --from 2021-07-01T00:00:00Z --to 2021-07-02T00:00:00Z --config config.yml
+authelia storage authentication-logs --limit 100 --offset 100 --config config.yml
+`
@@ -11,6 +11,7 @@ import (
 	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/authorization"
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/geoip"
 	"github.com/authelia/authelia/v4/internal/logging"
 	"github.com/authelia/authelia/v4/internal/middlewares"
 	"github.com/authelia/authelia/v4/internal/notification"
@@ -41,18 +42,21 @@ func NewRootCmd() (cmd *cobra.Command) {
 	cmdWithConfigFlags(cmd)
 
 	cmd.AddCommand(
+		NewAccessControlCommand(),
 		newBuildInfoCmd(),
 		NewCertificatesCmd(),
 		newCompletionCmd(),
 		NewHashPasswordCmd(),
 		NewRSACmd(),
+		NewSessionsCmd(),
+		NewStorageCmd(),
 		newValidateConfigCmd(),
 	)
 
 	return cmd
 }
 
-func cmdRootRun(_ *cobra.Command, _ []string) {
+func cmdRootRun(cmd *cobra.Command, _ []string) {
 	logger := logging.Logger()
 
 	logger.Infof("Authelia %s is starting", utils.Version())
@@ -82,9 +86,27 @@ func cmdRootRun(_ *cobra.Command, _ []string) {
 
 	doStartupChecks(config, &providers)
 
+	configs, _ := cmd.Flags().GetStringSlice("config")
+
+	startAccessControlReloader(configs, config.AccessControl.GeoIP, providers.Authorizer)
+
 	server.Start(*config, providers)
 }
 
+// getStorageProvider returns the configured storage provider or nil if none is configured.
+func getStorageProvider(config *schema.Configuration) storage.Provider {
+	switch {
+	case config.Storage.PostgreSQL != nil:
+		return storage.NewPostgreSQLProvider(*config.Storage.PostgreSQL, config.Storage.EncryptionKey)
+	case config.Storage.MySQL != nil:
+		return storage.NewMySQLProvider(*config.Storage.MySQL, config.Storage.EncryptionKey)
+	case config.Storage.Local != nil:
+		return storage.NewSQLiteProvider(config.Storage.Local.Path, config.Storage.EncryptionKey)
+	default:
+		return nil
+	}
+}
+
 func getProviders(config *schema.Configuration) (providers middlewares.Providers, warnings []error, errors []error) {
 	// TODO: Adjust this so the CertPool can be used like a provider.
 	autheliaCertPool, warnings, errors := utils.NewX509CertPool(config.CertificatesDirectory)
@@ -92,16 +114,8 @@ func getProviders(config *schema.Configuration) (providers middlewares.Providers
 		return providers, warnings, errors
 	}
 
-	var storageProvider storage.Provider
-
-	switch {
-	case config.Storage.PostgreSQL != nil:
-		storageProvider = storage.NewPostgreSQLProvider(*config.Storage.PostgreSQL)
-	case config.Storage.MySQL != nil:
-		storageProvider = storage.NewMySQLProvider(*config.Storage.MySQL)
-	case config.Storage.Local != nil:
-		storageProvider = storage.NewSQLiteProvider(config.Storage.Local.Path)
-	default:
+	storageProvider := getStorageProvider(config)
+	if storageProvider == nil {
 		// TODO: Add storage provider startup check and remove this.
 		errors = append(errors, fmt.Errorf("unrecognized storage provider"))
 	}
@@ -133,8 +147,17 @@ func getProviders(config *schema.Configuration) (providers middlewares.Providers
 	}
 
 	clock := utils.RealClock{}
-	authorizer := authorization.NewAuthorizer(config)
-	sessionProvider := session.NewProvider(config.Session, autheliaCertPool)
+	authorizer := authorization.NewAuthorizer(config, clock)
+
+	if config.AccessControl.GeoIP != nil {
+		geoipProvider, err := geoip.NewMaxMindProvider(config.AccessControl.GeoIP)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			authorizer.SetAccessControl(config.AccessControl, geoipProvider)
+		}
+	}
+	sessionProvider := session.NewProvider(config.Session, autheliaCertPool, storageProvider)
 	regulator := regulation.NewRegulator(config.Regulation, storageProvider, clock)
 
 	oidcProvider, err := oidc.NewOpenIDConnectProvider(config.IdentityProviders.OIDC)
@@ -142,6 +165,15 @@ func getProviders(config *schema.Configuration) (providers middlewares.Providers
 		errors = append(errors, err)
 	}
 
+	var identityAssertion *oidc.KeyManager
+
+	if config.IdentityAssertion != nil {
+		identityAssertion, err = oidc.NewKeyManagerWithPrivateKeyData(config.IdentityAssertion.IssuerPrivateKey)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("identity assertion: %w", err))
+		}
+	}
+
 	return middlewares.Providers{
 		Authorizer:      authorizer,
 		UserProvider:    userProvider,
@@ -151,6 +183,8 @@ func getProviders(config *schema.Configuration) (providers middlewares.Providers
 		NTP:             ntpProvider,
 		Notifier:        notifier,
 		SessionProvider: sessionProvider,
+
+		IdentityAssertion: identityAssertion,
 	}, warnings, errors
 }
 
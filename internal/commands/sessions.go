@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/configuration/validator"
+	"github.com/authelia/authelia/v4/internal/logging"
+	"github.com/authelia/authelia/v4/internal/session"
+	"github.com/authelia/authelia/v4/internal/storage"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// NewSessionsCmd returns a new Sessions Cmd.
+func NewSessionsCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "sessions",
+		Short: "Helpers for the sessions of the users",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newSessionsRevokeCmd(),
+	)
+
+	return cmd
+}
+
+func newSessionsRevokeCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "revoke [username]",
+		Short:   "Revokes every session of a user",
+		Long:    sessionsRevokeLong,
+		Example: sessionsRevokeExample,
+		Args:    cobra.ExactArgs(1),
+		Run:     cmdSessionsRevokeRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	return cmd
+}
+
+func cmdSessionsRevokeRun(cmd *cobra.Command, args []string) {
+	logger := logging.Logger()
+
+	configs, _ := cmd.Flags().GetStringSlice("config")
+
+	conf, err := loadSessionsConfiguration(configs)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if conf.Session.Redis == nil && conf.Session.SQL == nil {
+		logger.Fatal("The sessions can only be revoked with the Redis or SQL session providers, the sessions stored in memory can only be revoked by the running process")
+	}
+
+	certPool, _, errs := utils.NewX509CertPool(conf.CertificatesDirectory)
+	if len(errs) != 0 {
+		logger.Fatalf("Error loading the certificates: %v", errs[0])
+	}
+
+	var storageProvider storage.Provider
+
+	if conf.Session.SQL != nil {
+		if storageProvider = getStorageProvider(conf); storageProvider == nil {
+			logger.Fatal("The SQL session provider requires a storage provider to be configured")
+		}
+	}
+
+	provider := session.NewProvider(conf.Session, certPool, storageProvider)
+
+	count, err := provider.RevokeSessions(args[0])
+	if err != nil {
+		logger.Fatalf("Error revoking the sessions: %v", err)
+	}
+
+	fmt.Printf("Revoked %d sessions of user %s\n", count, args[0])
+}
+
+func loadSessionsConfiguration(configs []string) (conf *schema.Configuration, err error) {
+	return loadConfiguration(configs, "session", func(conf *schema.Configuration, val *schema.StructValidator) {
+		validator.ValidateSession(&conf.Session, val)
+	})
+}
@@ -0,0 +1,548 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/configuration/validator"
+	"github.com/authelia/authelia/v4/internal/logging"
+	"github.com/authelia/authelia/v4/internal/storage"
+)
+
+// NewStorageCmd returns a new Storage Cmd.
+func NewStorageCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "storage",
+		Short: "Helpers for the storage provider",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newStorageAuthenticationLogsCmd(),
+		newStorageEncryptionCmd(),
+		newStorageExportCmd(),
+		newStorageImportCmd(),
+		newStorageMigrateCmd(),
+	)
+
+	return cmd
+}
+
+func newStorageAuthenticationLogsCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "authentication-logs",
+		Short:   "Lists the attempts of the authentication log",
+		Long:    storageAuthenticationLogsLong,
+		Example: storageAuthenticationLogsExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdStorageAuthenticationLogsRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	cmd.Flags().String("username", "", "the user who made the attempts")
+	cmd.Flags().String("ip", "", "the IP address of the client who made the attempts")
+	cmd.Flags().String("from", "", "the time from which the attempts are listed in the RFC3339 format")
+	cmd.Flags().String("to", "", "the time until which the attempts are listed in the RFC3339 format")
+	cmd.Flags().Int("limit", storage.AuthenticationLogsDefaultLimit, "the maximum number of attempts listed")
+	cmd.Flags().Int("offset", 0, "the number of latest attempts skipped")
+
+	return cmd
+}
+
+func newStorageEncryptionCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "encryption",
+		Short: "Manages the encryption of the storage provider",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newStorageEncryptionChangeKeyCmd(),
+	)
+
+	return cmd
+}
+
+func newStorageEncryptionChangeKeyCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "change-key",
+		Short:   "Re-encrypts the database with a new encryption key",
+		Long:    storageEncryptionChangeKeyLong,
+		Example: storageEncryptionChangeKeyExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdStorageEncryptionChangeKeyRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	cmd.Flags().String("new-encryption-key-file", "", "the file containing the new encryption key of the storage provider")
+
+	return cmd
+}
+
+func newStorageExportCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "export",
+		Short:   "Exports the second factors and the preferences of the users",
+		Long:    storageExportLong,
+		Example: storageExportExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdStorageExportRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	cmd.Flags().StringP("file", "f", "", "the file the export is written to instead of the standard output")
+	cmd.Flags().String("format", "yaml", "the format of the export, 'yaml' or 'json'")
+	cmd.Flags().String("encryption-key-file", "", "the file containing the key encrypting the TOTP secrets and U2F devices of the export")
+
+	return cmd
+}
+
+func newStorageImportCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "import [file]",
+		Short:   "Imports the second factors and the preferences of the users",
+		Long:    storageImportLong,
+		Example: storageImportExample,
+		Args:    cobra.ExactArgs(1),
+		Run:     cmdStorageImportRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	cmd.Flags().String("conflict", storage.ImportModeAbort, "what's done with the users who already have second factors, 'abort', 'skip' or 'overwrite'")
+	cmd.Flags().String("encryption-key-file", "", "the file containing the key the TOTP secrets and U2F devices of the export are encrypted with")
+
+	return cmd
+}
+
+func newStorageMigrateCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrates the schema of the storage provider",
+		Long:  storageMigrateLong,
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(
+		newStorageMigrateUpCmd(),
+		newStorageMigrateDownCmd(),
+		newStorageMigrateListCmd(),
+		newStorageMigrateHistoryCmd(),
+	)
+
+	return cmd
+}
+
+func newStorageMigrateUpCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "up",
+		Short:   "Migrates the schema up to the latest or target version",
+		Example: storageMigrateUpExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdStorageMigrateUpRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	cmd.Flags().IntP("target", "t", int(storage.LatestSchemaVersion()), "the version of the schema to migrate to")
+	cmd.Flags().Bool("dry-run", false, "print the statements of the migrations without running them")
+
+	return cmd
+}
+
+func newStorageMigrateDownCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "down",
+		Short:   "Migrates the schema down to the target version",
+		Long:    storageMigrateDownLong,
+		Example: storageMigrateDownExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdStorageMigrateDownRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	cmd.Flags().IntP("target", "t", 0, "the version of the schema to migrate to")
+	cmd.Flags().Bool("dry-run", false, "print the statements of the migrations without running them")
+	cmd.Flags().Bool("destroy-data", false, "confirm the data of the tables and columns of the reverted migrations is destroyed")
+
+	_ = cmd.MarkFlagRequired("target")
+
+	return cmd
+}
+
+func newStorageMigrateListCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "list",
+		Short:   "Lists the migrations of the schema and whether they're applied",
+		Example: storageMigrateListExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdStorageMigrateListRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	return cmd
+}
+
+func newStorageMigrateHistoryCmd() (cmd *cobra.Command) {
+	cmd = &cobra.Command{
+		Use:     "history",
+		Short:   "Shows the history of the migrations of the schema",
+		Example: storageMigrateHistoryExample,
+		Args:    cobra.NoArgs,
+		Run:     cmdStorageMigrateHistoryRun,
+	}
+
+	cmdWithConfigFlags(cmd)
+
+	return cmd
+}
+
+func cmdStorageAuthenticationLogsRun(cmd *cobra.Command, _ []string) {
+	logger := logging.Logger()
+
+	filter := storage.AuthenticationLogFilter{}
+
+	filter.Username, _ = cmd.Flags().GetString("username")
+	filter.RemoteIP, _ = cmd.Flags().GetString("ip")
+	filter.Limit, _ = cmd.Flags().GetInt("limit")
+	filter.Offset, _ = cmd.Flags().GetInt("offset")
+
+	for _, flag := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		text, _ := cmd.Flags().GetString(flag.name)
+		if text == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			logger.Fatalf("Error parsing the --%s flag, it must be in the RFC3339 format: %v", flag.name, err)
+		}
+
+		*flag.value = t
+	}
+
+	provider := getStorageTransferProvider(cmd)
+
+	attempts, err := provider.LoadAuthenticationLogs(filter)
+	if err != nil {
+		logger.Fatalf("Error reading the authentication logs: %v", err)
+	}
+
+	if len(attempts) == 0 {
+		fmt.Println("No authentication attempt matches the filter")
+
+		return
+	}
+
+	fmt.Printf("Time\tUsername\tEvent\tType\tSuccessful\tBanned\tRemote IP\tRequest\tUser Agent\n")
+
+	for _, attempt := range attempts {
+		remoteIP := "-"
+
+		if attempt.RemoteIP != nil {
+			remoteIP = attempt.RemoteIP.String()
+		}
+
+		fmt.Printf("%s\t%s\t%s\t%s\t%t\t%t\t%s\t%s\t%s\n",
+			attempt.Time.Format("2006-01-02 15:04:05 MST"), attempt.Username, attempt.Event, attempt.Type,
+			attempt.Successful, attempt.Banned, remoteIP, strings.TrimSpace(attempt.RequestMethod+" "+attempt.RequestURL),
+			attempt.UserAgent)
+	}
+}
+
+func cmdStorageEncryptionChangeKeyRun(cmd *cobra.Command, _ []string) {
+	logger := logging.Logger()
+
+	key, err := getSecretFlag(cmd, "new-encryption-key-file", envStorageNewEncryptionKey)
+	if err != nil {
+		logger.Fatalf("Error reading the new storage encryption key: %v", err)
+	}
+
+	if key == "" {
+		logger.Fatalf("The new storage encryption key must be set with the --new-encryption-key-file flag or the %s environment variable", envStorageNewEncryptionKey)
+	}
+
+	if len(key) < 20 {
+		logger.Fatal("The new storage encryption key must be at least 20 characters long")
+	}
+
+	provider := getStorageMigrationProvider(cmd)
+	defer provider.Close()
+
+	version, err := provider.SchemaVersion()
+	if err != nil {
+		logger.Fatalf("Error reading the version of the storage schema: %v", err)
+	}
+
+	if version != storage.LatestSchemaVersion() {
+		logger.Fatalf("The storage schema is v%d and must be migrated to the latest version v%d with the 'authelia storage migrate up' command first", version, storage.LatestSchemaVersion())
+	}
+
+	if err = provider.ChangeEncryptionKey(key); err != nil {
+		logger.Fatalf("Error changing the storage encryption key: %v", err)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Changed the storage encryption key, set the storage.encryption_key configuration to the new key")
+}
+
+func cmdStorageExportRun(cmd *cobra.Command, _ []string) {
+	logger := logging.Logger()
+
+	file, _ := cmd.Flags().GetString("file")
+	format, _ := cmd.Flags().GetString("format")
+
+	key, err := getSecretFlag(cmd, "encryption-key-file", envStorageExportEncryptionKey)
+	if err != nil {
+		logger.Fatalf("Error reading the encryption key of the export: %v", err)
+	}
+
+	var marshal func(v interface{}) ([]byte, error)
+
+	switch format {
+	case "yaml":
+		marshal = yaml.Marshal
+	case "json":
+		marshal = func(v interface{}) ([]byte, error) {
+			data, err := json.MarshalIndent(v, "", "  ")
+
+			return append(data, '\n'), err
+		}
+	default:
+		logger.Fatalf("Unknown export format '%s', it must be 'yaml' or 'json'", format)
+	}
+
+	provider := getStorageTransferProvider(cmd)
+
+	export, err := storage.ExportSecondFactors(provider, key)
+	if err != nil {
+		logger.Fatalf("Error exporting the second factors: %v", err)
+	}
+
+	data, err := marshal(export)
+	if err != nil {
+		logger.Fatalf("Error encoding the export: %v", err)
+	}
+
+	if file == "" {
+		fmt.Println(strings.TrimSuffix(string(data), "\n"))
+
+		return
+	}
+
+	// The export contains the secrets of the second factors of the users.
+	if err = ioutil.WriteFile(file, data, 0600); err != nil {
+		logger.Fatalf("Error writing the export: %v", err)
+	}
+
+	fmt.Printf("Exported the second factors of %d users to %s\n", len(export.Users), file)
+}
+
+func cmdStorageImportRun(cmd *cobra.Command, args []string) {
+	logger := logging.Logger()
+
+	mode, _ := cmd.Flags().GetString("conflict")
+
+	key, err := getSecretFlag(cmd, "encryption-key-file", envStorageExportEncryptionKey)
+	if err != nil {
+		logger.Fatalf("Error reading the encryption key of the export: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		logger.Fatalf("Error reading the export: %v", err)
+	}
+
+	// JSON is a subset of YAML so both formats are decoded as YAML.
+	export := &storage.Export{}
+	if err = yaml.Unmarshal(data, export); err != nil {
+		logger.Fatalf("Error decoding the export: %v", err)
+	}
+
+	provider := getStorageTransferProvider(cmd)
+
+	result, err := storage.ImportSecondFactors(provider, export, key, mode)
+
+	switch {
+	case err == storage.ErrImportConflicts:
+		logger.Fatalf("The import was aborted since these users already have second factors: %s. Use the --conflict flag to skip or overwrite them", strings.Join(result.Conflicts, ", "))
+	case err != nil:
+		logger.Fatalf("Error importing the second factors: %v", err)
+	}
+
+	if len(result.Skipped) != 0 {
+		fmt.Printf("Skipped the users who already have second factors: %s\n", strings.Join(result.Skipped, ", "))
+	}
+
+	fmt.Printf("Imported the second factors of %d users\n", len(result.Imported))
+}
+
+func cmdStorageMigrateUpRun(cmd *cobra.Command, _ []string) {
+	cmdStorageMigrateRun(cmd, true)
+}
+
+func cmdStorageMigrateDownRun(cmd *cobra.Command, _ []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	destroyData, _ := cmd.Flags().GetBool("destroy-data")
+
+	if !dryRun && !destroyData {
+		logging.Logger().Fatal("Migrating the schema down destroys the data of the reverted migrations, the --destroy-data flag is required to confirm it")
+	}
+
+	cmdStorageMigrateRun(cmd, false)
+}
+
+func cmdStorageMigrateRun(cmd *cobra.Command, up bool) {
+	logger := logging.Logger()
+
+	target, _ := cmd.Flags().GetInt("target")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	provider := getStorageMigrationProvider(cmd)
+	defer provider.Close()
+
+	version, err := provider.SchemaVersion()
+	if err != nil {
+		logger.Fatalf("Error reading the version of the storage schema: %v", err)
+	}
+
+	switch {
+	case up && storage.SchemaVersion(target) < version:
+		logger.Fatalf("The target version v%d is older than the current version v%d of the storage schema, use the down command instead", target, version)
+	case !up && storage.SchemaVersion(target) > version:
+		logger.Fatalf("The target version v%d is newer than the current version v%d of the storage schema, use the up command instead", target, version)
+	case storage.SchemaVersion(target) == version:
+		fmt.Fprintf(cmd.OutOrStdout(), "The storage schema is already at version v%d\n", version)
+
+		return
+	}
+
+	statements, err := provider.MigrateSchema(storage.SchemaVersion(target), dryRun)
+	if err != nil {
+		logger.Fatalf("Error migrating the storage schema: %v", err)
+	}
+
+	if dryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "Statements of the migration of the storage schema from v%d to v%d:\n", version, target)
+
+		for _, statement := range statements {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s;\n", statement)
+		}
+
+		return
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Migrated the storage schema from v%d to v%d\n", version, target)
+}
+
+func cmdStorageMigrateListRun(cmd *cobra.Command, _ []string) {
+	provider := getStorageMigrationProvider(cmd)
+	defer provider.Close()
+
+	version, err := provider.SchemaVersion()
+	if err != nil {
+		logging.Logger().Fatalf("Error reading the version of the storage schema: %v", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Version\tApplied\tDescription\n")
+
+	for _, migration := range storage.Migrations() {
+		applied := "no"
+
+		if migration.Version <= version {
+			applied = "yes"
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "v%d\t%s\t%s\n", migration.Version, applied, migration.Description)
+	}
+}
+
+func cmdStorageMigrateHistoryRun(cmd *cobra.Command, _ []string) {
+	provider := getStorageMigrationProvider(cmd)
+	defer provider.Close()
+
+	records, err := provider.SchemaMigrationHistory()
+	if err != nil {
+		logging.Logger().Fatalf("Error reading the history of the storage schema migrations: %v", err)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No migration of the storage schema was recorded")
+
+		return
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Time\tBefore\tAfter\tAuthelia Version\n")
+
+	for _, record := range records {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\tv%d\tv%d\t%s\n", record.Time.Format("2006-01-02 15:04:05 MST"), record.Before, record.After, record.AutheliaVersion)
+	}
+}
+
+// getSecretFlag returns the secret of the file of the flag, or of the environment variable when the flag isn't set. The
+// secrets aren't accepted as flag values since the arguments of a process are visible to the other users of the host.
+func getSecretFlag(cmd *cobra.Command, name, env string) (secret string, err error) {
+	path, _ := cmd.Flags().GetString(name)
+	if path == "" {
+		return os.Getenv(env), nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading the file of the --%s flag: %w", name, err)
+	}
+
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+func getStorageMigrationProvider(cmd *cobra.Command) *storage.SQLProvider {
+	logger := logging.Logger()
+
+	configs, _ := cmd.Flags().GetStringSlice("config")
+
+	conf, err := loadStorageConfiguration(configs)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	provider := storage.NewSQLProviderWithoutUpgrade(conf.Storage)
+	if provider == nil {
+		logger.Fatal("A storage provider must be configured to migrate the storage schema")
+	}
+
+	return provider
+}
+
+// getStorageTransferProvider returns the storage provider of the configuration, the schema is migrated to the latest
+// version and the encryption key is checked like on startup.
+func getStorageTransferProvider(cmd *cobra.Command) storage.Provider {
+	configs, _ := cmd.Flags().GetStringSlice("config")
+
+	conf, err := loadStorageConfiguration(configs)
+	if err != nil {
+		logging.Logger().Fatal(err)
+	}
+
+	return getStorageProvider(conf)
+}
+
+func loadStorageConfiguration(configs []string) (conf *schema.Configuration, err error) {
+	return loadConfiguration(configs, "storage", func(conf *schema.Configuration, val *schema.StructValidator) {
+		validator.ValidateStorage(conf.Storage, val)
+	})
+}
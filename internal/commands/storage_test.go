@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/storage"
+)
+
+func writeTestStorageConfiguration(t *testing.T) string {
+	return writeTestConfiguration(t, fmt.Sprintf(`
+storage:
+  encryption_key: a_not_so_secure_encryption_key
+  local:
+    path: %s
+`, filepath.Join(t.TempDir(), "db.sqlite3")))
+}
+
+func TestShouldMigrateStorageSchemaUpAndDown(t *testing.T) {
+	path := writeTestStorageConfiguration(t)
+	latest := storage.LatestSchemaVersion()
+
+	output, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path)
+
+	require.False(t, exited, logged)
+	assert.Equal(t, fmt.Sprintf("Migrated the storage schema from v0 to v%d\n", latest), output)
+
+	output, logged, exited = executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path)
+
+	require.False(t, exited, logged)
+	assert.Equal(t, fmt.Sprintf("The storage schema is already at version v%d\n", latest), output)
+
+	output, logged, exited = executeTestCommand(t, newStorageMigrateCmd(), "list", "--config", path)
+
+	require.False(t, exited, logged)
+	assert.Contains(t, output, fmt.Sprintf("v%d\tyes\t", latest))
+
+	output, logged, exited = executeTestCommand(t, newStorageMigrateCmd(), "down", "--config", path, "--target", "1", "--destroy-data")
+
+	require.False(t, exited, logged)
+	assert.Equal(t, fmt.Sprintf("Migrated the storage schema from v%d to v1\n", latest), output)
+
+	output, logged, exited = executeTestCommand(t, newStorageMigrateCmd(), "history", "--config", path)
+
+	require.False(t, exited, logged)
+	assert.Contains(t, output, "\tv0\tv1\t")
+	assert.Contains(t, output, fmt.Sprintf("\tv%d\tv%d\t", latest, latest-1))
+	assert.Contains(t, output, "\tv2\tv1\t")
+}
+
+func TestShouldPrintStatementsOfStorageSchemaMigrationDryRun(t *testing.T) {
+	path := writeTestStorageConfiguration(t)
+
+	output, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path, "--target", "1", "--dry-run")
+
+	require.False(t, exited, logged)
+	assert.Contains(t, output, "Statements of the migration of the storage schema from v0 to v1:\n")
+	assert.Contains(t, output, "CREATE TABLE")
+
+	output, logged, exited = executeTestCommand(t, newStorageMigrateCmd(), "list", "--config", path)
+
+	require.False(t, exited, logged)
+	assert.Contains(t, output, "v1\tno\t")
+}
+
+func TestShouldExitWhenMigratingStorageSchemaDownWithoutDestroyData(t *testing.T) {
+	path := writeTestStorageConfiguration(t)
+
+	_, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "down", "--config", path, "--target", "0")
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "the --destroy-data flag is required to confirm it")
+}
+
+func TestShouldExitWhenMigratingStorageSchemaInTheWrongDirection(t *testing.T) {
+	path := writeTestStorageConfiguration(t)
+
+	_, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path, "--target", "1")
+	require.False(t, exited, logged)
+
+	_, logged, exited = executeTestCommand(t, newStorageMigrateCmd(), "down", "--config", path, "--target", "2", "--destroy-data")
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "The target version v2 is newer than the current version v1 of the storage schema, use the up command instead")
+
+	_, logged, exited = executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path, "--target", "0")
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "The target version v0 is older than the current version v1 of the storage schema, use the down command instead")
+}
+
+func TestShouldExitWhenMigratingStorageSchemaWithInvalidConfiguration(t *testing.T) {
+	path := writeTestConfiguration(t, `
+storage:
+  encryption_key: short
+  local:
+    path: /tmp/db.sqlite3
+`)
+
+	_, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path)
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "errors occurred validating the storage configuration")
+}
+
+func TestShouldExitWhenMigratingStorageSchemaWithoutStorage(t *testing.T) {
+	path := writeTestConfiguration(t, `
+log:
+  level: info
+`)
+
+	_, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path)
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "errors occurred validating the storage configuration")
+}
+
+func TestShouldChangeStorageEncryptionKeyFromFile(t *testing.T) {
+	path := writeTestStorageConfiguration(t)
+
+	_, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path)
+	require.False(t, exited, logged)
+
+	keyPath := filepath.Join(t.TempDir(), "new_encryption_key")
+	require.NoError(t, ioutil.WriteFile(keyPath, []byte("a_new_not_so_secure_encryption_key\n"), 0600))
+
+	output, logged, exited := executeTestCommand(t, newStorageEncryptionChangeKeyCmd(), "--config", path, "--new-encryption-key-file", keyPath)
+
+	require.False(t, exited, logged)
+	assert.Equal(t, "Changed the storage encryption key, set the storage.encryption_key configuration to the new key\n", output)
+
+	// The database is no longer readable with the previous key.
+	_, logged, exited = executeTestCommand(t, newStorageEncryptionChangeKeyCmd(), "--config", path, "--new-encryption-key-file", keyPath)
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "Error changing the storage encryption key")
+}
+
+func TestShouldChangeStorageEncryptionKeyFromEnvironment(t *testing.T) {
+	path := writeTestStorageConfiguration(t)
+
+	_, logged, exited := executeTestCommand(t, newStorageMigrateCmd(), "up", "--config", path)
+	require.False(t, exited, logged)
+
+	require.NoError(t, os.Setenv(envStorageNewEncryptionKey, "a_new_not_so_secure_encryption_key"))
+
+	t.Cleanup(func() {
+		_ = os.Unsetenv(envStorageNewEncryptionKey)
+	})
+
+	_, logged, exited = executeTestCommand(t, newStorageEncryptionChangeKeyCmd(), "--config", path)
+
+	assert.False(t, exited, logged)
+}
+
+func TestShouldExitWhenChangingStorageEncryptionKeyWithoutKey(t *testing.T) {
+	path := writeTestStorageConfiguration(t)
+
+	_, logged, exited := executeTestCommand(t, newStorageEncryptionChangeKeyCmd(), "--config", path)
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "The new storage encryption key must be set with the --new-encryption-key-file flag or the AUTHELIA_STORAGE_NEW_ENCRYPTION_KEY environment variable")
+
+	_, logged, exited = executeTestCommand(t, newStorageEncryptionChangeKeyCmd(), "--config", path, "--new-encryption-key-file", filepath.Join(t.TempDir(), "missing"))
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "Error reading the new storage encryption key: error reading the file of the --new-encryption-key-file flag")
+}
+
+func TestShouldExportAndImportEncryptedSecondFactors(t *testing.T) {
+	dir := t.TempDir()
+
+	source := storage.NewSQLiteProvider(filepath.Join(dir, "source.sqlite3"), "a_not_so_secure_encryption_key")
+	require.NoError(t, source.SavePreferred2FAMethod("john", "totp"))
+	require.NoError(t, source.SaveTOTPSecret("john", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, source.SaveU2FDeviceHandle("harry", []byte("abc"), []byte("123")))
+
+	destinationPath := filepath.Join(dir, "destination.sqlite3")
+
+	destination := storage.NewSQLiteProvider(destinationPath, "a_not_so_secure_encryption_key")
+	require.NoError(t, destination.SaveU2FDeviceHandle("john", []byte("def"), []byte("456")))
+
+	keyPath := filepath.Join(dir, "export_key")
+	require.NoError(t, ioutil.WriteFile(keyPath, []byte("a_very_important_export_key\n"), 0600))
+
+	exportPath := filepath.Join(dir, "export.yml")
+
+	_, logged, exited := executeTestCommand(t, newStorageExportCmd(),
+		"--config", writeTestConfiguration(t, fmt.Sprintf(`
+storage:
+  encryption_key: a_not_so_secure_encryption_key
+  local:
+    path: %s
+`, filepath.Join(dir, "source.sqlite3"))), "--file", exportPath, "--encryption-key-file", keyPath)
+
+	require.False(t, exited, logged)
+
+	data, err := ioutil.ReadFile(exportPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "key_derivation: argon2id")
+	assert.NotContains(t, string(data), "JBSWY3DPEHPK3PXP")
+
+	configPath := writeTestConfiguration(t, fmt.Sprintf(`
+storage:
+  encryption_key: a_not_so_secure_encryption_key
+  local:
+    path: %s
+`, destinationPath))
+
+	_, logged, exited = executeTestCommand(t, newStorageImportCmd(), exportPath, "--config", configPath)
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "the export is encrypted, its encryption key must be provided")
+
+	_, logged, exited = executeTestCommand(t, newStorageImportCmd(), exportPath, "--config", configPath, "--encryption-key-file", keyPath)
+
+	assert.True(t, exited)
+	assert.Contains(t, logged, "The import was aborted since these users already have second factors: john")
+
+	_, logged, exited = executeTestCommand(t, newStorageImportCmd(), exportPath, "--config", configPath, "--encryption-key-file", keyPath, "--conflict", "overwrite")
+
+	require.False(t, exited, logged)
+
+	secret, err := destination.LoadTOTPSecret("john")
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+
+	// The U2F device of john is missing from the export so it's removed.
+	_, _, err = destination.LoadU2FDeviceHandle("john")
+	assert.Equal(t, storage.ErrNoU2FDeviceHandle, err)
+
+	keyHandle, _, err := destination.LoadU2FDeviceHandle("harry")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("abc"), keyHandle)
+}
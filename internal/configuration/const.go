@@ -25,6 +25,10 @@ const (
 	errFmtGenerateConfiguration = "error occurred generating configuration: %+v"
 )
 
-var secretSuffixes = []string{"key", "secret", "password", "token"}
+var secretSuffixes = []string{"key", "secret", "password", "token", "secrets"}
+
+// secretListSuffixes are the suffixes of the secret keys which hold a list, the secret files of these keys contain one
+// secret per line.
+var secretListSuffixes = []string{"secrets"}
 var errNoSources = errors.New("no sources provided")
 var errNoValidator = errors.New("no validator provided")
@@ -45,6 +45,10 @@ func isSecretKey(key string) (isSecretKey bool) {
 	return utils.IsStringInSliceSuffix(key, secretSuffixes)
 }
 
+func isSecretListKey(key string) (isSecretListKey bool) {
+	return utils.IsStringInSliceSuffix(key, secretListSuffixes)
+}
+
 func loadSecret(path string) (value string, err error) {
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -53,3 +57,18 @@ func loadSecret(path string) (value string, err error) {
 
 	return strings.TrimRight(string(content), "\n"), err
 }
+
+func loadSecretList(path string) (values []string, err error) {
+	value, err := loadSecret(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+
+	return values, nil
+}
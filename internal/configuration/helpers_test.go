@@ -13,6 +13,13 @@ func TestIsSecretKey(t *testing.T) {
 	assert.True(t, isSecretKey("my.password"))
 	assert.False(t, isSecretKey("my.passwords"))
 	assert.False(t, isSecretKey("my.passwords"))
+	assert.True(t, isSecretKey("session.previous_secrets"))
+}
+
+func TestIsSecretListKey(t *testing.T) {
+	assert.True(t, isSecretListKey("session.previous_secrets"))
+	assert.False(t, isSecretListKey("session.secret"))
+	assert.False(t, isSecretListKey("jwt_secret"))
 }
 
 func TestGetEnvConfigMaps(t *testing.T) {
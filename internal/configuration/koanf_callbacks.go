@@ -39,6 +39,16 @@ func koanfEnvironmentSecretsCallback(keyMap map[string]string, validator *schema
 			return "", nil
 		}
 
+		if isSecretListKey(k) {
+			v, err := loadSecretList(value)
+			if err != nil {
+				validator.Push(fmt.Errorf(errFmtSecretIOIssue, value, k, err))
+				return k, []string{}
+			}
+
+			return k, v
+		}
+
 		v, err := loadSecret(value)
 		if err != nil {
 			validator.Push(fmt.Errorf(errFmtSecretIOIssue, value, k, err))
@@ -80,6 +80,28 @@ func TestKoanfSecretCallbackWithValidSecrets(t *testing.T) {
 	assert.Equal(t, "value two", value)
 }
 
+func TestKoanfSecretCallbackWithValidSecretList(t *testing.T) {
+	keyMap := map[string]string{
+		"AUTHELIA_SESSION_PREVIOUS_SECRETS_FILE": "session.previous_secrets",
+	}
+
+	dir, err := ioutil.TempDir("", "authelia-test-callbacks")
+	assert.NoError(t, err)
+
+	secrets := filepath.Join(dir, "previous_secrets")
+
+	assert.NoError(t, testCreateFile(secrets, "value one\n\nvalue two\n", 0600))
+
+	val := schema.NewStructValidator()
+
+	callback := koanfEnvironmentSecretsCallback(keyMap, val)
+
+	key, value := callback("AUTHELIA_SESSION_PREVIOUS_SECRETS_FILE", secrets)
+	assert.Equal(t, "session.previous_secrets", key)
+	assert.Equal(t, []string{"value one", "value two"}, value)
+	assert.Len(t, val.Errors(), 0)
+}
+
 func TestKoanfSecretCallbackShouldIgnoreUndetectedSecrets(t *testing.T) {
 	keyMap := map[string]string{
 		"AUTHELIA__JWT_SECRET": "jwt_secret",
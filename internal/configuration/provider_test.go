@@ -198,6 +198,26 @@ func TestShouldValidateConfigurationWithEnvSecrets(t *testing.T) {
 	assert.Equal(t, "example_secret value", config.Storage.MySQL.Password)
 }
 
+func TestShouldLoadPreviousSessionSecretsFromSecretFile(t *testing.T) {
+	testReset()
+
+	assert.NoError(t, os.Setenv(DefaultEnvPrefix+"SESSION_SECRET_FILE", "./test_resources/example_secret"))
+	assert.NoError(t, os.Setenv(DefaultEnvPrefix+"SESSION_PREVIOUS_SECRETS_FILE", "./test_resources/example_previous_secrets"))
+	assert.NoError(t, os.Setenv(DefaultEnvPrefix+"STORAGE_MYSQL_PASSWORD_FILE", "./test_resources/example_secret"))
+	assert.NoError(t, os.Setenv(DefaultEnvPrefix+"JWT_SECRET_FILE", "./test_resources/example_secret"))
+	assert.NoError(t, os.Setenv(DefaultEnvPrefix+"AUTHENTICATION_BACKEND_LDAP_PASSWORD_FILE", "./test_resources/example_secret"))
+
+	val := schema.NewStructValidator()
+	_, config, err := Load(val, NewDefaultSources([]string{"./test_resources/config.yml"}, DefaultEnvPrefix, DefaultEnvDelimiter)...)
+
+	assert.NoError(t, err)
+	assert.Len(t, val.Errors(), 0)
+	assert.Len(t, val.Warnings(), 0)
+
+	assert.Equal(t, "example_secret value", config.Session.Secret)
+	assert.Equal(t, []string{"previous_secret one", "previous_secret two"}, config.Session.PreviousSecrets)
+}
+
 func TestShouldValidateAndRaiseErrorsOnBadConfiguration(t *testing.T) {
 	testReset()
 
@@ -264,6 +284,7 @@ func testReset() {
 	testUnsetEnvName("JWT_SECRET")
 	testUnsetEnvName("DUO_API_SECRET_KEY")
 	testUnsetEnvName("SESSION_SECRET")
+	testUnsetEnvName("SESSION_PREVIOUS_SECRETS")
 	testUnsetEnvName("AUTHENTICATION_BACKEND_LDAP_PASSWORD")
 	testUnsetEnvName("AUTHENTICATION_BACKEND_LDAP_URL")
 	testUnsetEnvName("NOTIFIER_SMTP_PASSWORD")
@@ -3,24 +3,65 @@ package schema
 // AccessControlConfiguration represents the configuration related to ACLs.
 type AccessControlConfiguration struct {
 	DefaultPolicy string       `koanf:"default_policy"`
+	GeoIP         *ACLGeoIP    `koanf:"geoip"`
 	Networks      []ACLNetwork `koanf:"networks"`
 	Rules         []ACLRule    `koanf:"rules"`
 }
 
+// ACLGeoIP represents the GeoIP databases used to determine the country and autonomous system of a client.
+type ACLGeoIP struct {
+	Path    string `koanf:"path"`
+	ASNPath string `koanf:"asn_path"`
+}
+
 // ACLNetwork represents one ACL network group entry; "weak" coerces a single value into slice.
 type ACLNetwork struct {
-	Name     string   `koanf:"name"`
-	Networks []string `koanf:"networks"`
+	Name      string   `koanf:"name"`
+	Networks  []string `koanf:"networks"`
+	Countries []string `koanf:"countries"`
+	ASNs      []uint   `koanf:"asns"`
 }
 
 // ACLRule represents one ACL rule entry; "weak" coerces a single value into slice.
 type ACLRule struct {
-	Domains   []string   `koanf:"domain"`
-	Policy    string     `koanf:"policy"`
-	Subjects  [][]string `koanf:"subject"`
-	Networks  []string   `koanf:"networks"`
-	Resources []string   `koanf:"resources"`
-	Methods   []string   `koanf:"methods"`
+	Domains              []string                 `koanf:"domain"`
+	DomainsRegex         []string                 `koanf:"domain_regex"`
+	Policy               string                   `koanf:"policy"`
+	Subjects             [][]string               `koanf:"subject"`
+	Networks             []string                 `koanf:"networks"`
+	Countries            []string                 `koanf:"countries"`
+	ASNs                 []uint                   `koanf:"asns"`
+	ClientIDs            []string                 `koanf:"client_ids"`
+	Scopes               []string                 `koanf:"scopes"`
+	Resources            []string                 `koanf:"resources"`
+	Methods              []string                 `koanf:"methods"`
+	Schedule             *ACLSchedule             `koanf:"schedule"`
+	MaxAuthenticationAge *ACLMaxAuthenticationAge `koanf:"max_authentication_age"`
+	Headers              []ACLHeader              `koanf:"headers"`
+}
+
+// ACLSchedule represents the days, times of day and date range during which an ACL rule entry applies, the times are
+// evaluated in the timezone of the schedule.
+type ACLSchedule struct {
+	Timezone string   `koanf:"timezone"`
+	Weekdays []string `koanf:"weekdays"`
+	Times    []string `koanf:"times"`
+	Start    string   `koanf:"start"`
+	End      string   `koanf:"end"`
+}
+
+// ACLMaxAuthenticationAge represents the maximum time since each authentication factor was performed for an ACL rule
+// entry.
+type ACLMaxAuthenticationAge struct {
+	OneFactor string `koanf:"one_factor"`
+	TwoFactor string `koanf:"two_factor"`
+}
+
+// ACLHeader represents a header of an ACL rule entry which is added to the authorized responses. The value is a
+// template rendered with the details of the user and the named subexpressions of the matched patterns.
+type ACLHeader struct {
+	Name  string `koanf:"name"`
+	Value string `koanf:"value"`
 }
 
 // DefaultACLNetwork represents the default configuration related to access control network group configuration.
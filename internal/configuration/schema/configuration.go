@@ -27,4 +27,6 @@ type Configuration struct {
 	Storage               StorageConfiguration               `koanf:"storage"`
 	Notifier              *NotifierConfiguration             `koanf:"notifier"`
 	Server                ServerConfiguration                `koanf:"server"`
+	IdentityAssertion     *IdentityAssertionConfiguration    `koanf:"identity_assertion"`
+	Impersonation         *ImpersonationConfiguration        `koanf:"impersonation"`
 }
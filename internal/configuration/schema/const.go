@@ -23,3 +23,15 @@ const LDAPImplementationCustom = "custom"
 
 // LDAPImplementationActiveDirectory is the string for the Active Directory LDAP implementation.
 const LDAPImplementationActiveDirectory = "activedirectory"
+
+// AuthzImplementationForwardAuth is the string for the authorization endpoint implementation used by the proxies
+// supporting the forward auth pattern such as Traefik and Caddy.
+const AuthzImplementationForwardAuth = "forward_auth"
+
+// AuthzImplementationAuthRequest is the string for the authorization endpoint implementation used by the proxies
+// supporting the auth request pattern such as nginx and HAProxy.
+const AuthzImplementationAuthRequest = "auth_request"
+
+// AuthzImplementationExtAuthz is the string for the authorization endpoint implementation used by the Envoy HTTP
+// external authorization filter.
+const AuthzImplementationExtAuthz = "ext_authz"
@@ -0,0 +1,18 @@
+package schema
+
+import "time"
+
+// IdentityAssertionConfiguration represents the configuration of the signed identity assertion returned by the
+// authorization endpoints.
+type IdentityAssertionConfiguration struct {
+	Header           string        `koanf:"header"`
+	Issuer           string        `koanf:"issuer"`
+	IssuerPrivateKey string        `koanf:"issuer_private_key"`
+	Lifespan         time.Duration `koanf:"lifespan"`
+}
+
+// DefaultIdentityAssertionConfiguration represents the default values of the IdentityAssertionConfiguration.
+var DefaultIdentityAssertionConfiguration = IdentityAssertionConfiguration{
+	Header:   "Remote-Assertion",
+	Lifespan: time.Minute,
+}
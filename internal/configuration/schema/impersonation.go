@@ -0,0 +1,14 @@
+package schema
+
+import "time"
+
+// ImpersonationConfiguration represents the configuration of the impersonation of the users by the administrators.
+type ImpersonationConfiguration struct {
+	Group    string        `koanf:"group"`
+	Duration time.Duration `koanf:"duration"`
+}
+
+// DefaultImpersonationConfiguration represents the default values of the ImpersonationConfiguration.
+var DefaultImpersonationConfiguration = ImpersonationConfiguration{
+	Duration: time.Hour,
+}
@@ -11,7 +11,9 @@ type ServerConfiguration struct {
 	EnableExpvars      bool   `koanf:"enable_endpoint_expvars"`
 	DisableHealthcheck bool   `koanf:"disable_healthcheck"`
 
-	TLS ServerTLSConfiguration `koanf:"tls"`
+	TLS       ServerTLSConfiguration       `koanf:"tls"`
+	Endpoints ServerEndpointsConfiguration `koanf:"endpoints"`
+	ExtAuthz  *ServerExtAuthzConfiguration `koanf:"ext_authz"`
 }
 
 // ServerTLSConfiguration represents the configuration of the http servers TLS options.
@@ -20,10 +22,44 @@ type ServerTLSConfiguration struct {
 	Key         string `koanf:"key"`
 }
 
+// ServerEndpointsConfiguration represents the configuration of the http server endpoints.
+type ServerEndpointsConfiguration struct {
+	Authz []ServerAuthzEndpointConfiguration `koanf:"authz"`
+}
+
+// ServerAuthzEndpointConfiguration represents the configuration of an authorization endpoint served at
+// /api/authz/<name>.
+type ServerAuthzEndpointConfiguration struct {
+	Name           string `koanf:"name"`
+	Implementation string `koanf:"implementation"`
+	PortalURL      string `koanf:"portal_url"`
+}
+
+// ServerExtAuthzConfiguration represents the configuration of the Envoy ext_authz gRPC server.
+type ServerExtAuthzConfiguration struct {
+	Host           string   `koanf:"host"`
+	Port           int      `koanf:"port"`
+	PortalURL      string   `koanf:"portal_url"`
+	TrustedProxies []string `koanf:"trusted_proxies"`
+}
+
 // DefaultServerConfiguration represents the default values of the ServerConfiguration.
 var DefaultServerConfiguration = ServerConfiguration{
 	Host:            "0.0.0.0",
 	Port:            9091,
 	ReadBufferSize:  4096,
 	WriteBufferSize: 4096,
+	Endpoints: ServerEndpointsConfiguration{
+		Authz: []ServerAuthzEndpointConfiguration{
+			{Name: "forward-auth", Implementation: AuthzImplementationForwardAuth},
+			{Name: "auth-request", Implementation: AuthzImplementationAuthRequest},
+			{Name: "ext-authz", Implementation: AuthzImplementationExtAuthz},
+		},
+	},
+}
+
+// DefaultServerExtAuthzConfiguration represents the default values of the ServerExtAuthzConfiguration.
+var DefaultServerExtAuthzConfiguration = ServerExtAuthzConfiguration{
+	Host: "0.0.0.0",
+	Port: 9092,
 }
@@ -1,5 +1,7 @@
 package schema
 
+import "time"
+
 // RedisNode Represents a Node.
 type RedisNode struct {
 	Host string `koanf:"host"`
@@ -15,6 +17,15 @@ type RedisHighAvailabilityConfiguration struct {
 	RouteRandomly    bool        `koanf:"route_randomly"`
 }
 
+// RedisClusterConfiguration holds configuration variables for Redis Cluster.
+type RedisClusterConfiguration struct {
+	Nodes            []RedisNode `koanf:"nodes"`
+	MaximumRedirects int         `koanf:"maximum_redirects"`
+	ReadFromReplicas bool        `koanf:"read_from_replicas"`
+	RouteByLatency   bool        `koanf:"route_by_latency"`
+	RouteRandomly    bool        `koanf:"route_randomly"`
+}
+
 // RedisSessionConfiguration represents the configuration related to redis session store.
 type RedisSessionConfiguration struct {
 	Host                     string                              `koanf:"host"`
@@ -26,18 +37,56 @@ type RedisSessionConfiguration struct {
 	MinimumIdleConnections   int                                 `koanf:"minimum_idle_connections"`
 	TLS                      *TLSConfig                          `koanf:"tls"`
 	HighAvailability         *RedisHighAvailabilityConfiguration `koanf:"high_availability"`
+	Cluster                  *RedisClusterConfiguration          `koanf:"cluster"`
+}
+
+// SQLSessionConfiguration represents the configuration related to the SQL session store, the sessions are persisted
+// in the SQL database of the storage.
+type SQLSessionConfiguration struct {
+	CleanupInterval time.Duration `koanf:"cleanup_interval"`
+}
+
+// SessionBindingConfiguration represents the configuration binding the sessions to the client they were authenticated
+// from.
+type SessionBindingConfiguration struct {
+	IP               string   `koanf:"ip"`
+	IPv4PrefixLength int      `koanf:"ipv4_prefix_length"`
+	IPv6PrefixLength int      `koanf:"ipv6_prefix_length"`
+	UserAgent        bool     `koanf:"user_agent"`
+	Policy           string   `koanf:"policy"`
+	TrustedNetworks  []string `koanf:"trusted_networks"`
+}
+
+// SessionDomainConfiguration represents the configuration of a domain the session cookies are issued for.
+type SessionDomainConfiguration struct {
+	Domain             string `koanf:"domain"`
+	Name               string `koanf:"name"`
+	Expiration         string `koanf:"expiration"`
+	Inactivity         string `koanf:"inactivity"`
+	RememberMeDuration string `koanf:"remember_me_duration"`
+	PortalURL          string `koanf:"portal_url"`
 }
 
 // SessionConfiguration represents the configuration related to user sessions.
 type SessionConfiguration struct {
-	Name               string                     `koanf:"name"`
-	Domain             string                     `koanf:"domain"`
-	SameSite           string                     `koanf:"same_site"`
-	Secret             string                     `koanf:"secret"`
-	Expiration         string                     `koanf:"expiration"`
-	Inactivity         string                     `koanf:"inactivity"`
-	RememberMeDuration string                     `koanf:"remember_me_duration"`
-	Redis              *RedisSessionConfiguration `koanf:"redis"`
+	Name               string                       `koanf:"name"`
+	Domain             string                       `koanf:"domain"`
+	Domains            []SessionDomainConfiguration `koanf:"domains"`
+	TrustedProxies     []string                     `koanf:"trusted_proxies"`
+	SameSite           string                       `koanf:"same_site"`
+	Secret             string                       `koanf:"secret"`
+	PreviousSecrets    []string                     `koanf:"previous_secrets"`
+	Expiration         string                       `koanf:"expiration"`
+	Inactivity         string                       `koanf:"inactivity"`
+	RememberMeDuration string                       `koanf:"remember_me_duration"`
+	AdminGroup         string                       `koanf:"admin_group"`
+	Redis              *RedisSessionConfiguration   `koanf:"redis"`
+	SQL                *SQLSessionConfiguration     `koanf:"sql"`
+	Binding            *SessionBindingConfiguration `koanf:"binding"`
+
+	// MaxConcurrent is the maximum number of concurrent sessions of a user, 0 disables the limit.
+	MaxConcurrent       int    `koanf:"max_concurrent"`
+	MaxConcurrentPolicy string `koanf:"max_concurrent_policy"`
 }
 
 // DefaultSessionConfiguration is the default session configuration.
@@ -47,4 +96,25 @@ var DefaultSessionConfiguration = SessionConfiguration{
 	Inactivity:         "5m",
 	RememberMeDuration: "1M",
 	SameSite:           "lax",
+	TrustedProxies:     []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+
+	MaxConcurrentPolicy: "evict_oldest",
+}
+
+// DefaultRedisClusterConfiguration is the default Redis Cluster session store configuration.
+var DefaultRedisClusterConfiguration = RedisClusterConfiguration{
+	MaximumRedirects: 3,
+}
+
+// DefaultSessionBindingConfiguration is the default session binding configuration.
+var DefaultSessionBindingConfiguration = SessionBindingConfiguration{
+	IP:               "subnet",
+	IPv4PrefixLength: 24,
+	IPv6PrefixLength: 64,
+	Policy:           "reject",
+}
+
+// DefaultSQLSessionConfiguration is the default SQL session store configuration.
+var DefaultSQLSessionConfiguration = SQLSessionConfiguration{
+	CleanupInterval: time.Minute * 5,
 }
@@ -5,6 +5,8 @@ import (
 	"net"
 	"regexp"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
 	"github.com/authelia/authelia/v4/internal/utils"
@@ -59,6 +61,10 @@ func ValidateAccessControl(configuration *schema.AccessControlConfiguration, val
 		validator.Push(fmt.Errorf("'default_policy' must either be 'deny', 'two_factor', 'one_factor' or 'bypass'"))
 	}
 
+	if configuration.GeoIP != nil && configuration.GeoIP.Path == "" && configuration.GeoIP.ASNPath == "" {
+		validator.Push(fmt.Errorf(errAccessControlGeoIPPathRequired))
+	}
+
 	if configuration.Networks != nil {
 		for _, n := range configuration.Networks {
 			for _, networks := range n.Networks {
@@ -66,10 +72,33 @@ func ValidateAccessControl(configuration *schema.AccessControlConfiguration, val
 					validator.Push(fmt.Errorf("Network %s from network group: %s must be a valid IP or CIDR", n.Networks, n.Name))
 				}
 			}
+
+			validateLocation(fmt.Sprintf("network group: %s", n.Name), n.Countries, n.ASNs, configuration, validator)
 		}
 	}
 }
 
+// IsCountryValid check if a country is a valid ISO 3166-1 alpha-2 code.
+func IsCountryValid(country string) (isValid bool) {
+	return reCountryCode.MatchString(country)
+}
+
+func validateLocation(name string, countries []string, asns []uint, configuration *schema.AccessControlConfiguration, validator *schema.StructValidator) {
+	for _, country := range countries {
+		if !IsCountryValid(country) {
+			validator.Push(fmt.Errorf(errFmtAccessControlInvalidCountry, country, name))
+		}
+	}
+
+	if len(countries) != 0 && (configuration.GeoIP == nil || configuration.GeoIP.Path == "") {
+		validator.Push(fmt.Errorf(errFmtAccessControlGeoIPRequired, "countries", name, "path"))
+	}
+
+	if len(asns) != 0 && (configuration.GeoIP == nil || configuration.GeoIP.Path == "" && configuration.GeoIP.ASNPath == "") {
+		validator.Push(fmt.Errorf(errFmtAccessControlGeoIPRequired, "asns", name, "path or asn_path"))
+	}
+}
+
 // ValidateRules validates an ACL Rule configuration.
 func ValidateRules(configuration schema.AccessControlConfiguration, validator *schema.StructValidator) {
 	if configuration.Rules == nil || len(configuration.Rules) == 0 {
@@ -87,7 +116,7 @@ func ValidateRules(configuration schema.AccessControlConfiguration, validator *s
 	for i, rule := range configuration.Rules {
 		rulePosition := i + 1
 
-		if len(rule.Domains) == 0 {
+		if len(rule.Domains)+len(rule.DomainsRegex) == 0 {
 			validator.Push(fmt.Errorf("Rule #%d is invalid, a policy must have one or more domains", rulePosition))
 		}
 
@@ -95,20 +124,105 @@ func ValidateRules(configuration schema.AccessControlConfiguration, validator *s
 			validator.Push(fmt.Errorf("Policy [%s] for rule #%d domain: %s is invalid, a policy must either be 'deny', 'two_factor', 'one_factor' or 'bypass'", rule.Policy, rulePosition, rule.Domains))
 		}
 
+		validateDomainRegex(rulePosition, rule, validator)
+
 		validateNetworks(rulePosition, rule, configuration, validator)
 
+		validateLocation(fmt.Sprintf("rule #%d domain: %s", rulePosition, rule.Domains), rule.Countries, rule.ASNs, &configuration, validator)
+
 		validateResources(rulePosition, rule, validator)
 
 		validateSubjects(rulePosition, rule, validator)
 
 		validateMethods(rulePosition, rule, validator)
 
+		validateSchedule(rulePosition, rule, validator)
+
+		validateMaxAuthenticationAge(rulePosition, rule, validator)
+
+		validateHeaders(rulePosition, rule, validator)
+
 		if rule.Policy == policyBypass && len(rule.Subjects) != 0 {
 			validator.Push(fmt.Errorf(errAccessControlInvalidPolicyWithSubjects, rulePosition, rule.Domains, rule.Subjects))
 		}
+
+		if rule.Policy == policyBypass && hasSubjectSubexp(rule) {
+			validator.Push(fmt.Errorf(errFmtAccessControlInvalidPolicyWithSubexp, rulePosition, rule.Domains))
+		}
+
+		if rule.Policy == policyBypass && len(rule.ClientIDs)+len(rule.Scopes) != 0 {
+			validator.Push(fmt.Errorf(errFmtAccessControlInvalidPolicyWithClients, rulePosition, rule.Domains))
+		}
+	}
+}
+
+// ValidateRulesClients validates the client criteria of the ACL rules against the OpenID Connect clients.
+func ValidateRulesClients(configuration *schema.Configuration, validator *schema.StructValidator) {
+	for i, rule := range configuration.AccessControl.Rules {
+		if len(rule.ClientIDs)+len(rule.Scopes) == 0 {
+			continue
+		}
+
+		if configuration.IdentityProviders.OIDC == nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlClientsRequireOIDC, i+1, rule.Domains))
+
+			continue
+		}
+
+		// The client ids are the allow-list opting the rule in to the access tokens so scopes alone are not enough.
+		if len(rule.ClientIDs) == 0 {
+			validator.Push(fmt.Errorf(errFmtAccessControlScopesRequireClients, i+1, rule.Domains))
+
+			continue
+		}
+
+		for _, id := range rule.ClientIDs {
+			if !isOpenIDConnectClientConfigured(configuration.IdentityProviders.OIDC.Clients, id) {
+				validator.Push(fmt.Errorf(errFmtAccessControlUnknownClient, id, i+1, rule.Domains))
+			}
+		}
 	}
 }
 
+func isOpenIDConnectClientConfigured(clients []schema.OpenIDConnectClientConfiguration, id string) bool {
+	for _, client := range clients {
+		if client.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateDomainRegex(rulePosition int, rule schema.ACLRule, validator *schema.StructValidator) {
+	for _, domainRegex := range rule.DomainsRegex {
+		if _, err := regexp.Compile(domainRegex); err != nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlInvalidDomainRegex, domainRegex, rulePosition, rule.Domains, err))
+		}
+	}
+}
+
+// hasSubjectSubexp returns true if one of the domain regex or resource patterns of the rule has a named subexpression
+// which is compared to the subject.
+func hasSubjectSubexp(rule schema.ACLRule) bool {
+	patterns := append(append([]string{}, rule.DomainsRegex...), rule.Resources...)
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+
+		for _, name := range re.SubexpNames() {
+			if strings.EqualFold(name, subexpNameUser) || strings.EqualFold(name, subexpNameGroup) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func validateNetworks(rulePosition int, rule schema.ACLRule, configuration schema.AccessControlConfiguration, validator *schema.StructValidator) {
 	for _, network := range rule.Networks {
 		if !IsNetworkValid(network) {
@@ -144,3 +258,115 @@ func validateMethods(rulePosition int, rule schema.ACLRule, validator *schema.St
 		}
 	}
 }
+
+func validateSchedule(rulePosition int, rule schema.ACLRule, validator *schema.StructValidator) {
+	if rule.Schedule == nil {
+		return
+	}
+
+	location := time.Local
+
+	if rule.Schedule.Timezone != "" {
+		var err error
+
+		if location, err = time.LoadLocation(rule.Schedule.Timezone); err != nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlScheduleInvalid, rulePosition, rule.Domains, "timezone", err))
+
+			location = time.Local
+		}
+	}
+
+	for _, weekday := range rule.Schedule.Weekdays {
+		if _, err := utils.ParseWeekday(weekday); err != nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlScheduleInvalid, rulePosition, rule.Domains, "weekdays", err))
+		}
+	}
+
+	for _, timeRange := range rule.Schedule.Times {
+		if _, _, err := utils.ParseTimeOfDayRange(timeRange); err != nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlScheduleInvalid, rulePosition, rule.Domains, "times", err))
+		}
+	}
+
+	var start, end time.Time
+
+	if rule.Schedule.Start != "" {
+		var err error
+
+		if start, _, err = utils.ParseDateTime(rule.Schedule.Start, location); err != nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlScheduleInvalid, rulePosition, rule.Domains, "start", err))
+		}
+	}
+
+	if rule.Schedule.End != "" {
+		var (
+			dateOnly bool
+			err      error
+		)
+
+		if end, dateOnly, err = utils.ParseDateTime(rule.Schedule.End, location); err != nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlScheduleInvalid, rulePosition, rule.Domains, "end", err))
+		} else if dateOnly {
+			// A date without a time is inclusive of the whole day like when the rule is evaluated.
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		validator.Push(fmt.Errorf(errFmtAccessControlScheduleEndBeforeStart, rulePosition, rule.Domains))
+	}
+}
+
+func validateMaxAuthenticationAge(rulePosition int, rule schema.ACLRule, validator *schema.StructValidator) {
+	if rule.MaxAuthenticationAge == nil {
+		return
+	}
+
+	validateMaxAuthenticationAgeFactor(rulePosition, rule, "one_factor", rule.MaxAuthenticationAge.OneFactor,
+		[]string{policyOneFactor, policyTwoFactor}, validator)
+	validateMaxAuthenticationAgeFactor(rulePosition, rule, "two_factor", rule.MaxAuthenticationAge.TwoFactor,
+		[]string{policyTwoFactor}, validator)
+}
+
+func validateMaxAuthenticationAgeFactor(rulePosition int, rule schema.ACLRule, factor, value string, policies []string, validator *schema.StructValidator) {
+	if value == "" {
+		return
+	}
+
+	if !utils.IsStringInSlice(rule.Policy, policies) {
+		validator.Push(fmt.Errorf(errFmtAccessControlMaxAuthenticationAgeP, factor, rulePosition, rule.Domains, rule.Policy))
+
+		return
+	}
+
+	duration, err := utils.ParseDurationString(value)
+
+	switch {
+	case err != nil:
+		validator.Push(fmt.Errorf(errFmtAccessControlMaxAuthenticationAge, factor, rulePosition, rule.Domains, err))
+	case duration <= 0:
+		validator.Push(fmt.Errorf(errFmtAccessControlMaxAuthenticationAge, factor, rulePosition, rule.Domains, "it must be greater than 0"))
+	}
+}
+
+func validateHeaders(rulePosition int, rule schema.ACLRule, validator *schema.StructValidator) {
+	var names []string
+
+	for _, header := range rule.Headers {
+		if !reHeaderName.MatchString(header.Name) {
+			validator.Push(fmt.Errorf(errFmtAccessControlInvalidHeaderName, header.Name, rulePosition, rule.Domains))
+
+			continue
+		}
+
+		if utils.IsStringInSliceFold(header.Name, names) {
+			validator.Push(fmt.Errorf(errFmtAccessControlDuplicateHeader, header.Name, rulePosition, rule.Domains))
+		}
+
+		names = append(names, header.Name)
+
+		if _, err := template.New(header.Name).Parse(header.Value); err != nil {
+			validator.Push(fmt.Errorf(errFmtAccessControlInvalidHeaderValue, header.Name, rulePosition, rule.Domains, err))
+		}
+	}
+}
@@ -19,6 +19,7 @@ type AccessControl struct {
 func (suite *AccessControl) SetupTest() {
 	suite.validator = schema.NewStructValidator()
 	suite.configuration.DefaultPolicy = policyDeny
+	suite.configuration.GeoIP = nil
 	suite.configuration.Networks = schema.DefaultACLNetwork
 	suite.configuration.Rules = schema.DefaultACLRule
 }
@@ -57,6 +58,78 @@ func (suite *AccessControl) TestShouldRaiseErrorInvalidNetworkGroupNetwork() {
 	suite.Assert().EqualError(suite.validator.Errors()[0], "Network [abc.def.ghi.jkl] from network group: internal must be a valid IP or CIDR")
 }
 
+func (suite *AccessControl) TestShouldValidateLocation() {
+	suite.configuration.GeoIP = &schema.ACLGeoIP{Path: "/config/GeoLite2-Country.mmdb"}
+	suite.configuration.Networks = []schema.ACLNetwork{
+		{
+			Name:      "eu",
+			Countries: []string{"FR", "de"},
+		},
+	}
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:   []string{"public.example.com"},
+			Policy:    "one_factor",
+			Countries: []string{"FR"},
+			ASNs:      []uint{64496},
+		},
+	}
+
+	ValidateAccessControl(&suite.configuration, suite.validator)
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidLocation() {
+	suite.configuration.GeoIP = &schema.ACLGeoIP{ASNPath: "/config/GeoLite2-ASN.mmdb"}
+	suite.configuration.Networks = []schema.ACLNetwork{
+		{
+			Name:      "eu",
+			Countries: []string{"FRA"},
+		},
+	}
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:   []string{"public.example.com"},
+			Policy:    "one_factor",
+			Countries: []string{"FR"},
+			ASNs:      []uint{64496},
+		},
+	}
+
+	ValidateAccessControl(&suite.configuration, suite.validator)
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 3)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Country FRA for network group: eu is invalid, must be an ISO 3166-1 alpha-2 code")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "The countries for network group: eu require the GeoIP path to be configured")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "The countries for rule #1 domain: [public.example.com] require the GeoIP path to be configured")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorGeoIPWithoutPath() {
+	suite.configuration.GeoIP = &schema.ACLGeoIP{}
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"public.example.com"},
+			Policy:  "one_factor",
+			ASNs:    []uint{64496},
+		},
+	}
+
+	ValidateAccessControl(&suite.configuration, suite.validator)
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "GeoIP must have either a path or an asn_path when configured")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "The asns for rule #1 domain: [public.example.com] require the GeoIP path or asn_path to be configured")
+}
+
 func (suite *AccessControl) TestShouldRaiseErrorWithNoRulesDefined() {
 	suite.configuration.Rules = []schema.ACLRule{}
 
@@ -182,6 +255,315 @@ func (suite *AccessControl) TestShouldRaiseErrorInvalidSubject() {
 	suite.Assert().EqualError(suite.validator.Errors()[1], fmt.Sprintf(errAccessControlInvalidPolicyWithSubjects, 1, domains, subjects))
 }
 
+func (suite *AccessControl) TestShouldValidateDomainRegex() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			DomainsRegex: []string{`^(?P<User>\w+)\.example\.com$`},
+			Policy:       "one_factor",
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidDomainRegex() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:      []string{"public.example.com"},
+			DomainsRegex: []string{`^(\w+\.example\.com$`},
+			Policy:       "one_factor",
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Domain regex ^(\\w+\\.example\\.com$ for rule #1 domain: [public.example.com] is invalid, error parsing regexp: missing closing ): `^(\\w+\\.example\\.com$`")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorBypassWithSubjectSubexp() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains:   []string{"public.example.com"},
+			Resources: []string{`^/users/(?P<User>\w+)/`},
+			Policy:    "bypass",
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], fmt.Sprintf(errFmtAccessControlInvalidPolicyWithSubexp, 1, []string{"public.example.com"}))
+}
+
+func (suite *AccessControl) TestShouldValidateSchedule() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			Schedule: &schema.ACLSchedule{
+				Timezone: "Europe/London",
+				Weekdays: []string{"monday", "fri"},
+				Times:    []string{"09:00-17:00", "22:00-02:00"},
+				Start:    "2021-01-01",
+				End:      "2021-12-31 18:00",
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *AccessControl) TestShouldValidateScheduleEndingOnTheDayOfTheStart() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			Schedule: &schema.ACLSchedule{
+				Start: "2021-07-01 09:00",
+				End:   "2021-07-01",
+			},
+		},
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			Schedule: &schema.ACLSchedule{
+				Start: "2021-07-01",
+				End:   "2021-07-01",
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidSchedule() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			Schedule: &schema.ACLSchedule{
+				Timezone: "Mars/Olympus_Mons",
+				Weekdays: []string{"someday"},
+				Times:    []string{"9-5"},
+				Start:    "2021-12-31",
+				End:      "2021-01-01",
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 4)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Schedule for rule #1 domain: [admin.example.com] has an invalid timezone: unknown time zone Mars/Olympus_Mons")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Schedule for rule #1 domain: [admin.example.com] has an invalid weekdays: could not parse 'someday' as a day of the week")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "Schedule for rule #1 domain: [admin.example.com] has an invalid times: could not parse '9-5' as a time range: the time '9' must be in the format of HH:MM")
+	suite.Assert().EqualError(suite.validator.Errors()[3], "Schedule for rule #1 domain: [admin.example.com] is invalid, the end must be after the start")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorScheduleEndingAtTheStart() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			Schedule: &schema.ACLSchedule{
+				Start: "2021-07-01 09:00",
+				End:   "2021-07-01 09:00",
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Schedule for rule #1 domain: [admin.example.com] is invalid, the end must be after the start")
+}
+
+func (suite *AccessControl) TestShouldValidateMaxAuthenticationAge() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			MaxAuthenticationAge: &schema.ACLMaxAuthenticationAge{
+				OneFactor: "1d",
+				TwoFactor: "10m",
+			},
+		},
+		{
+			Domains: []string{"app.example.com"},
+			Policy:  "one_factor",
+			MaxAuthenticationAge: &schema.ACLMaxAuthenticationAge{
+				OneFactor: "1h",
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidMaxAuthenticationAge() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"admin.example.com"},
+			Policy:  "two_factor",
+			MaxAuthenticationAge: &schema.ACLMaxAuthenticationAge{
+				OneFactor: "abc",
+				TwoFactor: "0",
+			},
+		},
+		{
+			Domains: []string{"app.example.com"},
+			Policy:  "one_factor",
+			MaxAuthenticationAge: &schema.ACLMaxAuthenticationAge{
+				TwoFactor: "10m",
+			},
+		},
+		{
+			Domains: []string{"public.example.com"},
+			Policy:  "bypass",
+			MaxAuthenticationAge: &schema.ACLMaxAuthenticationAge{
+				OneFactor: "10m",
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 4)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Max authentication age one_factor for rule #1 domain: [admin.example.com] is invalid, could not convert the input string of abc into a duration")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Max authentication age two_factor for rule #1 domain: [admin.example.com] is invalid, it must be greater than 0")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "Max authentication age two_factor for rule #2 domain: [app.example.com] is invalid, it can't be used with policy [one_factor]")
+	suite.Assert().EqualError(suite.validator.Errors()[3], "Max authentication age one_factor for rule #3 domain: [public.example.com] is invalid, it can't be used with policy [bypass]")
+}
+
+func (suite *AccessControl) TestShouldValidateHeaders() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			DomainsRegex: []string{`^(?P<tenant>\w+)\.example\.com$`},
+			Policy:       "one_factor",
+			Headers: []schema.ACLHeader{
+				{Name: "X-WEBAUTH-USER", Value: "{{ .Username }}"},
+				{Name: "X-Groups", Value: `{{ .Groups.Join "|" }}`},
+				{Name: "X-Tenant", Value: "{{ .Captures.tenant }}"},
+				{Name: "X-Static", Value: "static"},
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorInvalidHeaders() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"app.example.com"},
+			Policy:  "one_factor",
+			Headers: []schema.ACLHeader{
+				{Name: "X User", Value: "{{ .Username }}"},
+				{Name: "X-User", Value: "{{ .Username }}"},
+				{Name: "x-user", Value: "{{ .Username"},
+			},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 3)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Header X User for rule #1 domain: [app.example.com] is invalid, the name must only contain alphanumeric characters and hyphens")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Header x-user for rule #1 domain: [app.example.com] is invalid, it's configured more than once")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "Header x-user for rule #1 domain: [app.example.com] has an invalid value template: template: x-user:1: unclosed action")
+}
+
+func (suite *AccessControl) TestShouldRaiseErrorBypassWithClients() {
+	suite.configuration.Rules = []schema.ACLRule{
+		{
+			Domains: []string{"public.example.com"},
+			Policy:  "bypass",
+			Scopes:  []string{"api:read"},
+		},
+	}
+
+	ValidateRules(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "policy [bypass] for rule #1 domain [public.example.com] with client_ids or scopes is invalid. It is not supported to configure both policy bypass and client criteria")
+}
+
+func (suite *AccessControl) TestShouldValidateRulesClients() {
+	config := &schema.Configuration{
+		AccessControl: schema.AccessControlConfiguration{
+			DefaultPolicy: policyDeny,
+			Rules: []schema.ACLRule{
+				{
+					Domains:   []string{"api.example.com"},
+					Policy:    "one_factor",
+					ClientIDs: []string{"app", "other"},
+				},
+				{
+					Domains: []string{"api.example.com"},
+					Policy:  "one_factor",
+					Scopes:  []string{"api:read"},
+				},
+				{
+					Domains: []string{"public.example.com"},
+					Policy:  "bypass",
+				},
+			},
+		},
+	}
+
+	ValidateRulesClients(config, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Rule #1 domain: [api.example.com] is invalid, client_ids and scopes require the OpenID Connect identity provider to be configured")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Rule #2 domain: [api.example.com] is invalid, client_ids and scopes require the OpenID Connect identity provider to be configured")
+
+	suite.validator.Clear()
+
+	config.IdentityProviders.OIDC = &schema.OpenIDConnectConfiguration{
+		Clients: []schema.OpenIDConnectClientConfiguration{{ID: "app"}},
+	}
+
+	ValidateRulesClients(config, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 2)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Client other for rule #1 domain: [api.example.com] is invalid, it's not an OpenID Connect client")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Rule #2 domain: [api.example.com] is invalid, scopes require the client_ids allowed to use access tokens to be configured")
+}
+
 func TestAccessControl(t *testing.T) {
 	suite.Run(t, new(AccessControl))
 }
@@ -66,6 +66,12 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 
 	ValidateIdentityProviders(&configuration.IdentityProviders, validator)
 
+	ValidateRulesClients(configuration, validator)
+
+	ValidateIdentityAssertion(configuration.IdentityAssertion, validator)
+
+	ValidateImpersonation(configuration.Impersonation, validator)
+
 	if configuration.NTP == nil {
 		configuration.NTP = &schema.DefaultNTPConfiguration
 	}
@@ -15,6 +15,12 @@ const (
 	policyDeny      = "deny"
 )
 
+// Access control named subexpression constants.
+const (
+	subexpNameUser  = "User"
+	subexpNameGroup = "Group"
+)
+
 // Hashing constants.
 const (
 	hashArgon2id = "argon2id"
@@ -29,6 +35,18 @@ const (
 	schemeHTTPS = "https"
 )
 
+// Session binding constants.
+const (
+	sessionBindingIPNone       = "none"
+	sessionBindingIPExact      = "exact"
+	sessionBindingIPSubnet     = "subnet"
+	sessionBindingPolicyReject = "reject"
+	sessionBindingPolicyStepUp = "step_up"
+
+	sessionMaxConcurrentPolicyEvictOldest = "evict_oldest"
+	sessionMaxConcurrentPolicyReject      = "reject"
+)
+
 // Test constants.
 const (
 	testBadTimer      = "-1"
@@ -97,6 +115,33 @@ const (
 	errFmtSessionRedisHostRequired        = "the host must be provided when using the %s session provider"
 	errFmtSessionRedisHostOrNodesRequired = "either the host or a node must be provided when using the %s session provider"
 
+	errSessionPreviousSecretEmpty = "session: the previous_secrets must not contain an empty secret"
+
+	errFmtSessionTrustedProxy = "session: the trusted proxy '%s' is not a valid IP or CIDR notation"
+
+	errFmtSessionMaxConcurrent       = "session: the max_concurrent option must be 0 or more but it is configured as %d"
+	errFmtSessionMaxConcurrentPolicy = "session: the max_concurrent_policy option must be one of 'evict_oldest' or 'reject' but it is configured as '%s'"
+
+	errSessionSQLAndRedis = "session: the sql and redis session providers can't both be configured"
+
+	errFmtSessionBindingIP             = "session: binding: the ip option must be one of 'none', 'exact' or 'subnet' but it is configured as '%s'"
+	errFmtSessionBindingPrefixLength   = "session: binding: the %s option must be between 1 and %d but it is configured as %d"
+	errFmtSessionBindingPolicy         = "session: binding: the policy option must be one of 'reject' or 'step_up' but it is configured as '%s'"
+	errFmtSessionBindingTrustedNetwork = "session: binding: the trusted network '%s' is not a valid IP or CIDR notation"
+
+	errFmtServerExtAuthzTrustedProxy = "server: ext_authz: the trusted proxy '%s' is not a valid IP or CIDR notation"
+
+	errSessionRedisClusterAndHighAvailability = "session: the redis cluster and high_availability options can't both be configured"
+	errSessionRedisClusterDatabaseIndex       = "session: the database_index must be 0 when using the redis cluster session provider"
+
+	errSessionDomainAndDomains   = "session: the domain option can't be used together with the domains option"
+	errFmtSessionDomainRequired  = "session: domain #%d must have a domain set"
+	errFmtSessionDomainWildcard  = "session: domain %s must be the root domain you're protecting instead of a wildcard domain"
+	errFmtSessionDomainDuplicate = "session: domain %s is configured more than once"
+	errFmtSessionDomainOverlap   = "session: domain %s overlaps with domain %s, a session domain can't be under another session domain"
+	errFmtSessionDomainDuration  = "session: domain %s has an invalid %s: %v"
+	errFmtSessionDomainPortalURL = "session: domain %s portal_url %s is invalid: %v"
+
 	errFileHashing  = "config key incorrect: authentication_backend.file.hashing should be authentication_backend.file.password"
 	errFilePHashing = "config key incorrect: authentication_backend.file.password_hashing should be authentication_backend.file.password"
 	errFilePOptions = "config key incorrect: authentication_backend.file.password_options should be authentication_backend.file.password"
@@ -104,6 +149,42 @@ const (
 	errAccessControlInvalidPolicyWithSubjects = "policy [bypass] for rule #%d domain %s with subjects %s is invalid. It is " +
 		"not supported to configure both policy bypass and subjects. For more information see: " +
 		"https://www.authelia.com/docs/configuration/access-control.html#combining-subjects-and-the-bypass-policy"
+	errFmtAccessControlInvalidDomainRegex      = "Domain regex %s for rule #%d domain: %s is invalid, %s"
+	errFmtAccessControlInvalidPolicyWithSubexp = "policy [bypass] for rule #%d domain %s with the 'User' or 'Group' " +
+		"named subexpressions is invalid. It is not supported to configure both policy bypass and subject specific " +
+		"named subexpressions"
+	errFmtAccessControlScheduleInvalid        = "Schedule for rule #%d domain: %s has an invalid %s: %v"
+	errFmtAccessControlScheduleEndBeforeStart = "Schedule for rule #%d domain: %s is invalid, the end must be after the start"
+	errAccessControlGeoIPPathRequired         = "GeoIP must have either a path or an asn_path when configured"
+	errFmtAccessControlInvalidCountry         = "Country %s for %s is invalid, must be an ISO 3166-1 alpha-2 code"
+	errFmtAccessControlGeoIPRequired          = "The %s for %s require the GeoIP %s to be configured"
+	errFmtAccessControlMaxAuthenticationAge   = "Max authentication age %s for rule #%d domain: %s is invalid, %v"
+	errFmtAccessControlMaxAuthenticationAgeP  = "Max authentication age %s for rule #%d domain: %s is invalid, it can't be " +
+		"used with policy [%s]"
+	errFmtAccessControlInvalidPolicyWithClients = "policy [bypass] for rule #%d domain %s with client_ids or scopes is " +
+		"invalid. It is not supported to configure both policy bypass and client criteria"
+	errFmtAccessControlClientsRequireOIDC = "Rule #%d domain: %s is invalid, client_ids and scopes require the " +
+		"OpenID Connect identity provider to be configured"
+	errFmtAccessControlUnknownClient        = "Client %s for rule #%d domain: %s is invalid, it's not an OpenID Connect client"
+	errFmtAccessControlScopesRequireClients = "Rule #%d domain: %s is invalid, scopes require the client_ids " +
+		"allowed to use access tokens to be configured"
+
+	errFmtAccessControlInvalidHeaderName = "Header %s for rule #%d domain: %s is invalid, the name must only " +
+		"contain alphanumeric characters and hyphens"
+	errFmtAccessControlDuplicateHeader    = "Header %s for rule #%d domain: %s is invalid, it's configured more than once"
+	errFmtAccessControlInvalidHeaderValue = "Header %s for rule #%d domain: %s has an invalid value template: %v"
+)
+
+const (
+	errFmtIdentityAssertionNoPrivateKey    = "identity assertion: issuer private key must be provided"
+	errFmtIdentityAssertionInvalidHeader   = "identity assertion: header %s is invalid, the name must only contain alphanumeric characters and hyphens"
+	errFmtIdentityAssertionInvalidIssuer   = "identity assertion: issuer %s is invalid: %v"
+	errFmtIdentityAssertionInvalidLifespan = "identity assertion: lifespan must be greater than 0"
+)
+
+const (
+	errFmtImpersonationNoGroup         = "impersonation: group must be provided"
+	errFmtImpersonationInvalidDuration = "impersonation: duration must be greater than 0"
 )
 
 var validLoggingLevels = []string{"trace", "debug", "info", "warn", "error"}
@@ -115,6 +196,9 @@ var validOIDCResponseModes = []string{"form_post", "query", "fragment"}
 var validOIDCUserinfoAlgorithms = []string{"none", "RS256"}
 
 var reKeyReplacer = regexp.MustCompile(`\[\d+]`)
+var reCountryCode = regexp.MustCompile(`^[A-Za-z]{2}$`)
+var reAuthzEndpointName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+var reHeaderName = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
 
 // ValidKeys is a list of valid keys that are not secret names. For the sake of consistency please place any secret in
 // the secret names map and reuse it in relevant sections.
@@ -152,6 +236,13 @@ var ValidKeys = []string{
 	"server.disable_healthcheck",
 	"server.tls.key",
 	"server.tls.certificate",
+	"server.endpoints.authz[].name",
+	"server.endpoints.authz[].implementation",
+	"server.endpoints.authz[].portal_url",
+	"server.ext_authz.host",
+	"server.ext_authz.port",
+	"server.ext_authz.portal_url",
+	"server.ext_authz.trusted_proxies",
 
 	// TOTP Keys.
 	"totp.issuer",
@@ -165,23 +256,65 @@ var ValidKeys = []string{
 
 	// Access Control Keys.
 	"access_control.default_policy",
+	"access_control.geoip",
+	"access_control.geoip.path",
+	"access_control.geoip.asn_path",
 	"access_control.networks",
 	"access_control.rules",
 	"access_control.rules[].domain",
+	"access_control.rules[].domain_regex",
 	"access_control.rules[].methods",
 	"access_control.rules[].networks",
+	"access_control.rules[].countries",
+	"access_control.rules[].asns",
+	"access_control.rules[].client_ids",
+	"access_control.rules[].scopes",
 	"access_control.rules[].subject",
 	"access_control.rules[].policy",
 	"access_control.rules[].resources",
+	"access_control.rules[].schedule",
+	"access_control.rules[].schedule.timezone",
+	"access_control.rules[].schedule.weekdays",
+	"access_control.rules[].schedule.times",
+	"access_control.rules[].schedule.start",
+	"access_control.rules[].schedule.end",
+	"access_control.rules[].max_authentication_age",
+	"access_control.rules[].max_authentication_age.one_factor",
+	"access_control.rules[].max_authentication_age.two_factor",
+	"access_control.rules[].headers",
+	"access_control.rules[].headers[].name",
+	"access_control.rules[].headers[].value",
 
 	// Session Keys.
 	"session.name",
 	"session.domain",
 	"session.secret",
+	"session.previous_secrets",
 	"session.same_site",
 	"session.expiration",
 	"session.inactivity",
 	"session.remember_me_duration",
+	"session.admin_group",
+	"session.max_concurrent",
+	"session.max_concurrent_policy",
+	"session.domains[].domain",
+	"session.domains[].name",
+	"session.domains[].expiration",
+	"session.domains[].inactivity",
+	"session.domains[].remember_me_duration",
+	"session.domains[].portal_url",
+	"session.trusted_proxies",
+
+	// Session Binding Keys.
+	"session.binding.ip",
+	"session.binding.ipv4_prefix_length",
+	"session.binding.ipv6_prefix_length",
+	"session.binding.user_agent",
+	"session.binding.policy",
+	"session.binding.trusted_networks",
+
+	// SQL Session Keys.
+	"session.sql.cleanup_interval",
 
 	// Redis Session Keys.
 	"session.redis.host",
@@ -199,12 +332,20 @@ var ValidKeys = []string{
 	"session.redis.high_availability.nodes",
 	"session.redis.high_availability.route_by_latency",
 	"session.redis.high_availability.route_randomly",
+	"session.redis.cluster.nodes",
+	"session.redis.cluster.maximum_redirects",
+	"session.redis.cluster.read_from_replicas",
+	"session.redis.cluster.route_by_latency",
+	"session.redis.cluster.route_randomly",
 	"session.redis.timeouts.dial",
 	"session.redis.timeouts.idle",
 	"session.redis.timeouts.pool",
 	"session.redis.timeouts.read",
 	"session.redis.timeouts.write",
 
+	// Storage Keys.
+	"storage.encryption_key",
+
 	// Local Storage Keys.
 	"storage.local.path",
 
@@ -308,6 +449,16 @@ var ValidKeys = []string{
 	"ntp.max_desync",
 	"ntp.disable_startup_check",
 	"ntp.disable_failure",
+
+	// Identity Assertion Keys.
+	"identity_assertion.header",
+	"identity_assertion.issuer",
+	"identity_assertion.issuer_private_key",
+	"identity_assertion.lifespan",
+
+	// Impersonation Keys.
+	"impersonation.group",
+	"impersonation.duration",
 }
 
 var replacedKeys = map[string]string{
@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// ValidateIdentityAssertion validates and update the identity assertion configuration.
+func ValidateIdentityAssertion(configuration *schema.IdentityAssertionConfiguration, validator *schema.StructValidator) {
+	if configuration == nil {
+		return
+	}
+
+	if configuration.IssuerPrivateKey == "" {
+		validator.Push(fmt.Errorf(errFmtIdentityAssertionNoPrivateKey))
+	}
+
+	if configuration.Header == "" {
+		configuration.Header = schema.DefaultIdentityAssertionConfiguration.Header
+	} else if !reHeaderName.MatchString(configuration.Header) {
+		validator.Push(fmt.Errorf(errFmtIdentityAssertionInvalidHeader, configuration.Header))
+	}
+
+	if configuration.Issuer != "" {
+		if err := utils.IsStringAbsURL(configuration.Issuer); err != nil {
+			validator.Push(fmt.Errorf(errFmtIdentityAssertionInvalidIssuer, configuration.Issuer, err))
+		}
+	}
+
+	switch {
+	case configuration.Lifespan == 0:
+		configuration.Lifespan = schema.DefaultIdentityAssertionConfiguration.Lifespan
+	case configuration.Lifespan < 0:
+		validator.Push(fmt.Errorf(errFmtIdentityAssertionInvalidLifespan))
+	}
+}
@@ -0,0 +1,47 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+func TestShouldNotValidateMissingIdentityAssertion(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	ValidateIdentityAssertion(nil, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldSetDefaultIdentityAssertionValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := &schema.IdentityAssertionConfiguration{IssuerPrivateKey: "key-material"}
+
+	ValidateIdentityAssertion(config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "Remote-Assertion", config.Header)
+	assert.Equal(t, time.Minute, config.Lifespan)
+}
+
+func TestShouldRaiseErrorsOnInvalidIdentityAssertion(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := &schema.IdentityAssertionConfiguration{
+		Header:   "Remote Assertion",
+		Issuer:   "auth.example.com",
+		Lifespan: -time.Minute,
+	}
+
+	ValidateIdentityAssertion(config, validator)
+
+	require.Len(t, validator.Errors(), 4)
+	assert.EqualError(t, validator.Errors()[0], "identity assertion: issuer private key must be provided")
+	assert.EqualError(t, validator.Errors()[1], "identity assertion: header Remote Assertion is invalid, the name must only contain alphanumeric characters and hyphens")
+	assert.EqualError(t, validator.Errors()[2], "identity assertion: issuer auth.example.com is invalid: the url 'auth.example.com' is not absolute because it doesn't start with a scheme like 'http://' or 'https://'")
+	assert.EqualError(t, validator.Errors()[3], "identity assertion: lifespan must be greater than 0")
+}
@@ -0,0 +1,25 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+// ValidateImpersonation validates and update the impersonation configuration.
+func ValidateImpersonation(configuration *schema.ImpersonationConfiguration, validator *schema.StructValidator) {
+	if configuration == nil {
+		return
+	}
+
+	if configuration.Group == "" {
+		validator.Push(fmt.Errorf(errFmtImpersonationNoGroup))
+	}
+
+	switch {
+	case configuration.Duration == 0:
+		configuration.Duration = schema.DefaultImpersonationConfiguration.Duration
+	case configuration.Duration < 0:
+		validator.Push(fmt.Errorf(errFmtImpersonationInvalidDuration))
+	}
+}
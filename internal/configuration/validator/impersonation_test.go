@@ -0,0 +1,40 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+func TestShouldNotValidateMissingImpersonation(t *testing.T) {
+	validator := schema.NewStructValidator()
+
+	ValidateImpersonation(nil, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldSetDefaultImpersonationValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := &schema.ImpersonationConfiguration{Group: "admins"}
+
+	ValidateImpersonation(config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+	assert.Equal(t, time.Hour, config.Duration)
+}
+
+func TestShouldRaiseErrorsOnInvalidImpersonation(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := &schema.ImpersonationConfiguration{Duration: -time.Minute}
+
+	ValidateImpersonation(config, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "impersonation: group must be provided")
+	assert.EqualError(t, validator.Errors()[1], "impersonation: duration must be greater than 0")
+}
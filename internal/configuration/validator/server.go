@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"net/url"
 	"path"
 	"strings"
 
@@ -48,6 +49,83 @@ func ValidateServer(configuration *schema.Configuration, validator *schema.Struc
 	} else if configuration.Server.WriteBufferSize < 0 {
 		validator.Push(fmt.Errorf("server write buffer size must be above 0"))
 	}
+
+	validateServerEndpointsAuthz(configuration, validator)
+
+	if configuration.Server.ExtAuthz != nil {
+		validateServerExtAuthz(configuration, validator)
+	}
+}
+
+func validateServerEndpointsAuthz(configuration *schema.Configuration, validator *schema.StructValidator) {
+	if len(configuration.Server.Endpoints.Authz) == 0 {
+		configuration.Server.Endpoints.Authz = append([]schema.ServerAuthzEndpointConfiguration{}, schema.DefaultServerConfiguration.Endpoints.Authz...)
+
+		return
+	}
+
+	names := make([]string, 0, len(configuration.Server.Endpoints.Authz))
+
+	for _, endpoint := range configuration.Server.Endpoints.Authz {
+		switch {
+		case !reAuthzEndpointName.MatchString(endpoint.Name):
+			validator.Push(fmt.Errorf("server: authz endpoint name '%s' is invalid, it must only contain lowercase alphanumeric characters and hyphens", endpoint.Name))
+		case utils.IsStringInSlice(endpoint.Name, names):
+			validator.Push(fmt.Errorf("server: authz endpoint name '%s' is configured more than once", endpoint.Name))
+		}
+
+		names = append(names, endpoint.Name)
+
+		switch endpoint.Implementation {
+		case schema.AuthzImplementationForwardAuth, schema.AuthzImplementationAuthRequest, schema.AuthzImplementationExtAuthz:
+		default:
+			validator.Push(fmt.Errorf("server: authz endpoint '%s' implementation '%s' is invalid, it must be either '%s', '%s' or '%s'",
+				endpoint.Name, endpoint.Implementation, schema.AuthzImplementationForwardAuth, schema.AuthzImplementationAuthRequest, schema.AuthzImplementationExtAuthz))
+		}
+
+		validateServerPortalURL(fmt.Sprintf("authz endpoint '%s'", endpoint.Name), endpoint.PortalURL, validator)
+	}
+}
+
+func validateServerExtAuthz(configuration *schema.Configuration, validator *schema.StructValidator) {
+	config := configuration.Server.ExtAuthz
+
+	if config.Host == "" {
+		config.Host = schema.DefaultServerExtAuthzConfiguration.Host
+	}
+
+	if config.Port == 0 {
+		config.Port = schema.DefaultServerExtAuthzConfiguration.Port
+	}
+
+	if config.Port < 0 || config.Port > 65535 {
+		validator.Push(fmt.Errorf("server: ext_authz port %d is invalid, it must be between 1 and 65535", config.Port))
+	} else if config.Host == configuration.Server.Host && config.Port == configuration.Server.Port {
+		validator.Push(fmt.Errorf("server: ext_authz port %d must be different from the server port", config.Port))
+	}
+
+	validateServerPortalURL("ext_authz", config.PortalURL, validator)
+
+	for _, network := range config.TrustedProxies {
+		if !IsNetworkValid(network) {
+			validator.Push(fmt.Errorf(errFmtServerExtAuthzTrustedProxy, network))
+		}
+	}
+}
+
+func validateServerPortalURL(name, value string, validator *schema.StructValidator) {
+	if value == "" {
+		return
+	}
+
+	portalURL, err := url.Parse(value)
+
+	switch {
+	case err != nil:
+		validator.Push(fmt.Errorf("server: %s portal_url is invalid: %v", name, err))
+	case portalURL.Scheme != "https" || portalURL.Host == "":
+		validator.Push(fmt.Errorf("server: %s portal_url %s is invalid, it must be an absolute https URL", name, value))
+	}
 }
 
 func applyDeprecatedServerConfiguration(configuration *schema.Configuration, validator *schema.StructValidator) {
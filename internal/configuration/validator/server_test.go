@@ -219,3 +219,82 @@ func TestShouldValidateAndUpdatePort(t *testing.T) {
 	require.Len(t, validator.Errors(), 0)
 	assert.Equal(t, 9091, config.Server.Port)
 }
+
+func TestShouldSetDefaultServerExtAuthzValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Server.ExtAuthz = &schema.ServerExtAuthzConfiguration{}
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "0.0.0.0", config.Server.ExtAuthz.Host)
+	assert.Equal(t, 9092, config.Server.ExtAuthz.Port)
+	assert.Equal(t, "", config.Server.ExtAuthz.PortalURL)
+}
+
+func TestShouldRaiseErrorOnInvalidServerExtAuthz(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Server.ExtAuthz = &schema.ServerExtAuthzConfiguration{
+		Host:      loopback,
+		Port:      9090,
+		PortalURL: "http://login.example.com",
+	}
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "server: ext_authz port 9090 must be different from the server port")
+	assert.EqualError(t, validator.Errors()[1], "server: ext_authz portal_url http://login.example.com is invalid, it must be an absolute https URL")
+
+	validator.Clear()
+
+	config.Server.ExtAuthz.Port = 70000
+	config.Server.ExtAuthz.PortalURL = "https://login.example.com"
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "server: ext_authz port 70000 is invalid, it must be between 1 and 65535")
+
+	validator.Clear()
+
+	config.Server.ExtAuthz.Port = 9092
+	config.Server.ExtAuthz.TrustedProxies = []string{"192.168.1.0/24", "10.0.0.1", "proxy.example.com"}
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "server: ext_authz: the trusted proxy 'proxy.example.com' is not a valid IP or CIDR notation")
+}
+
+func TestShouldSetDefaultServerEndpointsAuthz(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, schema.DefaultServerConfiguration.Endpoints.Authz, config.Server.Endpoints.Authz)
+}
+
+func TestShouldRaiseErrorOnInvalidServerEndpointsAuthz(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.Server.Endpoints.Authz = []schema.ServerAuthzEndpointConfiguration{
+		{Name: "nginx", Implementation: schema.AuthzImplementationAuthRequest, PortalURL: "https://login.example.com"},
+		{Name: "nginx", Implementation: schema.AuthzImplementationForwardAuth},
+		{Name: "Traefik", Implementation: "forwardauth"},
+		{Name: "envoy", Implementation: schema.AuthzImplementationExtAuthz, PortalURL: "login.example.com"},
+	}
+
+	ValidateServer(&config, validator)
+
+	require.Len(t, validator.Errors(), 4)
+	assert.EqualError(t, validator.Errors()[0], "server: authz endpoint name 'nginx' is configured more than once")
+	assert.EqualError(t, validator.Errors()[1], "server: authz endpoint name 'Traefik' is invalid, it must only contain lowercase alphanumeric characters and hyphens")
+	assert.EqualError(t, validator.Errors()[2], "server: authz endpoint 'Traefik' implementation 'forwardauth' is invalid, it must be either 'forward_auth', 'auth_request' or 'ext_authz'")
+	assert.EqualError(t, validator.Errors()[3], "server: authz endpoint 'envoy' portal_url login.example.com is invalid, it must be an absolute https URL")
+	assert.Len(t, config.Server.Endpoints.Authz, 4)
+}
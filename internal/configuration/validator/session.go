@@ -3,6 +3,7 @@ package validator
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
@@ -16,18 +17,29 @@ func ValidateSession(configuration *schema.SessionConfiguration, validator *sche
 	}
 
 	if configuration.Redis != nil {
-		if configuration.Redis.HighAvailability != nil {
+		switch {
+		case configuration.Redis.Cluster != nil:
+			validateRedisCluster(configuration, validator)
+		case configuration.Redis.HighAvailability != nil:
 			if configuration.Redis.HighAvailability.SentinelName != "" {
 				validateRedisSentinel(configuration, validator)
 			} else {
 				validator.Push(fmt.Errorf("Session provider redis is configured for high availability but doesn't have a sentinel_name which is required"))
 			}
-		} else {
+		default:
 			validateRedis(configuration, validator)
 		}
 	}
 
+	if configuration.SQL != nil {
+		validateSQL(configuration, validator)
+	}
+
 	validateSession(configuration, validator)
+
+	if configuration.Binding != nil {
+		validateSessionBinding(configuration.Binding, validator)
+	}
 }
 
 func validateSession(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
@@ -49,19 +61,161 @@ func validateSession(configuration *schema.SessionConfiguration, validator *sche
 		validator.Push(fmt.Errorf("Error occurred parsing session remember_me_duration string: %s", err))
 	}
 
-	if configuration.Domain == "" {
+	switch {
+	case configuration.Domain == "" && len(configuration.Domains) == 0:
 		validator.Push(errors.New("Set domain of the session object"))
+	case configuration.Domain != "" && len(configuration.Domains) != 0:
+		validator.Push(errors.New(errSessionDomainAndDomains))
 	}
 
 	if strings.Contains(configuration.Domain, "*") {
 		validator.Push(errors.New("The domain of the session must be the root domain you're protecting instead of a wildcard domain"))
 	}
 
+	validateSessionDomains(configuration, validator)
+
+	if len(configuration.TrustedProxies) == 0 {
+		configuration.TrustedProxies = schema.DefaultSessionConfiguration.TrustedProxies
+	}
+
+	for _, network := range configuration.TrustedProxies {
+		if !IsNetworkValid(network) {
+			validator.Push(fmt.Errorf(errFmtSessionTrustedProxy, network))
+		}
+	}
+
+	for _, secret := range configuration.PreviousSecrets {
+		if secret == "" {
+			validator.Push(errors.New(errSessionPreviousSecretEmpty))
+			break
+		}
+	}
+
 	if configuration.SameSite == "" {
 		configuration.SameSite = schema.DefaultSessionConfiguration.SameSite
 	} else if configuration.SameSite != "none" && configuration.SameSite != "lax" && configuration.SameSite != "strict" {
 		validator.Push(errors.New("session same_site is configured incorrectly, must be one of 'none', 'lax', or 'strict'"))
 	}
+
+	if configuration.MaxConcurrent < 0 {
+		validator.Push(fmt.Errorf(errFmtSessionMaxConcurrent, configuration.MaxConcurrent))
+	}
+
+	switch configuration.MaxConcurrentPolicy {
+	case "":
+		configuration.MaxConcurrentPolicy = schema.DefaultSessionConfiguration.MaxConcurrentPolicy
+	case sessionMaxConcurrentPolicyEvictOldest, sessionMaxConcurrentPolicyReject:
+		break
+	default:
+		validator.Push(fmt.Errorf(errFmtSessionMaxConcurrentPolicy, configuration.MaxConcurrentPolicy))
+	}
+}
+
+func validateSessionDomains(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
+	var domains []string
+
+	for i, d := range configuration.Domains {
+		switch {
+		case d.Domain == "":
+			validator.Push(fmt.Errorf(errFmtSessionDomainRequired, i+1))
+
+			continue
+		case strings.Contains(d.Domain, "*"):
+			validator.Push(fmt.Errorf(errFmtSessionDomainWildcard, d.Domain))
+		case utils.IsStringInSlice(d.Domain, domains):
+			validator.Push(fmt.Errorf(errFmtSessionDomainDuplicate, d.Domain))
+		default:
+			// The session domain of a request would be ambiguous if a domain was under another one.
+			for _, domain := range domains {
+				if strings.HasSuffix(d.Domain, "."+domain) || strings.HasSuffix(domain, "."+d.Domain) {
+					validator.Push(fmt.Errorf(errFmtSessionDomainOverlap, d.Domain, domain))
+				}
+			}
+		}
+
+		domains = append(domains, d.Domain)
+
+		if d.Name == "" {
+			configuration.Domains[i].Name = configuration.Name
+		}
+
+		if d.Expiration == "" {
+			configuration.Domains[i].Expiration = configuration.Expiration
+		} else if _, err := utils.ParseDurationString(d.Expiration); err != nil {
+			validator.Push(fmt.Errorf(errFmtSessionDomainDuration, d.Domain, "expiration", err))
+		}
+
+		if d.Inactivity == "" {
+			configuration.Domains[i].Inactivity = configuration.Inactivity
+		} else if _, err := utils.ParseDurationString(d.Inactivity); err != nil {
+			validator.Push(fmt.Errorf(errFmtSessionDomainDuration, d.Domain, "inactivity", err))
+		}
+
+		if d.RememberMeDuration == "" {
+			configuration.Domains[i].RememberMeDuration = configuration.RememberMeDuration
+		} else if _, err := utils.ParseDurationString(d.RememberMeDuration); err != nil {
+			validator.Push(fmt.Errorf(errFmtSessionDomainDuration, d.Domain, "remember_me_duration", err))
+		}
+
+		validateSessionDomainPortalURL(d, validator)
+	}
+}
+
+func validateSessionDomainPortalURL(configuration schema.SessionDomainConfiguration, validator *schema.StructValidator) {
+	if configuration.PortalURL == "" {
+		return
+	}
+
+	portalURL, err := url.Parse(configuration.PortalURL)
+
+	switch {
+	case err != nil:
+		validator.Push(fmt.Errorf(errFmtSessionDomainPortalURL, configuration.Domain, configuration.PortalURL, err))
+	case portalURL.Scheme != "https" || portalURL.Host == "":
+		validator.Push(fmt.Errorf(errFmtSessionDomainPortalURL, configuration.Domain, configuration.PortalURL, "it must be an absolute https URL"))
+	case portalURL.Hostname() != configuration.Domain && !strings.HasSuffix(portalURL.Hostname(), "."+configuration.Domain):
+		validator.Push(fmt.Errorf(errFmtSessionDomainPortalURL, configuration.Domain, configuration.PortalURL, "it must be under the domain"))
+	}
+}
+
+func validateSessionBinding(configuration *schema.SessionBindingConfiguration, validator *schema.StructValidator) {
+	switch configuration.IP {
+	case "":
+		configuration.IP = schema.DefaultSessionBindingConfiguration.IP
+	case sessionBindingIPNone, sessionBindingIPExact, sessionBindingIPSubnet:
+		break
+	default:
+		validator.Push(fmt.Errorf(errFmtSessionBindingIP, configuration.IP))
+	}
+
+	switch {
+	case configuration.IPv4PrefixLength == 0:
+		configuration.IPv4PrefixLength = schema.DefaultSessionBindingConfiguration.IPv4PrefixLength
+	case configuration.IPv4PrefixLength < 0 || configuration.IPv4PrefixLength > 32:
+		validator.Push(fmt.Errorf(errFmtSessionBindingPrefixLength, "ipv4_prefix_length", 32, configuration.IPv4PrefixLength))
+	}
+
+	switch {
+	case configuration.IPv6PrefixLength == 0:
+		configuration.IPv6PrefixLength = schema.DefaultSessionBindingConfiguration.IPv6PrefixLength
+	case configuration.IPv6PrefixLength < 0 || configuration.IPv6PrefixLength > 128:
+		validator.Push(fmt.Errorf(errFmtSessionBindingPrefixLength, "ipv6_prefix_length", 128, configuration.IPv6PrefixLength))
+	}
+
+	switch configuration.Policy {
+	case "":
+		configuration.Policy = schema.DefaultSessionBindingConfiguration.Policy
+	case sessionBindingPolicyReject, sessionBindingPolicyStepUp:
+		break
+	default:
+		validator.Push(fmt.Errorf(errFmtSessionBindingPolicy, configuration.Policy))
+	}
+
+	for _, network := range configuration.TrustedNetworks {
+		if !IsNetworkValid(network) {
+			validator.Push(fmt.Errorf(errFmtSessionBindingTrustedNetwork, network))
+		}
+	}
 }
 
 func validateRedis(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
@@ -84,6 +238,20 @@ func validateRedis(configuration *schema.SessionConfiguration, validator *schema
 	}
 }
 
+func validateSQL(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
+	if configuration.Redis != nil {
+		validator.Push(errors.New(errSessionSQLAndRedis))
+	}
+
+	if configuration.Secret == "" {
+		validator.Push(fmt.Errorf(errFmtSessionSecretRedisProvider, "sql"))
+	}
+
+	if configuration.SQL.CleanupInterval <= 0 {
+		configuration.SQL.CleanupInterval = schema.DefaultSQLSessionConfiguration.CleanupInterval
+	}
+}
+
 func validateRedisSentinel(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
 	if configuration.Redis.Port == 0 {
 		configuration.Redis.Port = 26379
@@ -94,6 +262,54 @@ func validateRedisSentinel(configuration *schema.SessionConfiguration, validator
 	validateHighAvailability(configuration, validator, "redis sentinel")
 }
 
+func validateRedisCluster(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
+	if configuration.Redis.HighAvailability != nil {
+		validator.Push(errors.New(errSessionRedisClusterAndHighAvailability))
+	}
+
+	if configuration.Redis.Host == "" && len(configuration.Redis.Cluster.Nodes) == 0 {
+		validator.Push(fmt.Errorf(errFmtSessionRedisHostOrNodesRequired, "redis cluster"))
+	}
+
+	if configuration.Secret == "" {
+		validator.Push(fmt.Errorf(errFmtSessionSecretRedisProvider, "redis cluster"))
+	}
+
+	if configuration.Redis.Host != "" {
+		if configuration.Redis.Port == 0 {
+			configuration.Redis.Port = 6379
+		} else if configuration.Redis.Port < 0 || configuration.Redis.Port > 65535 {
+			validator.Push(fmt.Errorf(errFmtSessionRedisPortRange, "redis cluster"))
+		}
+	}
+
+	// Redis Cluster only supports the database 0.
+	if configuration.Redis.DatabaseIndex != 0 {
+		validator.Push(errors.New(errSessionRedisClusterDatabaseIndex))
+	}
+
+	for i, node := range configuration.Redis.Cluster.Nodes {
+		if node.Host == "" {
+			validator.Push(errors.New("The redis cluster nodes require a host set but you have not set the host for one or more nodes"))
+			break
+		}
+
+		if node.Port == 0 {
+			configuration.Redis.Cluster.Nodes[i].Port = 6379
+		} else if node.Port < 0 || node.Port > 65535 {
+			validator.Push(fmt.Errorf(errFmtSessionRedisPortRange, "redis cluster"))
+		}
+	}
+
+	if configuration.Redis.Cluster.MaximumRedirects == 0 {
+		configuration.Redis.Cluster.MaximumRedirects = schema.DefaultRedisClusterConfiguration.MaximumRedirects
+	}
+
+	if configuration.Redis.MaximumActiveConnections <= 0 {
+		configuration.Redis.MaximumActiveConnections = 8
+	}
+}
+
 func validateHighAvailability(configuration *schema.SessionConfiguration, validator *schema.StructValidator, provider string) {
 	if configuration.Redis.Host == "" && len(configuration.Redis.HighAvailability.Nodes) == 0 {
 		validator.Push(fmt.Errorf(errFmtSessionRedisHostOrNodesRequired, provider))
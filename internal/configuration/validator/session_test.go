@@ -350,6 +350,89 @@ func TestShouldRaiseErrorWhenRedisHostAndHighAvailabilityNodesEmpty(t *testing.T
 	assert.EqualError(t, validator.Errors()[0], fmt.Sprintf(errFmtSessionRedisHostOrNodesRequired, "redis sentinel"))
 }
 
+func TestShouldSetDefaultPortsWhenRedisClusterHasNodes(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+
+	config.Redis = &schema.RedisSessionConfiguration{
+		Host: "redis",
+		Cluster: &schema.RedisClusterConfiguration{
+			Nodes: []schema.RedisNode{
+				{
+					Host: "node-1",
+					Port: 7000,
+				},
+				{
+					Host: "node-2",
+				},
+			},
+			ReadFromReplicas: true,
+		},
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+
+	assert.Equal(t, 6379, config.Redis.Port)
+	assert.Equal(t, 7000, config.Redis.Cluster.Nodes[0].Port)
+	assert.Equal(t, 6379, config.Redis.Cluster.Nodes[1].Port)
+	assert.Equal(t, 3, config.Redis.Cluster.MaximumRedirects)
+	assert.Equal(t, 8, config.Redis.MaximumActiveConnections)
+}
+
+func TestShouldRaiseErrorsWhenRedisClusterOptionsIncorrectlyConfigured(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+
+	config.Secret = ""
+	config.Redis = &schema.RedisSessionConfiguration{
+		DatabaseIndex:    1,
+		HighAvailability: &schema.RedisHighAvailabilityConfiguration{SentinelName: "sentinel"},
+		Cluster:          &schema.RedisClusterConfiguration{},
+	}
+
+	ValidateSession(&config, validator)
+
+	errors := validator.Errors()
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, errors, 4)
+
+	assert.EqualError(t, errors[0], errSessionRedisClusterAndHighAvailability)
+	assert.EqualError(t, errors[1], fmt.Sprintf(errFmtSessionRedisHostOrNodesRequired, "redis cluster"))
+	assert.EqualError(t, errors[2], fmt.Sprintf(errFmtSessionSecretRedisProvider, "redis cluster"))
+	assert.EqualError(t, errors[3], errSessionRedisClusterDatabaseIndex)
+
+	validator.Clear()
+
+	config.Secret = testJWTSecret
+	config.Redis = &schema.RedisSessionConfiguration{
+		Cluster: &schema.RedisClusterConfiguration{
+			Nodes: []schema.RedisNode{
+				{
+					Host: "node-1",
+					Port: 65536,
+				},
+				{
+					Port: 7000,
+				},
+			},
+		},
+	}
+
+	ValidateSession(&config, validator)
+
+	errors = validator.Errors()
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, errors, 2)
+
+	assert.EqualError(t, errors[0], fmt.Sprintf(errFmtSessionRedisPortRange, "redis cluster"))
+	assert.EqualError(t, errors[1], "The redis cluster nodes require a host set but you have not set the host for one or more nodes")
+}
+
 func TestShouldRaiseErrorsWhenRedisHostNotSet(t *testing.T) {
 	validator := schema.NewStructValidator()
 	config := newDefaultSessionConfig()
@@ -456,3 +539,236 @@ func TestShouldSetDefaultRememberMeDuration(t *testing.T) {
 	assert.False(t, validator.HasErrors())
 	assert.Equal(t, config.RememberMeDuration, schema.DefaultSessionConfiguration.RememberMeDuration)
 }
+
+func TestShouldSetDefaultSessionDomainsOptions(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Domain = ""
+	config.Domains = []schema.SessionDomainConfiguration{
+		{Domain: "example.com"},
+		{Domain: "example.net", Name: "authelia_net", Expiration: "2h", Inactivity: "10m", RememberMeDuration: "0", PortalURL: "https://login.example.net"},
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, schema.SessionDomainConfiguration{
+		Domain:             "example.com",
+		Name:               schema.DefaultSessionConfiguration.Name,
+		Expiration:         schema.DefaultSessionConfiguration.Expiration,
+		Inactivity:         schema.DefaultSessionConfiguration.Inactivity,
+		RememberMeDuration: schema.DefaultSessionConfiguration.RememberMeDuration,
+	}, config.Domains[0])
+	assert.Equal(t, schema.SessionDomainConfiguration{
+		Domain:             "example.net",
+		Name:               "authelia_net",
+		Expiration:         "2h",
+		Inactivity:         "10m",
+		RememberMeDuration: "0",
+		PortalURL:          "https://login.example.net",
+	}, config.Domains[1])
+}
+
+func TestShouldRaiseErrorWhenDomainAndDomainsSet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Domains = []schema.SessionDomainConfiguration{{Domain: "example.net"}}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], errSessionDomainAndDomains)
+}
+
+func TestShouldRaiseErrorsWhenSessionDomainsIncorrectlyConfigured(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Domain = ""
+	config.Domains = []schema.SessionDomainConfiguration{
+		{Domain: "example.com", Expiration: "1 year"},
+		{},
+		{Domain: "*.example.net", PortalURL: "http://login.example.net"},
+		{Domain: "example.com", PortalURL: "https://login.example.org"},
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 6)
+	assert.EqualError(t, validator.Errors()[0], "session: domain example.com has an invalid expiration: could not convert the input string of 1 year into a duration")
+	assert.EqualError(t, validator.Errors()[1], "session: domain #2 must have a domain set")
+	assert.EqualError(t, validator.Errors()[2], "session: domain *.example.net must be the root domain you're protecting instead of a wildcard domain")
+	assert.EqualError(t, validator.Errors()[3], "session: domain *.example.net portal_url http://login.example.net is invalid: it must be an absolute https URL")
+	assert.EqualError(t, validator.Errors()[4], "session: domain example.com is configured more than once")
+	assert.EqualError(t, validator.Errors()[5], "session: domain example.com portal_url https://login.example.org is invalid: it must be under the domain")
+}
+
+func TestShouldRaiseErrorWhenSessionDomainsOverlap(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Domain = ""
+	config.Domains = []schema.SessionDomainConfiguration{
+		{Domain: "auth.example.com"},
+		{Domain: "example.net"},
+		{Domain: "example.com"},
+		{Domain: "app.example.net"},
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "session: domain example.com overlaps with domain auth.example.com, a session domain can't be under another session domain")
+	assert.EqualError(t, validator.Errors()[1], "session: domain app.example.net overlaps with domain example.net, a session domain can't be under another session domain")
+}
+
+func TestShouldSetDefaultSessionTrustedProxies(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, schema.DefaultSessionConfiguration.TrustedProxies, config.TrustedProxies)
+}
+
+func TestShouldRaiseErrorWhenSessionTrustedProxyInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.TrustedProxies = []string{"10.0.0.1", "192.168.0.0/16", "proxy.example.com"}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "session: the trusted proxy 'proxy.example.com' is not a valid IP or CIDR notation")
+}
+
+func TestShouldSetDefaultSQLSessionCleanupInterval(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.SQL = &schema.SQLSessionConfiguration{}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+	assert.Equal(t, schema.DefaultSQLSessionConfiguration.CleanupInterval, config.SQL.CleanupInterval)
+}
+
+func TestShouldRaiseErrorWhenSQLIsUsedAndSecretNotSet(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Secret = ""
+	config.SQL = &schema.SQLSessionConfiguration{}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], fmt.Sprintf(errFmtSessionSecretRedisProvider, "sql"))
+}
+
+func TestShouldRaiseErrorWhenSQLAndRedisAreUsed(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.SQL = &schema.SQLSessionConfiguration{}
+	config.Redis = &schema.RedisSessionConfiguration{
+		Host: "redis.localhost",
+		Port: 6379,
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], errSessionSQLAndRedis)
+}
+
+func TestShouldSetDefaultSessionBindingOptions(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Binding = &schema.SessionBindingConfiguration{}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+
+	assert.Equal(t, "subnet", config.Binding.IP)
+	assert.Equal(t, 24, config.Binding.IPv4PrefixLength)
+	assert.Equal(t, 64, config.Binding.IPv6PrefixLength)
+	assert.Equal(t, "reject", config.Binding.Policy)
+	assert.False(t, config.Binding.UserAgent)
+}
+
+func TestShouldRaiseErrorsWhenSessionBindingIncorrectlyConfigured(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Binding = &schema.SessionBindingConfiguration{
+		IP:               "strict",
+		IPv4PrefixLength: 33,
+		IPv6PrefixLength: -1,
+		Policy:           "logout",
+		TrustedNetworks:  []string{"10.0.0.0/8", "10.0.0.0/33", "abc"},
+	}
+
+	ValidateSession(&config, validator)
+
+	errors := validator.Errors()
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, errors, 6)
+
+	assert.EqualError(t, errors[0], fmt.Sprintf(errFmtSessionBindingIP, "strict"))
+	assert.EqualError(t, errors[1], fmt.Sprintf(errFmtSessionBindingPrefixLength, "ipv4_prefix_length", 32, 33))
+	assert.EqualError(t, errors[2], fmt.Sprintf(errFmtSessionBindingPrefixLength, "ipv6_prefix_length", 128, -1))
+	assert.EqualError(t, errors[3], fmt.Sprintf(errFmtSessionBindingPolicy, "logout"))
+	assert.EqualError(t, errors[4], fmt.Sprintf(errFmtSessionBindingTrustedNetwork, "10.0.0.0/33"))
+	assert.EqualError(t, errors[5], fmt.Sprintf(errFmtSessionBindingTrustedNetwork, "abc"))
+}
+
+func TestShouldRaiseErrorWhenPreviousSecretEmpty(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.PreviousSecrets = []string{"previous", ""}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 1)
+
+	assert.EqualError(t, validator.Errors()[0], errSessionPreviousSecretEmpty)
+}
+
+func TestShouldSetDefaultMaxConcurrentPolicy(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.MaxConcurrent = 3
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+
+	assert.Equal(t, 3, config.MaxConcurrent)
+	assert.Equal(t, "evict_oldest", config.MaxConcurrentPolicy)
+}
+
+func TestShouldRaiseErrorsWhenMaxConcurrentIsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.MaxConcurrent = -1
+	config.MaxConcurrentPolicy = "evict_newest"
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 2)
+
+	assert.EqualError(t, validator.Errors()[0], fmt.Sprintf(errFmtSessionMaxConcurrent, -1))
+	assert.EqualError(t, validator.Errors()[1], fmt.Sprintf(errFmtSessionMaxConcurrentPolicy, "evict_newest"))
+}
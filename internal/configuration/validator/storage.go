@@ -20,6 +20,10 @@ func ValidateStorage(configuration schema.StorageConfiguration, validator *schem
 	case configuration.Local != nil:
 		validateLocalStorageConfiguration(configuration.Local, validator)
 	}
+
+	if configuration.EncryptionKey != "" && len(configuration.EncryptionKey) < 20 {
+		validator.Push(errors.New("the storage encryption key must be at least 20 characters long"))
+	}
 }
 
 func validateMySQLConfiguration(configuration *schema.SQLStorageConfiguration, validator *schema.StructValidator) {
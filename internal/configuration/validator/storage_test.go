@@ -50,6 +50,25 @@ func (suite *StorageSuite) TestShouldValidateLocalPathIsProvided() {
 	suite.Assert().False(suite.validator.HasErrors())
 }
 
+func (suite *StorageSuite) TestShouldValidateEncryptionKeyLength() {
+	suite.configuration.EncryptionKey = "too short"
+
+	ValidateStorage(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "the storage encryption key must be at least 20 characters long")
+
+	suite.validator.Clear()
+	suite.configuration.EncryptionKey = "a_very_important_secret_key"
+
+	ValidateStorage(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+}
+
 func (suite *StorageSuite) TestShouldValidateSQLUsernamePasswordAndDatabaseAreProvided() {
 	suite.configuration.MySQL = &schema.MySQLStorageConfiguration{}
 	ValidateStorage(suite.configuration, suite.validator)
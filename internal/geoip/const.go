@@ -0,0 +1,9 @@
+package geoip
+
+import "errors"
+
+// cacheSize is the maximum number of records cached by the MaxMindProvider before the cache is cleared.
+const cacheSize = 10000
+
+// ErrClosed is returned by the lookups of a closed provider.
+var ErrClosed = errors.New("the GeoIP databases are closed")
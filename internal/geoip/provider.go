@@ -0,0 +1,161 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+// NewMaxMindProvider opens the MaxMind DB files of the configuration and returns a MaxMindProvider.
+func NewMaxMindProvider(config *schema.ACLGeoIP) (provider *MaxMindProvider, err error) {
+	provider = &MaxMindProvider{
+		config: config,
+		cache:  map[string]Record{},
+	}
+
+	if provider.country, provider.asn, err = openMaxMindReaders(config); err != nil {
+		return nil, err
+	}
+
+	return provider, nil
+}
+
+// Lookup returns the location of the IP address. The records are cached until the cache is full or the databases
+// are reloaded. It returns ErrClosed once the provider is closed.
+func (p *MaxMindProvider) Lookup(ip net.IP) (record Record, err error) {
+	if ip == nil {
+		return record, nil
+	}
+
+	key := string(ip.To16())
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.closed {
+		return record, ErrClosed
+	}
+
+	p.cacheMutex.Lock()
+	record, ok := p.cache[key]
+	p.cacheMutex.Unlock()
+
+	if ok {
+		return record, nil
+	}
+
+	if p.country != nil {
+		if err = lookupMaxMindRecord(p.country, ip, &record); err != nil {
+			return Record{}, err
+		}
+	}
+
+	if p.asn != nil {
+		if err = lookupMaxMindRecord(p.asn, ip, &record); err != nil {
+			return Record{}, err
+		}
+	}
+
+	p.cacheMutex.Lock()
+
+	if len(p.cache) >= cacheSize {
+		p.cache = map[string]Record{}
+	}
+
+	p.cache[key] = record
+
+	p.cacheMutex.Unlock()
+
+	return record, nil
+}
+
+// Reload opens the MaxMind DB files again and replaces the current databases. If they can't be opened the current
+// databases remain in use. A closed provider is not reloaded.
+func (p *MaxMindProvider) Reload() (err error) {
+	country, asn, err := openMaxMindReaders(p.config)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		closeMaxMindReaders(country, asn)
+
+		return nil
+	}
+
+	closeMaxMindReaders(p.country, p.asn)
+
+	p.country, p.asn = country, asn
+	p.cache = map[string]Record{}
+
+	return nil
+}
+
+// Close closes the MaxMind DB files, the provider isn't reloaded anymore once it's closed.
+func (p *MaxMindProvider) Close() (err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	closeMaxMindReaders(p.country, p.asn)
+
+	p.country, p.asn = nil, nil
+	p.closed = true
+
+	return nil
+}
+
+func openMaxMindReaders(config *schema.ACLGeoIP) (country, asn *maxminddb.Reader, err error) {
+	if config.Path != "" {
+		if country, err = maxminddb.Open(config.Path); err != nil {
+			return nil, nil, fmt.Errorf("unable to open the GeoIP database %s: %w", config.Path, err)
+		}
+	}
+
+	if config.ASNPath != "" {
+		if asn, err = maxminddb.Open(config.ASNPath); err != nil {
+			closeMaxMindReaders(country, nil)
+
+			return nil, nil, fmt.Errorf("unable to open the GeoIP database %s: %w", config.ASNPath, err)
+		}
+	}
+
+	return country, asn, nil
+}
+
+func closeMaxMindReaders(readers ...*maxminddb.Reader) {
+	for _, reader := range readers {
+		if reader != nil {
+			_ = reader.Close()
+		}
+	}
+}
+
+// lookupMaxMindRecord fills the fields of the record which are present in the database for the IP address.
+func lookupMaxMindRecord(reader *maxminddb.Reader, ip net.IP, record *Record) (err error) {
+	// An IPv6 address can't be looked up in a database which only contains IPv4 addresses.
+	if ip.To4() == nil && reader.Metadata.IPVersion == 4 {
+		return nil
+	}
+
+	var result maxMindRecord
+
+	if err = reader.Lookup(ip, &result); err != nil {
+		return fmt.Errorf("unable to lookup the location of %s: %w", ip, err)
+	}
+
+	if result.Country.ISOCode != "" {
+		record.Country = result.Country.ISOCode
+	}
+
+	if result.ASN != 0 {
+		record.ASN = result.ASN
+	}
+
+	return nil
+}
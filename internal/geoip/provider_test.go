@@ -0,0 +1,262 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+// testNetwork is a network and the location written to a test MaxMind DB file.
+type testNetwork struct {
+	CIDR    string
+	Country string
+	ASN     uint32
+}
+
+type testNode struct {
+	children [2]*testNode
+	data     [2][]byte
+}
+
+// writeTestDatabase writes an IPv4 MaxMind DB file containing the networks.
+func writeTestDatabase(t *testing.T, path string, networks ...testNetwork) {
+	root := &testNode{}
+
+	for _, network := range networks {
+		_, cidr, err := net.ParseCIDR(network.CIDR)
+		require.NoError(t, err)
+
+		ones, _ := cidr.Mask.Size()
+		ip := cidr.IP.To4()
+		node := root
+
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+
+			if i == ones-1 {
+				node.data[bit] = encodeTestRecord(network)
+
+				break
+			}
+
+			if node.children[bit] == nil {
+				node.children[bit] = &testNode{}
+			}
+
+			node = node.children[bit]
+		}
+	}
+
+	var nodes []*testNode
+
+	var walk func(node *testNode)
+
+	walk = func(node *testNode) {
+		nodes = append(nodes, node)
+
+		for _, child := range node.children {
+			if child != nil {
+				walk(child)
+			}
+		}
+	}
+
+	walk(root)
+
+	ids := map[*testNode]int{}
+	for i, node := range nodes {
+		ids[node] = i
+	}
+
+	var tree, data []byte
+
+	for _, node := range nodes {
+		for bit := 0; bit < 2; bit++ {
+			value := len(nodes)
+
+			switch {
+			case node.children[bit] != nil:
+				value = ids[node.children[bit]]
+			case node.data[bit] != nil:
+				value = len(nodes) + 16 + len(data)
+				data = append(data, node.data[bit]...)
+			}
+
+			tree = append(tree, byte(value>>16), byte(value>>8), byte(value))
+		}
+	}
+
+	buffer := append(tree, make([]byte, 16)...)
+	buffer = append(buffer, data...)
+	buffer = append(buffer, []byte("\xAB\xCD\xEFMaxMind.com")...)
+	buffer = append(buffer, encodeTestMap(
+		"binary_format_major_version", encodeTestUint16(2),
+		"binary_format_minor_version", encodeTestUint16(0),
+		"database_type", encodeTestString("Test"),
+		"ip_version", encodeTestUint16(4),
+		"node_count", encodeTestUint32(uint32(len(nodes))),
+		"record_size", encodeTestUint16(24),
+	)...)
+
+	require.NoError(t, os.WriteFile(path, buffer, 0600))
+}
+
+func encodeTestRecord(network testNetwork) []byte {
+	var values []interface{}
+
+	if network.Country != "" {
+		values = append(values, "country", encodeTestMap("iso_code", encodeTestString(network.Country)))
+	}
+
+	if network.ASN != 0 {
+		values = append(values, "autonomous_system_number", encodeTestUint32(network.ASN))
+	}
+
+	return encodeTestMap(values...)
+}
+
+func encodeTestMap(pairs ...interface{}) []byte {
+	encoded := []byte{0xE0 | byte(len(pairs)/2)}
+
+	for i := 0; i < len(pairs); i += 2 {
+		encoded = append(encoded, encodeTestString(pairs[i].(string))...)
+		encoded = append(encoded, pairs[i+1].([]byte)...)
+	}
+
+	return encoded
+}
+
+func encodeTestString(value string) []byte {
+	return append([]byte{0x40 | byte(len(value))}, value...)
+}
+
+func encodeTestUint16(value uint16) []byte {
+	encoded := []byte{0xA0 | 2, 0, 0}
+	binary.BigEndian.PutUint16(encoded[1:], value)
+
+	return encoded
+}
+
+func encodeTestUint32(value uint32) []byte {
+	encoded := []byte{0xC0 | 4, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(encoded[1:], value)
+
+	return encoded
+}
+
+func TestShouldLookupCountryAndASN(t *testing.T) {
+	dir := t.TempDir()
+
+	config := &schema.ACLGeoIP{
+		Path:    filepath.Join(dir, "country.mmdb"),
+		ASNPath: filepath.Join(dir, "asn.mmdb"),
+	}
+
+	writeTestDatabase(t, config.Path,
+		testNetwork{CIDR: "1.0.0.0/8", Country: "FR"},
+		testNetwork{CIDR: "2.128.0.0/9", Country: "DE"})
+	writeTestDatabase(t, config.ASNPath,
+		testNetwork{CIDR: "1.2.0.0/16", ASN: 64500})
+
+	provider, err := NewMaxMindProvider(config)
+	require.NoError(t, err)
+
+	defer provider.Close()
+
+	testCases := []struct {
+		ip       string
+		expected Record
+	}{
+		{"1.1.1.1", Record{Country: "FR"}},
+		{"1.2.3.4", Record{Country: "FR", ASN: 64500}},
+		{"2.200.0.1", Record{Country: "DE"}},
+		{"2.1.0.1", Record{}},
+		{"10.0.0.1", Record{}},
+		{"2001:db8::1", Record{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			record, err := provider.Lookup(net.ParseIP(tc.ip))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, record)
+
+			// The second lookup is served by the cache.
+			record, err = provider.Lookup(net.ParseIP(tc.ip))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, record)
+		})
+	}
+
+	record, err := provider.Lookup(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Record{}, record)
+}
+
+func TestShouldReloadDatabase(t *testing.T) {
+	dir := t.TempDir()
+
+	config := &schema.ACLGeoIP{
+		Path: filepath.Join(dir, "country.mmdb"),
+	}
+
+	writeTestDatabase(t, config.Path, testNetwork{CIDR: "1.0.0.0/8", Country: "FR"})
+
+	provider, err := NewMaxMindProvider(config)
+	require.NoError(t, err)
+
+	defer provider.Close()
+
+	record, err := provider.Lookup(net.ParseIP("1.1.1.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "FR", record.Country)
+
+	// The file is replaced rather than written to as it's memory mapped.
+	writeTestDatabase(t, config.Path+".tmp", testNetwork{CIDR: "1.0.0.0/8", Country: "BE"})
+	require.NoError(t, os.Rename(config.Path+".tmp", config.Path))
+
+	require.NoError(t, provider.Reload())
+
+	record, err = provider.Lookup(net.ParseIP("1.1.1.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "BE", record.Country)
+
+	require.NoError(t, os.WriteFile(config.Path, []byte("invalid"), 0600))
+
+	assert.Error(t, provider.Reload())
+
+	record, err = provider.Lookup(net.ParseIP("1.1.1.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "BE", record.Country)
+}
+
+func TestShouldFailToOpenMissingDatabase(t *testing.T) {
+	provider, err := NewMaxMindProvider(&schema.ACLGeoIP{Path: filepath.Join(t.TempDir(), "missing.mmdb")})
+
+	assert.Nil(t, provider)
+	assert.Error(t, err)
+}
+
+func TestShouldNotReloadNorLookupClosedDatabase(t *testing.T) {
+	config := &schema.ACLGeoIP{
+		Path: filepath.Join(t.TempDir(), "country.mmdb"),
+	}
+
+	writeTestDatabase(t, config.Path, testNetwork{CIDR: "1.0.0.0/8", Country: "FR"})
+
+	provider, err := NewMaxMindProvider(config)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Close())
+	require.NoError(t, provider.Reload())
+
+	_, err = provider.Lookup(net.ParseIP("1.1.1.1"))
+	assert.Equal(t, ErrClosed, err)
+}
@@ -0,0 +1,43 @@
+package geoip
+
+import (
+	"net"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+// Provider looks up the location of IP addresses.
+type Provider interface {
+	Lookup(ip net.IP) (record Record, err error)
+}
+
+// Record is the location of an IP address. The Country is empty and the ASN is 0 when they are unknown.
+type Record struct {
+	Country string
+	ASN     uint
+}
+
+// MaxMindProvider is a Provider which uses MaxMind DB files.
+type MaxMindProvider struct {
+	config *schema.ACLGeoIP
+
+	mutex   sync.RWMutex
+	country *maxminddb.Reader
+	asn     *maxminddb.Reader
+	closed  bool
+
+	cacheMutex sync.Mutex
+	cache      map[string]Record
+}
+
+// maxMindRecord is the subset of the MaxMind GeoIP2 Country, GeoIP2 City, and GeoLite2 ASN records used by the
+// MaxMindProvider.
+type maxMindRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	ASN uint `maxminddb:"autonomous_system_number"`
+}
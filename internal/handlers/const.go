@@ -1,5 +1,9 @@
 package handlers
 
+import (
+	"github.com/authelia/authelia/v4/internal/authentication"
+)
+
 const (
 	// ActionTOTPRegistration is the string representation of the action for which the token has been produced.
 	ActionTOTPRegistration = "RegisterTOTPDevice"
@@ -25,6 +29,9 @@ const (
 	headerRemoteName   = "Remote-Name"
 	headerRemoteEmail  = "Remote-Email"
 	headerRemoteGroups = "Remote-Groups"
+
+	// headerRemoteImpersonatedBy is the header naming the administrator impersonating the user.
+	headerRemoteImpersonatedBy = "Remote-Impersonated-By"
 )
 
 const (
@@ -43,6 +50,7 @@ const (
 	messageUnableToRegisterSecurityKey     = "Unable to register your security key."
 	messageUnableToResetPassword           = "Unable to reset your password."
 	messageMFAValidationFailed             = "Authentication failed, please retry later."
+	messageMaxConcurrentSessions           = "Too many active sessions, sign out from another device and retry."
 )
 
 const (
@@ -73,6 +81,33 @@ const (
 	pathOpenIDConnectConsent = "/api/oidc/consent"
 )
 
+// PathIdentityAssertionJWKs is the path of the JSON Web Key Set of the keys signing the identity assertions.
+const PathIdentityAssertionJWKs = "/api/identity-assertion/jwks"
+
+const (
+	// personalAccessTokenPrefix is the prefix of every personal access token which distinguishes them from the bearer
+	// tokens of the protected applications.
+	personalAccessTokenPrefix      = "authelia_pat_"
+	personalAccessTokenRandomBytes = 32
+
+	oneFactor = "one_factor"
+	twoFactor = "two_factor"
+)
+
+// Authentication method references of the identity assertions, see RFC8176.
+const (
+	amrPassword        = "pwd"
+	amrMultiFactor     = "mfa"
+	amrOneTimePassword = "otp"
+	amrHardwareKey     = "hwk"
+	amrMultiChannel    = "mca"
+)
+
+var personalAccessTokenLevels = map[string]authentication.Level{
+	oneFactor: authentication.OneFactor,
+	twoFactor: authentication.TwoFactor,
+}
+
 const (
 	accept = "accept"
 	reject = "reject"
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
+	"github.com/authelia/authelia/v4/internal/storage"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// AdminAuthenticationLogsGet lists the attempts of the authentication log, the latest first, optionally filtered by
+// user, IP and time range with the username, ip, from and to query parameters and paginated with the limit and offset
+// query parameters. Only the members of the configured administrators group can list the attempts.
+func AdminAuthenticationLogsGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	if !utils.IsStringInSliceFold(ctx.Configuration.Session.AdminGroup, userSession.Groups) {
+		ctx.Logger.Errorf("User %s attempted to list the authentication logs without being a member of the group %s", userSession.Username, ctx.Configuration.Session.AdminGroup)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	filter, err := parseAuthenticationLogFilter(ctx)
+	if err != nil {
+		ctx.Error(err, messageOperationFailed)
+		return
+	}
+
+	filter = filter.Bounded()
+
+	attempts, err := ctx.Providers.StorageProvider.LoadAuthenticationLogs(filter)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to load the authentication logs: %s", err), messageOperationFailed)
+		return
+	}
+
+	response := authenticationLogsResponse{
+		Attempts: make([]authenticationLogResponse, 0, len(attempts)),
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	}
+
+	for _, attempt := range attempts {
+		var remoteIP string
+
+		if attempt.RemoteIP != nil {
+			remoteIP = attempt.RemoteIP.String()
+		}
+
+		response.Attempts = append(response.Attempts, authenticationLogResponse{
+			Username:      attempt.Username,
+			Successful:    attempt.Successful,
+			Time:          attempt.Time.Unix(),
+			Event:         attempt.Event,
+			Actor:         attempt.Actor,
+			Type:          attempt.Type,
+			RemoteIP:      remoteIP,
+			UserAgent:     attempt.UserAgent,
+			RequestMethod: attempt.RequestMethod,
+			RequestURL:    attempt.RequestURL,
+			Banned:        attempt.Banned,
+		})
+	}
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set authentication logs response in body: %s", err)
+	}
+}
+
+func parseAuthenticationLogFilter(ctx *middlewares.AutheliaCtx) (filter storage.AuthenticationLogFilter, err error) {
+	args := ctx.QueryArgs()
+
+	filter.Username = string(args.Peek("username"))
+	filter.RemoteIP = string(args.Peek("ip"))
+
+	for _, t := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := args.Peek(t.name); len(value) != 0 {
+			if *t.value, err = time.Parse(time.RFC3339, string(value)); err != nil {
+				return filter, fmt.Errorf("unable to parse the %s time of the authentication logs, it must be in the RFC3339 format: %s", t.name, err)
+			}
+		}
+	}
+
+	for _, n := range []struct {
+		name  string
+		value *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		if value := args.Peek(n.name); len(value) != 0 {
+			if *n.value, err = strconv.Atoi(string(value)); err != nil {
+				return filter, fmt.Errorf("unable to parse the %s of the authentication logs: %s", n.name, err)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// appendAuthenticationLog appends the attempt to the authentication log, failing to log the attempt doesn't fail the
+// request.
+func appendAuthenticationLog(ctx *middlewares.AutheliaCtx, attempt models.AuthenticationAttempt) {
+	if err := ctx.Providers.StorageProvider.AppendAuthenticationLog(attempt); err != nil {
+		ctx.Logger.Errorf("Unable to record the %s attempt of user %s: %s", attempt.Event, attempt.Username, err)
+	}
+}
+
+func markSecondFactorAttempt(ctx *middlewares.AutheliaCtx, authType, username, targetURL string, successful bool) {
+	attempt := ctx.NewAuthenticationAttempt(models.AuthenticationEventSecondFactor, authType, username, successful)
+	attempt.RequestURL = targetURL
+
+	appendAuthenticationLog(ctx, attempt)
+}
+
+// markBasicAuthFailure marks the failed basic auth attempts with the regulator so they count towards the ban of the
+// user, the successful ones aren't logged since basic auth authenticates every request.
+func markBasicAuthFailure(ctx *middlewares.AutheliaCtx, username string, targetURL *url.URL, method []byte) {
+	attempt := ctx.NewAuthenticationAttempt(models.AuthenticationEventFirstFactor, models.AuthenticationTypeBasic, username, false)
+	attempt.RequestMethod = string(method)
+	attempt.RequestURL = targetURL.String()
+
+	if err := ctx.Providers.Regulator.Mark(attempt); err != nil {
+		ctx.Logger.Errorf("Unable to mark the basic auth attempt of user %s: %s", username, err)
+	}
+}
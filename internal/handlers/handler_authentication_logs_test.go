@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/mocks"
+	"github.com/authelia/authelia/v4/internal/models"
+	"github.com/authelia/authelia/v4/internal/storage"
+)
+
+type AuthenticationLogsSuite struct {
+	suite.Suite
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *AuthenticationLogsSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Configuration.Session.AdminGroup = "admins"
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.SetOneFactor(time.Unix(1625048140, 0), &authentication.UserDetails{Username: testUsername, Groups: []string{"admins"}}, false)
+	require.NoError(s.T(), s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *AuthenticationLogsSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *AuthenticationLogsSuite) TestShouldListAuthenticationLogsAsAdministrator() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadAuthenticationLogs(gomock.Eq(storage.AuthenticationLogFilter{
+			Username: "harry",
+			RemoteIP: "192.168.0.10",
+			From:     time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+			To:       time.Date(2021, 7, 2, 0, 0, 0, 0, time.UTC),
+			Limit:    storage.AuthenticationLogsMaxLimit,
+			Offset:   10,
+		})).
+		Return([]models.AuthenticationAttempt{{
+			Username:   "harry",
+			Time:       time.Unix(1625097600, 0),
+			Event:      models.AuthenticationEventSecondFactor,
+			Type:       models.AuthenticationTypeTOTP,
+			RemoteIP:   net.ParseIP("192.168.0.10"),
+			UserAgent:  "Firefox",
+			RequestURL: "https://home.example.com",
+		}}, nil)
+
+	s.mock.Ctx.QueryArgs().Add("username", "harry")
+	s.mock.Ctx.QueryArgs().Add("ip", "192.168.0.10")
+	s.mock.Ctx.QueryArgs().Add("from", "2021-07-01T00:00:00Z")
+	s.mock.Ctx.QueryArgs().Add("to", "2021-07-02T00:00:00Z")
+	s.mock.Ctx.QueryArgs().Add("limit", "1000")
+	s.mock.Ctx.QueryArgs().Add("offset", "10")
+
+	AdminAuthenticationLogsGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), authenticationLogsResponse{
+		Attempts: []authenticationLogResponse{{
+			Username:   "harry",
+			Time:       1625097600,
+			Event:      models.AuthenticationEventSecondFactor,
+			Type:       models.AuthenticationTypeTOTP,
+			RemoteIP:   "192.168.0.10",
+			UserAgent:  "Firefox",
+			RequestURL: "https://home.example.com",
+		}},
+		Limit:  storage.AuthenticationLogsMaxLimit,
+		Offset: 10,
+	})
+}
+
+func (s *AuthenticationLogsSuite) TestShouldRejectMalformedTimeRange() {
+	s.mock.Ctx.QueryArgs().Add("from", "yesterday")
+
+	AdminAuthenticationLogsGet(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), messageOperationFailed)
+	s.Assert().Contains(s.mock.Hook.LastEntry().Message, "unable to parse the from time of the authentication logs")
+}
+
+func (s *AuthenticationLogsSuite) TestShouldNotListAuthenticationLogsWithoutBeingAdministrator() {
+	s.mock.Ctx.Configuration.Session.AdminGroup = "operators"
+
+	AdminAuthenticationLogsGet(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *AuthenticationLogsSuite) TestShouldListAuthenticationLogsAsAdministratorWithGroupOfDifferentCase() {
+	s.mock.Ctx.Configuration.Session.AdminGroup = "Admins"
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadAuthenticationLogs(gomock.Any()).
+		Return(nil, nil)
+
+	AdminAuthenticationLogsGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), authenticationLogsResponse{
+		Attempts: []authenticationLogResponse{},
+		Limit:    storage.AuthenticationLogsDefaultLimit,
+	})
+}
+
+func (s *AuthenticationLogsSuite) TestShouldRequireTwoFactorToListAuthenticationLogs() {
+	middlewares.RequireTwoFactor(AdminAuthenticationLogsGet)(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+}
+
+func TestRunAuthenticationLogsSuite(t *testing.T) {
+	suite.Run(t, new(AuthenticationLogsSuite))
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/middlewares"
+)
+
+// AuthzGet returns the handler of the authorization endpoint served at /api/authz/<name>. Unlike VerifyGet, each
+// implementation extracts the original request from a fixed set of headers and has fixed response semantics:
+//
+// - forward_auth: the original URL is built from the X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-URI headers
+//   and the method is the X-Forwarded-Method header. The users are redirected to the login portal.
+//
+// - auth_request: the original URL is the X-Original-URL header and the method is the X-Original-Method header. The
+//   unauthorized responses always have the 401 status code and the Location header of the login portal.
+//
+// - ext_authz: the original URL is built from the X-Forwarded-Proto and Host headers and from the path following the
+//   endpoint path, the method is the method of the request. The users are redirected to the login portal.
+func AuthzGet(cfg schema.AuthenticationBackendConfiguration, endpoint schema.ServerAuthzEndpointConfiguration) middlewares.RequestHandler {
+	getPortalURL := func(ctx *middlewares.AutheliaCtx) string {
+		if endpoint.PortalURL != "" {
+			return endpoint.PortalURL
+		}
+
+		return getPortalURLFromQuery(ctx)
+	}
+
+	switch endpoint.Implementation {
+	case schema.AuthzImplementationAuthRequest:
+		return newAuthzHandler(cfg, authzImplementation{
+			getOriginalURL:    (*middlewares.AutheliaCtx).GetXOriginalURL,
+			getOriginalMethod: (*middlewares.AutheliaCtx).XOriginalMethod,
+			getPortalURL:      getPortalURL,
+		})
+	case schema.AuthzImplementationExtAuthz:
+		prefix := AuthzEndpointPath(endpoint.Name)
+
+		return newAuthzHandler(cfg, authzImplementation{
+			getOriginalURL: func(ctx *middlewares.AutheliaCtx) (*url.URL, error) {
+				return getExtAuthzOriginalURL(ctx, prefix)
+			},
+			getOriginalMethod: func(ctx *middlewares.AutheliaCtx) []byte {
+				return ctx.Method()
+			},
+			// The query of the request belongs to the original request so only the configured URL can be used.
+			getPortalURL: func(_ *middlewares.AutheliaCtx) string {
+				return endpoint.PortalURL
+			},
+			redirect: true,
+		})
+	default:
+		return newAuthzHandler(cfg, authzImplementation{
+			getOriginalURL:    (*middlewares.AutheliaCtx).GetXForwardedURL,
+			getOriginalMethod: (*middlewares.AutheliaCtx).XForwardedMethod,
+			getPortalURL:      getPortalURL,
+			redirect:          true,
+		})
+	}
+}
+
+// AuthzEndpointPath returns the path of the authorization endpoint with the name.
+func AuthzEndpointPath(name string) string {
+	return "/api/authz/" + name
+}
+
+// getExtAuthzOriginalURL returns the original URL of a request from the Envoy HTTP external authorization filter which
+// appends the path of the original request to the path of the endpoint and keeps its Host header.
+func getExtAuthzOriginalURL(ctx *middlewares.AutheliaCtx, prefix string) (*url.URL, error) {
+	forwardedProto := ctx.XForwardedProto()
+	if forwardedProto == nil {
+		return nil, fmt.Errorf("Missing header X-Forwarded-Proto")
+	}
+
+	requestURI := strings.TrimPrefix(string(ctx.RequestURI()), prefix)
+	if !strings.HasPrefix(requestURI, "/") {
+		requestURI = "/" + requestURI
+	}
+
+	rawURL := string(forwardedProto) + "://" + string(ctx.Host()) + requestURI
+
+	originalURL, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse URL %s: %v", rawURL, err)
+	}
+
+	return originalURL, nil
+}
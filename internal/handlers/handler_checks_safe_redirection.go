@@ -25,7 +25,13 @@ func CheckSafeRedirection(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
-	safe, err := utils.IsRedirectionURISafe(reqBody.URI, ctx.Configuration.Session.Domain)
+	domain, err := ctx.Providers.SessionProvider.GetDomain(ctx.RequestCtx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to determine the session domain of the request: %w", err), messageOperationFailed)
+		return
+	}
+
+	safe, err := utils.IsRedirectionURISafe(reqBody.URI, domain.Domain)
 	if err != nil {
 		ctx.Error(fmt.Errorf("unable to determine if uri %s is safe to redirect to: %w", reqBody.URI, err), messageOperationFailed)
 		return
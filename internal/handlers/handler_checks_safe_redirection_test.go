@@ -4,21 +4,37 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
 	"github.com/authelia/authelia/v4/internal/mocks"
 	"github.com/authelia/authelia/v4/internal/session"
 )
 
 var exampleDotComDomain = "example.com"
 
-func TestCheckSafeRedirection_ForbiddenCall(t *testing.T) {
-	mock := mocks.NewMockAutheliaCtxWithUserSession(t, session.UserSession{
+func newSafeRedirectionMock(t *testing.T, configuration schema.SessionConfiguration, level authentication.Level) *mocks.MockAutheliaCtx {
+	mock := mocks.NewMockAutheliaCtx(t)
+	mock.Ctx.Configuration.Session = configuration
+
+	// Reload the session provider since the configuration is indirect.
+	mock.Ctx.Providers.SessionProvider = session.NewProvider(mock.Ctx.Configuration.Session, nil, nil)
+
+	mock.Ctx.Request.SetHost("login.example.com")
+
+	err := mock.Ctx.SaveSession(session.UserSession{
 		Username:            "john",
-		AuthenticationLevel: authentication.NotAuthenticated,
+		AuthenticationLevel: level,
 	})
+	require.NoError(t, err)
+
+	return mock
+}
+
+func TestCheckSafeRedirection_ForbiddenCall(t *testing.T) {
+	mock := newSafeRedirectionMock(t, schema.SessionConfiguration{Domain: exampleDotComDomain}, authentication.NotAuthenticated)
 	defer mock.Close()
-	mock.Ctx.Configuration.Session.Domain = exampleDotComDomain
 
 	mock.SetRequestBody(t, checkURIWithinDomainRequestBody{
 		URI: "http://myapp.example.com",
@@ -29,12 +45,8 @@ func TestCheckSafeRedirection_ForbiddenCall(t *testing.T) {
 }
 
 func TestCheckSafeRedirection_UnsafeRedirection(t *testing.T) {
-	mock := mocks.NewMockAutheliaCtxWithUserSession(t, session.UserSession{
-		Username:            "john",
-		AuthenticationLevel: authentication.OneFactor,
-	})
+	mock := newSafeRedirectionMock(t, schema.SessionConfiguration{Domain: exampleDotComDomain}, authentication.OneFactor)
 	defer mock.Close()
-	mock.Ctx.Configuration.Session.Domain = exampleDotComDomain
 
 	mock.SetRequestBody(t, checkURIWithinDomainRequestBody{
 		URI: "http://myapp.com",
@@ -47,12 +59,8 @@ func TestCheckSafeRedirection_UnsafeRedirection(t *testing.T) {
 }
 
 func TestCheckSafeRedirection_SafeRedirection(t *testing.T) {
-	mock := mocks.NewMockAutheliaCtxWithUserSession(t, session.UserSession{
-		Username:            "john",
-		AuthenticationLevel: authentication.OneFactor,
-	})
+	mock := newSafeRedirectionMock(t, schema.SessionConfiguration{Domain: exampleDotComDomain}, authentication.OneFactor)
 	defer mock.Close()
-	mock.Ctx.Configuration.Session.Domain = exampleDotComDomain
 
 	mock.SetRequestBody(t, checkURIWithinDomainRequestBody{
 		URI: "https://myapp.example.com",
@@ -63,3 +71,39 @@ func TestCheckSafeRedirection_SafeRedirection(t *testing.T) {
 		OK: true,
 	})
 }
+
+func TestCheckSafeRedirection_ShouldCheckAgainstDomainOfRequest(t *testing.T) {
+	configuration := schema.SessionConfiguration{
+		Name: "authelia_session",
+		Domains: []schema.SessionDomainConfiguration{
+			{Domain: exampleDotComDomain, Name: "authelia_session"},
+			{Domain: "example.net", Name: "authelia_session"},
+		},
+	}
+
+	testCases := []struct {
+		uri  string
+		safe bool
+	}{
+		{"https://myapp.example.net", true},
+		{"https://myapp.example.com", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.uri, func(t *testing.T) {
+			mock := newSafeRedirectionMock(t, configuration, authentication.OneFactor)
+			defer mock.Close()
+
+			mock.Ctx.Request.SetHost("login.example.net")
+
+			mock.SetRequestBody(t, checkURIWithinDomainRequestBody{
+				URI: tc.uri,
+			})
+
+			CheckSafeRedirection(mock.Ctx)
+			mock.Assert200OK(t, checkURIWithinDomainResponseBody{
+				OK: tc.safe,
+			})
+		})
+	}
+}
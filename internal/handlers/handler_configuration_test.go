@@ -21,7 +21,7 @@ func (s *SecondFactorAvailableMethodsFixture) SetupTest() {
 		AccessControl: schema.AccessControlConfiguration{
 			DefaultPolicy: "deny",
 			Rules:         []schema.ACLRule{},
-		}})
+		}}, &s.mock.Clock)
 }
 
 func (s *SecondFactorAvailableMethodsFixture) TearDownTest() {
@@ -85,7 +85,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsDisab
 						Policy:  "bypass",
 					},
 				},
-			}})
+			}}, &s.mock.Clock)
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
 		AvailableMethods:    []string{"totp", "u2f"},
@@ -117,7 +117,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsEnabl
 					Policy:  "bypass",
 				},
 			},
-		}})
+		}}, &s.mock.Clock)
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
 		AvailableMethods:    []string{"totp", "u2f"},
@@ -150,7 +150,7 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsEnabl
 						Policy:  "bypass",
 					},
 				},
-			}})
+			}}, &s.mock.Clock)
 	ConfigurationGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), ConfigurationBody{
 		AvailableMethods:    []string{"totp", "u2f"},
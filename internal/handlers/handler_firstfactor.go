@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
 	"github.com/authelia/authelia/v4/internal/regulation"
 	"github.com/authelia/authelia/v4/internal/session"
 )
@@ -77,10 +79,18 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 			return
 		}
 
+		attempt := ctx.NewAuthenticationAttempt(models.AuthenticationEventFirstFactor, models.AuthenticationType1FA, bodyJSON.Username, false)
+		attempt.RequestMethod = bodyJSON.RequestMethod
+		attempt.RequestURL = bodyJSON.TargetURL
+
 		bannedUntil, err := ctx.Providers.Regulator.Regulate(bodyJSON.Username)
 
 		if err != nil {
 			if err == regulation.ErrUserIsBanned {
+				// The banned attempts are logged apart from the regulation so they don't extend the ban.
+				attempt.Banned = true
+				appendAuthenticationLog(ctx, attempt)
+
 				handleAuthenticationUnauthorized(ctx, fmt.Errorf("user %s is banned until %s", bodyJSON.Username, bannedUntil), messageAuthenticationFailed)
 				return
 			}
@@ -95,7 +105,7 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		if err != nil {
 			ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
 
-			if err := ctx.Providers.Regulator.Mark(bodyJSON.Username, false); err != nil {
+			if err := ctx.Providers.Regulator.Mark(attempt); err != nil {
 				ctx.Logger.Errorf("Unable to mark authentication: %s", err.Error())
 			}
 
@@ -107,7 +117,7 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		if !userPasswordOk {
 			ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
 
-			if err := ctx.Providers.Regulator.Mark(bodyJSON.Username, false); err != nil {
+			if err := ctx.Providers.Regulator.Mark(attempt); err != nil {
 				ctx.Logger.Errorf("Unable to mark authentication: %s", err.Error())
 			}
 
@@ -117,7 +127,8 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		}
 
 		ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
-		err = ctx.Providers.Regulator.Mark(bodyJSON.Username, true)
+		attempt.Successful = true
+		err = ctx.Providers.Regulator.Mark(attempt)
 
 		if err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("unable to mark authentication: %s", err.Error()), messageAuthenticationFailed)
@@ -145,12 +156,20 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 			return
 		}
 
+		domain, err := ctx.Providers.SessionProvider.GetDomain(ctx.RequestCtx)
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("unable to determine the session domain of the request of user %s: %s", bodyJSON.Username, err), messageAuthenticationFailed)
+			return
+		}
+
+		rememberMe := domain.RememberMe
+
 		// Check if bodyJSON.KeepMeLoggedIn can be deref'd and derive the value based on the configuration and JSON data
-		keepMeLoggedIn := ctx.Providers.SessionProvider.RememberMe != 0 && bodyJSON.KeepMeLoggedIn != nil && *bodyJSON.KeepMeLoggedIn
+		keepMeLoggedIn := rememberMe != 0 && bodyJSON.KeepMeLoggedIn != nil && *bodyJSON.KeepMeLoggedIn
 
 		// Set the cookie to expire if remember me is enabled and the user has asked us to
 		if keepMeLoggedIn {
-			err = ctx.Providers.SessionProvider.UpdateExpiration(ctx.RequestCtx, ctx.Providers.SessionProvider.RememberMe)
+			err = ctx.Providers.SessionProvider.UpdateExpiration(ctx.RequestCtx, rememberMe)
 			if err != nil {
 				handleAuthenticationUnauthorized(ctx, fmt.Errorf("unable to update expiration timer for user %s: %s", bodyJSON.Username, err.Error()), messageAuthenticationFailed)
 				return
@@ -167,7 +186,27 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 
 		ctx.Logger.Tracef("Details for user %s => groups: %s, emails %s", bodyJSON.Username, userDetails.Groups, userDetails.Emails)
 
+		evicted, err := ctx.Providers.SessionProvider.ReserveSession(ctx.RequestCtx, userDetails.Username)
+
+		for _, info := range evicted {
+			ctx.Logger.Infof("Session %s of user %s was evicted since the user reached the maximum number of concurrent sessions", info.ID, userDetails.Username)
+
+			markSessionEviction(ctx, userDetails.Username)
+		}
+
+		if err != nil {
+			if errors.Is(err, session.ErrMaxConcurrentSessions) {
+				handleAuthenticationUnauthorized(ctx, fmt.Errorf("user %s reached the maximum number of concurrent sessions", bodyJSON.Username), messageMaxConcurrentSessions)
+				return
+			}
+
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("unable to reserve session of user %s: %s", bodyJSON.Username, err.Error()), messageAuthenticationFailed)
+
+			return
+		}
+
 		userSession.SetOneFactor(ctx.Clock.Now(), userDetails, keepMeLoggedIn)
+		userSession.SetClient(ctx.ClientIP(), ctx.UserAgent())
 
 		if refresh, refreshInterval := getProfileRefreshSettings(ctx.Configuration.AuthenticationBackend); refresh {
 			userSession.RefreshTTL = ctx.Clock.Now().Add(refreshInterval)
@@ -188,3 +227,11 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		}
 	}
 }
+
+func markSessionEviction(ctx *middlewares.AutheliaCtx, username string) {
+	err := ctx.Providers.StorageProvider.AppendAuthenticationLog(
+		ctx.NewAuthenticationAttempt(models.AuthenticationEventSessionEvicted, "", username, true))
+	if err != nil {
+		ctx.Logger.Errorf("Unable to record the eviction of a session of user %s: %s", username, err)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"net"
 	"sync"
 	"testing"
 	"time"
@@ -9,12 +10,15 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/authorization"
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
 	"github.com/authelia/authelia/v4/internal/mocks"
 	"github.com/authelia/authelia/v4/internal/models"
+	"github.com/authelia/authelia/v4/internal/regulation"
+	"github.com/authelia/authelia/v4/internal/session"
 )
 
 type FirstFactorSuite struct {
@@ -62,6 +66,9 @@ func (s *FirstFactorSuite) TestShouldFailIfUserProviderCheckPasswordFail() {
 			Username:   "test",
 			Successful: false,
 			Time:       s.mock.Clock.Now(),
+			Event:      models.AuthenticationEventFirstFactor,
+			Type:       models.AuthenticationType1FA,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
 		}))
 
 	s.mock.Ctx.Request.SetBodyString(`{
@@ -87,6 +94,9 @@ func (s *FirstFactorSuite) TestShouldCheckAuthenticationIsMarkedWhenInvalidCrede
 			Username:   "test",
 			Successful: false,
 			Time:       s.mock.Clock.Now(),
+			Event:      models.AuthenticationEventFirstFactor,
+			Type:       models.AuthenticationType1FA,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
 		}))
 
 	s.mock.Ctx.Request.SetBodyString(`{
@@ -98,6 +108,48 @@ func (s *FirstFactorSuite) TestShouldCheckAuthenticationIsMarkedWhenInvalidCrede
 	FirstFactorPost(0, false)(s.mock.Ctx)
 }
 
+func (s *FirstFactorSuite) TestShouldMarkBannedAttemptWithClientAndRequest() {
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.RegulationConfiguration{
+		MaxRetries: 1,
+		FindTime:   "2m",
+		BanTime:    "5m",
+	}, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("test"), gomock.Any()).
+		Return([]models.AuthenticationAttempt{{Username: "test", Time: s.mock.Clock.Now()}}, nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:      "test",
+			Time:          s.mock.Clock.Now(),
+			Event:         models.AuthenticationEventFirstFactor,
+			Type:          models.AuthenticationType1FA,
+			RemoteIP:      net.ParseIP("192.168.0.10"),
+			UserAgent:     "Firefox",
+			RequestMethod: "GET",
+			RequestURL:    "https://home.example.com",
+			Banned:        true,
+		})).
+		Return(nil)
+
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.10")
+	s.mock.Ctx.Request.Header.SetUserAgent("Firefox")
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"targetURL": "https://home.example.com",
+		"requestMethod": "GET"
+	}`)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+}
+
 func (s *FirstFactorSuite) TestShouldFailIfUserProviderGetDetailsFail() {
 	s.mock.UserProviderMock.
 		EXPECT().
@@ -272,6 +324,93 @@ func (s *FirstFactorSuite) TestShouldSaveUsernameFromAuthenticationBackendInSess
 	assert.Equal(s.T(), []string{"dev", "admins"}, session.Groups)
 }
 
+func (s *FirstFactorSuite) setupMaxConcurrentSessions(policy string) {
+	s.mock.Ctx.Configuration.Session.MaxConcurrent = 1
+	s.mock.Ctx.Configuration.Session.MaxConcurrentPolicy = policy
+
+	// Reload the session provider since the configuration is indirect.
+	s.mock.Ctx.Providers.SessionProvider = session.NewProvider(s.mock.Ctx.Configuration.Session, nil, nil)
+
+	ctx := &fasthttp.RequestCtx{}
+
+	userSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(ctx)
+	s.Require().NoError(err)
+
+	userSession.SetOneFactor(s.mock.Clock.Now(), &authentication.UserDetails{Username: "test"}, false)
+	s.Require().NoError(s.mock.Ctx.Providers.SessionProvider.SaveSession(ctx, userSession))
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, nil)
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		GetDetails(gomock.Eq("test")).
+		Return(&authentication.UserDetails{
+			Username: "test",
+			Emails:   []string{"test@example.com"},
+			Groups:   []string{"dev", "admins"},
+		}, nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": false
+	}`)
+}
+
+func (s *FirstFactorSuite) TestShouldEvictOldestSessionWhenMaxConcurrentSessionsReached() {
+	s.setupMaxConcurrentSessions("evict_oldest")
+
+	var events []string
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		DoAndReturn(func(attempt models.AuthenticationAttempt) error {
+			assert.Equal(s.T(), "test", attempt.Username)
+
+			events = append(events, attempt.Event)
+
+			return nil
+		}).
+		Times(2)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+	assert.Contains(s.T(), events, models.AuthenticationEventSessionEvicted)
+
+	sessions, err := s.mock.Ctx.Providers.SessionProvider.ListSessions("test")
+	s.Require().NoError(err)
+	s.Require().Len(sessions, 1)
+
+	currentID, err := s.mock.Ctx.Providers.SessionProvider.CurrentSessionID(s.mock.Ctx.RequestCtx)
+	s.Require().NoError(err)
+	assert.Equal(s.T(), currentID, sessions[0].ID)
+}
+
+func (s *FirstFactorSuite) TestShouldRejectLoginWhenMaxConcurrentSessionsReached() {
+	s.setupMaxConcurrentSessions("reject")
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), messageMaxConcurrentSessions)
+
+	userSession := s.mock.Ctx.GetSession()
+	assert.Equal(s.T(), "", userSession.Username)
+
+	sessions, err := s.mock.Ctx.Providers.SessionProvider.ListSessions("test")
+	s.Require().NoError(err)
+	assert.Len(s.T(), sessions, 1)
+}
+
 type FirstFactorRedirectionSuite struct {
 	suite.Suite
 
@@ -288,7 +427,7 @@ func (s *FirstFactorRedirectionSuite) SetupTest() {
 			Policy:  "one_factor",
 		},
 	}
-	s.mock.Ctx.Providers.Authorizer = authorization.NewAuthorizer(&s.mock.Ctx.Configuration)
+	s.mock.Ctx.Providers.Authorizer = authorization.NewAuthorizer(&s.mock.Ctx.Configuration, &s.mock.Clock)
 
 	s.mock.UserProviderMock.
 		EXPECT().
@@ -363,7 +502,7 @@ func (s *FirstFactorRedirectionSuite) TestShouldReply200WhenNoTargetURLProvidedA
 		AccessControl: schema.AccessControlConfiguration{
 			DefaultPolicy: "two_factor",
 		},
-	})
+	}, &s.mock.Clock)
 	s.mock.Ctx.Request.SetBodyString(`{
 		"username": "test",
 		"password": "hello",
@@ -395,7 +534,7 @@ func (s *FirstFactorRedirectionSuite) TestShouldReply200WhenUnsafeTargetURLProvi
 					Policy:  "two_factor",
 				},
 			},
-		}})
+		}}, &s.mock.Clock)
 	s.mock.Ctx.Request.SetBodyString(`{
 		"username": "test",
 		"password": "hello",
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/ory/fosite/token/jwt"
+
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/middlewares"
+)
+
+// IdentityAssertionJWKsGet serves the JSON Web Key Set of the keys signing the identity assertions.
+func IdentityAssertionJWKsGet(ctx *middlewares.AutheliaCtx) {
+	ctx.SetContentType("application/json")
+
+	if err := json.NewEncoder(ctx).Encode(ctx.Providers.IdentityAssertion.GetKeySet()); err != nil {
+		ctx.Error(err, "failed to serve jwk set")
+	}
+}
+
+// setIdentityAssertionHeader sets the header with the signed identity assertion of the user for the target URL. The
+// assertion is not set for anonymous users or when the identity assertions are disabled.
+func setIdentityAssertionHeader(ctx *middlewares.AutheliaCtx, targetURL *url.URL, username, name string, groups, emails []string, authLevel authentication.Level, secondFactorMethod string) {
+	if username == "" || ctx.Providers.IdentityAssertion == nil || ctx.Configuration.IdentityAssertion == nil {
+		return
+	}
+
+	token, err := newIdentityAssertion(ctx, targetURL, username, name, groups, emails, authLevel, secondFactorMethod)
+	if err != nil {
+		ctx.Logger.Errorf("Unable to sign the identity assertion of user %s: %v", username, err)
+
+		return
+	}
+
+	ctx.Response.Header.Set(ctx.Configuration.IdentityAssertion.Header, token)
+}
+
+func newIdentityAssertion(ctx *middlewares.AutheliaCtx, targetURL *url.URL, username, name string, groups, emails []string, authLevel authentication.Level, secondFactorMethod string) (token string, err error) {
+	config := ctx.Configuration.IdentityAssertion
+	strategy := ctx.Providers.IdentityAssertion.Strategy()
+	now := ctx.Clock.Now()
+
+	claims := jwt.MapClaims{
+		"jti":        uuid.New().String(),
+		"sub":        username,
+		"aud":        []string{fmt.Sprintf("%s://%s", targetURL.Scheme, targetURL.Host)},
+		"iat":        now.Unix(),
+		"nbf":        now.Unix(),
+		"exp":        now.Add(config.Lifespan).Unix(),
+		"name":       name,
+		"groups":     groups,
+		"auth_level": authenticationLevelName(authLevel),
+		"amr":        authenticationMethodRefs(authLevel, secondFactorMethod),
+	}
+
+	if config.Issuer != "" {
+		claims["iss"] = config.Issuer
+	}
+
+	if len(emails) != 0 {
+		claims["email"] = emails[0]
+	}
+
+	keyID, err := strategy.GetPublicKeyID(ctx.RequestCtx)
+	if err != nil {
+		return "", err
+	}
+
+	token, _, err = strategy.Generate(ctx.RequestCtx, claims, &jwt.Headers{
+		Extra: map[string]interface{}{"kid": keyID},
+	})
+
+	return token, err
+}
+
+// authenticationMethodRefs returns the RFC8176 authentication method references of the authentication level and the
+// method of the second factor.
+func authenticationMethodRefs(authLevel authentication.Level, secondFactorMethod string) (amr []string) {
+	if authLevel >= authentication.OneFactor {
+		amr = append(amr, amrPassword)
+	}
+
+	if authLevel >= authentication.TwoFactor {
+		amr = append(amr, amrMultiFactor)
+
+		switch secondFactorMethod {
+		case authentication.TOTP:
+			amr = append(amr, amrOneTimePassword)
+		case authentication.U2F:
+			amr = append(amr, amrHardwareKey)
+		case authentication.Push:
+			amr = append(amr, amrMultiChannel)
+		}
+	}
+
+	return amr
+}
+
+// authenticationLevelName returns the name of the authentication level which is the name of the matching policy.
+func authenticationLevelName(authLevel authentication.Level) string {
+	switch authLevel {
+	case authentication.OneFactor:
+		return oneFactor
+	case authentication.TwoFactor:
+		return twoFactor
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
+	"github.com/authelia/authelia/v4/internal/session"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// ImpersonationStartPost starts the impersonation of a user by the administrator identified by the session. The
+// administrators must be members of the configured group and cannot impersonate other administrators nor the members
+// of the session admin group.
+func ImpersonationStartPost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := impersonationRequestBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		ctx.Error(err, messageOperationFailed)
+		return
+	}
+
+	config := ctx.Configuration.Impersonation
+	userSession := ctx.GetSession()
+
+	if userSession.ImpersonatedBy != nil {
+		ctx.Error(fmt.Errorf("user %s is already impersonated by %s", userSession.Username, userSession.ImpersonatedBy.Username), messageOperationFailed)
+		return
+	}
+
+	if !utils.IsStringInSliceFold(config.Group, userSession.Groups) {
+		ctx.Logger.Errorf("User %s attempted to impersonate user %s without being a member of the group %s", userSession.Username, bodyJSON.Username, config.Group)
+		ctx.MarkImpersonation(models.AuthenticationEventImpersonationStart, bodyJSON.Username, userSession.Username, false)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	if bodyJSON.Username == userSession.Username {
+		ctx.Error(fmt.Errorf("user %s attempted to impersonate themselves", userSession.Username), messageOperationFailed)
+		return
+	}
+
+	details, err := ctx.Providers.UserProvider.GetDetails(bodyJSON.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to retrieve the details of user %s to impersonate: %s", bodyJSON.Username, err), messageOperationFailed)
+		return
+	}
+
+	for _, group := range []string{config.Group, ctx.Configuration.Session.AdminGroup} {
+		if group == "" || !utils.IsStringInSliceFold(group, details.Groups) {
+			continue
+		}
+
+		ctx.Logger.Errorf("User %s attempted to impersonate user %s who is a member of the group %s", userSession.Username, details.Username, group)
+		ctx.MarkImpersonation(models.AuthenticationEventImpersonationStart, details.Username, userSession.Username, false)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	actor := userSession.Username
+
+	// The impersonation is refused when it cannot be recorded in the authentication log.
+	if err = ctx.AppendImpersonationLog(models.AuthenticationEventImpersonationStart, details.Username, actor, true); err != nil {
+		ctx.Error(fmt.Errorf("unable to record the impersonation of user %s by %s: %s", details.Username, actor, err), messageOperationFailed)
+		return
+	}
+
+	userSession.StartImpersonation(ctx.Clock.Now(), details, config.Duration)
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		ctx.Error(fmt.Errorf("unable to save the session of user %s impersonating user %s: %s", actor, details.Username, err), messageOperationFailed)
+		return
+	}
+
+	ctx.Logger.Infof("User %s started impersonating user %s", actor, details.Username)
+
+	if err = ctx.SetJSONBody(newImpersonationResponse(userSession)); err != nil {
+		ctx.Logger.Errorf("Unable to set impersonation response in body: %s", err)
+	}
+}
+
+// ImpersonationStopPost stops the impersonation of the user identified by the session and restores the identity of
+// the administrator.
+func ImpersonationStopPost(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	if userSession.ImpersonatedBy == nil {
+		ctx.Error(fmt.Errorf("user %s is not impersonated", userSession.Username), messageOperationFailed)
+		return
+	}
+
+	if err := ctx.StopImpersonation(&userSession); err != nil {
+		ctx.Error(err, messageOperationFailed)
+		return
+	}
+
+	ctx.ReplyOK()
+}
+
+func newImpersonationResponse(userSession session.UserSession) impersonationResponse {
+	return impersonationResponse{
+		Username:       userSession.Username,
+		ImpersonatedBy: userSession.ImpersonatedBy.Username,
+		ExpiresAt:      userSession.ImpersonatedBy.ExpiresAt,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/url"
 
 	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
 	"github.com/authelia/authelia/v4/internal/utils"
 )
 
@@ -26,14 +27,22 @@ func LogoutPost(ctx *middlewares.AutheliaCtx) {
 		ctx.Error(fmt.Errorf("unable to parse body during logout: %s", err), messageOperationFailed)
 	}
 
+	// Logging out ends the impersonation along with the session of the administrator.
+	if userSession := ctx.GetSession(); userSession.ImpersonatedBy != nil {
+		ctx.Logger.Infof("User %s stopped impersonating user %s by logging out", userSession.ImpersonatedBy.Username, userSession.Username)
+		ctx.MarkImpersonation(models.AuthenticationEventImpersonationStop, userSession.Username, userSession.ImpersonatedBy.Username, true)
+	}
+
 	err = ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
 	if err != nil {
 		ctx.Error(fmt.Errorf("unable to destroy session during logout: %s", err), messageOperationFailed)
 	}
 
-	redirectionURL, err := url.Parse(body.TargetURL)
-	if err == nil {
-		responseBody.SafeTargetURL = utils.IsRedirectionSafe(*redirectionURL, ctx.Configuration.Session.Domain)
+	// The target URL is unsafe when the host of the request isn't under any session domain.
+	if domain, err := ctx.Providers.SessionProvider.GetDomain(ctx.RequestCtx); err == nil {
+		if redirectionURL, err := url.Parse(body.TargetURL); err == nil {
+			responseBody.SafeTargetURL = utils.IsRedirectionSafe(*redirectionURL, domain.Domain)
+		}
 	}
 
 	if body.TargetURL != "" {
@@ -44,6 +44,14 @@ func oidcAuthorization(ctx *middlewares.AutheliaCtx, rw http.ResponseWriter, r *
 
 	isAuthInsufficient := !client.IsAuthenticationLevelSufficient(userSession.AuthenticationLevel)
 
+	if !isAuthInsufficient && isMaxAgeExceeded(ar.GetRequestForm().Get("max_age"), clientID, client.Policy, userSession, ctx.Clock.Now()) {
+		ctx.Logger.Debugf("User %s must authenticate again as the authentication exceeds the max age of the request from client %s",
+			userSession.Username, clientID)
+
+		userSession = lowerAuthenticationLevel(userSession, client.Policy)
+		isAuthInsufficient = true
+	}
+
 	if isAuthInsufficient || (isConsentMissing(userSession.OIDCWorkflowSession, requestedScopes, requestedAudience)) {
 		oidcAuthorizeHandleAuthorizationOrConsentInsufficient(ctx, userSession, client, isAuthInsufficient, rw, r, ar)
 
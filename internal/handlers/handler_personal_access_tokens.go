@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
+	"github.com/authelia/authelia/v4/internal/storage"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// PersonalAccessTokensGet lists the personal access tokens of the user identified by the session.
+func PersonalAccessTokensGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	tokens, err := ctx.Providers.StorageProvider.LoadPersonalAccessTokens(userSession.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to load the personal access tokens of user %s: %s", userSession.Username, err), messageOperationFailed)
+		return
+	}
+
+	response := make([]personalAccessTokenResponse, 0, len(tokens))
+
+	for _, token := range tokens {
+		response = append(response, newPersonalAccessTokenResponse(token))
+	}
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set personal access tokens response in body: %s", err)
+	}
+}
+
+// PersonalAccessTokenPost creates a personal access token for the user identified by the session. The token is only
+// returned in this response, only its hash is stored.
+func PersonalAccessTokenPost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := personalAccessTokenRequestBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		ctx.Error(err, messageOperationFailed)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	level, ok := personalAccessTokenLevels[bodyJSON.Level]
+	if !ok {
+		ctx.Error(fmt.Errorf("unknown level '%s', it should be one of %s, %s", bodyJSON.Level, oneFactor, twoFactor), messageOperationFailed)
+		return
+	}
+
+	// A token must not grant a higher authentication level than the one the user has reached in the session creating it.
+	if level > userSession.AuthenticationLevel {
+		ctx.Logger.Errorf("User %s attempted to create a personal access token with the level %s without having completed it", userSession.Username, bodyJSON.Level)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	expiration, err := utils.ParseDurationString(bodyJSON.Expiration)
+	if err != nil || expiration <= 0 {
+		ctx.Error(fmt.Errorf("invalid expiration '%s' for the personal access token, it must be a positive duration", bodyJSON.Expiration), messageOperationFailed)
+		return
+	}
+
+	for _, domain := range bodyJSON.Domains {
+		if domain == "" || strings.Contains(domain, ",") {
+			ctx.Error(fmt.Errorf("invalid domain '%s' for the personal access token", domain), messageOperationFailed)
+			return
+		}
+	}
+
+	value, err := generatePersonalAccessToken()
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to generate a personal access token: %s", err), messageOperationFailed)
+		return
+	}
+
+	now := ctx.Clock.Now()
+
+	token := models.PersonalAccessToken{
+		ID:       uuid.New().String(),
+		Username: userSession.Username,
+		Name:     bodyJSON.Name,
+		Hash:     utils.HashSHA256FromString(value),
+		Level:    level,
+		Domains:  bodyJSON.Domains,
+		Created:  time.Unix(now.Unix(), 0),
+		Expires:  time.Unix(now.Add(expiration).Unix(), 0),
+	}
+
+	if err = ctx.Providers.StorageProvider.SavePersonalAccessToken(token); err != nil {
+		ctx.Error(fmt.Errorf("unable to save the personal access token of user %s: %s", userSession.Username, err), messageOperationFailed)
+		return
+	}
+
+	ctx.Logger.Debugf("User %s created the personal access token %s named %s", userSession.Username, token.ID, token.Name)
+
+	response := newPersonalAccessTokenResponse(token)
+	response.Token = value
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set personal access token response in body: %s", err)
+	}
+}
+
+// PersonalAccessTokenRevokePost revokes a personal access token of the user identified by the session.
+func PersonalAccessTokenRevokePost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := personalAccessTokenRevokeRequestBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		ctx.Error(err, messageOperationFailed)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	if err = ctx.Providers.StorageProvider.RevokePersonalAccessToken(userSession.Username, bodyJSON.ID); err != nil {
+		ctx.Error(fmt.Errorf("unable to revoke the personal access token %s of user %s: %s", bodyJSON.ID, userSession.Username, err), messageOperationFailed)
+		return
+	}
+
+	ctx.Logger.Debugf("User %s revoked the personal access token %s", userSession.Username, bodyJSON.ID)
+
+	ctx.ReplyOK()
+}
+
+// verifyPersonalAccessToken verify that the personal access token is valid for the target URL and returns the details
+// of the user who owns it.
+func verifyPersonalAccessToken(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, value string, domain string) (username, name string, groups, emails []string, authLevel authentication.Level, err error) {
+	token, err := ctx.Providers.StorageProvider.LoadPersonalAccessTokenByHash(utils.HashSHA256FromString(value))
+	if err != nil {
+		if err == storage.ErrNoPersonalAccessToken {
+			return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("personal access token is unknown")
+		}
+
+		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("unable to load personal access token: %s", err)
+	}
+
+	now := ctx.Clock.Now()
+
+	switch {
+	case token.Revoked:
+		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("personal access token %s of user %s has been revoked", token.ID, token.Username)
+	case !now.Before(token.Expires):
+		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("personal access token %s of user %s has expired", token.ID, token.Username)
+	case !isDomainAllowedForPersonalAccessToken(domain, token.Domains):
+		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("personal access token %s of user %s is not allowed to access %s", token.ID, token.Username, targetURL.String())
+	}
+
+	details, err := ctx.Providers.UserProvider.GetDetails(token.Username)
+	if err != nil {
+		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("unable to retrieve details of user %s: %s", token.Username, err)
+	}
+
+	if err = ctx.Providers.StorageProvider.UpdatePersonalAccessTokenLastUsed(token.ID, now); err != nil {
+		ctx.Logger.Errorf("Unable to update the last use of personal access token %s of user %s: %s", token.ID, token.Username, err)
+	}
+
+	return token.Username, details.DisplayName, details.Groups, details.Emails, token.Level, nil
+}
+
+// isDomainAllowedForPersonalAccessToken returns true if the domains are empty or if one of them is either equal to the
+// domain or is a wildcard domain such as *.example.com matching it.
+func isDomainAllowedForPersonalAccessToken(domain string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+
+	domain = strings.ToLower(domain)
+
+	for _, allowed := range domains {
+		allowed = strings.ToLower(allowed)
+
+		if allowed == domain || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(domain, allowed[1:])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func generatePersonalAccessToken() (token string, err error) {
+	data := make([]byte, personalAccessTokenRandomBytes)
+
+	if _, err = rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return personalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func newPersonalAccessTokenResponse(token models.PersonalAccessToken) (response personalAccessTokenResponse) {
+	response = personalAccessTokenResponse{
+		ID:        token.ID,
+		Name:      token.Name,
+		Domains:   token.Domains,
+		CreatedAt: token.Created.Unix(),
+		ExpiresAt: token.Expires.Unix(),
+		Revoked:   token.Revoked,
+	}
+
+	for name, level := range personalAccessTokenLevels {
+		if level == token.Level {
+			response.Level = name
+		}
+	}
+
+	if response.Domains == nil {
+		response.Domains = []string{}
+	}
+
+	if !token.LastUsed.IsZero() {
+		response.LastUsed = token.LastUsed.Unix()
+	}
+
+	return response
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/session"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// SessionsGet lists the active sessions of the user identified by the session.
+func SessionsGet(ctx *middlewares.AutheliaCtx) {
+	username := ctx.GetSession().RealUsername()
+
+	sessions, err := ctx.Providers.SessionProvider.ListSessions(username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to list the sessions of user %s: %s", username, err), messageOperationFailed)
+		return
+	}
+
+	currentID, err := ctx.Providers.SessionProvider.CurrentSessionID(ctx.RequestCtx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to identify the current session of user %s: %s", username, err), messageOperationFailed)
+		return
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+
+	for _, info := range sessions {
+		response = append(response, sessionResponse{
+			ID:           info.ID,
+			CreatedAt:    info.CreatedAt,
+			LastActivity: info.LastActivity,
+			IP:           info.IP,
+			UserAgent:    info.UserAgent,
+			Current:      info.ID == currentID,
+		})
+	}
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set sessions response in body: %s", err)
+	}
+}
+
+// SessionRevokePost revokes a session of the user identified by the session, which can be the session itself.
+func SessionRevokePost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := sessionRevokeRequestBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		ctx.Error(err, messageOperationFailed)
+		return
+	}
+
+	username := ctx.GetSession().RealUsername()
+
+	err = ctx.Providers.SessionProvider.RevokeSession(username, bodyJSON.ID)
+
+	switch {
+	case errors.Is(err, session.ErrSessionNotFound):
+		ctx.Error(fmt.Errorf("user %s attempted to revoke the session %s which does not exist or is not theirs", username, bodyJSON.ID), messageOperationFailed)
+		return
+	case err != nil:
+		ctx.Error(fmt.Errorf("unable to revoke the session %s of user %s: %s", bodyJSON.ID, username, err), messageOperationFailed)
+		return
+	}
+
+	ctx.Logger.Debugf("User %s revoked the session %s", username, bodyJSON.ID)
+
+	ctx.ReplyOK()
+}
+
+// AdminSessionsRevokePost revokes every session of a user, for example after the compromise of their account. Only
+// the members of the configured administrators group can revoke the sessions of the other users.
+func AdminSessionsRevokePost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := sessionsRevokeRequestBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		ctx.Error(err, messageOperationFailed)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	if !utils.IsStringInSliceFold(ctx.Configuration.Session.AdminGroup, userSession.Groups) {
+		ctx.Logger.Errorf("User %s attempted to revoke the sessions of user %s without being a member of the group %s", userSession.Username, bodyJSON.Username, ctx.Configuration.Session.AdminGroup)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	count, err := ctx.Providers.SessionProvider.RevokeSessions(bodyJSON.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to revoke the sessions of user %s: %s", bodyJSON.Username, err), messageOperationFailed)
+		return
+	}
+
+	ctx.Logger.Infof("User %s revoked the %d sessions of user %s", userSession.Username, count, bodyJSON.Username)
+
+	if err = ctx.SetJSONBody(sessionsRevokeResponse{Revoked: count}); err != nil {
+		ctx.Logger.Errorf("Unable to set sessions revocation response in body: %s", err)
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/mocks"
+)
+
+type SessionsSuite struct {
+	suite.Suite
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *SessionsSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.1")
+	s.mock.Ctx.Request.Header.SetUserAgent("Firefox")
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.SetOneFactor(time.Unix(1625048140, 0), &authentication.UserDetails{Username: testUsername, Groups: []string{"admins"}}, false)
+	require.NoError(s.T(), s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *SessionsSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+// newSession creates another session of the user in the session provider of the mock.
+func (s *SessionsSuite) newSession(username, userAgent string, lastActivity int64) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Forwarded-For", "192.168.0.2")
+	ctx.Request.Header.SetUserAgent(userAgent)
+
+	provider := s.mock.Ctx.Providers.SessionProvider
+
+	userSession, err := provider.GetSession(ctx)
+	s.Require().NoError(err)
+
+	userSession.SetOneFactor(time.Unix(1625048140, 0), &authentication.UserDetails{Username: username}, false)
+	userSession.LastActivity = lastActivity
+	s.Require().NoError(provider.SaveSession(ctx, userSession))
+
+	return ctx
+}
+
+func (s *SessionsSuite) listSessions() []sessionResponse {
+	s.mock.Ctx.Response.Reset()
+	SessionsGet(s.mock.Ctx)
+
+	var response struct {
+		Status string            `json:"status"`
+		Data   []sessionResponse `json:"data"`
+	}
+
+	s.Require().NoError(json.Unmarshal(s.mock.Ctx.Response.Body(), &response))
+	s.Require().Equal("OK", response.Status)
+
+	return response.Data
+}
+
+func (s *SessionsSuite) TestShouldListSessions() {
+	s.newSession(testUsername, "Chrome", 0)
+	s.newSession("harry", "Safari", 0)
+
+	sessions := s.listSessions()
+
+	s.Require().Len(sessions, 2)
+	s.Assert().Equal("Firefox", sessions[0].UserAgent)
+	s.Assert().Equal("192.168.0.1", sessions[0].IP)
+	s.Assert().Equal(int64(1625048140), sessions[0].CreatedAt)
+	s.Assert().True(sessions[0].Current)
+	s.Assert().Equal("Chrome", sessions[1].UserAgent)
+	s.Assert().False(sessions[1].Current)
+}
+
+func (s *SessionsSuite) TestShouldRevokeSession() {
+	other := s.newSession(testUsername, "Chrome", 0)
+
+	sessions := s.listSessions()
+	s.Require().Len(sessions, 2)
+
+	s.mock.Ctx.Response.Reset()
+	s.mock.Ctx.Request.SetBodyString(`{"id":"` + sessions[1].ID + `"}`)
+	SessionRevokePost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+
+	userSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(other)
+	s.Require().NoError(err)
+	s.Assert().Equal("", userSession.Username)
+
+	sessions = s.listSessions()
+	s.Require().Len(sessions, 1)
+	s.Assert().True(sessions[0].Current)
+}
+
+func (s *SessionsSuite) TestShouldNotRevokeSessionOfOtherUser() {
+	other := s.newSession("harry", "Safari", 0)
+
+	id, err := s.mock.Ctx.Providers.SessionProvider.CurrentSessionID(other)
+	s.Require().NoError(err)
+
+	s.mock.Ctx.Request.SetBodyString(`{"id":"` + id + `"}`)
+	SessionRevokePost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), messageOperationFailed)
+
+	userSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(other)
+	s.Require().NoError(err)
+	s.Assert().Equal("harry", userSession.Username)
+}
+
+func (s *SessionsSuite) TestShouldRevokeSessionsOfUserAsAdministrator() {
+	s.mock.Ctx.Configuration.Session.AdminGroup = "admins"
+
+	first := s.newSession("harry", "Safari", 0)
+	second := s.newSession("harry", "Chrome", 0)
+
+	s.mock.Ctx.Request.SetBodyString(`{"username":"harry"}`)
+	AdminSessionsRevokePost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), sessionsRevokeResponse{Revoked: 2})
+
+	for _, ctx := range []*fasthttp.RequestCtx{first, second} {
+		userSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(ctx)
+		s.Require().NoError(err)
+		s.Assert().Equal("", userSession.Username)
+	}
+}
+
+func (s *SessionsSuite) TestShouldNotRevokeSessionsOfUserWithoutBeingAdministrator() {
+	s.mock.Ctx.Configuration.Session.AdminGroup = "operators"
+
+	other := s.newSession("harry", "Safari", 0)
+
+	s.mock.Ctx.Request.SetBodyString(`{"username":"harry"}`)
+	AdminSessionsRevokePost(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+
+	userSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(other)
+	s.Require().NoError(err)
+	s.Assert().Equal("harry", userSession.Username)
+}
+
+func (s *SessionsSuite) TestShouldRevokeSessionsOfUserAsAdministratorWithGroupOfDifferentCase() {
+	s.mock.Ctx.Configuration.Session.AdminGroup = "Admins"
+
+	s.newSession("harry", "Safari", 0)
+
+	s.mock.Ctx.Request.SetBodyString(`{"username":"harry"}`)
+	AdminSessionsRevokePost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), sessionsRevokeResponse{Revoked: 1})
+}
+
+func (s *SessionsSuite) TestShouldRequireTwoFactorToRevokeSessionsOfUser() {
+	s.mock.Ctx.Configuration.Session.AdminGroup = "admins"
+
+	other := s.newSession("harry", "Safari", 0)
+
+	s.mock.Ctx.Request.SetBodyString(`{"username":"harry"}`)
+	middlewares.RequireTwoFactor(AdminSessionsRevokePost)(s.mock.Ctx)
+
+	s.Assert().Equal(403, s.mock.Ctx.Response.StatusCode())
+
+	userSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(other)
+	s.Require().NoError(err)
+	s.Assert().Equal("harry", userSession.Username)
+}
+
+func TestRunSessionsSuite(t *testing.T) {
+	suite.Run(t, new(SessionsSuite))
+}
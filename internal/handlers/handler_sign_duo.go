@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/duo"
 	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
 )
 
 // SecondFactorDuoPost handler for sending a push notification via duo api.
@@ -53,10 +55,14 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 		}
 
 		if duoResponse.Response.Result != testResultAllow {
+			markSecondFactorAttempt(ctx, models.AuthenticationTypeDuo, userSession.Username, requestBody.TargetURL, false)
 			ctx.ReplyUnauthorized()
+
 			return
 		}
 
+		markSecondFactorAttempt(ctx, models.AuthenticationTypeDuo, userSession.Username, requestBody.TargetURL, true)
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -64,7 +70,8 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 			return
 		}
 
-		userSession.SetTwoFactor(ctx.Clock.Now())
+		userSession.SetTwoFactor(ctx.Clock.Now(), authentication.Push)
+		userSession.SetClient(ctx.ClientIP(), ctx.UserAgent())
 
 		err = ctx.SaveSession(userSession)
 		if err != nil {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/authelia/authelia/v4/internal/duo"
 	"github.com/authelia/authelia/v4/internal/mocks"
+	"github.com/authelia/authelia/v4/internal/models"
 )
 
 type SecondFactorDuoPostSuite struct {
@@ -35,6 +36,8 @@ func (s *SecondFactorDuoPostSuite) TearDownTest() {
 }
 
 func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndAllowAccess() {
+	s.mock.Ctx.Clock = &s.mock.Clock
+
 	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
 
 	values := url.Values{}
@@ -51,12 +54,26 @@ func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndAllowAccess() {
 
 	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://target.example.com\"}")
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   "john",
+			Successful: true,
+			Time:       s.mock.Clock.Now(),
+			Event:      models.AuthenticationEventSecondFactor,
+			Type:       models.AuthenticationTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			RequestURL: "https://target.example.com",
+		})).
+		Return(nil)
+
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
 
 	assert.Equal(s.T(), s.mock.Ctx.Response.StatusCode(), 200)
 }
 
 func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndDenyAccess() {
+	s.mock.Ctx.Clock = &s.mock.Clock
+
 	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
 
 	values := url.Values{}
@@ -73,6 +90,18 @@ func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndDenyAccess() {
 
 	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://target.example.com\"}")
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   "john",
+			Successful: false,
+			Time:       s.mock.Clock.Now(),
+			Event:      models.AuthenticationEventSecondFactor,
+			Type:       models.AuthenticationTypeDuo,
+			RemoteIP:   s.mock.Ctx.RemoteIP(),
+			RequestURL: "https://target.example.com",
+		})).
+		Return(nil)
+
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
 
 	assert.Equal(s.T(), s.mock.Ctx.Response.StatusCode(), 401)
@@ -111,6 +140,10 @@ func (s *SecondFactorDuoPostSuite) TestShouldRedirectUserToDefaultURL() {
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), redirectResponse{
 		Redirect: testRedirectionURL,
@@ -129,6 +162,10 @@ func (s *SecondFactorDuoPostSuite) TestShouldNotReturnRedirectURL() {
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), nil)
 }
@@ -147,6 +184,10 @@ func (s *SecondFactorDuoPostSuite) TestShouldRedirectUserToSafeTargetURL() {
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), redirectResponse{
 		Redirect: "https://mydomain.local",
@@ -167,6 +208,10 @@ func (s *SecondFactorDuoPostSuite) TestShouldNotRedirectToUnsafeURL() {
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), nil)
 }
@@ -188,6 +233,10 @@ func (s *SecondFactorDuoPostSuite) TestShouldRegenerateSessionForPreventingSessi
 	r := regexp.MustCompile("^authelia_session=(.*); path=")
 	res := r.FindAllStringSubmatch(string(s.mock.Ctx.Response.Header.PeekCookie("authelia_session")), -1)
 
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
 	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), nil)
 
@@ -3,7 +3,9 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
 )
 
 // SecondFactorTOTPPost validate the TOTP passcode provided by the user.
@@ -27,15 +29,19 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 
 		isValid, err := totpVerifier.Verify(requestBody.Token, secret)
 		if err != nil {
+			markSecondFactorAttempt(ctx, models.AuthenticationTypeTOTP, userSession.Username, requestBody.TargetURL, false)
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("error occurred during OTP validation for user %s: %s", userSession.Username, err), messageMFAValidationFailed)
 			return
 		}
 
 		if !isValid {
+			markSecondFactorAttempt(ctx, models.AuthenticationTypeTOTP, userSession.Username, requestBody.TargetURL, false)
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("wrong passcode during TOTP validation for user %s", userSession.Username), messageMFAValidationFailed)
 			return
 		}
 
+		markSecondFactorAttempt(ctx, models.AuthenticationTypeTOTP, userSession.Username, requestBody.TargetURL, true)
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -43,7 +49,8 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 			return
 		}
 
-		userSession.SetTwoFactor(ctx.Clock.Now())
+		userSession.SetTwoFactor(ctx.Clock.Now(), authentication.TOTP)
+		userSession.SetClient(ctx.ClientIP(), ctx.UserAgent())
 
 		err = ctx.SaveSession(userSession)
 		if err != nil {
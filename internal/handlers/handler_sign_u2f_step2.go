@@ -3,7 +3,9 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/models"
 )
 
 // SecondFactorU2FSignPost handler for completing a signing request.
@@ -35,10 +37,14 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 			*userSession.U2FChallenge)
 
 		if err != nil {
+			markSecondFactorAttempt(ctx, models.AuthenticationTypeU2F, userSession.Username, requestBody.TargetURL, false)
 			ctx.Error(err, messageMFAValidationFailed)
+
 			return
 		}
 
+		markSecondFactorAttempt(ctx, models.AuthenticationTypeU2F, userSession.Username, requestBody.TargetURL, true)
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -46,7 +52,8 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 			return
 		}
 
-		userSession.SetTwoFactor(ctx.Clock.Now())
+		userSession.SetTwoFactor(ctx.Clock.Now(), authentication.U2F)
+		userSession.SetClient(ctx.ClientIP(), ctx.UserAgent())
 
 		err = ctx.SaveSession(userSession)
 		if err != nil {
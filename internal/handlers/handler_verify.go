@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
-	"net"
 	"net/url"
 	"strings"
 	"time"
@@ -15,12 +14,13 @@ import (
 	"github.com/authelia/authelia/v4/internal/authorization"
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
 	"github.com/authelia/authelia/v4/internal/middlewares"
+	"github.com/authelia/authelia/v4/internal/regulation"
 	"github.com/authelia/authelia/v4/internal/session"
 	"github.com/authelia/authelia/v4/internal/utils"
 )
 
-func isURLUnderProtectedDomain(url *url.URL, domain string) bool {
-	return strings.HasSuffix(url.Hostname(), domain)
+func isURLUnderProtectedDomain(url *url.URL, provider *session.Provider) bool {
+	return provider.GetDomainByHost(url.Hostname()) != nil
 }
 
 func isSchemeHTTPS(url *url.URL) bool {
@@ -53,17 +53,16 @@ func parseBasicAuth(header, auth string) (username, password string, err error)
 	return cs[:s], cs[s+1:], nil
 }
 
-// isTargetURLAuthorized check whether the given user is authorized to access the resource.
+// isTargetURLAuthorized check whether the given subject is authorized to access the resource. The requirements of the
+// matching rule are returned so the caller can verify the age of the authentication.
 func isTargetURLAuthorized(authorizer *authorization.Authorizer, targetURL url.URL,
-	username string, userGroups []string, clientIP net.IP, method []byte, authLevel authentication.Level) authorizationMatching {
-	level := authorizer.GetRequiredLevel(
-		authorization.Subject{
-			Username: username,
-			Groups:   userGroups,
-			IP:       clientIP,
-		},
-		authorization.NewObjectRaw(&targetURL, method))
+	subject authorization.Subject, method []byte, authLevel authentication.Level) (authorizationMatching, authorization.Requirements) {
+	requirements := authorizer.GetRequirements(subject, authorization.NewObjectRaw(&targetURL, method))
+
+	return isLevelAuthorized(requirements.Level, subject.Username, authLevel), requirements
+}
 
+func isLevelAuthorized(level authorization.Level, username string, authLevel authentication.Level) authorizationMatching {
 	switch {
 	case level == authorization.Bypass:
 		return Authorized
@@ -84,22 +83,37 @@ func isTargetURLAuthorized(authorizer *authorization.Authorizer, targetURL url.U
 }
 
 // verifyBasicAuth verify that the provided username and password are correct and
-// that the user is authorized to target the resource.
-func verifyBasicAuth(header string, auth []byte, ctx *middlewares.AutheliaCtx) (username, name string, groups, emails []string, authLevel authentication.Level, err error) {
+// that the user is authorized to target the resource. The failed attempts are regulated like the first factor attempts
+// of the portal.
+func verifyBasicAuth(header string, auth []byte, ctx *middlewares.AutheliaCtx, targetURL *url.URL, method []byte) (username, name string, groups, emails []string, authLevel authentication.Level, err error) {
 	username, password, err := parseBasicAuth(header, string(auth))
 
 	if err != nil {
 		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("unable to parse content of %s header: %s", header, err)
 	}
 
+	bannedUntil, err := ctx.Providers.Regulator.Regulate(username)
+	if err != nil {
+		// The attempts of the banned users aren't logged, the credentials of each request would add a row otherwise.
+		if err == regulation.ErrUserIsBanned {
+			return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("user %s is banned until %s", username, bannedUntil)
+		}
+
+		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("unable to regulate authentication: %s", err)
+	}
+
 	authenticated, err := ctx.Providers.UserProvider.CheckUserPassword(username, password)
 
 	if err != nil {
+		markBasicAuthFailure(ctx, username, targetURL, method)
+
 		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("unable to check credentials extracted from %s header: %s", header, err)
 	}
 
 	// If the user is not correctly authenticated, send a 401.
 	if !authenticated {
+		markBasicAuthFailure(ctx, username, targetURL, method)
+
 		// Request Basic Authentication otherwise
 		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("user %s is not authenticated", username)
 	}
@@ -113,6 +127,26 @@ func verifyBasicAuth(header string, auth []byte, ctx *middlewares.AutheliaCtx) (
 	return username, details.DisplayName, details.Groups, details.Emails, authentication.OneFactor, nil
 }
 
+// parseBearerToken returns the token of an Authorization header using the Bearer scheme.
+func parseBearerToken(header []byte) (token string, ok bool) {
+	const prefix = "Bearer "
+
+	if len(header) <= len(prefix) || !strings.EqualFold(string(header[:len(prefix)]), prefix) {
+		return "", false
+	}
+
+	return string(header[len(prefix):]), true
+}
+
+// parsePersonalAccessToken returns the personal access token of an Authorization header using the Bearer scheme.
+func parsePersonalAccessToken(header []byte) (token string, ok bool) {
+	if token, ok = parseBearerToken(header); !ok {
+		return "", false
+	}
+
+	return token, strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
 // setForwardedHeaders set the forwarded User, Groups, Name and Email headers.
 func setForwardedHeaders(headers *fasthttp.ResponseHeader, username, name string, groups, emails []string) {
 	if username != "" {
@@ -128,9 +162,51 @@ func setForwardedHeaders(headers *fasthttp.ResponseHeader, username, name string
 	}
 }
 
+// setRuleHeaders set the headers of the access control rule which authorized the request. The headers which can't be
+// rendered or whose value contains a line break are skipped.
+func setRuleHeaders(ctx *middlewares.AutheliaCtx, requirements authorization.Requirements, username, name string, groups, emails []string) {
+	if len(requirements.Headers) == 0 {
+		return
+	}
+
+	data := authorization.HeaderTemplateData{
+		Username:    username,
+		DisplayName: name,
+		Emails:      emails,
+		Groups:      groups,
+		Captures:    requirements.Captures,
+	}
+
+	if len(emails) != 0 {
+		data.Email = emails[0]
+	}
+
+	for _, header := range requirements.Headers {
+		value, err := header.Render(data)
+		if err != nil {
+			ctx.Logger.Errorf("Unable to render the value of header %s for user %s: %v", header.Name, username, err)
+
+			continue
+		}
+
+		if strings.ContainsAny(value, "\r\n") {
+			ctx.Logger.Errorf("Unable to set header %s for user %s: the value contains a line break", header.Name, username)
+
+			continue
+		}
+
+		ctx.Response.Header.Set(header.Name, value)
+	}
+}
+
 // hasUserBeenInactiveTooLong checks whether the user has been inactive for too long.
-func hasUserBeenInactiveTooLong(ctx *middlewares.AutheliaCtx) (bool, error) { //nolint:unparam
-	maxInactivityPeriod := int64(ctx.Providers.SessionProvider.Inactivity.Seconds())
+func hasUserBeenInactiveTooLong(ctx *middlewares.AutheliaCtx) (bool, error) {
+	domain, err := ctx.Providers.SessionProvider.GetDomain(ctx.RequestCtx)
+	if err != nil {
+		return false, err
+	}
+
+	maxInactivityPeriod := int64(domain.Inactivity.Seconds())
 	if maxInactivityPeriod == 0 {
 		return false, nil
 	}
@@ -148,6 +224,45 @@ func hasUserBeenInactiveTooLong(ctx *middlewares.AutheliaCtx) (bool, error) { //
 	return false, nil
 }
 
+// verifyAuthenticationAge checks whether the authentication factors required by the rule were performed within the
+// maximum authentication age. When a factor is too old the authentication level of the session is lowered so the user
+// is asked to perform it again by the portal.
+func verifyAuthenticationAge(ctx *middlewares.AutheliaCtx, requirements authorization.Requirements) (err error) {
+	if requirements.MaxAuthenticationAge.IsZero() {
+		return nil
+	}
+
+	userSession := ctx.GetSession()
+	now := ctx.Clock.Now().Unix()
+
+	maxOneFactorAge := int64(requirements.MaxAuthenticationAge.OneFactor.Seconds())
+	maxTwoFactorAge := int64(requirements.MaxAuthenticationAge.TwoFactor.Seconds())
+
+	ctx.Logger.Tracef("Authentication age report: OneFactor=%d, MaxOneFactor=%d, TwoFactor=%d, MaxTwoFactor=%d",
+		now-userSession.FirstFactorAuthnTimestamp, maxOneFactorAge, now-userSession.SecondFactorAuthnTimestamp, maxTwoFactorAge)
+
+	switch {
+	case maxOneFactorAge != 0 && now-userSession.FirstFactorAuthnTimestamp > maxOneFactorAge:
+		// Destroy the session so the user has to perform the first factor again.
+		if err = ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx); err != nil {
+			return fmt.Errorf("unable to destroy user session after the first factor exceeded the maximum authentication age: %s", err)
+		}
+
+		return fmt.Errorf("the first factor of user %s exceeded the maximum authentication age of %s", userSession.Username, requirements.MaxAuthenticationAge.OneFactor)
+	case requirements.Level == authorization.TwoFactor && maxTwoFactorAge != 0 && now-userSession.SecondFactorAuthnTimestamp > maxTwoFactorAge:
+		// Lower the authentication level so the user has to perform the second factor again.
+		userSession.AuthenticationLevel = authentication.OneFactor
+
+		if err = ctx.SaveSession(userSession); err != nil {
+			return fmt.Errorf("unable to update user session after the second factor exceeded the maximum authentication age: %s", err)
+		}
+
+		return fmt.Errorf("the second factor of user %s exceeded the maximum authentication age of %s", userSession.Username, requirements.MaxAuthenticationAge.TwoFactor)
+	}
+
+	return nil
+}
+
 // verifySessionCookie verifies if a user is identified by a cookie.
 func verifySessionCookie(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userSession *session.UserSession, refreshProfile bool,
 	refreshProfileInterval time.Duration) (username, name string, groups, emails []string, authLevel authentication.Level, err error) {
@@ -194,7 +309,7 @@ func verifySessionCookie(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userS
 	return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.AuthenticationLevel, nil
 }
 
-func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, isBasicAuth bool, username string, method []byte) {
+func handleUnauthorized(ctx *middlewares.AutheliaCtx, impl authzImplementation, targetURL fmt.Stringer, isBasicAuth bool, username string, method []byte) {
 	var (
 		statusCode            int
 		redirectionURL        string
@@ -209,6 +324,14 @@ func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, is
 		friendlyUsername = username
 	}
 
+	if _, ok := parseBearerToken(ctx.Request.Header.Peek(HeaderAuthorization)); ok && isBasicAuth {
+		ctx.Logger.Infof("Access to %s is not authorized to user %s with a bearer token, sending 401 response", targetURL.String(), friendlyUsername)
+		ctx.ReplyUnauthorized()
+		ctx.Response.Header.Add("WWW-Authenticate", "Bearer realm=\"Authentication required\", error=\"invalid_token\"")
+
+		return
+	}
+
 	if isBasicAuth {
 		ctx.Logger.Infof("Access to %s is not authorized to user %s, sending 401 response with basic auth header", targetURL.String(), friendlyUsername)
 		ctx.ReplyUnauthorized()
@@ -220,7 +343,7 @@ func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, is
 	// Kubernetes ingress controller and Traefik use the rd parameter of the verify
 	// endpoint to provide the URL of the login portal. The target URL of the user
 	// is computed from X-Forwarded-* headers or X-Original-URL.
-	rd := string(ctx.QueryArgs().Peek("rd"))
+	rd := impl.getPortalURL(ctx)
 	rm := string(method)
 
 	switch rm {
@@ -240,7 +363,7 @@ func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, is
 	}
 
 	switch {
-	case ctx.IsXHR() || !ctx.AcceptsMIME("text/html") || rd == "":
+	case !impl.redirect || ctx.IsXHR() || !ctx.AcceptsMIME("text/html") || rd == "":
 		statusCode = fasthttp.StatusUnauthorized
 	default:
 		switch rm {
@@ -402,7 +525,25 @@ func getProfileRefreshSettings(cfg schema.AuthenticationBackendConfiguration) (r
 	return refresh, refreshInterval
 }
 
-func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile bool, refreshProfileInterval time.Duration) (isBasicAuth bool, username, name string, groups, emails []string, authLevel authentication.Level, err error) {
+func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, method []byte, refreshProfile bool, refreshProfileInterval time.Duration) (isBasicAuth bool, username, name string, groups, emails []string, authLevel authentication.Level, grant accessTokenGrant, err error) {
+	// Personal access tokens and OpenID Connect access tokens are stateless like basic auth, other bearer tokens belong
+	// to the protected applications.
+	if token, ok := parseBearerToken(ctx.Request.Header.Peek(HeaderAuthorization)); ok {
+		if strings.HasPrefix(token, personalAccessTokenPrefix) {
+			username, name, groups, emails, authLevel, err = verifyPersonalAccessToken(ctx, targetURL, token, targetURL.Hostname())
+			return true, username, name, groups, emails, authLevel, grant, err
+		}
+
+		if ctx.Providers.OpenIDConnect.Fosite != nil {
+			username, name, groups, emails, authLevel, grant, err = verifyOpenIDConnectAccessToken(ctx, token)
+			if err == nil {
+				return true, username, name, groups, emails, authLevel, grant, nil
+			}
+
+			ctx.Logger.Debugf("Bearer token is not a valid OpenID Connect access token, the session cookie is verified instead: %s", err)
+		}
+	}
+
 	authHeader := HeaderProxyAuthorization
 	if bytes.Equal(ctx.QueryArgs().Peek("auth"), []byte("basic")) {
 		authHeader = HeaderAuthorization
@@ -418,7 +559,7 @@ func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile
 	}
 
 	if isBasicAuth {
-		username, name, groups, emails, authLevel, err = verifyBasicAuth(authHeader, authValue, ctx)
+		username, name, groups, emails, authLevel, err = verifyBasicAuth(authHeader, authValue, ctx, targetURL, method)
 		return
 	}
 
@@ -440,13 +581,26 @@ func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile
 	return
 }
 
-// VerifyGet returns the handler verifying if a request is allowed to go through.
+// VerifyGet returns the handler verifying if a request is allowed to go through. The original URL is extracted from
+// either the X-Original-URL header or the X-Forwarded-* headers, and the users are redirected to the URL of the rd
+// parameter when they are not authorized.
 func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.RequestHandler {
+	return newAuthzHandler(cfg, authzImplementation{
+		getOriginalURL:    (*middlewares.AutheliaCtx).GetOriginalURL,
+		getOriginalMethod: (*middlewares.AutheliaCtx).XForwardedMethod,
+		getPortalURL:      getPortalURLFromQuery,
+		redirect:          true,
+	})
+}
+
+// newAuthzHandler returns the handler verifying if a request is allowed to go through using the implementation to
+// extract the original request and to respond to unauthorized requests.
+func newAuthzHandler(cfg schema.AuthenticationBackendConfiguration, impl authzImplementation) middlewares.RequestHandler {
 	refreshProfile, refreshProfileInterval := getProfileRefreshSettings(cfg)
 
 	return func(ctx *middlewares.AutheliaCtx) {
 		ctx.Logger.Tracef("Headers=%s", ctx.Request.Header.String())
-		targetURL, err := ctx.GetOriginalURL()
+		targetURL, err := impl.getOriginalURL(ctx)
 
 		if err != nil {
 			ctx.Logger.Errorf("Unable to parse target URL: %s", err)
@@ -463,16 +617,15 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 			return
 		}
 
-		if !isURLUnderProtectedDomain(targetURL, ctx.Configuration.Session.Domain) {
-			ctx.Logger.Errorf("Target URL %s is not under the protected domain %s",
-				targetURL.String(), ctx.Configuration.Session.Domain)
+		if !isURLUnderProtectedDomain(targetURL, ctx.Providers.SessionProvider) {
+			ctx.Logger.Errorf("Target URL %s is not under any of the protected domains", targetURL.String())
 			ctx.ReplyUnauthorized()
 
 			return
 		}
 
-		method := ctx.XForwardedMethod()
-		isBasicAuth, username, name, groups, emails, authLevel, err := verifyAuth(ctx, targetURL, refreshProfile, refreshProfileInterval)
+		method := impl.getOriginalMethod(ctx)
+		isBasicAuth, username, name, groups, emails, authLevel, grant, err := verifyAuth(ctx, targetURL, method, refreshProfile, refreshProfileInterval)
 
 		if err != nil {
 			ctx.Logger.Errorf("Error caught when verifying user authorization: %s", err)
@@ -482,22 +635,51 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 				return
 			}
 
-			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method)
+			handleUnauthorized(ctx, impl, targetURL, isBasicAuth, username, method)
 
 			return
 		}
 
-		authorized := isTargetURLAuthorized(ctx.Providers.Authorizer, *targetURL, username,
-			groups, ctx.RemoteIP(), method, authLevel)
+		subject := authorization.Subject{
+			Username: username,
+			Groups:   groups,
+			IP:       ctx.RemoteIP(),
+			ClientID: grant.ClientID,
+			Scopes:   grant.Scopes,
+		}
+
+		authorized, requirements := isTargetURLAuthorized(ctx.Providers.Authorizer, *targetURL, subject, method, authLevel)
+
+		if authorized == Authorized && !isBasicAuth && requirements.Level != authorization.Bypass {
+			if err = verifyAuthenticationAge(ctx, requirements); err != nil {
+				ctx.Logger.Infof("Access to %s requires a more recent authentication: %s", targetURL.String(), err)
+
+				authorized = NotAuthorized
+			}
+		}
 
 		switch authorized {
 		case Forbidden:
 			ctx.Logger.Infof("Access to %s is forbidden to user %s", targetURL.String(), username)
 			ctx.ReplyForbidden()
 		case NotAuthorized:
-			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method)
+			handleUnauthorized(ctx, impl, targetURL, isBasicAuth, username, method)
 		case Authorized:
 			setForwardedHeaders(&ctx.Response.Header, username, name, groups, emails)
+			setRuleHeaders(ctx, requirements, username, name, groups, emails)
+
+			var userSession session.UserSession
+			if !isBasicAuth {
+				userSession = ctx.GetSession()
+			}
+
+			if userSession.ImpersonatedBy != nil {
+				ctx.Response.Header.Set(headerRemoteImpersonatedBy, userSession.ImpersonatedBy.Username)
+			}
+
+			if ctx.Providers.IdentityAssertion != nil {
+				setIdentityAssertionHeader(ctx, targetURL, username, name, groups, emails, authLevel, userSession.SecondFactorMethod)
+			}
 		}
 
 		if err := updateActivityTimestamp(ctx, isBasicAuth, username); err != nil {
@@ -505,3 +687,19 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 		}
 	}
 }
+
+// getPortalURLFromQuery returns the URL of the login portal from the rd parameter, or the portal URL of the session
+// domain of the request if the parameter is absent. It's empty when the host of the request isn't under any session
+// domain.
+func getPortalURLFromQuery(ctx *middlewares.AutheliaCtx) string {
+	if rd := ctx.QueryArgs().Peek("rd"); len(rd) != 0 {
+		return string(rd)
+	}
+
+	domain, err := ctx.Providers.SessionProvider.GetDomain(ctx.RequestCtx)
+	if err != nil {
+		return ""
+	}
+
+	return domain.PortalURL
+}
@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ory/fosite"
 	"github.com/ory/fosite/handler/openid"
 	"github.com/ory/fosite/token/jwt"
 
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/authorization"
+	"github.com/authelia/authelia/v4/internal/middlewares"
 	"github.com/authelia/authelia/v4/internal/oidc"
 	"github.com/authelia/authelia/v4/internal/session"
 	"github.com/authelia/authelia/v4/internal/utils"
@@ -20,6 +28,44 @@ func isConsentMissing(workflow *session.OIDCWorkflowSession, requestedScopes, re
 		len(requestedAudience) > 0 && utils.IsStringSlicesDifferentFold(requestedAudience, workflow.GrantedAudience)
 }
 
+// isMaxAgeExceeded returns true if the time since the user performed the authentication required by the policy is
+// greater than the maxAge parameter of the authorization request. Authentication performed after the workflow of the
+// client was created always satisfies the maxAge parameter so the user is not asked to authenticate repeatedly.
+func isMaxAgeExceeded(maxAge, clientID string, policy authorization.Level, userSession session.UserSession, now time.Time) (exceeded bool) {
+	if maxAge == "" {
+		return false
+	}
+
+	seconds, err := strconv.ParseInt(maxAge, 10, 64)
+	if err != nil || seconds < 0 {
+		return false
+	}
+
+	authTime, err := userSession.AuthenticatedTime(policy)
+	if err != nil {
+		return false
+	}
+
+	if workflow := userSession.OIDCWorkflowSession; workflow != nil && workflow.ClientID == clientID &&
+		authTime.Unix() >= workflow.CreatedTimestamp {
+		return false
+	}
+
+	return now.Unix()-authTime.Unix() > seconds
+}
+
+// lowerAuthenticationLevel returns the session with the authentication level lowered so the user has to perform the
+// authentication required by the policy again.
+func lowerAuthenticationLevel(userSession session.UserSession, policy authorization.Level) session.UserSession {
+	if policy == authorization.TwoFactor {
+		userSession.AuthenticationLevel = authentication.OneFactor
+
+		return userSession
+	}
+
+	return session.NewDefaultUserSession()
+}
+
 func newOpenIDSession(subject string) *oidc.OpenIDSession {
 	return &oidc.OpenIDSession{
 		DefaultSession: &openid.DefaultSession{
@@ -30,3 +76,45 @@ func newOpenIDSession(subject string) *oidc.OpenIDSession {
 		Extra: map[string]interface{}{},
 	}
 }
+
+// verifyOpenIDConnectAccessToken verify that the OpenID Connect access token is active and returns the details of the
+// user it was issued to as well as the client and the scopes granted to it.
+func verifyOpenIDConnectAccessToken(ctx *middlewares.AutheliaCtx, token string) (username, name string, groups, emails []string, authLevel authentication.Level, grant accessTokenGrant, err error) {
+	tokenType, ar, err := ctx.Providers.OpenIDConnect.Fosite.IntrospectToken(ctx, token, fosite.AccessToken, newOpenIDSession(""))
+	if err != nil {
+		return "", "", nil, nil, authentication.NotAuthenticated, grant, fmt.Errorf("unable to introspect the access token: %w", err)
+	}
+
+	if tokenType != fosite.AccessToken {
+		return "", "", nil, nil, authentication.NotAuthenticated, grant, fmt.Errorf("token is a %s rather than an access token", tokenType)
+	}
+
+	client, ok := ar.GetClient().(*oidc.InternalClient)
+	if !ok {
+		return "", "", nil, nil, authentication.NotAuthenticated, grant, fmt.Errorf("unable to assert the type of the client of the access token")
+	}
+
+	// Tokens issued with the client credentials grant have no subject and can't be mapped to a user.
+	username = ar.GetSession().GetSubject()
+	if username == "" {
+		return "", "", nil, nil, authentication.NotAuthenticated, grant, fmt.Errorf("access token of client %s has no subject", client.GetID())
+	}
+
+	details, err := ctx.Providers.UserProvider.GetDetails(username)
+	if err != nil {
+		return "", "", nil, nil, authentication.NotAuthenticated, grant, fmt.Errorf("unable to retrieve details of user %s: %s", username, err)
+	}
+
+	// The user had to satisfy the authorization policy of the client to be issued the access token.
+	authLevel = authentication.OneFactor
+	if client.Policy == authorization.TwoFactor {
+		authLevel = authentication.TwoFactor
+	}
+
+	grant = accessTokenGrant{
+		ClientID: client.GetID(),
+		Scopes:   ar.GetGrantedScopes(),
+	}
+
+	return username, details.DisplayName, details.Groups, details.Emails, authLevel, grant, nil
+}
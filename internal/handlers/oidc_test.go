@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/authorization"
 	"github.com/authelia/authelia/v4/internal/session"
 )
 
@@ -31,3 +34,42 @@ func TestShouldDetectIfConsentIsMissing(t *testing.T) {
 	requestedAudience = []string{"https://not.authelia.com"}
 	assert.True(t, isConsentMissing(workflow, requestedScopes, requestedAudience))
 }
+
+func TestShouldDetectIfMaxAgeIsExceeded(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	userSession := session.NewDefaultUserSession()
+	userSession.FirstFactorAuthnTimestamp = now.Add(-1 * time.Hour).Unix()
+	userSession.SecondFactorAuthnTimestamp = now.Add(-1 * time.Minute).Unix()
+
+	assert.False(t, isMaxAgeExceeded("", "client", authorization.OneFactor, userSession, now))
+	assert.False(t, isMaxAgeExceeded("abc", "client", authorization.OneFactor, userSession, now))
+	assert.False(t, isMaxAgeExceeded("3600", "client", authorization.OneFactor, userSession, now))
+	assert.True(t, isMaxAgeExceeded("3599", "client", authorization.OneFactor, userSession, now))
+	assert.False(t, isMaxAgeExceeded("60", "client", authorization.TwoFactor, userSession, now))
+	assert.True(t, isMaxAgeExceeded("0", "client", authorization.TwoFactor, userSession, now))
+
+	// Authentication performed during the workflow of the client satisfies the max age.
+	userSession.OIDCWorkflowSession = &session.OIDCWorkflowSession{
+		ClientID:         "client",
+		CreatedTimestamp: now.Add(-2 * time.Minute).Unix(),
+	}
+
+	assert.False(t, isMaxAgeExceeded("0", "client", authorization.TwoFactor, userSession, now))
+	assert.True(t, isMaxAgeExceeded("0", "other", authorization.TwoFactor, userSession, now))
+	assert.True(t, isMaxAgeExceeded("0", "client", authorization.OneFactor, userSession, now))
+}
+
+func TestShouldLowerAuthenticationLevel(t *testing.T) {
+	userSession := session.NewDefaultUserSession()
+	userSession.Username = "john"
+	userSession.AuthenticationLevel = authentication.TwoFactor
+
+	lowered := lowerAuthenticationLevel(userSession, authorization.TwoFactor)
+	assert.Equal(t, "john", lowered.Username)
+	assert.Equal(t, authentication.OneFactor, lowered.AuthenticationLevel)
+
+	lowered = lowerAuthenticationLevel(userSession, authorization.OneFactor)
+	assert.Equal(t, "", lowered.Username)
+	assert.Equal(t, authentication.NotAuthenticated, lowered.AuthenticationLevel)
+}
@@ -85,7 +85,13 @@ func Handle1FAResponse(ctx *middlewares.AutheliaCtx, targetURI, requestMethod st
 		return
 	}
 
-	safeRedirection := utils.IsRedirectionSafe(*targetURL, ctx.Configuration.Session.Domain)
+	domain, err := ctx.Providers.SessionProvider.GetDomain(ctx.RequestCtx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to determine the session domain of the request: %s", err), messageAuthenticationFailed)
+		return
+	}
+
+	safeRedirection := utils.IsRedirectionSafe(*targetURL, domain.Domain)
 
 	if !safeRedirection {
 		ctx.Logger.Debugf("Redirection URL %s is not safe", targetURI)
@@ -125,7 +131,13 @@ func Handle2FAResponse(ctx *middlewares.AutheliaCtx, targetURI string) {
 		return
 	}
 
-	safe, err := utils.IsRedirectionURISafe(targetURI, ctx.Configuration.Session.Domain)
+	domain, err := ctx.Providers.SessionProvider.GetDomain(ctx.RequestCtx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("unable to determine the session domain of the request: %s", err), messageMFAValidationFailed)
+		return
+	}
+
+	safe, err := utils.IsRedirectionURISafe(targetURI, domain.Domain)
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("unable to check target URL: %s", err), messageMFAValidationFailed)
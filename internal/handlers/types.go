@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"net/url"
+
 	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/middlewares"
 )
 
 // MethodList is the list of available methods.
@@ -91,3 +94,108 @@ type resetPasswordStep1RequestBody struct {
 type resetPasswordStep2RequestBody struct {
 	Password string `json:"password"`
 }
+
+// personalAccessTokenRequestBody is the model of the request body used to create a personal access token.
+type personalAccessTokenRequestBody struct {
+	Name       string   `json:"name" valid:"required"`
+	Level      string   `json:"level" valid:"required"`
+	Domains    []string `json:"domains"`
+	Expiration string   `json:"expiration" valid:"required"`
+}
+
+// personalAccessTokenRevokeRequestBody is the model of the request body used to revoke a personal access token.
+type personalAccessTokenRevokeRequestBody struct {
+	ID string `json:"id" valid:"required"`
+}
+
+// personalAccessTokenResponse is the model of a personal access token, the token itself is only set in the response
+// of its creation.
+type personalAccessTokenResponse struct {
+	ID        string   `json:"id"`
+	Token     string   `json:"token,omitempty"`
+	Name      string   `json:"name"`
+	Level     string   `json:"level"`
+	Domains   []string `json:"domains"`
+	CreatedAt int64    `json:"created_at"`
+	ExpiresAt int64    `json:"expires_at"`
+	LastUsed  int64    `json:"last_used,omitempty"`
+	Revoked   bool     `json:"revoked"`
+}
+
+// accessTokenGrant is the client and the scopes granted to an OpenID Connect access token used as credentials.
+type accessTokenGrant struct {
+	ClientID string
+	Scopes   []string
+}
+
+// authzImplementation describes how an authorization endpoint extracts the original request from the request of the
+// proxy and how it responds to unauthorized requests.
+type authzImplementation struct {
+	getOriginalURL    func(ctx *middlewares.AutheliaCtx) (*url.URL, error)
+	getOriginalMethod func(ctx *middlewares.AutheliaCtx) []byte
+	getPortalURL      func(ctx *middlewares.AutheliaCtx) string
+
+	// redirect is false when the proxy turns the 401 responses into redirections to the login portal itself using the
+	// Location header.
+	redirect bool
+}
+
+// impersonationRequestBody is the model of the request body used to start the impersonation of a user.
+type impersonationRequestBody struct {
+	Username string `json:"username" valid:"required"`
+}
+
+// impersonationResponse is the model of the impersonation of a user by an administrator.
+type impersonationResponse struct {
+	Username       string `json:"username"`
+	ImpersonatedBy string `json:"impersonated_by"`
+	ExpiresAt      int64  `json:"expires_at"`
+}
+
+// sessionResponse is the model of an active session of the user.
+type sessionResponse struct {
+	ID           string `json:"id"`
+	CreatedAt    int64  `json:"created_at"`
+	LastActivity int64  `json:"last_activity"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+	Current      bool   `json:"current"`
+}
+
+// sessionRevokeRequestBody is the model of the request body used to revoke a session of the user.
+type sessionRevokeRequestBody struct {
+	ID string `json:"id" valid:"required"`
+}
+
+// sessionsRevokeRequestBody is the model of the request body used by the administrators to revoke every session of a
+// user.
+type sessionsRevokeRequestBody struct {
+	Username string `json:"username" valid:"required"`
+}
+
+// sessionsRevokeResponse is the model of the response of the revocation of every session of a user.
+type sessionsRevokeResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// authenticationLogResponse is the model of an attempt of the authentication log.
+type authenticationLogResponse struct {
+	Username      string `json:"username"`
+	Successful    bool   `json:"successful"`
+	Time          int64  `json:"time"`
+	Event         string `json:"event"`
+	Actor         string `json:"actor,omitempty"`
+	Type          string `json:"type"`
+	RemoteIP      string `json:"remote_ip"`
+	UserAgent     string `json:"user_agent"`
+	RequestMethod string `json:"request_method"`
+	RequestURL    string `json:"request_url"`
+	Banned        bool   `json:"banned"`
+}
+
+// authenticationLogsResponse is the model of a page of the attempts of the authentication log, the latest first.
+type authenticationLogsResponse struct {
+	Attempts []authenticationLogResponse `json:"attempts"`
+	Limit    int                         `json:"limit"`
+	Offset   int                         `json:"offset"`
+}
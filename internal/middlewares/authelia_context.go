@@ -12,7 +12,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 
+	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/models"
 	"github.com/authelia/authelia/v4/internal/session"
 	"github.com/authelia/authelia/v4/internal/utils"
 )
@@ -151,12 +153,19 @@ func (c *AutheliaCtx) ExternalRootURL() (string, error) {
 	return externalRootURL, nil
 }
 
+// XOriginalMethod return the content of the X-Original-Method header.
+func (c *AutheliaCtx) XOriginalMethod() []byte {
+	return c.RequestCtx.Request.Header.Peek(headerXOriginalMethod)
+}
+
 // XOriginalURL return the content of the X-Original-URL header.
 func (c *AutheliaCtx) XOriginalURL() []byte {
 	return c.RequestCtx.Request.Header.Peek(headerXOriginalURL)
 }
 
-// GetSession return the user session. Any update will be saved in cache.
+// GetSession return the user session. Any update will be saved in cache. The impersonation of the user is stopped
+// when it has expired so every handler sees the identity of the administrator once it has, and the session is
+// verified against the client it's bound to when the session binding is configured.
 func (c *AutheliaCtx) GetSession() session.UserSession {
 	userSession, err := c.Providers.SessionProvider.GetSession(c.RequestCtx)
 	if err != nil {
@@ -164,9 +173,123 @@ func (c *AutheliaCtx) GetSession() session.UserSession {
 		return session.NewDefaultUserSession()
 	}
 
+	if userSession.IsImpersonationExpired(c.Clock.Now()) {
+		if err = c.StopImpersonation(&userSession); err != nil {
+			c.Logger.Errorf("Unable to stop the expired impersonation: %s", err)
+			return session.NewDefaultUserSession()
+		}
+	}
+
+	destroyed, err := c.verifySessionBinding(&userSession)
+	if err != nil {
+		c.Logger.Errorf("Unable to verify the session binding: %s", err)
+		return session.NewDefaultUserSession()
+	}
+
+	if destroyed {
+		return session.NewDefaultUserSession()
+	}
+
 	return userSession
 }
 
+// verifySessionBinding checks the client of the request is the client the session is bound to. When the client drifted
+// from it, the session is destroyed with the reject policy. With the step up policy, the authentication level of a
+// session authenticated with two factors is lowered to one factor and the session is bound to the client so the user
+// is asked to perform the second factor again by the portal, the other sessions are destroyed. The session follows
+// the IP of the client when it changes within the tolerances of the binding.
+func (c *AutheliaCtx) verifySessionBinding(userSession *session.UserSession) (destroyed bool, err error) {
+	if c.Configuration.Session.Binding == nil || userSession.Username == "" {
+		return false, nil
+	}
+
+	ip := c.ClientIP()
+
+	bindingErr := c.Providers.SessionProvider.VerifyClient(c.RequestCtx, *userSession)
+
+	switch {
+	case bindingErr == nil:
+		if userSession.ClientIP == "" || userSession.ClientIP == ip.String() {
+			return false, nil
+		}
+
+		c.Logger.Infof("The IP of the client of the session of user %s changed from %s to %s", userSession.Username, userSession.ClientIP, ip)
+
+		userSession.ClientIP = ip.String()
+
+		if err = c.SaveSession(*userSession); err != nil {
+			return false, fmt.Errorf("unable to update the IP the session of user %s is bound to: %w", userSession.Username, err)
+		}
+
+		return false, nil
+	case c.Configuration.Session.Binding.Policy == sessionBindingPolicyStepUp && userSession.AuthenticationLevel == authentication.TwoFactor:
+		c.Logger.Warnf("Lowering the authentication level of the session of user %s to one factor: %s", userSession.Username, bindingErr)
+
+		userSession.AuthenticationLevel = authentication.OneFactor
+		userSession.SetClient(ip, c.UserAgent())
+
+		if err = c.SaveSession(*userSession); err != nil {
+			return false, fmt.Errorf("unable to update the session of user %s after the client drifted from the client the session is bound to: %w", userSession.Username, err)
+		}
+
+		return false, nil
+	default:
+		c.Logger.Warnf("Destroying the session of user %s: %s", userSession.Username, bindingErr)
+
+		if err = c.Providers.SessionProvider.DestroySession(c.RequestCtx); err != nil {
+			return false, fmt.Errorf("unable to destroy the session of user %s after the client drifted from the client the session is bound to: %w", userSession.Username, err)
+		}
+
+		return true, nil
+	}
+}
+
+// StopImpersonation restores the identity of the administrator impersonating the user of the session, saves the
+// session and records the end of the impersonation in the authentication log.
+func (c *AutheliaCtx) StopImpersonation(userSession *session.UserSession) error {
+	username, actor := userSession.Username, userSession.ImpersonatedBy.Username
+
+	userSession.StopImpersonation()
+
+	if err := c.SaveSession(*userSession); err != nil {
+		return fmt.Errorf("unable to save the session of user %s after impersonating user %s: %s", actor, username, err)
+	}
+
+	c.Logger.Infof("User %s stopped impersonating user %s", actor, username)
+	c.MarkImpersonation(models.AuthenticationEventImpersonationStop, username, actor, true)
+
+	return nil
+}
+
+// MarkImpersonation records an impersonation event in the authentication log, the failures are only logged.
+func (c *AutheliaCtx) MarkImpersonation(event, username, actor string, successful bool) {
+	if err := c.AppendImpersonationLog(event, username, actor, successful); err != nil {
+		c.Logger.Errorf("Unable to record the %s event of user %s impersonated by %s: %s", event, username, actor, err)
+	}
+}
+
+// AppendImpersonationLog records an impersonation event in the authentication log.
+func (c *AutheliaCtx) AppendImpersonationLog(event, username, actor string, successful bool) error {
+	attempt := c.NewAuthenticationAttempt(event, "", username, successful)
+	attempt.Actor = actor
+
+	return c.Providers.StorageProvider.AppendAuthenticationLog(attempt)
+}
+
+// NewAuthenticationAttempt returns the authentication attempt of the user made at the current time by the client of
+// the request.
+func (c *AutheliaCtx) NewAuthenticationAttempt(event, authType, username string, successful bool) models.AuthenticationAttempt {
+	return models.AuthenticationAttempt{
+		Username:   username,
+		Successful: successful,
+		Time:       c.Clock.Now(),
+		Event:      event,
+		Type:       authType,
+		RemoteIP:   c.RemoteIP(),
+		UserAgent:  string(c.UserAgent()),
+	}
+}
+
 // SaveSession save the content of the session.
 func (c *AutheliaCtx) SaveSession(userSession session.UserSession) error {
 	return c.Providers.SessionProvider.SaveSession(c.RequestCtx, userSession)
@@ -214,32 +337,43 @@ func (c *AutheliaCtx) SetJSONBody(value interface{}) error {
 
 // RemoteIP return the remote IP taking X-Forwarded-For header into account if provided.
 func (c *AutheliaCtx) RemoteIP() net.IP {
-	XForwardedFor := c.Request.Header.Peek("X-Forwarded-For")
-	if XForwardedFor != nil {
-		ips := strings.Split(string(XForwardedFor), ",")
-
-		if len(ips) > 0 {
-			return net.ParseIP(strings.Trim(ips[0], " "))
-		}
-	}
+	return utils.GetRemoteIP(c.RequestCtx)
+}
 
-	return c.RequestCtx.RemoteIP()
+// ClientIP returns the IP of the client the session is bound to and indexed with, the X-Forwarded-For header is only
+// trusted when the request is sent by one of the trusted proxies of the session.
+func (c *AutheliaCtx) ClientIP() net.IP {
+	return c.Providers.SessionProvider.ClientIP(c.RequestCtx)
 }
 
 // GetOriginalURL extract the URL from the request headers (X-Original-URI or X-Forwarded-* headers).
 func (c *AutheliaCtx) GetOriginalURL() (*url.URL, error) {
-	originalURL := c.XOriginalURL()
-	if originalURL != nil {
-		parsedURL, err := url.ParseRequestURI(string(originalURL))
-		if err != nil {
-			return nil, fmt.Errorf("Unable to parse URL extracted from X-Original-URL header: %v", err)
-		}
+	if c.XOriginalURL() != nil {
+		return c.GetXOriginalURL()
+	}
+
+	return c.GetXForwardedURL()
+}
 
-		c.Logger.Trace("Using X-Original-URL header content as targeted site URL")
+// GetXOriginalURL extract the URL from the X-Original-URL header.
+func (c *AutheliaCtx) GetXOriginalURL() (*url.URL, error) {
+	originalURL := c.XOriginalURL()
+	if originalURL == nil {
+		return nil, errMissingXOriginalURL
+	}
 
-		return parsedURL, nil
+	parsedURL, err := url.ParseRequestURI(string(originalURL))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse URL extracted from X-Original-URL header: %v", err)
 	}
 
+	c.Logger.Trace("Using X-Original-URL header content as targeted site URL")
+
+	return parsedURL, nil
+}
+
+// GetXForwardedURL extract the URL from the X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-URI headers.
+func (c *AutheliaCtx) GetXForwardedURL() (*url.URL, error) {
 	forwardedProto := c.XForwardedProto()
 	forwardedHost := c.XForwardedHost()
 	forwardedURI := c.XForwardedURI()
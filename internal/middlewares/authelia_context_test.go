@@ -19,7 +19,7 @@ func TestShouldCallNextWithAutheliaCtx(t *testing.T) {
 	ctx := &fasthttp.RequestCtx{}
 	configuration := schema.Configuration{}
 	userProvider := mocks.NewMockUserProvider(ctrl)
-	sessionProvider := session.NewProvider(configuration.Session, nil)
+	sessionProvider := session.NewProvider(configuration.Session, nil, nil)
 	providers := middlewares.Providers{
 		UserProvider:    userProvider,
 		SessionProvider: sessionProvider,
@@ -62,6 +62,41 @@ func TestShouldGetOriginalURLFromForwardedHeadersWithoutURI(t *testing.T) {
 	assert.Equal(t, expectedURL, originalURL)
 }
 
+func TestShouldOnlyGetXOriginalURLFromOriginalURLHeader(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	mock.Ctx.Request.Header.Set("X-Forwarded-Host", "home.example.com")
+
+	_, err := mock.Ctx.GetXOriginalURL()
+	assert.EqualError(t, err, "Missing header X-Original-URL")
+
+	mock.Ctx.Request.Header.Set("X-Original-URL", "https://app.example.com/path")
+
+	originalURL, err := mock.Ctx.GetXOriginalURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/path", originalURL.String())
+}
+
+func TestShouldOnlyGetXForwardedURLFromForwardedHeaders(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Request.Header.Set("X-Original-URL", "https://app.example.com/path")
+
+	_, err := mock.Ctx.GetXForwardedURL()
+	assert.EqualError(t, err, "Missing header X-Forwarded-Proto")
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	mock.Ctx.Request.Header.Set("X-Forwarded-Host", "home.example.com")
+	mock.Ctx.Request.Header.Set("X-Forwarded-URI", "/path?a=1")
+
+	originalURL, err := mock.Ctx.GetXForwardedURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://home.example.com/path?a=1", originalURL.String())
+}
+
 func TestShouldGetOriginalURLFromForwardedHeadersWithURI(t *testing.T) {
 	mock := mocks.NewMockAutheliaCtx(t)
 	defer mock.Close()
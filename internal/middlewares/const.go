@@ -8,6 +8,7 @@ const (
 	headerXForwardedHost   = "X-Forwarded-Host"
 	headerXForwardedURI    = "X-Forwarded-URI"
 	headerXOriginalURL     = "X-Original-URL"
+	headerXOriginalMethod  = "X-Original-Method"
 	headerXRequestedWith   = "X-Requested-With"
 )
 
@@ -29,3 +30,7 @@ const (
 )
 
 var protoHostSeparator = []byte("://")
+
+// sessionBindingPolicyStepUp is the session binding policy lowering the authentication level of the sessions which
+// drifted from the client they are bound to instead of destroying them.
+const sessionBindingPolicyStepUp = "step_up"
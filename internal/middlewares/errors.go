@@ -4,3 +4,4 @@ import "errors"
 
 var errMissingXForwardedHost = errors.New("Missing header X-Forwarded-Host")
 var errMissingXForwardedProto = errors.New("Missing header X-Forwarded-Proto")
+var errMissingXOriginalURL = errors.New("Missing header X-Original-URL")
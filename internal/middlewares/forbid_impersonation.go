@@ -0,0 +1,16 @@
+package middlewares
+
+// ForbidImpersonation check the user is not impersonated to execute the next handler. It protects the handlers which
+// change the credentials or the sessions of the user from the administrators impersonating them.
+func ForbidImpersonation(next RequestHandler) RequestHandler {
+	return func(ctx *AutheliaCtx) {
+		if userSession := ctx.GetSession(); userSession.ImpersonatedBy != nil {
+			ctx.Logger.Warnf("User %s attempted to %s %s while impersonating user %s", userSession.ImpersonatedBy.Username, ctx.Method(), ctx.Path(), userSession.Username)
+			ctx.ReplyForbidden()
+
+			return
+		}
+
+		next(ctx)
+	}
+}
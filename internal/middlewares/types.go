@@ -43,6 +43,9 @@ type Providers struct {
 	UserProvider    authentication.UserProvider
 	StorageProvider storage.Provider
 	Notifier        notification.Notifier
+
+	// IdentityAssertion manages the keys signing the identity assertions, it's nil when they're disabled.
+	IdentityAssertion *oidc.KeyManager
 }
 
 // RequestHandler represents an Authelia request handler.
@@ -3,6 +3,7 @@ package mocks
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"testing"
 	"time"
 
@@ -69,6 +70,7 @@ func NewMockAutheliaCtx(t *testing.T) *MockAutheliaCtx {
 	configuration := schema.Configuration{}
 	configuration.Session.RememberMeDuration = schema.DefaultSessionConfiguration.RememberMeDuration
 	configuration.Session.Name = "authelia_session"
+	configuration.Session.TrustedProxies = schema.DefaultSessionConfiguration.TrustedProxies
 	configuration.AccessControl.DefaultPolicy = "deny"
 	configuration.AccessControl.Rules = []schema.ACLRule{{
 		Domains: []string{"bypass.example.com"},
@@ -105,14 +107,16 @@ func NewMockAutheliaCtx(t *testing.T) *MockAutheliaCtx {
 	providers.Notifier = mockAuthelia.NotifierMock
 
 	providers.Authorizer = authorization.NewAuthorizer(
-		&configuration)
+		&configuration, &mockAuthelia.Clock)
 
 	providers.SessionProvider = session.NewProvider(
-		configuration.Session, nil)
+		configuration.Session, nil, nil)
 
 	providers.Regulator = regulation.NewRegulator(configuration.Regulation, providers.StorageProvider, &mockAuthelia.Clock)
 
 	request := &fasthttp.RequestCtx{}
+	// The requests are sent by a proxy on the loopback like the reverse proxy in front of Authelia.
+	request.SetRemoteAddr(&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
 	// Set a cookie to identify this client throughout the test.
 	// request.Request.Header.SetCookie("authelia_session", "client_cookie")
 
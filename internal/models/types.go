@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"net"
+	"time"
+
+	"github.com/authelia/authelia/v4/internal/authentication"
+)
 
 // AuthenticationAttempt represent an authentication attempt.
 type AuthenticationAttempt struct {
@@ -10,4 +15,62 @@ type AuthenticationAttempt struct {
 	Successful bool
 	// The time of the attempt.
 	Time time.Time
+	// The event of the attempt, i.e. the first factor, the start and stop of an impersonation or the eviction of a session.
+	Event string
+	// The administrator who impersonated the user for the impersonation events.
+	Actor string
+	// The type of the factor used by the attempt, i.e. 1fa, totp, u2f, duo or basic, empty for the other events.
+	Type string
+	// The IP address of the client who made the attempt.
+	RemoteIP net.IP
+	// The user agent of the client who made the attempt.
+	UserAgent string
+	// The method of the request the user tried to access.
+	RequestMethod string
+	// The URL of the request the user tried to access.
+	RequestURL string
+	// Banned true if the attempt was rejected because the user was banned by the regulation.
+	Banned bool
+}
+
+// The events of the authentication log.
+const (
+	AuthenticationEventFirstFactor        = "first_factor"
+	AuthenticationEventSecondFactor       = "second_factor"
+	AuthenticationEventImpersonationStart = "impersonation_start"
+	AuthenticationEventImpersonationStop  = "impersonation_stop"
+	AuthenticationEventSessionEvicted     = "session_evicted"
+)
+
+// The types of the factors of the authentication attempts.
+const (
+	AuthenticationType1FA   = "1fa"
+	AuthenticationTypeTOTP  = "totp"
+	AuthenticationTypeU2F   = "u2f"
+	AuthenticationTypeDuo   = "duo"
+	AuthenticationTypeBasic = "basic"
+)
+
+// PersonalAccessToken represents a personal access token minted by a user for API and CLI clients.
+type PersonalAccessToken struct {
+	// The unique identifier of the token.
+	ID string
+	// The user who owns the token.
+	Username string
+	// The name chosen by the user to identify the token.
+	Name string
+	// The SHA256 hash of the token, the token itself is never stored.
+	Hash string
+	// The authentication level granted to the requests using the token.
+	Level authentication.Level
+	// The domains the token is restricted to, the token is valid for any domain if empty.
+	Domains []string
+	// The time the token was created.
+	Created time.Time
+	// The time the token expires.
+	Expires time.Time
+	// The last time the token was used, zero if it has never been used.
+	LastUsed time.Time
+	// True if the token has been revoked.
+	Revoked bool
 }
@@ -18,9 +18,15 @@ import (
 // NewKeyManagerWithConfiguration when provided a schema.OpenIDConnectConfiguration creates a new KeyManager and adds an
 // initial key to the manager.
 func NewKeyManagerWithConfiguration(configuration *schema.OpenIDConnectConfiguration) (manager *KeyManager, err error) {
+	return NewKeyManagerWithPrivateKeyData(configuration.IssuerPrivateKey)
+}
+
+// NewKeyManagerWithPrivateKeyData creates a new KeyManager and adds the key in the PEM string format as the initial
+// key of the manager.
+func NewKeyManagerWithPrivateKeyData(data string) (manager *KeyManager, err error) {
 	manager = NewKeyManager()
 
-	_, _, err = manager.AddActivePrivateKeyData(configuration.IssuerPrivateKey)
+	_, _, err = manager.AddActivePrivateKeyData(data)
 	if err != nil {
 		return nil, err
 	}
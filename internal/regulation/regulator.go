@@ -40,14 +40,18 @@ func NewRegulator(configuration *schema.RegulationConfiguration, provider storag
 	return regulator
 }
 
-// Mark mark an authentication attempt.
+// Mark mark a first factor authentication attempt at the current time, the client and the request of the attempt are
+// logged along with it. The attempt is made on the portal unless it's a basic auth attempt, both are regulated alike.
 // We split Mark and Regulate in order to avoid timing attacks.
-func (r *Regulator) Mark(username string, successful bool) error {
-	return r.storageProvider.AppendAuthenticationLog(models.AuthenticationAttempt{
-		Username:   username,
-		Successful: successful,
-		Time:       r.clock.Now(),
-	})
+func (r *Regulator) Mark(attempt models.AuthenticationAttempt) error {
+	attempt.Time = r.clock.Now()
+	attempt.Event = models.AuthenticationEventFirstFactor
+
+	if attempt.Type != models.AuthenticationTypeBasic {
+		attempt.Type = models.AuthenticationType1FA
+	}
+
+	return r.storageProvider.AppendAuthenticationLog(attempt)
 }
 
 // Regulate regulate the authentication attempts for a given user.
@@ -7,6 +7,11 @@ const indexFile = "index.html"
 
 const dev = "dev"
 
+const (
+	headerXOriginalURL     = "X-Original-URL"
+	headerXForwardedMethod = "X-Forwarded-Method"
+)
+
 const healthCheckEnv = `# Written by Authelia Process
 X_AUTHELIA_HEALTHCHECK=1
 X_AUTHELIA_HEALTHCHECK_SCHEME=%s
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/authelia/authelia/v4/internal/authorization"
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/handlers"
+	"github.com/authelia/authelia/v4/internal/logging"
+	"github.com/authelia/authelia/v4/internal/middlewares"
+)
+
+// extAuthzServer implements the Envoy ext_authz gRPC API. Each check request is translated into a request to the
+// verify handler so both entry points share the same session, authorization and header forwarding logic.
+type extAuthzServer struct {
+	handler    fasthttp.RequestHandler
+	portalURL  string
+	authorizer *authorization.Authorizer
+
+	// identityAssertionHeader is the header of the identity assertion, it's empty when it's not configured.
+	identityAssertionHeader string
+
+	// trustedProxies are the networks of the clients of Envoy whose X-Forwarded-For header is trusted.
+	trustedProxies []*net.IPNet
+}
+
+func newExtAuthzServer(configuration schema.Configuration, providers middlewares.Providers, handler middlewares.RequestHandler) *extAuthzServer {
+	server := &extAuthzServer{
+		handler:    middlewares.AutheliaMiddleware(configuration, providers)(handler),
+		authorizer: providers.Authorizer,
+	}
+
+	if configuration.IdentityAssertion != nil {
+		server.identityAssertionHeader = configuration.IdentityAssertion.Header
+	}
+
+	if configuration.Server.ExtAuthz != nil {
+		server.portalURL = configuration.Server.ExtAuthz.PortalURL
+
+		for _, network := range configuration.Server.ExtAuthz.TrustedProxies {
+			if !strings.Contains(network, "/") {
+				if ip := net.ParseIP(network); ip.To4() != nil {
+					network += "/32"
+				} else {
+					network += "/128"
+				}
+			}
+
+			// Ignore the error as it will be handled by validator.
+			if _, cidr, err := net.ParseCIDR(network); err == nil {
+				server.trustedProxies = append(server.trustedProxies, cidr)
+			}
+		}
+	}
+
+	return server
+}
+
+// Check implements authv3.AuthorizationServer.
+func (s *extAuthzServer) Check(_ context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+	if httpReq == nil {
+		return nil, fmt.Errorf("check request has no http request attributes")
+	}
+
+	ctx := &fasthttp.RequestCtx{}
+
+	request := &fasthttp.Request{}
+	request.Header.SetMethod(fasthttp.MethodGet)
+	request.SetRequestURI(s.verifyURI())
+
+	source := extAuthzSourceAddr(req)
+	trusted := s.isTrustedProxy(source.IP)
+
+	for name, value := range httpReq.GetHeaders() {
+		// Skip the HTTP/2 pseudo headers and the headers describing the original request which are set below from the
+		// attributes of the check request. The client IP is the source address unless the source is a trusted proxy.
+		if strings.HasPrefix(name, ":") || isExtAuthzOverriddenHeader(name) || (!trusted && isExtAuthzForwardedForHeader(name)) {
+			continue
+		}
+
+		request.Header.Set(name, value)
+	}
+
+	// The session domain is determined from the host of the original request whatever the source of the request.
+	request.Header.SetHost(httpReq.GetHost())
+	request.Header.Set(headerXOriginalURL, extAuthzOriginalURL(httpReq))
+	request.Header.Set(headerXForwardedMethod, httpReq.GetMethod())
+
+	ctx.Init(request, source, nil)
+
+	s.handler(ctx)
+
+	return newExtAuthzCheckResponse(&ctx.Response, s.isUpstreamHeader), nil
+}
+
+func (s *extAuthzServer) isTrustedProxy(ip net.IP) bool {
+	for _, network := range s.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isUpstreamHeader returns true if the header of an authorized request is forwarded to the upstream, only the
+// headers identifying the user and the headers of the rules are forwarded.
+func (s *extAuthzServer) isUpstreamHeader(name string) bool {
+	switch {
+	case isExtAuthzRemoteHeader(name):
+		return true
+	case s.identityAssertionHeader != "" && strings.EqualFold(name, s.identityAssertionHeader):
+		return true
+	default:
+		return s.authorizer.IsRuleHeader(name)
+	}
+}
+
+func (s *extAuthzServer) verifyURI() string {
+	if s.portalURL == "" {
+		return "/api/verify"
+	}
+
+	return "/api/verify?rd=" + url.QueryEscape(s.portalURL)
+}
+
+func isExtAuthzOverriddenHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "host", "x-original-url", "x-forwarded-method", "x-forwarded-proto", "x-forwarded-host", "x-forwarded-uri":
+		return true
+	}
+
+	return false
+}
+
+// extAuthzOriginalURL returns the URL of the original request. The path attribute includes the query.
+func extAuthzOriginalURL(httpReq *authv3.AttributeContext_HttpRequest) string {
+	scheme := httpReq.GetScheme()
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + httpReq.GetHost() + httpReq.GetPath()
+}
+
+func isExtAuthzForwardedForHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "x-forwarded-for", "x-real-ip", "forwarded":
+		return true
+	}
+
+	return false
+}
+
+func isExtAuthzRemoteHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "remote-user", "remote-groups", "remote-name", "remote-email", "remote-impersonated-by":
+		return true
+	}
+
+	return false
+}
+
+// extAuthzSourceAddr returns the address of the downstream client, the loopback address is returned when it's unknown.
+func extAuthzSourceAddr(req *authv3.CheckRequest) *net.TCPAddr {
+	addr := req.GetAttributes().GetSource().GetAddress().GetSocketAddress()
+
+	if ip := net.ParseIP(addr.GetAddress()); ip != nil {
+		return &net.TCPAddr{IP: ip, Port: int(addr.GetPortValue())}
+	}
+
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+// newExtAuthzCheckResponse converts the response of the verify handler into a check response. The headers of an
+// authorized request allowed by isUpstreamHeader are forwarded to the upstream, the headers of other responses are
+// returned to the client.
+func newExtAuthzCheckResponse(response *fasthttp.Response, isUpstreamHeader func(name string) bool) *authv3.CheckResponse {
+	statusCode := response.StatusCode()
+
+	if statusCode == fasthttp.StatusOK {
+		headers := make([]*corev3.HeaderValueOption, 0, 4)
+
+		response.Header.VisitAll(func(key, value []byte) {
+			if !isUpstreamHeader(string(key)) {
+				return
+			}
+
+			headers = append(headers, newExtAuthzHeaderValueOption(string(key), string(value)))
+		})
+
+		return &authv3.CheckResponse{
+			Status: &status.Status{Code: int32(codes.OK)},
+			HttpResponse: &authv3.CheckResponse_OkResponse{
+				OkResponse: &authv3.OkHttpResponse{Headers: headers},
+			},
+		}
+	}
+
+	headers := make([]*corev3.HeaderValueOption, 0, 4)
+
+	response.Header.VisitAll(func(key, value []byte) {
+		if isExtAuthzResponseHeader(string(key)) {
+			return
+		}
+
+		headers = append(headers, newExtAuthzHeaderValueOption(string(key), string(value)))
+	})
+
+	response.Header.VisitAllCookie(func(_, value []byte) {
+		headers = append(headers, newExtAuthzHeaderValueOption(fasthttp.HeaderSetCookie, string(value)))
+	})
+
+	code := codes.PermissionDenied
+	if statusCode != fasthttp.StatusForbidden {
+		code = codes.Unauthenticated
+	}
+
+	return &authv3.CheckResponse{
+		Status: &status.Status{Code: int32(code)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(statusCode)},
+				Headers: headers,
+				Body:    string(response.Body()),
+			},
+		},
+	}
+}
+
+// isExtAuthzResponseHeader returns true if the header describes the response of the verify handler itself.
+func isExtAuthzResponseHeader(name string) bool {
+	switch name {
+	case fasthttp.HeaderContentLength, fasthttp.HeaderDate, fasthttp.HeaderServer, fasthttp.HeaderConnection:
+		return true
+	}
+
+	return false
+}
+
+func newExtAuthzHeaderValueOption(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header: &corev3.HeaderValue{Key: key, Value: value},
+		Append: wrapperspb.Bool(false),
+	}
+}
+
+// startExtAuthz starts the Envoy ext_authz gRPC server. It uses TLS when the http server does.
+func startExtAuthz(configuration schema.Configuration, providers middlewares.Providers) {
+	logger := logging.Logger()
+
+	var options []grpc.ServerOption
+
+	if configuration.Server.TLS.Certificate != "" && configuration.Server.TLS.Key != "" {
+		creds, err := credentials.NewServerTLSFromFile(configuration.Server.TLS.Certificate, configuration.Server.TLS.Key)
+		if err != nil {
+			logger.Fatalf("Error initializing ext_authz TLS credentials: %s", err)
+		}
+
+		options = append(options, grpc.Creds(creds))
+	}
+
+	server := grpc.NewServer(options...)
+
+	authv3.RegisterAuthorizationServer(server, newExtAuthzServer(configuration, providers, handlers.VerifyGet(configuration.AuthenticationBackend)))
+
+	addrPattern := net.JoinHostPort(configuration.Server.ExtAuthz.Host, strconv.Itoa(configuration.Server.ExtAuthz.Port))
+
+	listener, err := net.Listen("tcp", addrPattern)
+	if err != nil {
+		logger.Fatalf("Error initializing ext_authz listener: %s", err)
+	}
+
+	logger.Infof("Listening for Envoy ext_authz gRPC connections on '%s'", addrPattern)
+
+	logger.Fatal(server.Serve(listener))
+}
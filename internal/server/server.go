@@ -27,16 +27,15 @@ var assets embed.FS
 
 func registerRoutes(configuration schema.Configuration, providers middlewares.Providers) fasthttp.RequestHandler {
 	autheliaMiddleware := middlewares.AutheliaMiddleware(configuration, providers)
-	rememberMe := strconv.FormatBool(configuration.Session.RememberMeDuration != "0")
 	resetPassword := strconv.FormatBool(!configuration.AuthenticationBackend.DisableResetPassword)
 
 	embeddedPath, _ := fs.Sub(assets, "public_html")
 	embeddedFS := fasthttpadaptor.NewFastHTTPHandler(http.FileServer(http.FS(embeddedPath)))
 	rootFiles := []string{"favicon.ico", "manifest.json", "robots.txt"}
 
-	serveIndexHandler := ServeTemplatedFile(embeddedAssets, indexFile, rememberMe, resetPassword, configuration.Session.Name, configuration.Theme)
-	serveSwaggerHandler := ServeTemplatedFile(swaggerAssets, indexFile, rememberMe, resetPassword, configuration.Session.Name, configuration.Theme)
-	serveSwaggerAPIHandler := ServeTemplatedFile(swaggerAssets, apiFile, rememberMe, resetPassword, configuration.Session.Name, configuration.Theme)
+	serveIndexHandler := ServeTemplatedFile(embeddedAssets, indexFile, resetPassword, configuration.Theme, providers.SessionProvider)
+	serveSwaggerHandler := ServeTemplatedFile(swaggerAssets, indexFile, resetPassword, configuration.Theme, providers.SessionProvider)
+	serveSwaggerAPIHandler := ServeTemplatedFile(swaggerAssets, apiFile, resetPassword, configuration.Theme, providers.SessionProvider)
 
 	r := router.New()
 	r.GET("/", serveIndexHandler)
@@ -61,6 +60,26 @@ func registerRoutes(configuration schema.Configuration, providers middlewares.Pr
 	r.GET("/api/verify", autheliaMiddleware(handlers.VerifyGet(configuration.AuthenticationBackend)))
 	r.HEAD("/api/verify", autheliaMiddleware(handlers.VerifyGet(configuration.AuthenticationBackend)))
 
+	for _, endpoint := range configuration.Server.Endpoints.Authz {
+		handler := autheliaMiddleware(handlers.AuthzGet(configuration.AuthenticationBackend, endpoint))
+		path := handlers.AuthzEndpointPath(endpoint.Name)
+
+		if endpoint.Implementation == schema.AuthzImplementationExtAuthz {
+			// The Envoy HTTP external authorization filter keeps the method and appends the path of the original request.
+			r.ANY(path, handler)
+			r.ANY(path+"/{path:*}", handler)
+
+			continue
+		}
+
+		r.GET(path, handler)
+		r.HEAD(path, handler)
+	}
+
+	if providers.IdentityAssertion != nil {
+		r.GET(handlers.PathIdentityAssertionJWKs, autheliaMiddleware(handlers.IdentityAssertionJWKsGet))
+	}
+
 	r.POST("/api/checks/safe-redirection", autheliaMiddleware(handlers.CheckSafeRedirection))
 
 	r.POST("/api/firstfactor", autheliaMiddleware(handlers.FirstFactorPost(1000, true)))
@@ -81,13 +100,42 @@ func registerRoutes(configuration schema.Configuration, providers middlewares.Pr
 	r.GET("/api/user/info", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.UserInfoGet)))
 	r.POST("/api/user/info/2fa_method", autheliaMiddleware(
-		middlewares.RequireFirstFactor(handlers.MethodPreferencePost)))
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.MethodPreferencePost))))
+
+	// Personal access tokens of the user.
+	r.GET("/api/user/tokens", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.PersonalAccessTokensGet)))
+	r.POST("/api/user/tokens", autheliaMiddleware(
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.PersonalAccessTokenPost))))
+	r.POST("/api/user/tokens/revoke", autheliaMiddleware(
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.PersonalAccessTokenRevokePost))))
+
+	// Active sessions of the user.
+	r.GET("/api/user/sessions", autheliaMiddleware(
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.SessionsGet))))
+	r.POST("/api/user/sessions/revoke", autheliaMiddleware(
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.SessionRevokePost))))
+
+	if configuration.Session.AdminGroup != "" {
+		r.POST("/api/admin/sessions/revoke", autheliaMiddleware(
+			middlewares.RequireTwoFactor(middlewares.ForbidImpersonation(handlers.AdminSessionsRevokePost))))
+		r.GET("/api/admin/authentication-logs", autheliaMiddleware(
+			middlewares.RequireTwoFactor(middlewares.ForbidImpersonation(handlers.AdminAuthenticationLogsGet))))
+	}
+
+	// Impersonation of the users by the administrators.
+	if configuration.Impersonation != nil {
+		r.POST("/api/user/impersonation/start", autheliaMiddleware(
+			middlewares.RequireTwoFactor(handlers.ImpersonationStartPost)))
+		r.POST("/api/user/impersonation/stop", autheliaMiddleware(
+			middlewares.RequireTwoFactor(handlers.ImpersonationStopPost)))
+	}
 
 	// TOTP related endpoints.
 	r.POST("/api/secondfactor/totp/identity/start", autheliaMiddleware(
-		middlewares.RequireFirstFactor(handlers.SecondFactorTOTPIdentityStart)))
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.SecondFactorTOTPIdentityStart))))
 	r.POST("/api/secondfactor/totp/identity/finish", autheliaMiddleware(
-		middlewares.RequireFirstFactor(handlers.SecondFactorTOTPIdentityFinish)))
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.SecondFactorTOTPIdentityFinish))))
 	r.POST("/api/secondfactor/totp", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.SecondFactorTOTPPost(&handlers.TOTPVerifierImpl{
 			Period: uint(configuration.TOTP.Period),
@@ -96,12 +144,12 @@ func registerRoutes(configuration schema.Configuration, providers middlewares.Pr
 
 	// U2F related endpoints.
 	r.POST("/api/secondfactor/u2f/identity/start", autheliaMiddleware(
-		middlewares.RequireFirstFactor(handlers.SecondFactorU2FIdentityStart)))
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.SecondFactorU2FIdentityStart))))
 	r.POST("/api/secondfactor/u2f/identity/finish", autheliaMiddleware(
-		middlewares.RequireFirstFactor(handlers.SecondFactorU2FIdentityFinish)))
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.SecondFactorU2FIdentityFinish))))
 
 	r.POST("/api/secondfactor/u2f/register", autheliaMiddleware(
-		middlewares.RequireFirstFactor(handlers.SecondFactorU2FRegister)))
+		middlewares.RequireFirstFactor(middlewares.ForbidImpersonation(handlers.SecondFactorU2FRegister))))
 
 	r.POST("/api/secondfactor/u2f/sign_request", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.SecondFactorU2FSignGet)))
@@ -156,6 +204,10 @@ func Start(configuration schema.Configuration, providers middlewares.Providers)
 
 	handler := registerRoutes(configuration, providers)
 
+	if configuration.Server.ExtAuthz != nil {
+		go startExtAuthz(configuration, providers)
+	}
+
 	server := &fasthttp.Server{
 		ErrorHandler:          autheliaErrorHandler,
 		Handler:               handler,
@@ -5,11 +5,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"text/template"
 
 	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/v4/internal/logging"
+	"github.com/authelia/authelia/v4/internal/session"
 	"github.com/authelia/authelia/v4/internal/utils"
 )
 
@@ -18,7 +20,7 @@ var alphaNumericRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUV
 // ServeTemplatedFile serves a templated version of a specified file,
 // this is utilised to pass information between the backend and frontend
 // and generate a nonce to support a restrictive CSP while using material-ui.
-func ServeTemplatedFile(publicDir, file, rememberMe, resetPassword, session, theme string) fasthttp.RequestHandler {
+func ServeTemplatedFile(publicDir, file, resetPassword, theme string, sessionProvider *session.Provider) fasthttp.RequestHandler {
 	logger := logging.Logger()
 
 	f, err := assets.Open(publicDir + file)
@@ -44,6 +46,17 @@ func ServeTemplatedFile(publicDir, file, rememberMe, resetPassword, session, the
 
 		nonce := utils.RandomString(32, alphaNumericRunes)
 
+		// The remember me option and the cookie name depend on the session domain the portal is served on.
+		domain, err := sessionProvider.GetDomain(ctx)
+		if err != nil {
+			ctx.Error("the host of the request is not under any session domain", fasthttp.StatusBadRequest)
+			logger.Errorf("Unable to serve %s: %v", file, err)
+
+			return
+		}
+
+		rememberMe := strconv.FormatBool(domain.RememberMe != 0)
+
 		switch extension := filepath.Ext(file); extension {
 		case ".html":
 			ctx.SetContentType("text/html; charset=utf-8")
@@ -60,7 +73,7 @@ func ServeTemplatedFile(publicDir, file, rememberMe, resetPassword, session, the
 			ctx.Response.Header.Add("Content-Security-Policy", fmt.Sprintf("default-src 'self' ; object-src 'none'; style-src 'self' 'nonce-%s'", nonce))
 		}
 
-		err := tmpl.Execute(ctx.Response.BodyWriter(), struct{ Base, CSPNonce, RememberMe, ResetPassword, Session, Theme string }{Base: base, CSPNonce: nonce, RememberMe: rememberMe, ResetPassword: resetPassword, Session: session, Theme: theme})
+		err = tmpl.Execute(ctx.Response.BodyWriter(), struct{ Base, CSPNonce, RememberMe, ResetPassword, Session, Theme string }{Base: base, CSPNonce: nonce, RememberMe: rememberMe, ResetPassword: resetPassword, Session: domain.Name, Theme: theme})
 		if err != nil {
 			ctx.Error("an error occurred", 503)
 			logger.Errorf("Unable to execute template: %v", err)
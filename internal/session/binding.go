@@ -0,0 +1,130 @@
+package session
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+// ErrSessionBindingIP is returned when the IP of the client drifted from the IP the session is bound to.
+var ErrSessionBindingIP = errors.New("the IP of the client doesn't match the IP the session is bound to")
+
+// ErrSessionBindingUserAgent is returned when the user agent of the client drifted from the user agent the session is
+// bound to.
+var ErrSessionBindingUserAgent = errors.New("the user agent of the client doesn't match the user agent the session is bound to")
+
+// Binding binds the sessions to the client they were authenticated from.
+type Binding struct {
+	ip              string
+	ipv4Mask        net.IPMask
+	ipv6Mask        net.IPMask
+	userAgent       bool
+	trustedNetworks []*net.IPNet
+}
+
+// NewBinding creates the session binding from its configuration, it returns nil when the binding isn't configured.
+func NewBinding(configuration *schema.SessionBindingConfiguration) *Binding {
+	if configuration == nil {
+		return nil
+	}
+
+	binding := &Binding{
+		ip:        configuration.IP,
+		ipv4Mask:  net.CIDRMask(configuration.IPv4PrefixLength, 32),
+		ipv6Mask:  net.CIDRMask(configuration.IPv6PrefixLength, 128),
+		userAgent: configuration.UserAgent,
+	}
+
+	binding.trustedNetworks = parseNetworks(configuration.TrustedNetworks)
+
+	return binding
+}
+
+// Verify checks the client is the client the session is bound to within the tolerances of the binding. The sessions
+// which weren't bound to a client are not verified.
+func (b *Binding) Verify(userSession UserSession, ip net.IP, userAgent []byte) error {
+	if b == nil || userSession.ClientIP == "" {
+		return nil
+	}
+
+	if b.ip != "none" && !b.isIPTolerated(net.ParseIP(userSession.ClientIP), ip) {
+		return fmt.Errorf("%w: the session is bound to %s and the client is %s", ErrSessionBindingIP, userSession.ClientIP, ip)
+	}
+
+	if b.userAgent && userSession.ClientUserAgentHash != hashUserAgent(userAgent) {
+		return ErrSessionBindingUserAgent
+	}
+
+	return nil
+}
+
+// isIPTolerated returns true if the IP of the client is the bound IP, is in the same subnet as the bound IP when the
+// subnet binding is configured, or is in one of the trusted networks.
+func (b *Binding) isIPTolerated(bound, ip net.IP) bool {
+	if ip == nil || bound == nil {
+		return false
+	}
+
+	if bound.Equal(ip) {
+		return true
+	}
+
+	if isIPInNetworks(ip, b.trustedNetworks) {
+		return true
+	}
+
+	if b.ip != "subnet" {
+		return false
+	}
+
+	if bound4, ip4 := bound.To4(), ip.To4(); bound4 != nil || ip4 != nil {
+		return bound4 != nil && ip4 != nil && bound4.Mask(b.ipv4Mask).Equal(ip4.Mask(b.ipv4Mask))
+	}
+
+	return bound.Mask(b.ipv6Mask).Equal(ip.Mask(b.ipv6Mask))
+}
+
+// VerifyClient checks the client of the request is the client the session is bound to if the binding is configured.
+func (p *Provider) VerifyClient(ctx *fasthttp.RequestCtx, userSession UserSession) error {
+	return p.binding.Verify(userSession, p.ClientIP(ctx), ctx.UserAgent())
+}
+
+// parseNetworks parses the networks in CIDR notation, the single IPs are parsed as networks of one IP.
+func parseNetworks(networks []string) (cidrs []*net.IPNet) {
+	for _, network := range networks {
+		if !strings.Contains(network, "/") {
+			if ip := net.ParseIP(network); ip.To4() != nil {
+				network += "/32"
+			} else {
+				network += "/128"
+			}
+		}
+
+		// Ignore the error as it will be handled by validator.
+		if _, cidr, err := net.ParseCIDR(network); err == nil {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	return cidrs
+}
+
+func isIPInNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hashUserAgent(userAgent []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(userAgent))
+}
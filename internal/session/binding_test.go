@@ -0,0 +1,68 @@
+package session
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+func TestShouldVerifyClientAgainstBinding(t *testing.T) {
+	boundSession := func(ip string) UserSession {
+		userSession := NewDefaultUserSession()
+		userSession.SetClient(net.ParseIP(ip), []byte("Mozilla/5.0"))
+
+		return userSession
+	}
+
+	testCases := []struct {
+		name          string
+		configuration schema.SessionBindingConfiguration
+		userSession   UserSession
+		ip            string
+		userAgent     string
+		expected      error
+	}{
+		{"ShouldAcceptSameIP", schema.SessionBindingConfiguration{IP: "exact"}, boundSession("192.168.1.10"), "192.168.1.10", "Mozilla/5.0", nil},
+		{"ShouldRejectOtherIP", schema.SessionBindingConfiguration{IP: "exact"}, boundSession("192.168.1.10"), "192.168.1.11", "Mozilla/5.0", ErrSessionBindingIP},
+		{"ShouldAcceptIPv4Subnet", schema.SessionBindingConfiguration{IP: "subnet", IPv4PrefixLength: 24, IPv6PrefixLength: 64}, boundSession("192.168.1.10"), "192.168.1.254", "Mozilla/5.0", nil},
+		{"ShouldRejectOtherIPv4Subnet", schema.SessionBindingConfiguration{IP: "subnet", IPv4PrefixLength: 24, IPv6PrefixLength: 64}, boundSession("192.168.1.10"), "192.168.2.10", "Mozilla/5.0", ErrSessionBindingIP},
+		{"ShouldAcceptIPv6Subnet", schema.SessionBindingConfiguration{IP: "subnet", IPv4PrefixLength: 24, IPv6PrefixLength: 64}, boundSession("2001:db8:1:1::10"), "2001:db8:1:1::beef", "Mozilla/5.0", nil},
+		{"ShouldRejectOtherIPv6Subnet", schema.SessionBindingConfiguration{IP: "subnet", IPv4PrefixLength: 24, IPv6PrefixLength: 64}, boundSession("2001:db8:1:1::10"), "2001:db8:1:2::10", "Mozilla/5.0", ErrSessionBindingIP},
+		{"ShouldRejectOtherIPFamily", schema.SessionBindingConfiguration{IP: "subnet", IPv4PrefixLength: 24, IPv6PrefixLength: 64}, boundSession("192.168.1.10"), "2001:db8:1:1::10", "Mozilla/5.0", ErrSessionBindingIP},
+		{"ShouldAcceptTrustedNetwork", schema.SessionBindingConfiguration{IP: "exact", TrustedNetworks: []string{"10.0.0.0/8", "172.16.0.1"}}, boundSession("192.168.1.10"), "10.20.30.40", "Mozilla/5.0", nil},
+		{"ShouldAcceptTrustedIP", schema.SessionBindingConfiguration{IP: "exact", TrustedNetworks: []string{"10.0.0.0/8", "172.16.0.1"}}, boundSession("192.168.1.10"), "172.16.0.1", "Mozilla/5.0", nil},
+		{"ShouldIgnoreIP", schema.SessionBindingConfiguration{IP: "none"}, boundSession("192.168.1.10"), "10.0.0.1", "curl/7.79.1", nil},
+		{"ShouldRejectOtherUserAgent", schema.SessionBindingConfiguration{IP: "none", UserAgent: true}, boundSession("192.168.1.10"), "192.168.1.10", "curl/7.79.1", ErrSessionBindingUserAgent},
+		{"ShouldAcceptSameUserAgent", schema.SessionBindingConfiguration{IP: "exact", UserAgent: true}, boundSession("192.168.1.10"), "192.168.1.10", "Mozilla/5.0", nil},
+		{"ShouldAcceptUnboundSession", schema.SessionBindingConfiguration{IP: "exact", UserAgent: true}, NewDefaultUserSession(), "10.0.0.1", "curl/7.79.1", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configuration := tc.configuration
+			binding := NewBinding(&configuration)
+
+			err := binding.Verify(tc.userSession, net.ParseIP(tc.ip), []byte(tc.userAgent))
+
+			if tc.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tc.expected), "expected %v but got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestShouldNotVerifyClientWhenBindingNotConfigured(t *testing.T) {
+	binding := NewBinding(nil)
+	assert.Nil(t, binding)
+
+	userSession := NewDefaultUserSession()
+	userSession.SetClient(net.ParseIP("192.168.1.10"), []byte("Mozilla/5.0"))
+
+	assert.NoError(t, binding.Verify(userSession, net.ParseIP("10.0.0.1"), []byte("curl/7.79.1")))
+}
@@ -1,7 +1,41 @@
 package session
 
+import "time"
+
 const userSessionStorerKey = "UserSession"
 
+// indexedAtStorerKey is the key of the time the session was last written to the index of the sessions of its user.
+const indexedAtStorerKey = "IndexedAt"
+
+// sessionIDLength is the length of the session IDs generated by fasthttp/session, they only contain ASCII letters.
+const sessionIDLength = 32
+
+// indexKeyPrefix is the prefix of the keys of the indexes of the sessions of the users in the session backend.
+const indexKeyPrefix = "index:"
+
+// lockKeyPrefix is the prefix of the keys of the locks of the indexes of the sessions in the session backend.
+const lockKeyPrefix = "lock:"
+
+const (
+	indexLockTTL           = 5 * time.Second
+	indexLockTimeout       = 5 * time.Second
+	indexLockRetryInterval = 20 * time.Millisecond
+)
+
+const (
+	// MaxConcurrentPolicyEvictOldest evicts the oldest sessions of the user when the limit is reached.
+	MaxConcurrentPolicyEvictOldest = "evict_oldest"
+
+	// MaxConcurrentPolicyReject rejects the new session of the user when the limit is reached.
+	MaxConcurrentPolicyReject = "reject"
+)
+
+// encryptionKeyIDMagic prefixes the data encrypted by the encrypting serializer, it's followed by the ID of the key
+// which encrypted the data.
+var encryptionKeyIDMagic = []byte{0xa5, 0x6b, 0x01}
+
+const encryptionKeyIDLength = 4
+
 const testDomain = "example.com"
 const testExpiration = "40"
 const testName = "my_session"
@@ -0,0 +1,195 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	fasthttpsession "github.com/fasthttp/session/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// Domain is a domain the session cookies are issued for.
+type Domain struct {
+	Domain     string
+	Name       string
+	PortalURL  string
+	Expiration time.Duration
+	RememberMe time.Duration
+	Inactivity time.Duration
+
+	holder *fasthttpsession.Session
+}
+
+// ErrDomainNotFound is returned when the host of the request isn't under any session domain.
+var ErrDomainNotFound = errors.New("the host of the request is not under any session domain")
+
+// GetDomain returns the session domain matching the host of the request.
+func (p *Provider) GetDomain(ctx *fasthttp.RequestCtx) (*Domain, error) {
+	host := p.requestHost(ctx)
+
+	if domain := p.GetDomainByHost(host); domain != nil {
+		return domain, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrDomainNotFound, host)
+}
+
+// GetDomainByHost returns the session domain the host is under or nil if the host isn't under any session domain.
+func (p *Provider) GetDomainByHost(host string) *Domain {
+	host = strings.ToLower(host)
+
+	for _, domain := range p.domains {
+		if domain.Domain == "" || host == domain.Domain || strings.HasSuffix(host, "."+domain.Domain) {
+			return domain
+		}
+	}
+
+	return nil
+}
+
+func (p *Provider) holder(ctx *fasthttp.RequestCtx) (*fasthttpsession.Session, error) {
+	domain, err := p.GetDomain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The other keys of the session backend such as the indexes of the sessions of the users must not be loaded or
+	// overwritten as a session, so only the session IDs in the format of the generated ones are read from the cookie.
+	if id := ctx.Request.Header.Cookie(domain.Name); len(id) != 0 && !isSessionID(id) {
+		ctx.Request.Header.DelCookie(domain.Name)
+	}
+
+	return domain.holder, nil
+}
+
+// isSessionID returns true if the ID has the format of the session IDs generated by fasthttp/session.
+func isSessionID(id []byte) bool {
+	if len(id) != sessionIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestHost returns the host the request was originally sent to, i.e. the host of the protected resource for the
+// authorization requests and the host of the portal for the other requests. The headers set by the proxy are only
+// trusted when the request is sent by one of the trusted proxies.
+func (p *Provider) requestHost(ctx *fasthttp.RequestCtx) string {
+	var host []byte
+
+	if isIPInNetworks(ctx.RemoteIP(), p.trustedProxies) {
+		if originalURL := ctx.Request.Header.Peek("X-Original-URL"); len(originalURL) != 0 {
+			if u, err := url.Parse(string(originalURL)); err == nil && u.Host != "" {
+				return u.Hostname()
+			}
+		}
+
+		host = ctx.Request.Header.Peek("X-Forwarded-Host")
+	}
+
+	if len(host) == 0 {
+		host = ctx.Host()
+	}
+
+	if hostname, _, err := net.SplitHostPort(string(host)); err == nil {
+		return hostname
+	}
+
+	return string(host)
+}
+
+// domainConfigurations returns the configuration of the session domains, the legacy domain option is used as the only
+// session domain when the domains option isn't set.
+// ClientIP returns the IP of the client of the request. The X-Forwarded-For header is only trusted when the request is
+// sent by one of the trusted proxies, the client is then the right-most hop of the header which isn't a trusted proxy.
+func (p *Provider) ClientIP(ctx *fasthttp.RequestCtx) net.IP {
+	ip := ctx.RemoteIP()
+
+	if !isIPInNetworks(ip, p.trustedProxies) {
+		return ip
+	}
+
+	hops := strings.Split(string(ctx.Request.Header.Peek("X-Forwarded-For")), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+
+		// The hops before an invalid one can't be trusted, the last valid hop is the client.
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+
+		if !isIPInNetworks(hop, p.trustedProxies) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func domainConfigurations(configuration schema.SessionConfiguration) []schema.SessionDomainConfiguration {
+	if len(configuration.Domains) != 0 {
+		return configuration.Domains
+	}
+
+	return []schema.SessionDomainConfiguration{{
+		Domain:             configuration.Domain,
+		Name:               configuration.Name,
+		Expiration:         configuration.Expiration,
+		Inactivity:         configuration.Inactivity,
+		RememberMeDuration: configuration.RememberMeDuration,
+	}}
+}
+
+func newDomain(configuration schema.SessionDomainConfiguration, config fasthttpsession.Config) (domain *Domain, err error) {
+	domain = &Domain{
+		Domain:    configuration.Domain,
+		Name:      configuration.Name,
+		PortalURL: configuration.PortalURL,
+	}
+
+	if domain.RememberMe, err = utils.ParseDurationString(configuration.RememberMeDuration); err != nil {
+		return nil, err
+	}
+
+	if domain.Inactivity, err = utils.ParseDurationString(configuration.Inactivity); err != nil {
+		return nil, err
+	}
+
+	config.CookieName = configuration.Name
+	config.Domain = configuration.Domain
+
+	// Ignore the error as it will be handled by validator.
+	domain.Expiration, _ = utils.ParseDurationString(configuration.Expiration)
+	config.Expiration = domain.Expiration
+
+	domain.holder = fasthttpsession.New(config)
+
+	return domain, nil
+}
+
+// sharedBackend is the backend of the session domains other than the first one, the expired sessions of the backend
+// are only collected by the first session domain.
+type sharedBackend struct {
+	fasthttpsession.Provider
+}
+
+// NeedGC implements fasthttpsession.Provider.
+func (sharedBackend) NeedGC() bool {
+	return false
+}
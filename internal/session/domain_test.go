@@ -0,0 +1,180 @@
+package session
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+func newDomainsTestProvider() *Provider {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domains = []schema.SessionDomainConfiguration{
+		{Domain: testDomain, Name: testName, Expiration: testExpiration, Inactivity: "5m", RememberMeDuration: "1M"},
+		{Domain: "example.net", Name: "authelia_net", Expiration: "2h", Inactivity: "10m", RememberMeDuration: "0", PortalURL: "https://login.example.net"},
+	}
+	configuration.TrustedProxies = []string{"10.0.0.0/8"}
+
+	return NewProvider(configuration, nil, nil)
+}
+
+func TestShouldSelectDomainFromRequestHost(t *testing.T) {
+	provider := newDomainsTestProvider()
+
+	testCases := []struct {
+		name     string
+		remoteIP string
+		headers  map[string]string
+		host     string
+		expected string
+	}{
+		{"ShouldUseHost", "10.0.0.1", nil, "login.example.net", "example.net"},
+		{"ShouldUseHostWithPort", "10.0.0.1", nil, "login.example.net:9091", "example.net"},
+		{"ShouldUseHostEqualToDomain", "10.0.0.1", nil, "example.net", "example.net"},
+		{"ShouldUseForwardedHost", "10.0.0.1", map[string]string{"X-Forwarded-Host": "app.example.net"}, "authelia:9091", "example.net"},
+		{"ShouldUseOriginalURL", "10.0.0.1", map[string]string{"X-Original-URL": "https://app.example.net/path", "X-Forwarded-Host": "login.example.com"}, "authelia:9091", "example.net"},
+		{"ShouldIgnoreForwardedHostOfUntrustedClient", "192.168.0.1", map[string]string{"X-Forwarded-Host": "app.example.net"}, "login.example.com", testDomain},
+		{"ShouldIgnoreOriginalURLOfUntrustedClient", "192.168.0.1", map[string]string{"X-Original-URL": "https://app.example.net/path"}, "login.example.com", testDomain},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.SetRemoteAddr(&net.TCPAddr{IP: net.ParseIP(tc.remoteIP)})
+			ctx.Request.Header.SetHost(tc.host)
+
+			for header, value := range tc.headers {
+				ctx.Request.Header.Set(header, value)
+			}
+
+			domain, err := provider.GetDomain(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, domain.Domain)
+		})
+	}
+}
+
+func TestShouldResolveClientIPFromTrustedProxies(t *testing.T) {
+	provider := newDomainsTestProvider()
+
+	testCases := []struct {
+		name          string
+		remoteIP      string
+		xForwardedFor string
+		expected      string
+	}{
+		{"ShouldUseRemoteIPWithoutHeader", "10.0.0.1", "", "10.0.0.1"},
+		{"ShouldUseForwardedIP", "10.0.0.1", "192.168.0.1", "192.168.0.1"},
+		{"ShouldUseRightMostUntrustedHop", "10.0.0.1", "1.2.3.4, 192.168.0.1, 10.0.0.2", "192.168.0.1"},
+		{"ShouldUseLeftMostHopWhenAllTrusted", "10.0.0.1", "10.0.0.3, 10.0.0.2", "10.0.0.3"},
+		{"ShouldStopAtInvalidHop", "10.0.0.1", "1.2.3.4, not-an-ip, 10.0.0.2", "10.0.0.2"},
+		{"ShouldIgnoreHeaderOfUntrustedClient", "192.168.0.1", "1.2.3.4", "192.168.0.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.SetRemoteAddr(&net.TCPAddr{IP: net.ParseIP(tc.remoteIP)})
+
+			if tc.xForwardedFor != "" {
+				ctx.Request.Header.Set("X-Forwarded-For", tc.xForwardedFor)
+			}
+
+			assert.Equal(t, tc.expected, provider.ClientIP(ctx).String())
+		})
+	}
+}
+
+func TestShouldNotSelectDomainWhenHostIsNotUnderAnyDomain(t *testing.T) {
+	provider := newDomainsTestProvider()
+
+	testCases := []struct {
+		name    string
+		headers map[string]string
+		host    string
+	}{
+		{"ShouldNotFallbackToFirstDomain", nil, "app.example.org"},
+		{"ShouldNotMatchPartialDomain", nil, "badexample.net"},
+		{"ShouldNotUseHostOfUntrustedClient", map[string]string{"X-Forwarded-Host": "app.example.net"}, "authelia:9091"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.Header.SetHost(tc.host)
+
+			for header, value := range tc.headers {
+				ctx.Request.Header.Set(header, value)
+			}
+
+			domain, err := provider.GetDomain(ctx)
+			assert.ErrorIs(t, err, ErrDomainNotFound)
+			assert.Nil(t, domain)
+
+			_, err = provider.GetSession(ctx)
+			assert.ErrorIs(t, err, ErrDomainNotFound)
+		})
+	}
+}
+
+func TestShouldReturnDomainOptions(t *testing.T) {
+	provider := newDomainsTestProvider()
+
+	domain := provider.GetDomainByHost("login.example.net")
+	require.NotNil(t, domain)
+
+	assert.Equal(t, "authelia_net", domain.Name)
+	assert.Equal(t, "https://login.example.net", domain.PortalURL)
+	assert.Equal(t, 2*time.Hour, domain.Expiration)
+	assert.Equal(t, 10*time.Minute, domain.Inactivity)
+	assert.Equal(t, time.Duration(0), domain.RememberMe)
+
+	assert.Nil(t, provider.GetDomainByHost("example.org"))
+}
+
+func TestShouldIssueCookieForDomainOfRequest(t *testing.T) {
+	provider := newDomainsTestProvider()
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetHost("login.example.net")
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	session.Username = testUsername
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+
+	cookie.SetKey("authelia_net")
+	require.True(t, ctx.Response.Header.Cookie(cookie))
+	assert.Equal(t, "example.net", string(cookie.Domain()))
+
+	cookie.SetKey(testName)
+	assert.False(t, ctx.Response.Header.Cookie(cookie))
+
+	session, err = provider.GetSession(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, testUsername, session.Username)
+}
+
+func TestShouldUseLegacyDomainAsOnlyDomain(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Inactivity = "5m"
+
+	provider := NewProvider(configuration, nil, nil)
+
+	require.Len(t, provider.domains, 1)
+	assert.Equal(t, testDomain, provider.domains[0].Domain)
+	assert.Equal(t, testName, provider.domains[0].Name)
+	assert.Equal(t, 5*time.Minute, provider.domains[0].Inactivity)
+}
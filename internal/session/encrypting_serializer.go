@@ -1,6 +1,7 @@
 package session
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 
@@ -9,15 +10,33 @@ import (
 	"github.com/authelia/authelia/v4/internal/utils"
 )
 
-// EncryptingSerializer a serializer encrypting the data with AES-GCM with 256-bit keys.
+// EncryptingSerializer a serializer encrypting the data with AES-GCM with 256-bit keys. The data is encrypted with the
+// key of the primary secret and prefixed with the ID of the key, the previous secrets are only used to decrypt the data
+// encrypted before the secret was rotated.
 type EncryptingSerializer struct {
+	keys []encryptionKey
+}
+
+type encryptionKey struct {
+	id  [encryptionKeyIDLength]byte
 	key [32]byte
 }
 
-// NewEncryptingSerializer return new encrypt instance.
-func NewEncryptingSerializer(secret string) *EncryptingSerializer {
-	key := sha256.Sum256([]byte(secret))
-	return &EncryptingSerializer{key}
+// NewEncryptingSerializer return new encrypt instance, the first secret is the primary secret and the other secrets are
+// the previous secrets ordered from the most recent to the oldest one.
+func NewEncryptingSerializer(secrets ...string) *EncryptingSerializer {
+	serializer := &EncryptingSerializer{}
+
+	for _, secret := range secrets {
+		key := encryptionKey{key: sha256.Sum256([]byte(secret))}
+
+		sum := sha256.Sum256(key.key[:])
+		copy(key.id[:], sum[:encryptionKeyIDLength])
+
+		serializer.keys = append(serializer.keys, key)
+	}
+
+	return serializer
 }
 
 // Encode encode and encrypt session.
@@ -31,7 +50,7 @@ func (e *EncryptingSerializer) Encode(src session.Dict) ([]byte, error) {
 		return nil, fmt.Errorf("unable to marshal session: %v", err)
 	}
 
-	encryptedDst, err := utils.Encrypt(dst, &e.key)
+	encryptedDst, err := e.encrypt(dst)
 	if err != nil {
 		return nil, fmt.Errorf("unable to encrypt session: %v", err)
 	}
@@ -47,7 +66,7 @@ func (e *EncryptingSerializer) Decode(dst *session.Dict, src []byte) error {
 
 	dst.Reset()
 
-	decryptedSrc, err := utils.Decrypt(src, &e.key)
+	decryptedSrc, err := e.decrypt(src)
 	if err != nil {
 		return fmt.Errorf("unable to decrypt session: %s", err)
 	}
@@ -56,3 +75,55 @@ func (e *EncryptingSerializer) Decode(dst *session.Dict, src []byte) error {
 
 	return err
 }
+
+func (e *EncryptingSerializer) encrypt(data []byte) ([]byte, error) {
+	primary := e.keys[0]
+
+	ciphertext, err := utils.Encrypt(data, &primary.key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixed := make([]byte, 0, len(encryptionKeyIDMagic)+encryptionKeyIDLength+len(ciphertext))
+	prefixed = append(prefixed, encryptionKeyIDMagic...)
+	prefixed = append(prefixed, primary.id[:]...)
+
+	return append(prefixed, ciphertext...), nil
+}
+
+// decrypt decrypts the data with the key identified by its prefix. The data encrypted before the key IDs were
+// introduced, or whose key isn't known, is decrypted by trying each key in order.
+func (e *EncryptingSerializer) decrypt(data []byte) (plaintext []byte, err error) {
+	if id, ciphertext, ok := splitEncryptionKeyID(data); ok {
+		for i := range e.keys {
+			if e.keys[i].id != id {
+				continue
+			}
+
+			if plaintext, err = utils.Decrypt(ciphertext, &e.keys[i].key); err == nil {
+				return plaintext, nil
+			}
+
+			break
+		}
+	}
+
+	for i := range e.keys {
+		if plaintext, err = utils.Decrypt(data, &e.keys[i].key); err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, err
+}
+
+func splitEncryptionKeyID(data []byte) (id [encryptionKeyIDLength]byte, ciphertext []byte, ok bool) {
+	if len(data) < len(encryptionKeyIDMagic)+encryptionKeyIDLength || !bytes.HasPrefix(data, encryptionKeyIDMagic) {
+		return id, nil, false
+	}
+
+	data = data[len(encryptionKeyIDMagic):]
+	copy(id[:], data[:encryptionKeyIDLength])
+
+	return id, data[encryptionKeyIDLength:], true
+}
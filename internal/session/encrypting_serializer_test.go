@@ -1,12 +1,15 @@
 package session
 
 import (
+	"crypto/sha256"
 	"testing"
 
 	"github.com/fasthttp/session/v2"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/utils"
 )
 
 func TestShouldEncryptAndDecrypt(t *testing.T) {
@@ -42,3 +45,64 @@ func TestShouldNotSupportUnencryptedSessionForBackwardCompatibility(t *testing.T
 	err = serializer.Decode(&decodedPayload, dst)
 	assert.EqualError(t, err, "unable to decrypt session: cipher: message authentication failed")
 }
+
+func TestShouldDecryptSessionEncryptedWithPreviousSecret(t *testing.T) {
+	payload := session.Dict{}
+	payload.Set("key", "value")
+
+	previous := NewEncryptingSerializer("previous")
+	encrypted, err := previous.Encode(payload)
+	require.NoError(t, err)
+
+	serializer := NewEncryptingSerializer("current", "previous")
+
+	decodedPayload := session.Dict{}
+	require.NoError(t, serializer.Decode(&decodedPayload, encrypted))
+	assert.Equal(t, "value", decodedPayload.Get("key"))
+
+	// The session is encrypted with the primary secret when it's saved again.
+	reencrypted, err := serializer.Encode(decodedPayload)
+	require.NoError(t, err)
+
+	current := NewEncryptingSerializer("current")
+
+	decodedPayload = session.Dict{}
+	require.NoError(t, current.Decode(&decodedPayload, reencrypted))
+	assert.Equal(t, "value", decodedPayload.Get("key"))
+
+	decodedPayload = session.Dict{}
+	assert.EqualError(t, previous.Decode(&decodedPayload, reencrypted), "unable to decrypt session: cipher: message authentication failed")
+}
+
+func TestShouldDecryptSessionEncryptedWithoutKeyID(t *testing.T) {
+	payload := session.Dict{}
+	payload.Set("key", "value")
+
+	dst, err := payload.MarshalMsg(nil)
+	require.NoError(t, err)
+
+	key := sha256.Sum256([]byte("previous"))
+	encrypted, err := utils.Encrypt(dst, &key)
+	require.NoError(t, err)
+
+	serializer := NewEncryptingSerializer("current", "previous")
+
+	decodedPayload := session.Dict{}
+	require.NoError(t, serializer.Decode(&decodedPayload, encrypted))
+	assert.Equal(t, "value", decodedPayload.Get("key"))
+}
+
+func TestShouldPrefixSessionWithPrimaryKeyID(t *testing.T) {
+	payload := session.Dict{}
+	payload.Set("key", "value")
+
+	serializer := NewEncryptingSerializer("current", "previous")
+
+	encrypted, err := serializer.Encode(payload)
+	require.NoError(t, err)
+
+	id, _, ok := splitEncryptionKeyID(encrypted)
+	require.True(t, ok)
+	assert.Equal(t, serializer.keys[0].id, id)
+	assert.NotEqual(t, serializer.keys[1].id, id)
+}
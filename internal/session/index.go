@@ -0,0 +1,443 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	fasthttpsession "github.com/fasthttp/session/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// ErrSessionNotFound is returned when revoking a session which is not in the index of the sessions of the user.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrMaxConcurrentSessions is returned when reserving a session for a user who reached the maximum number of
+// concurrent sessions and the policy rejects the new sessions.
+var ErrMaxConcurrentSessions = errors.New("maximum number of concurrent sessions reached")
+
+// ListSessions returns the active sessions of the user sorted by last activity, the most recent first. The sessions
+// which expired since they were indexed are removed from the index.
+func (p *Provider) ListSessions(username string) (sessions []SessionInfo, err error) {
+	unlock, err := p.lockIndex(username)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	index, err := p.loadIndex(username)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := false
+
+	for sessionID, entry := range index {
+		data, err := p.backend.Get([]byte(sessionID))
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve a session of user %s: %w", username, err)
+		}
+
+		if len(data) == 0 {
+			delete(index, sessionID)
+
+			pruned = true
+
+			continue
+		}
+
+		// The index isn't updated on every request, the last activity is read from the session itself.
+		if userSession, err := p.decodeUserSession(data); err == nil && userSession.LastActivity > entry.LastActivity {
+			entry.LastActivity = userSession.LastActivity
+		}
+
+		sessions = append(sessions, entry.info())
+	}
+
+	if pruned {
+		if err = p.saveIndex(username, index); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivity > sessions[j].LastActivity
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession destroys the session of the user identified by the ID of its SessionInfo.
+func (p *Provider) RevokeSession(username, id string) error {
+	unlock, err := p.lockIndex(username)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	index, err := p.loadIndex(username)
+	if err != nil {
+		return err
+	}
+
+	for sessionID := range index {
+		if sessionInfoID(sessionID) != id {
+			continue
+		}
+
+		if err = p.backend.Destroy([]byte(sessionID)); err != nil {
+			return fmt.Errorf("unable to destroy a session of user %s: %w", username, err)
+		}
+
+		delete(index, sessionID)
+
+		return p.saveIndex(username, index)
+	}
+
+	return ErrSessionNotFound
+}
+
+// RevokeSessions destroys every session of the user and returns the number of sessions destroyed.
+func (p *Provider) RevokeSessions(username string) (count int, err error) {
+	unlock, err := p.lockIndex(username)
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	index, err := p.loadIndex(username)
+	if err != nil {
+		return 0, err
+	}
+
+	for sessionID := range index {
+		if err = p.backend.Destroy([]byte(sessionID)); err != nil {
+			return count, fmt.Errorf("unable to destroy a session of user %s: %w", username, err)
+		}
+
+		delete(index, sessionID)
+
+		count++
+	}
+
+	if err = p.backend.Destroy(indexKey(username)); err != nil {
+		return count, fmt.Errorf("unable to destroy the session index of user %s: %w", username, err)
+	}
+
+	return count, nil
+}
+
+// ReserveSession adds the session of the request to the index of the sessions of the user before the user is
+// authenticated in order to enforce the maximum number of concurrent sessions. When the user reached the maximum, the
+// oldest sessions are destroyed and returned or ErrMaxConcurrentSessions is returned depending on the policy. The
+// index is locked while the sessions are counted so the limit holds when the backend is shared by several instances.
+func (p *Provider) ReserveSession(ctx *fasthttp.RequestCtx, username string) (evicted []SessionInfo, err error) {
+	if p.maxConcurrent == 0 {
+		return nil, nil
+	}
+
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := holder.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := string(store.GetSessionID())
+
+	unlock, err := p.lockIndex(username)
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	index, err := p.loadIndex(username)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]sessionIndexEntry, 0, len(index))
+
+	for id, entry := range index {
+		// The session of the request is the session being reserved, it may not be saved yet.
+		if id == sessionID {
+			continue
+		}
+
+		data, err := p.backend.Get([]byte(id))
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve a session of user %s: %w", username, err)
+		}
+
+		if len(data) == 0 {
+			delete(index, id)
+
+			continue
+		}
+
+		active = append(active, entry)
+	}
+
+	if len(active) >= p.maxConcurrent {
+		if p.maxConcurrentPolicy == MaxConcurrentPolicyReject {
+			return nil, ErrMaxConcurrentSessions
+		}
+
+		sort.Slice(active, func(i, j int) bool {
+			return active[i].CreatedAt < active[j].CreatedAt
+		})
+
+		for _, entry := range active[:len(active)-p.maxConcurrent+1] {
+			if err = p.backend.Destroy([]byte(entry.SessionID)); err != nil {
+				return evicted, fmt.Errorf("unable to destroy a session of user %s: %w", username, err)
+			}
+
+			delete(index, entry.SessionID)
+
+			evicted = append(evicted, entry.info())
+		}
+	}
+
+	now := time.Now().Unix()
+
+	index[sessionID] = sessionIndexEntry{
+		SessionID:    sessionID,
+		CreatedAt:    now,
+		LastActivity: now,
+		IP:           p.ClientIP(ctx).String(),
+		UserAgent:    string(ctx.UserAgent()),
+	}
+
+	return evicted, p.saveIndex(username, index)
+}
+
+// CurrentSessionID returns the ID of the SessionInfo of the session of the request.
+func (p *Provider) CurrentSessionID(ctx *fasthttp.RequestCtx) (string, error) {
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := holder.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return sessionInfoID(string(store.GetSessionID())), nil
+}
+
+// isIndexOutdated returns true if the session must be written to the index of the sessions of its user when it's saved,
+// i.e. when the session is created, when its user changes or when the index may expire before the session.
+func (p *Provider) isIndexOutdated(previousJSON []byte, indexedAt int64, userSession UserSession) bool {
+	if userSession.RealUsername() == "" {
+		return false
+	}
+
+	var previous UserSession
+
+	if len(previousJSON) == 0 || json.Unmarshal(previousJSON, &previous) != nil {
+		return true
+	}
+
+	if previous.RealUsername() != userSession.RealUsername() {
+		return true
+	}
+
+	return time.Since(time.Unix(indexedAt, 0)) > p.indexExpiration/2
+}
+
+// decodeUserSession decodes the user session from the data of a session stored in the backend.
+func (p *Provider) decodeUserSession(data []byte) (userSession UserSession, err error) {
+	dict := fasthttpsession.Dict{}
+
+	if p.serializer != nil {
+		err = p.serializer.Decode(&dict, data)
+	} else {
+		err = fasthttpsession.Base64Decode(&dict, data)
+	}
+
+	if err != nil {
+		return userSession, err
+	}
+
+	userSessionJSON, ok := dict.Get(userSessionStorerKey).([]byte)
+	if !ok {
+		return userSession, fmt.Errorf("the session has no user session")
+	}
+
+	err = json.Unmarshal(userSessionJSON, &userSession)
+
+	return userSession, err
+}
+
+// indexSession adds or updates the session in the index of the sessions of its user.
+func (p *Provider) indexSession(ctx *fasthttp.RequestCtx, sessionID string, userSession UserSession) error {
+	username := userSession.RealUsername()
+	if username == "" {
+		return nil
+	}
+
+	unlock, err := p.lockIndex(username)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	index, err := p.loadIndex(username)
+	if err != nil {
+		return err
+	}
+
+	index[sessionID] = sessionIndexEntry{
+		SessionID:    sessionID,
+		CreatedAt:    userSession.FirstFactorAuthnTimestamp,
+		LastActivity: userSession.LastActivity,
+		IP:           p.ClientIP(ctx).String(),
+		UserAgent:    string(ctx.UserAgent()),
+	}
+
+	return p.saveIndex(username, index)
+}
+
+// unindexSession removes the session from the index of the sessions of its user.
+func (p *Provider) unindexSession(sessionID string, userSession UserSession) error {
+	username := userSession.RealUsername()
+	if username == "" {
+		return nil
+	}
+
+	unlock, err := p.lockIndex(username)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	index, err := p.loadIndex(username)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := index[sessionID]; !ok {
+		return nil
+	}
+
+	delete(index, sessionID)
+
+	return p.saveIndex(username, index)
+}
+
+// reindexSession replaces the session ID of the session in the index of the sessions of its user after the session
+// ID is regenerated.
+func (p *Provider) reindexSession(sessionID, newSessionID string, userSession UserSession) error {
+	username := userSession.RealUsername()
+	if username == "" {
+		return nil
+	}
+
+	unlock, err := p.lockIndex(username)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	index, err := p.loadIndex(username)
+	if err != nil {
+		return err
+	}
+
+	entry, ok := index[sessionID]
+	if !ok {
+		return nil
+	}
+
+	delete(index, sessionID)
+
+	entry.SessionID = newSessionID
+	index[newSessionID] = entry
+
+	return p.saveIndex(username, index)
+}
+
+func (p *Provider) loadIndex(username string) (index map[string]sessionIndexEntry, err error) {
+	data, err := p.backend.Get(indexKey(username))
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the session index of user %s: %w", username, err)
+	}
+
+	index = map[string]sessionIndexEntry{}
+
+	if len(data) == 0 {
+		return index, nil
+	}
+
+	if p.serializer != nil {
+		if data, err = p.serializer.decrypt(data); err != nil {
+			return nil, fmt.Errorf("unable to decrypt the session index of user %s: %w", username, err)
+		}
+	}
+
+	if err = json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("unable to parse the session index of user %s: %w", username, err)
+	}
+
+	return index, nil
+}
+
+func (p *Provider) saveIndex(username string, index map[string]sessionIndexEntry) (err error) {
+	if len(index) == 0 {
+		if err = p.backend.Destroy(indexKey(username)); err != nil {
+			return fmt.Errorf("unable to destroy the session index of user %s: %w", username, err)
+		}
+
+		return nil
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	if p.serializer != nil {
+		if data, err = p.serializer.encrypt(data); err != nil {
+			return fmt.Errorf("unable to encrypt the session index of user %s: %w", username, err)
+		}
+	}
+
+	if err = p.backend.Save(indexKey(username), data, p.indexExpiration); err != nil {
+		return fmt.Errorf("unable to save the session index of user %s: %w", username, err)
+	}
+
+	return nil
+}
+
+func (e sessionIndexEntry) info() SessionInfo {
+	return SessionInfo{
+		ID:           sessionInfoID(e.SessionID),
+		CreatedAt:    e.CreatedAt,
+		LastActivity: e.LastActivity,
+		IP:           e.IP,
+		UserAgent:    e.UserAgent,
+	}
+}
+
+func indexKey(username string) []byte {
+	return []byte(indexKeyPrefix + username)
+}
+
+func sessionInfoID(sessionID string) string {
+	return utils.HashSHA256FromString(sessionID)
+}
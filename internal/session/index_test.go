@@ -0,0 +1,390 @@
+package session
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+)
+
+func newIndexTestProvider() *Provider {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.TrustedProxies = []string{"127.0.0.1"}
+
+	return NewProvider(configuration, nil, nil)
+}
+
+func newIndexTestSession(t *testing.T, provider *Provider, username, ip, userAgent string, lastActivity int64) *fasthttp.RequestCtx {
+	ctx := newTestRequestCtx()
+	ctx.SetRemoteAddr(&net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	ctx.Request.Header.Set("X-Forwarded-For", ip)
+	ctx.Request.Header.SetUserAgent(userAgent)
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	session.SetOneFactor(time.Unix(1625048140, 0), &authentication.UserDetails{Username: username}, false)
+	session.LastActivity = lastActivity
+
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	return ctx
+}
+
+func TestShouldListSessionsOfUser(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	first := newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+	second := newIndexTestSession(t, provider, testUsername, "192.168.0.2", "Chrome", 1625048160)
+	newIndexTestSession(t, provider, "harry", "192.168.0.3", "Safari", 1625048170)
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	secondID, err := provider.CurrentSessionID(second)
+	require.NoError(t, err)
+
+	assert.Equal(t, SessionInfo{
+		ID:           secondID,
+		CreatedAt:    1625048140,
+		LastActivity: 1625048160,
+		IP:           "192.168.0.2",
+		UserAgent:    "Chrome",
+	}, sessions[0])
+
+	firstID, err := provider.CurrentSessionID(first)
+	require.NoError(t, err)
+
+	assert.Equal(t, firstID, sessions[1].ID)
+	assert.Equal(t, "Firefox", sessions[1].UserAgent)
+
+	sessions, err = provider.ListSessions("ron")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 0)
+}
+
+func TestShouldNotLoadIndexAsSessionOfCookie(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+
+	ctx := newTestRequestCtx()
+	ctx.Request.Header.SetCookie(testName, indexKeyPrefix+testUsername)
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "", session.Username)
+
+	session.SetOneFactor(time.Unix(1625048140, 0), &authentication.UserDetails{Username: "harry"}, false)
+	require.NoError(t, provider.SaveSession(ctx, session))
+	require.NoError(t, provider.RegenerateSession(ctx))
+
+	id, err := provider.CurrentSessionID(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, sessionInfoID(indexKeyPrefix+testUsername), id)
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Firefox", sessions[0].UserAgent)
+}
+
+func TestShouldOnlyIndexSessionWhenCreatedOrUserChanges(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	ctx := newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+
+	index, err := provider.loadIndex(testUsername)
+	require.NoError(t, err)
+	require.Len(t, index, 1)
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	session.LastActivity = 1625048200
+
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	index, err = provider.loadIndex(testUsername)
+	require.NoError(t, err)
+	require.Len(t, index, 1)
+
+	for _, entry := range index {
+		assert.Equal(t, int64(1625048150), entry.LastActivity)
+	}
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, int64(1625048200), sessions[0].LastActivity)
+
+	session.SetOneFactor(time.Unix(1625048210, 0), &authentication.UserDetails{Username: "harry"}, false)
+
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	sessions, err = provider.ListSessions("harry")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestShouldRevokeSessionOfUser(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	first := newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+	second := newIndexTestSession(t, provider, testUsername, "192.168.0.2", "Chrome", 1625048160)
+
+	firstID, err := provider.CurrentSessionID(first)
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrSessionNotFound, provider.RevokeSession("harry", firstID))
+	require.NoError(t, provider.RevokeSession(testUsername, firstID))
+	assert.Equal(t, ErrSessionNotFound, provider.RevokeSession(testUsername, firstID))
+
+	session, err := provider.GetSession(first)
+	require.NoError(t, err)
+	assert.Equal(t, "", session.Username)
+
+	session, err = provider.GetSession(second)
+	require.NoError(t, err)
+	assert.Equal(t, testUsername, session.Username)
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Chrome", sessions[0].UserAgent)
+}
+
+func TestShouldRevokeAllSessionsOfUser(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	first := newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+	second := newIndexTestSession(t, provider, testUsername, "192.168.0.2", "Chrome", 1625048160)
+	other := newIndexTestSession(t, provider, "harry", "192.168.0.3", "Safari", 1625048170)
+
+	count, err := provider.RevokeSessions(testUsername)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	for _, ctx := range []*fasthttp.RequestCtx{first, second} {
+		session, err := provider.GetSession(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "", session.Username)
+	}
+
+	session, err := provider.GetSession(other)
+	require.NoError(t, err)
+	assert.Equal(t, "harry", session.Username)
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 0)
+}
+
+func TestShouldUnindexDestroyedSession(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	ctx := newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+	newIndexTestSession(t, provider, testUsername, "192.168.0.2", "Chrome", 1625048160)
+
+	require.NoError(t, provider.DestroySession(ctx))
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Chrome", sessions[0].UserAgent)
+}
+
+func TestShouldReindexRegeneratedSession(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	ctx := newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+
+	id, err := provider.CurrentSessionID(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.RegenerateSession(ctx))
+
+	newID, err := provider.CurrentSessionID(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, id, newID)
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, newID, sessions[0].ID)
+	assert.Equal(t, "Firefox", sessions[0].UserAgent)
+}
+
+func TestShouldPruneExpiredSessionsFromIndex(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	ctx := newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+
+	holder, err := provider.holder(ctx)
+	require.NoError(t, err)
+
+	store, err := holder.Get(ctx)
+	require.NoError(t, err)
+
+	// Destroys the session in the backend without going through the provider like an expiration does.
+	require.NoError(t, provider.backend.Destroy(store.GetSessionID()))
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 0)
+
+	data, err := provider.backend.Get(indexKey(testUsername))
+	require.NoError(t, err)
+	assert.Len(t, data, 0)
+}
+
+func TestShouldIndexImpersonatedSessionUnderAdministrator(t *testing.T) {
+	provider := newIndexTestProvider()
+
+	ctx := newTestRequestCtx()
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	session.SetOneFactor(time.Unix(1625048140, 0), &authentication.UserDetails{Username: testUsername}, false)
+	session.StartImpersonation(time.Unix(1625048150, 0), &authentication.UserDetails{Username: "harry"}, time.Hour)
+
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	sessions, err = provider.ListSessions("harry")
+	require.NoError(t, err)
+	assert.Len(t, sessions, 0)
+}
+
+func TestShouldEncryptIndexWithRedisSerializer(t *testing.T) {
+	provider := newIndexTestProvider()
+	provider.serializer = NewEncryptingSerializer("abc")
+
+	newIndexTestSession(t, provider, testUsername, "192.168.0.1", "Firefox", 1625048150)
+
+	data, err := provider.backend.Get(indexKey(testUsername))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "Firefox")
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Firefox", sessions[0].UserAgent)
+}
+
+func newMaxConcurrentTestProvider(maxConcurrent int, policy string) *Provider {
+	provider := newIndexTestProvider()
+	provider.maxConcurrent = maxConcurrent
+	provider.maxConcurrentPolicy = policy
+
+	return provider
+}
+
+func newMaxConcurrentTestSession(t *testing.T, provider *Provider, userAgent string, authenticatedAt int64) (*fasthttp.RequestCtx, []SessionInfo, error) {
+	ctx := newTestRequestCtx()
+	ctx.Request.Header.SetUserAgent(userAgent)
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	evicted, err := provider.ReserveSession(ctx, testUsername)
+	if err != nil {
+		return ctx, evicted, err
+	}
+
+	session.SetOneFactor(time.Unix(authenticatedAt, 0), &authentication.UserDetails{Username: testUsername}, false)
+
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	return ctx, evicted, nil
+}
+
+func TestShouldEvictOldestSessionsWhenMaxConcurrentReached(t *testing.T) {
+	provider := newMaxConcurrentTestProvider(2, MaxConcurrentPolicyEvictOldest)
+
+	first, evicted, err := newMaxConcurrentTestSession(t, provider, "Firefox", 1625048140)
+	require.NoError(t, err)
+	assert.Len(t, evicted, 0)
+
+	_, evicted, err = newMaxConcurrentTestSession(t, provider, "Chrome", 1625048150)
+	require.NoError(t, err)
+	assert.Len(t, evicted, 0)
+
+	firstID, err := provider.CurrentSessionID(first)
+	require.NoError(t, err)
+
+	_, evicted, err = newMaxConcurrentTestSession(t, provider, "Safari", 1625048160)
+	require.NoError(t, err)
+	require.Len(t, evicted, 1)
+	assert.Equal(t, firstID, evicted[0].ID)
+	assert.Equal(t, "Firefox", evicted[0].UserAgent)
+
+	session, err := provider.GetSession(first)
+	require.NoError(t, err)
+	assert.Equal(t, "", session.Username)
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "Safari", sessions[0].UserAgent)
+	assert.Equal(t, "Chrome", sessions[1].UserAgent)
+}
+
+func TestShouldRejectSessionWhenMaxConcurrentReached(t *testing.T) {
+	provider := newMaxConcurrentTestProvider(1, MaxConcurrentPolicyReject)
+
+	first, _, err := newMaxConcurrentTestSession(t, provider, "Firefox", 1625048140)
+	require.NoError(t, err)
+
+	_, evicted, err := newMaxConcurrentTestSession(t, provider, "Chrome", 1625048150)
+	assert.Equal(t, ErrMaxConcurrentSessions, err)
+	assert.Len(t, evicted, 0)
+
+	session, err := provider.GetSession(first)
+	require.NoError(t, err)
+	assert.Equal(t, testUsername, session.Username)
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "Firefox", sessions[0].UserAgent)
+}
+
+func TestShouldNotCountReservedSessionAgainstMaxConcurrent(t *testing.T) {
+	provider := newMaxConcurrentTestProvider(1, MaxConcurrentPolicyReject)
+
+	ctx, _, err := newMaxConcurrentTestSession(t, provider, "Firefox", 1625048140)
+	require.NoError(t, err)
+
+	evicted, err := provider.ReserveSession(ctx, testUsername)
+	require.NoError(t, err)
+	assert.Len(t, evicted, 0)
+}
+
+func TestShouldNotLimitSessionsWhenMaxConcurrentDisabled(t *testing.T) {
+	provider := newMaxConcurrentTestProvider(0, MaxConcurrentPolicyReject)
+
+	for _, userAgent := range []string{"Firefox", "Chrome", "Safari"} {
+		_, evicted, err := newMaxConcurrentTestSession(t, provider, userAgent, 1625048140)
+		require.NoError(t, err)
+		assert.Len(t, evicted, 0)
+	}
+
+	sessions, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 3)
+}
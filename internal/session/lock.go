@@ -0,0 +1,105 @@
+package session
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// indexLocker is implemented by the session backends which can be shared by several instances of Authelia, it locks
+// the index of the sessions of a user across the instances.
+type indexLocker interface {
+	// TryLock acquires the lock identified by the key if it's not held and returns true if it was acquired. The lock
+	// is released after the ttl if its owner doesn't release it.
+	TryLock(key, token []byte, ttl time.Duration) (bool, error)
+
+	// Unlock releases the lock identified by the key if it's held by the owner of the token.
+	Unlock(key, token []byte) error
+}
+
+// indexMutexes holds a mutex per user whose index is locked, the mutex of a user is removed once it's not used.
+type indexMutexes struct {
+	mutex   sync.Mutex
+	entries map[string]*indexMutex
+}
+
+type indexMutex struct {
+	sync.Mutex
+
+	refs int
+}
+
+// lock locks the mutex of the user, the mutex of the other users are not affected.
+func (m *indexMutexes) lock(username string) (unlock func()) {
+	m.mutex.Lock()
+
+	if m.entries == nil {
+		m.entries = make(map[string]*indexMutex)
+	}
+
+	entry, ok := m.entries[username]
+	if !ok {
+		entry = &indexMutex{}
+		m.entries[username] = entry
+	}
+
+	entry.refs++
+
+	m.mutex.Unlock()
+
+	entry.Lock()
+
+	return func() {
+		entry.Unlock()
+
+		m.mutex.Lock()
+
+		if entry.refs--; entry.refs == 0 {
+			delete(m.entries, username)
+		}
+
+		m.mutex.Unlock()
+	}
+}
+
+// lockIndex locks the index of the sessions of the user, the index is also locked across the instances of Authelia
+// when the session backend is shared. The returned function releases the lock.
+func (p *Provider) lockIndex(username string) (unlock func(), err error) {
+	locker, ok := p.backend.(indexLocker)
+	if !ok {
+		return p.indexMutexes.lock(username), nil
+	}
+
+	// The distributed lock also excludes the other requests of this instance, no mutex is held while waiting for it.
+	key := []byte(lockKeyPrefix + indexKeyPrefix + username)
+
+	token := make([]byte, 16)
+	if _, err = rand.Read(token); err != nil {
+		return nil, fmt.Errorf("unable to generate the lock token of the session index of user %s: %w", username, err)
+	}
+
+	deadline := time.Now().Add(indexLockTimeout)
+
+	for {
+		locked, err := locker.TryLock(key, token, indexLockTTL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to lock the session index of user %s: %w", username, err)
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("unable to lock the session index of user %s: timed out after %s", username, indexLockTimeout)
+		}
+
+		time.Sleep(indexLockRetryInterval)
+	}
+
+	return func() {
+		// The lock expires after its TTL if it can't be released.
+		_ = locker.Unlock(key, token)
+	}, nil
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldNotBlockOtherUsersWhenIndexIsLocked(t *testing.T) {
+	provider := &Provider{}
+
+	unlock, err := provider.lockIndex("john")
+	assert.NoError(t, err)
+
+	locked := make(chan func())
+
+	go func() {
+		unlockHarry, err := provider.lockIndex("harry")
+		assert.NoError(t, err)
+
+		locked <- unlockHarry
+	}()
+
+	select {
+	case unlockHarry := <-locked:
+		unlockHarry()
+	case <-time.After(time.Second):
+		t.Fatal("the index of harry is blocked by the lock of the index of john")
+	}
+
+	unlock()
+
+	assert.Len(t, provider.indexMutexes.entries, 0)
+}
+
+func TestShouldSerializeLocksOfTheSameUser(t *testing.T) {
+	provider := &Provider{}
+
+	unlock, err := provider.lockIndex("john")
+	assert.NoError(t, err)
+
+	locked := make(chan func())
+
+	go func() {
+		unlockSecond, err := provider.lockIndex("john")
+		assert.NoError(t, err)
+
+		locked <- unlockSecond
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("the index of john was locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+
+	(<-locked)()
+
+	assert.Len(t, provider.indexMutexes.entries, 0)
+}
@@ -3,61 +3,71 @@ package session
 import (
 	"crypto/x509"
 	"encoding/json"
+	"net"
 	"time"
 
 	fasthttpsession "github.com/fasthttp/session/v2"
 	"github.com/fasthttp/session/v2/providers/memory"
-	"github.com/fasthttp/session/v2/providers/redis"
 	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
 	"github.com/authelia/authelia/v4/internal/logging"
-	"github.com/authelia/authelia/v4/internal/utils"
+	"github.com/authelia/authelia/v4/internal/storage"
 )
 
 // Provider a session provider.
 type Provider struct {
-	sessionHolder *fasthttpsession.Session
-	RememberMe    time.Duration
-	Inactivity    time.Duration
+	domains []*Domain
+
+	backend         fasthttpsession.Provider
+	serializer      *EncryptingSerializer
+	binding         *Binding
+	trustedProxies  []*net.IPNet
+	indexExpiration time.Duration
+	indexMutexes    indexMutexes
+
+	maxConcurrent       int
+	maxConcurrentPolicy string
 }
 
-// NewProvider instantiate a session provider given a configuration.
-func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertPool) *Provider {
+// NewProvider instantiate a session provider given a configuration, the storage provider is only used by the sql
+// session provider.
+func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertPool, storageProvider storage.Provider) *Provider {
 	providerConfig := NewProviderConfig(configuration, certPool)
 
 	provider := new(Provider)
-	provider.sessionHolder = fasthttpsession.New(providerConfig.config)
 
 	logger := logging.Logger()
 
-	duration, err := utils.ParseDurationString(configuration.RememberMeDuration)
-	if err != nil {
-		logger.Fatal(err)
-	}
+	provider.serializer = providerConfig.serializer
+	provider.binding = NewBinding(configuration.Binding)
+	provider.trustedProxies = parseNetworks(configuration.TrustedProxies)
+	provider.maxConcurrent = configuration.MaxConcurrent
+	provider.maxConcurrentPolicy = configuration.MaxConcurrentPolicy
 
-	provider.RememberMe = duration
-
-	duration, err = utils.ParseDurationString(configuration.Inactivity)
-	if err != nil {
-		logger.Fatal(err)
-	}
-
-	provider.Inactivity = duration
-
-	var providerImpl fasthttpsession.Provider
+	var (
+		providerImpl fasthttpsession.Provider
+		err          error
+	)
 
 	switch {
 	case providerConfig.redisConfig != nil:
-		providerImpl, err = redis.New(*providerConfig.redisConfig)
+		providerImpl, err = newRedisBackend(*providerConfig.redisConfig)
 		if err != nil {
 			logger.Fatal(err)
 		}
 	case providerConfig.redisSentinelConfig != nil:
-		providerImpl, err = redis.NewFailoverCluster(*providerConfig.redisSentinelConfig)
+		providerImpl, err = newRedisSentinelBackend(*providerConfig.redisSentinelConfig)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	case providerConfig.redisClusterConfig != nil:
+		providerImpl, err = newRedisClusterBackend(providerConfig.redisClusterConfig)
 		if err != nil {
 			logger.Fatal(err)
 		}
+	case providerConfig.providerName == "sql":
+		providerImpl = newSQLBackend(storageProvider)
 	default:
 		providerImpl, err = memory.New(memory.Config{})
 		if err != nil {
@@ -65,9 +75,35 @@ func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertP
 		}
 	}
 
-	err = provider.sessionHolder.SetProvider(providerImpl)
-	if err != nil {
-		logger.Fatal(err)
+	provider.backend = providerImpl
+
+	for i, domainConfiguration := range domainConfigurations(configuration) {
+		domain, err := newDomain(domainConfiguration, providerConfig.config)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		// All the session domains share the backend which must only be garbage collected once.
+		if i == 0 {
+			err = domain.holder.SetProvider(providerImpl)
+		} else {
+			err = domain.holder.SetProvider(sharedBackend{providerImpl})
+		}
+
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		// The index of the sessions of a user must outlive the sessions it references.
+		if domain.Expiration > provider.indexExpiration {
+			provider.indexExpiration = domain.Expiration
+		}
+
+		if domain.RememberMe > provider.indexExpiration {
+			provider.indexExpiration = domain.RememberMe
+		}
+
+		provider.domains = append(provider.domains, domain)
 	}
 
 	return provider
@@ -75,7 +111,12 @@ func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertP
 
 // GetSession return the user session from a request.
 func (p *Provider) GetSession(ctx *fasthttp.RequestCtx) (UserSession, error) {
-	store, err := p.sessionHolder.Get(ctx)
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return NewDefaultUserSession(), err
+	}
+
+	store, err := holder.Get(ctx)
 
 	if err != nil {
 		return NewDefaultUserSession(), err
@@ -105,12 +146,25 @@ func (p *Provider) GetSession(ctx *fasthttp.RequestCtx) (UserSession, error) {
 
 // SaveSession save the user session.
 func (p *Provider) SaveSession(ctx *fasthttp.RequestCtx, userSession UserSession) error {
-	store, err := p.sessionHolder.Get(ctx)
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return err
+	}
+
+	store, err := holder.Get(ctx)
 
 	if err != nil {
 		return err
 	}
 
+	previousJSON, _ := store.Get(userSessionStorerKey).([]byte)
+	indexedAt, _ := store.Get(indexedAtStorerKey).(int64)
+
+	index := p.isIndexOutdated(previousJSON, indexedAt, userSession)
+	if index {
+		store.Set(indexedAtStorerKey, time.Now().Unix())
+	}
+
 	userSessionJSON, err := json.Marshal(userSession)
 
 	if err != nil {
@@ -119,30 +173,93 @@ func (p *Provider) SaveSession(ctx *fasthttp.RequestCtx, userSession UserSession
 
 	store.Set(userSessionStorerKey, userSessionJSON)
 
-	err = p.sessionHolder.Save(ctx, store)
+	sessionID := string(store.GetSessionID())
+
+	err = holder.Save(ctx, store)
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if !index {
+		return nil
+	}
+
+	return p.indexSession(ctx, sessionID, userSession)
 }
 
 // RegenerateSession regenerate a session ID.
 func (p *Provider) RegenerateSession(ctx *fasthttp.RequestCtx) error {
-	err := p.sessionHolder.Regenerate(ctx)
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return err
+	}
+
+	sessionID, userSession, err := p.getSessionIDAndUserSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = holder.Regenerate(ctx); err != nil {
+		return err
+	}
+
+	newSessionID, _, err := p.getSessionIDAndUserSession(ctx)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return p.reindexSession(sessionID, newSessionID, userSession)
 }
 
 // DestroySession destroy a session ID and delete the cookie.
 func (p *Provider) DestroySession(ctx *fasthttp.RequestCtx) error {
-	return p.sessionHolder.Destroy(ctx)
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return err
+	}
+
+	sessionID, userSession, err := p.getSessionIDAndUserSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = holder.Destroy(ctx); err != nil {
+		return err
+	}
+
+	return p.unindexSession(sessionID, userSession)
+}
+
+func (p *Provider) getSessionIDAndUserSession(ctx *fasthttp.RequestCtx) (sessionID string, userSession UserSession, err error) {
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return "", userSession, err
+	}
+
+	store, err := holder.Get(ctx)
+	if err != nil {
+		return "", userSession, err
+	}
+
+	sessionID = string(store.GetSessionID())
+
+	// A session which can't be decoded isn't indexed, its entry if any is pruned when the sessions are listed.
+	if userSession, err = p.GetSession(ctx); err != nil {
+		return sessionID, NewDefaultUserSession(), nil
+	}
+
+	return sessionID, userSession, nil
 }
 
 // UpdateExpiration update the expiration of the cookie and session.
 func (p *Provider) UpdateExpiration(ctx *fasthttp.RequestCtx, expiration time.Duration) error {
-	store, err := p.sessionHolder.Get(ctx)
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return err
+	}
+
+	store, err := holder.Get(ctx)
 
 	if err != nil {
 		return err
@@ -154,12 +271,17 @@ func (p *Provider) UpdateExpiration(ctx *fasthttp.RequestCtx, expiration time.Du
 		return err
 	}
 
-	return p.sessionHolder.Save(ctx, store)
+	return holder.Save(ctx, store)
 }
 
 // GetExpiration get the expiration of the current session.
 func (p *Provider) GetExpiration(ctx *fasthttp.RequestCtx) (time.Duration, error) {
-	store, err := p.sessionHolder.Get(ctx)
+	holder, err := p.holder(ctx)
+	if err != nil {
+		return time.Duration(0), err
+	}
+
+	store, err := holder.Get(ctx)
 
 	if err != nil {
 		return time.Duration(0), err
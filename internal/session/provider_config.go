@@ -8,6 +8,7 @@ import (
 
 	"github.com/fasthttp/session/v2"
 	"github.com/fasthttp/session/v2/providers/redis"
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
@@ -52,12 +53,16 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 
 	var redisSentinelConfig *redis.FailoverConfig
 
+	var redisClusterConfig *goredis.ClusterOptions
+
 	var providerName string
 
+	var serializer *EncryptingSerializer
+
 	// If redis configuration is provided, then use the redis provider.
 	switch {
 	case configuration.Redis != nil:
-		serializer := NewEncryptingSerializer(configuration.Secret)
+		serializer = NewEncryptingSerializer(append([]string{configuration.Secret}, configuration.PreviousSecrets...)...)
 
 		var tlsConfig *tls.Config
 
@@ -65,7 +70,35 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 			tlsConfig = utils.NewTLSConfig(configuration.Redis.TLS, tls.VersionTLS12, certPool)
 		}
 
-		if configuration.Redis.HighAvailability != nil && configuration.Redis.HighAvailability.SentinelName != "" {
+		switch {
+		case configuration.Redis.Cluster != nil:
+			addrs := make([]string, 0)
+
+			if configuration.Redis.Host != "" {
+				addrs = append(addrs, fmt.Sprintf("%s:%d", strings.ToLower(configuration.Redis.Host), configuration.Redis.Port))
+			}
+
+			for _, node := range configuration.Redis.Cluster.Nodes {
+				addr := fmt.Sprintf("%s:%d", strings.ToLower(node.Host), node.Port)
+				if !utils.IsStringInSlice(addr, addrs) {
+					addrs = append(addrs, addr)
+				}
+			}
+
+			providerName = "redis-cluster"
+			redisClusterConfig = &goredis.ClusterOptions{
+				Addrs:          addrs,
+				MaxRedirects:   configuration.Redis.Cluster.MaximumRedirects,
+				ReadOnly:       configuration.Redis.Cluster.ReadFromReplicas,
+				RouteByLatency: configuration.Redis.Cluster.RouteByLatency,
+				RouteRandomly:  configuration.Redis.Cluster.RouteRandomly,
+				Username:       configuration.Redis.Username,
+				Password:       configuration.Redis.Password,
+				PoolSize:       configuration.Redis.MaximumActiveConnections,
+				MinIdleConns:   configuration.Redis.MinimumIdleConnections,
+				TLSConfig:      tlsConfig,
+			}
+		case configuration.Redis.HighAvailability != nil && configuration.Redis.HighAvailability.SentinelName != "":
 			addrs := make([]string, 0)
 
 			if configuration.Redis.Host != "" {
@@ -96,7 +129,7 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 				TLSConfig:        tlsConfig,
 				KeyPrefix:        "authelia-session",
 			}
-		} else {
+		default:
 			providerName = "redis"
 			network := "tcp"
 
@@ -124,6 +157,15 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 			}
 		}
 
+		config.EncodeFunc = serializer.Encode
+		config.DecodeFunc = serializer.Decode
+	case configuration.SQL != nil:
+		serializer = NewEncryptingSerializer(append([]string{configuration.Secret}, configuration.PreviousSecrets...)...)
+		providerName = "sql"
+
+		// The expired sessions are deleted from the database by the garbage collector of the sessions.
+		config.GCLifetime = configuration.SQL.CleanupInterval
+
 		config.EncodeFunc = serializer.Encode
 		config.DecodeFunc = serializer.Decode
 	default:
@@ -134,6 +176,8 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 		config,
 		redisConfig,
 		redisSentinelConfig,
+		redisClusterConfig,
 		providerName,
+		serializer,
 	}
 }
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"crypto/tls"
 	"testing"
@@ -185,6 +186,61 @@ func TestShouldCreateRedisSentinelSessionProvider(t *testing.T) {
 	assert.Nil(t, pConfig.TLSConfig)
 }
 
+func TestShouldCreateRedisClusterSessionProvider(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Redis = &schema.RedisSessionConfiguration{
+		Host:                     "REDIS.example.com",
+		Port:                     6379,
+		Username:                 "authelia",
+		Password:                 "pass",
+		MaximumActiveConnections: 8,
+		MinimumIdleConnections:   2,
+		TLS: &schema.TLSConfig{
+			ServerName:     "redis.fqdn.example.com",
+			MinimumVersion: "TLS1.3",
+		},
+		Cluster: &schema.RedisClusterConfiguration{
+			Nodes: []schema.RedisNode{
+				{
+					Host: "redis2.example.com",
+					Port: 6379,
+				},
+				{
+					Host: "redis.example.com",
+					Port: 6379,
+				},
+			},
+			MaximumRedirects: 5,
+			ReadFromReplicas: true,
+			RouteByLatency:   true,
+		},
+	}
+	providerConfig := NewProviderConfig(configuration, nil)
+
+	assert.Nil(t, providerConfig.redisConfig)
+	assert.Nil(t, providerConfig.redisSentinelConfig)
+	assert.Equal(t, "redis-cluster", providerConfig.providerName)
+
+	pConfig := providerConfig.redisClusterConfig
+	require.NotNil(t, pConfig)
+	assert.Equal(t, []string{"redis.example.com:6379", "redis2.example.com:6379"}, pConfig.Addrs)
+	assert.Equal(t, "authelia", pConfig.Username)
+	assert.Equal(t, "pass", pConfig.Password)
+	assert.Equal(t, 5, pConfig.MaxRedirects)
+	assert.True(t, pConfig.ReadOnly)
+	assert.True(t, pConfig.RouteByLatency)
+	assert.False(t, pConfig.RouteRandomly)
+	assert.Equal(t, 8, pConfig.PoolSize)
+	assert.Equal(t, 2, pConfig.MinIdleConns)
+
+	require.NotNil(t, pConfig.TLSConfig)
+	assert.Equal(t, uint16(tls.VersionTLS13), pConfig.TLSConfig.MinVersion)
+	assert.Equal(t, "redis.fqdn.example.com", pConfig.TLSConfig.ServerName)
+}
+
 func TestShouldSetCookieSameSite(t *testing.T) {
 	configuration := schema.SessionConfiguration{}
 	configuration.Domain = testDomain
@@ -277,12 +333,36 @@ func TestShouldUseEncryptingSerializerWithRedis(t *testing.T) {
 	encoded, err := providerConfig.config.EncodeFunc(payload)
 	require.NoError(t, err)
 
-	// Now we try to decrypt what has been serialized
+	// Now we try to decrypt what has been serialized, the ciphertext is prefixed with the ID of the key.
 	key := sha256.Sum256([]byte("abc"))
-	decrypted, err := utils.Decrypt(encoded, &key)
+	require.True(t, bytes.HasPrefix(encoded, encryptionKeyIDMagic))
+	decrypted, err := utils.Decrypt(encoded[len(encryptionKeyIDMagic)+encryptionKeyIDLength:], &key)
 	require.NoError(t, err)
 
 	decoded := session.Dict{}
 	_, _ = decoded.UnmarshalMsg(decrypted)
 	assert.Equal(t, "value", decoded.Get("key"))
 }
+
+func TestShouldUsePreviousSecretsToDecryptSessions(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Secret = "abc"
+	configuration.Redis = &schema.RedisSessionConfiguration{
+		Host: "redis.example.com",
+		Port: 6379,
+	}
+
+	payload := session.Dict{}
+	payload.Set("key", "value")
+
+	encoded, err := NewProviderConfig(configuration, nil).config.EncodeFunc(payload)
+	require.NoError(t, err)
+
+	configuration.Secret = "def"
+	configuration.PreviousSecrets = []string{"abc"}
+	providerConfig := NewProviderConfig(configuration, nil)
+
+	decoded := session.Dict{}
+	require.NoError(t, providerConfig.config.DecodeFunc(&decoded, encoded))
+	assert.Equal(t, "value", decoded.Get("key"))
+}
@@ -13,14 +13,22 @@ import (
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
 )
 
-func TestShouldInitializerSession(t *testing.T) {
+// newTestRequestCtx returns a request sent to a host under the test session domain.
+func newTestRequestCtx() *fasthttp.RequestCtx {
 	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetHost("login." + testDomain)
+
+	return ctx
+}
+
+func TestShouldInitializerSession(t *testing.T) {
+	ctx := newTestRequestCtx()
 	configuration := schema.SessionConfiguration{}
 	configuration.Domain = testDomain
 	configuration.Name = testName
 	configuration.Expiration = testExpiration
 
-	provider := NewProvider(configuration, nil)
+	provider := NewProvider(configuration, nil, nil)
 	session, err := provider.GetSession(ctx)
 	require.NoError(t, err)
 
@@ -28,14 +36,14 @@ func TestShouldInitializerSession(t *testing.T) {
 }
 
 func TestShouldUpdateSession(t *testing.T) {
-	ctx := &fasthttp.RequestCtx{}
+	ctx := newTestRequestCtx()
 
 	configuration := schema.SessionConfiguration{}
 	configuration.Domain = testDomain
 	configuration.Name = testName
 	configuration.Expiration = testExpiration
 
-	provider := NewProvider(configuration, nil)
+	provider := NewProvider(configuration, nil, nil)
 	session, _ := provider.GetSession(ctx)
 
 	session.Username = testUsername
@@ -54,7 +62,7 @@ func TestShouldUpdateSession(t *testing.T) {
 }
 
 func TestShouldSetSessionAuthenticationLevels(t *testing.T) {
-	ctx := &fasthttp.RequestCtx{}
+	ctx := newTestRequestCtx()
 	configuration := schema.SessionConfiguration{}
 
 	timeOneFactor := time.Unix(1625048140, 0)
@@ -65,7 +73,7 @@ func TestShouldSetSessionAuthenticationLevels(t *testing.T) {
 	configuration.Name = testName
 	configuration.Expiration = testExpiration
 
-	provider := NewProvider(configuration, nil)
+	provider := NewProvider(configuration, nil, nil)
 	session, _ := provider.GetSession(ctx)
 
 	session.SetOneFactor(timeOneFactor, &authentication.UserDetails{Username: testUsername}, false)
@@ -95,7 +103,7 @@ func TestShouldSetSessionAuthenticationLevels(t *testing.T) {
 		FirstFactorAuthnTimestamp: timeOneFactor.Unix(),
 	}, session)
 
-	session.SetTwoFactor(timeTwoFactor)
+	session.SetTwoFactor(timeTwoFactor, authentication.TOTP)
 
 	err = provider.SaveSession(ctx, session)
 	require.NoError(t, err)
@@ -109,6 +117,7 @@ func TestShouldSetSessionAuthenticationLevels(t *testing.T) {
 		LastActivity:               timeTwoFactor.Unix(),
 		FirstFactorAuthnTimestamp:  timeOneFactor.Unix(),
 		SecondFactorAuthnTimestamp: timeTwoFactor.Unix(),
+		SecondFactorMethod:         authentication.TOTP,
 	}, session)
 
 	authAt, err = session.AuthenticatedTime(authorization.OneFactor)
@@ -124,14 +133,53 @@ func TestShouldSetSessionAuthenticationLevels(t *testing.T) {
 	assert.Equal(t, timeZeroFactor, authAt)
 }
 
+func TestShouldStartAndStopImpersonation(t *testing.T) {
+	ctx := newTestRequestCtx()
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+
+	now := time.Unix(1625048140, 0)
+
+	provider := NewProvider(configuration, nil, nil)
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	session.SetOneFactor(now, &authentication.UserDetails{Username: testUsername, Groups: []string{"admins"}}, false)
+	session.StartImpersonation(now, &authentication.UserDetails{Username: "harry", DisplayName: "Harry Potter", Groups: []string{"dev"}, Emails: []string{"harry@example.com"}}, time.Hour)
+
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	session, err = provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "harry", session.Username)
+	assert.Equal(t, "Harry Potter", session.DisplayName)
+	assert.Equal(t, []string{"dev"}, session.Groups)
+	assert.Equal(t, []string{"harry@example.com"}, session.Emails)
+	assert.Equal(t, authentication.OneFactor, session.AuthenticationLevel)
+	assert.Equal(t, &Impersonator{Username: testUsername, Groups: []string{"admins"}, ExpiresAt: now.Add(time.Hour).Unix()}, session.ImpersonatedBy)
+
+	assert.False(t, session.IsImpersonationExpired(now.Add(time.Minute)))
+	assert.True(t, session.IsImpersonationExpired(now.Add(time.Hour)))
+
+	session.StopImpersonation()
+
+	assert.Equal(t, testUsername, session.Username)
+	assert.Equal(t, []string{"admins"}, session.Groups)
+	assert.Nil(t, session.ImpersonatedBy)
+	assert.False(t, session.IsImpersonationExpired(now.Add(time.Hour)))
+}
+
 func TestShouldDestroySessionAndWipeSessionData(t *testing.T) {
-	ctx := &fasthttp.RequestCtx{}
+	ctx := newTestRequestCtx()
 	configuration := schema.SessionConfiguration{}
 	configuration.Domain = testDomain
 	configuration.Name = testName
 	configuration.Expiration = testExpiration
 
-	provider := NewProvider(configuration, nil)
+	provider := NewProvider(configuration, nil, nil)
 	session, err := provider.GetSession(ctx)
 	require.NoError(t, err)
 
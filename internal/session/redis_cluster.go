@@ -0,0 +1,120 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// redisClusterKeyPrefix is the prefix of the keys of the sessions, it's the same as the other redis session providers.
+const redisClusterKeyPrefix = "authelia-session:"
+
+// redisClusterBackend is a session backend persisting the encrypted sessions in a Redis Cluster. The client discovers
+// the slots of the cluster from the seed nodes and follows the MOVED and ASK redirections when the slots are migrated.
+type redisClusterBackend struct {
+	redisLocker
+
+	db *goredis.ClusterClient
+}
+
+func newRedisClusterBackend(options *goredis.ClusterOptions) (*redisClusterBackend, error) {
+	goredis.SetLogger(newRedisLogger())
+
+	db := goredis.NewClusterClient(options)
+
+	if err := db.Ping(context.Background()).Err(); err != nil {
+		_ = db.Close()
+
+		return nil, fmt.Errorf("Redis Cluster connection error: %w", err)
+	}
+
+	return &redisClusterBackend{redisLocker: redisLocker{db: db, prefix: redisClusterKeyPrefix}, db: db}, nil
+}
+
+func (b *redisClusterBackend) key(id []byte) string {
+	return redisClusterKeyPrefix + string(id)
+}
+
+// Get implements fasthttpsession.Provider.
+func (b *redisClusterBackend) Get(id []byte) ([]byte, error) {
+	reply, err := b.db.Get(context.Background(), b.key(id)).Bytes()
+	if err != nil && err != goredis.Nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// Save implements fasthttpsession.Provider.
+func (b *redisClusterBackend) Save(id, data []byte, expiration time.Duration) error {
+	return b.db.Set(context.Background(), b.key(id), data, expiration).Err()
+}
+
+// Regenerate implements fasthttpsession.Provider. The session is copied instead of being renamed since the keys of
+// the old and the new session ID generally belong to different hash slots which RENAME doesn't support.
+func (b *redisClusterBackend) Regenerate(id, newID []byte, expiration time.Duration) error {
+	ctx := context.Background()
+
+	data, err := b.db.Get(ctx, b.key(id)).Bytes()
+
+	switch {
+	case err == goredis.Nil:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if err = b.db.Set(ctx, b.key(newID), data, expiration).Err(); err != nil {
+		return err
+	}
+
+	return b.db.Del(ctx, b.key(id)).Err()
+}
+
+// Destroy implements fasthttpsession.Provider.
+func (b *redisClusterBackend) Destroy(id []byte) error {
+	return b.db.Del(context.Background(), b.key(id)).Err()
+}
+
+// Count implements fasthttpsession.Provider, the keys are spread across the masters of the cluster which are
+// scanned concurrently.
+func (b *redisClusterBackend) Count() int {
+	var count int64
+
+	err := b.db.ForEachMaster(context.Background(), func(ctx context.Context, master *goredis.Client) error {
+		var cursor uint64
+
+		for {
+			keys, next, err := master.Scan(ctx, cursor, redisClusterKeyPrefix+"*", 1000).Result()
+			if err != nil {
+				return err
+			}
+
+			atomic.AddInt64(&count, int64(len(keys)))
+
+			if next == 0 {
+				return nil
+			}
+
+			cursor = next
+		}
+	})
+	if err != nil {
+		return 0
+	}
+
+	return int(count)
+}
+
+// NeedGC implements fasthttpsession.Provider, the sessions expire through the TTL of the keys.
+func (b *redisClusterBackend) NeedGC() bool {
+	return false
+}
+
+// GC implements fasthttpsession.Provider.
+func (b *redisClusterBackend) GC() error {
+	return nil
+}
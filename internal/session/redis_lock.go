@@ -0,0 +1,76 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/fasthttp/session/v2/providers/redis"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// redisUnlockScript deletes the key of the lock only if it still holds the token of the owner, the lock may have
+// expired and been acquired by another instance in the meantime.
+var redisUnlockScript = goredis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// redisLocker locks the indexes of the sessions in Redis with keys expiring after the TTL of the lock.
+type redisLocker struct {
+	db     goredis.Cmdable
+	prefix string
+}
+
+// TryLock implements indexLocker.
+func (l redisLocker) TryLock(key, token []byte, ttl time.Duration) (bool, error) {
+	return l.db.SetNX(context.Background(), l.prefix+string(key), token, ttl).Result()
+}
+
+// Unlock implements indexLocker.
+func (l redisLocker) Unlock(key, token []byte) error {
+	return redisUnlockScript.Run(context.Background(), l.db, []string{l.prefix + string(key)}, token).Err()
+}
+
+// redisBackend is the backend of the redis and redis sentinel session providers. The client of the fasthttp/session
+// provider isn't exposed so the locks use a client of their own.
+type redisBackend struct {
+	*redis.Provider
+	redisLocker
+}
+
+func newRedisBackend(config redis.Config) (*redisBackend, error) {
+	provider, err := redis.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	db := goredis.NewClient(&goredis.Options{
+		Network:   config.Network,
+		Addr:      config.Addr,
+		Username:  config.Username,
+		Password:  config.Password,
+		DB:        config.DB,
+		TLSConfig: config.TLSConfig,
+	})
+
+	return &redisBackend{Provider: provider, redisLocker: redisLocker{db: db, prefix: config.KeyPrefix + ":"}}, nil
+}
+
+func newRedisSentinelBackend(config redis.FailoverConfig) (*redisBackend, error) {
+	provider, err := redis.NewFailoverCluster(config)
+	if err != nil {
+		return nil, err
+	}
+
+	db := goredis.NewFailoverClusterClient(&goredis.FailoverOptions{
+		MasterName:       config.MasterName,
+		SentinelAddrs:    config.SentinelAddrs,
+		SentinelPassword: config.SentinelPassword,
+		Username:         config.Username,
+		Password:         config.Password,
+		DB:               config.DB,
+		TLSConfig:        config.TLSConfig,
+	})
+
+	return &redisBackend{Provider: provider, redisLocker: redisLocker{db: db, prefix: config.KeyPrefix + ":"}}, nil
+}
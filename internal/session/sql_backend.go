@@ -0,0 +1,79 @@
+package session
+
+import (
+	"time"
+
+	"github.com/authelia/authelia/v4/internal/storage"
+)
+
+// sqlBackend is a session backend persisting the encrypted sessions in the SQL database of the storage provider.
+type sqlBackend struct {
+	storage storage.Provider
+}
+
+func newSQLBackend(storageProvider storage.Provider) *sqlBackend {
+	return &sqlBackend{storage: storageProvider}
+}
+
+// Get implements fasthttpsession.Provider.
+func (b *sqlBackend) Get(id []byte) ([]byte, error) {
+	return b.storage.LoadSession(string(id), time.Now())
+}
+
+// Save implements fasthttpsession.Provider.
+func (b *sqlBackend) Save(id, data []byte, expiration time.Duration) error {
+	return b.storage.SaveSession(string(id), data, expiresAt(expiration))
+}
+
+// Regenerate implements fasthttpsession.Provider.
+func (b *sqlBackend) Regenerate(id, newID []byte, expiration time.Duration) error {
+	return b.storage.RegenerateSession(string(id), string(newID), expiresAt(expiration))
+}
+
+// Destroy implements fasthttpsession.Provider.
+func (b *sqlBackend) Destroy(id []byte) error {
+	return b.storage.DestroySession(string(id))
+}
+
+// Count implements fasthttpsession.Provider.
+func (b *sqlBackend) Count() int {
+	count, err := b.storage.CountSessions(time.Now())
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+// NeedGC implements fasthttpsession.Provider.
+func (b *sqlBackend) NeedGC() bool {
+	return true
+}
+
+// GC implements fasthttpsession.Provider.
+func (b *sqlBackend) GC() error {
+	_, err := b.storage.DeleteExpiredSessions(time.Now())
+
+	return err
+}
+
+// TryLock implements indexLocker.
+func (b *sqlBackend) TryLock(key, token []byte, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	return b.storage.LockSession(string(key), token, now.Add(ttl), now)
+}
+
+// Unlock implements indexLocker.
+func (b *sqlBackend) Unlock(key, token []byte) error {
+	return b.storage.UnlockSession(string(key), token)
+}
+
+// expiresAt returns the time a session saved now with the expiration expires at, the zero time if it never expires.
+func expiresAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(expiration)
+}
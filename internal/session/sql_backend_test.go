@@ -0,0 +1,145 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/storage"
+)
+
+func TestShouldPersistSessionsInSQLStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageProvider := storage.NewMockProvider(ctrl)
+
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Secret = "abc"
+	configuration.SQL = &schema.SQLSessionConfiguration{CleanupInterval: time.Hour}
+
+	provider := NewProvider(configuration, nil, storageProvider)
+
+	var saved []byte
+
+	storageProvider.EXPECT().
+		SaveSession(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(id string, data []byte, expiresAt time.Time) error {
+			if id == string(indexKey(testUsername)) {
+				return nil
+			}
+
+			saved = data
+
+			assert.WithinDuration(t, time.Now().Add(40*time.Second), expiresAt, 5*time.Second)
+
+			return nil
+		}).
+		Times(2)
+
+	storageProvider.EXPECT().
+		LoadSession(gomock.Eq(string(indexKey(testUsername))), gomock.Any()).
+		Return(nil, nil)
+
+	storageProvider.EXPECT().
+		LockSession(gomock.Eq("lock:index:john"), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(true, nil)
+
+	storageProvider.EXPECT().
+		UnlockSession(gomock.Eq("lock:index:john"), gomock.Any()).
+		Return(nil)
+
+	ctx := newTestRequestCtx()
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	session.Username = testUsername
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	require.NotNil(t, saved)
+	assert.NotContains(t, string(saved), testUsername)
+
+	decrypted, err := provider.serializer.decrypt(saved)
+	require.NoError(t, err)
+	assert.Contains(t, string(decrypted), testUsername)
+}
+
+func TestShouldDeleteExpiredSessionsFromSQLStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageProvider := storage.NewMockProvider(ctrl)
+	backend := newSQLBackend(storageProvider)
+
+	storageProvider.EXPECT().
+		DeleteExpiredSessions(gomock.Any()).
+		Return(int64(2), nil)
+
+	assert.True(t, backend.NeedGC())
+	assert.NoError(t, backend.GC())
+}
+
+func TestShouldNotExpireSessionsWithoutExpirationInSQLStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageProvider := storage.NewMockProvider(ctrl)
+	backend := newSQLBackend(storageProvider)
+
+	storageProvider.EXPECT().
+		SaveSession(gomock.Eq("abc"), gomock.Eq([]byte("data")), gomock.Eq(time.Time{})).
+		Return(nil)
+
+	storageProvider.EXPECT().
+		RegenerateSession(gomock.Eq("abc"), gomock.Eq("def"), gomock.Eq(time.Time{})).
+		Return(nil)
+
+	assert.NoError(t, backend.Save([]byte("abc"), []byte("data"), 0))
+	assert.NoError(t, backend.Regenerate([]byte("abc"), []byte("def"), 0))
+}
+
+func TestShouldLockSessionIndexInSQLStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storageProvider := storage.NewMockProvider(ctrl)
+
+	provider := &Provider{backend: newSQLBackend(storageProvider)}
+
+	var token []byte
+
+	gomock.InOrder(
+		storageProvider.EXPECT().
+			LockSession(gomock.Eq("lock:index:john"), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(false, nil),
+		storageProvider.EXPECT().
+			LockSession(gomock.Eq("lock:index:john"), gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(id string, data []byte, expiresAt, now time.Time) (bool, error) {
+				token = data
+
+				assert.Equal(t, now.Add(indexLockTTL), expiresAt)
+
+				return true, nil
+			}),
+		storageProvider.EXPECT().
+			UnlockSession(gomock.Eq("lock:index:john"), gomock.Any()).
+			DoAndReturn(func(id string, data []byte) error {
+				assert.Equal(t, token, data)
+
+				return nil
+			}),
+	)
+
+	unlock, err := provider.lockIndex(testUsername)
+	require.NoError(t, err)
+
+	unlock()
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/fasthttp/session/v2"
 	"github.com/fasthttp/session/v2/providers/redis"
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 	"github.com/tstranex/u2f"
 
@@ -19,7 +20,9 @@ type ProviderConfig struct {
 	config              session.Config
 	redisConfig         *redis.Config
 	redisSentinelConfig *redis.FailoverConfig
+	redisClusterConfig  *goredis.ClusterOptions
 	providerName        string
+	serializer          *EncryptingSerializer
 }
 
 // U2FRegistration is a serializable version of a U2F registration.
@@ -43,6 +46,17 @@ type UserSession struct {
 	FirstFactorAuthnTimestamp  int64
 	SecondFactorAuthnTimestamp int64
 
+	// SecondFactorMethod is the method used to perform the second factor, i.e. totp, u2f or mobile_push.
+	SecondFactorMethod string
+
+	// ImpersonatedBy is the administrator impersonating the user of the session if not null.
+	ImpersonatedBy *Impersonator
+
+	// ClientIP and ClientUserAgentHash identify the client which authenticated the session, the session binding
+	// verifies the requests against them.
+	ClientIP            string
+	ClientUserAgentHash string
+
 	// The challenge generated in first step of U2F registration (after identity verification) or authentication.
 	// This is used reused in the second phase to check that the challenge has been completed.
 	U2FChallenge *u2f.Challenge
@@ -60,6 +74,37 @@ type UserSession struct {
 	RefreshTTL time.Time
 }
 
+// Impersonator is the identity of an administrator impersonating a user, it is restored when the impersonation stops.
+type Impersonator struct {
+	Username    string
+	DisplayName string
+	Groups      []string
+	Emails      []string
+
+	// ExpiresAt is the unix timestamp the impersonation stops at.
+	ExpiresAt int64
+}
+
+// SessionInfo is the information about an active session of a user.
+type SessionInfo struct {
+	// ID identifies the session without disclosing the session ID, it's the SHA256 checksum of the session ID.
+	ID string
+
+	CreatedAt    int64
+	LastActivity int64
+	IP           string
+	UserAgent    string
+}
+
+// sessionIndexEntry is the entry of a session in the index of the sessions of a user.
+type sessionIndexEntry struct {
+	SessionID    string `json:"session_id"`
+	CreatedAt    int64  `json:"created_at"`
+	LastActivity int64  `json:"last_activity"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+}
+
 // Identity identity of the user who is being verified.
 type Identity struct {
 	Username string
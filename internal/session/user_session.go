@@ -2,6 +2,7 @@ package session
 
 import (
 	"errors"
+	"net"
 	"time"
 
 	"github.com/authelia/authelia/v4/internal/authentication"
@@ -31,13 +32,74 @@ func (s *UserSession) SetOneFactor(now time.Time, details *authentication.UserDe
 	s.Emails = details.Emails
 }
 
-// SetTwoFactor sets the expected property values for two factor authentication.
-func (s *UserSession) SetTwoFactor(now time.Time) {
+// SetTwoFactor sets the expected property values for two factor authentication with the method.
+func (s *UserSession) SetTwoFactor(now time.Time, method string) {
 	s.SecondFactorAuthnTimestamp = now.Unix()
+	s.SecondFactorMethod = method
 	s.LastActivity = now.Unix()
 	s.AuthenticationLevel = authentication.TwoFactor
 }
 
+// SetClient records the client which authenticated the session, the session is bound to it when the session binding
+// is configured.
+func (s *UserSession) SetClient(ip net.IP, userAgent []byte) {
+	s.ClientUserAgentHash = hashUserAgent(userAgent)
+
+	if ip == nil {
+		s.ClientIP = ""
+
+		return
+	}
+
+	s.ClientIP = ip.String()
+}
+
+// StartImpersonation replaces the identity of the user with the identity of the impersonated user until the duration
+// elapses. The authentication level of the administrator is kept.
+func (s *UserSession) StartImpersonation(now time.Time, details *authentication.UserDetails, duration time.Duration) {
+	s.ImpersonatedBy = &Impersonator{
+		Username:    s.Username,
+		DisplayName: s.DisplayName,
+		Groups:      s.Groups,
+		Emails:      s.Emails,
+		ExpiresAt:   now.Add(duration).Unix(),
+	}
+
+	s.Username = details.Username
+	s.DisplayName = details.DisplayName
+	s.Groups = details.Groups
+	s.Emails = details.Emails
+}
+
+// StopImpersonation restores the identity of the administrator impersonating the user.
+func (s *UserSession) StopImpersonation() {
+	if s.ImpersonatedBy == nil {
+		return
+	}
+
+	s.Username = s.ImpersonatedBy.Username
+	s.DisplayName = s.ImpersonatedBy.DisplayName
+	s.Groups = s.ImpersonatedBy.Groups
+	s.Emails = s.ImpersonatedBy.Emails
+
+	s.ImpersonatedBy = nil
+}
+
+// IsImpersonationExpired returns true if the user is impersonated and the impersonation has expired.
+func (s UserSession) IsImpersonationExpired(now time.Time) bool {
+	return s.ImpersonatedBy != nil && now.Unix() >= s.ImpersonatedBy.ExpiresAt
+}
+
+// RealUsername returns the username of the user who authenticated the session which is the administrator when a user
+// is impersonated. The sessions are indexed under this username.
+func (s UserSession) RealUsername() string {
+	if s.ImpersonatedBy != nil {
+		return s.ImpersonatedBy.Username
+	}
+
+	return s.Username
+}
+
 // AuthenticatedTime returns the unix timestamp this session authenticated successfully at the given level.
 func (s UserSession) AuthenticatedTime(level authorization.Level) (authenticatedTime time.Time, err error) {
 	switch level {
@@ -2,11 +2,24 @@ package storage
 
 import (
 	"fmt"
+
+	"github.com/authelia/authelia/v4/internal/models"
 )
 
-const storageSchemaCurrentVersion = SchemaVersion(1)
-const storageSchemaUpgradeMessage = "Storage schema upgraded to v"
+const storageSchemaCurrentVersion = SchemaVersion(6)
 const storageSchemaUpgradeErrorText = "storage schema upgrade failed at v"
+const storageSchemaDowngradeErrorText = "storage schema downgrade failed at v"
+
+// storageSchemaMigrationsCategory is the category of the config table the history of the migrations is recorded in.
+const storageSchemaMigrationsCategory = "schema_migrations"
+
+// storageEncryptionCategory is the category of the config table the value checking the encryption key is saved in.
+const storageEncryptionCategory = "encryption"
+const storageEncryptionCheckKey = "check"
+const storageEncryptionCheckValue = "authelia"
+
+// storageSchemaEncryptionVersion is the version of the schema widening the column of the encrypted TOTP secrets.
+const storageSchemaEncryptionVersion = SchemaVersion(5)
 
 // Keep table names in lower case because some DB does not support upper case.
 const userPreferencesTableName = "user_preferences"
@@ -15,24 +28,108 @@ const totpSecretsTableName = "totp_secrets"
 const u2fDeviceHandlesTableName = "u2f_devices"
 const authenticationLogsTableName = "authentication_logs"
 const configTableName = "config"
+const personalAccessTokensTableName = "personal_access_tokens"
+const sessionsTableName = "sessions"
 
-// sqlUpgradeCreateTableStatements is a map of the schema version number, plus a map of the table name and the statement used to create it.
-// The statement is fmt.Sprintf'd with the table name as the first argument.
-var sqlUpgradeCreateTableStatements = map[SchemaVersion]map[string]string{
-	SchemaVersion(1): {
-		userPreferencesTableName:            "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, second_factor_method VARCHAR(11))",
-		identityVerificationTokensTableName: "CREATE TABLE %s (token VARCHAR(512))",
-		totpSecretsTableName:                "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, secret VARCHAR(64))",
-		u2fDeviceHandlesTableName:           "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, keyHandle TEXT, publicKey TEXT)",
-		authenticationLogsTableName:         "CREATE TABLE %s (username VARCHAR(100), successful BOOL, time INTEGER)",
-		configTableName:                     "CREATE TABLE %s (category VARCHAR(32) NOT NULL, key_name VARCHAR(32) NOT NULL, value TEXT, PRIMARY KEY (category, key_name))",
-	},
-}
+// The user agents and the URLs of the requests of the authentication logs are truncated to the size of their columns.
+const authenticationLogUserAgentMaxLength = 512
+const authenticationLogRequestURLMaxLength = 2048
 
-// sqlUpgradesCreateTableIndexesStatements is a map of t he schema version number, plus a slice of statements to create all of the indexes.
-var sqlUpgradesCreateTableIndexesStatements = map[SchemaVersion][]string{
-	SchemaVersion(1): {
-		fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s (username, time)", authenticationLogsTableName),
+// The number of authentication logs returned by a query when no limit is given and the maximum number returned.
+const (
+	AuthenticationLogsDefaultLimit = 50
+	AuthenticationLogsMaxLimit     = 500
+)
+
+// sqlMigrations are the migrations of the schema of the SQL storage providers ordered by version, the migration at
+// index i migrates the schema from v{i} to v{i+1}. The tables are created with their name as the first argument of
+// fmt.Sprintf.
+var sqlMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "Create the initial tables",
+		tables: []migrationTable{
+			{authenticationLogsTableName, "CREATE TABLE %s (username VARCHAR(100), successful BOOL, time INTEGER)"},
+			{configTableName, "CREATE TABLE %s (category VARCHAR(32) NOT NULL, key_name VARCHAR(32) NOT NULL, value TEXT, PRIMARY KEY (category, key_name))"},
+			{identityVerificationTokensTableName, "CREATE TABLE %s (token VARCHAR(512))"},
+			{totpSecretsTableName, "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, secret VARCHAR(64))"},
+			{u2fDeviceHandlesTableName, "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, keyHandle TEXT, publicKey TEXT)"},
+			{userPreferencesTableName, "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, second_factor_method VARCHAR(11))"},
+		},
+		indexes: []migrationIndex{
+			{"usr_time_idx", authenticationLogsTableName, "username, time"},
+		},
+	},
+	{
+		Version:     2,
+		Description: "Create the personal access tokens table",
+		tables: []migrationTable{
+			{personalAccessTokensTableName, "CREATE TABLE %s (id VARCHAR(36) PRIMARY KEY, username VARCHAR(100) NOT NULL, name VARCHAR(100) NOT NULL, token_hash VARCHAR(64) NOT NULL UNIQUE, auth_level INTEGER, domains TEXT, created_at BIGINT, expires_at BIGINT, last_used BIGINT, revoked BOOL)"},
+		},
+	},
+	{
+		Version:     3,
+		Description: "Add the event and the actor of the authentication logs",
+		up: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN event VARCHAR(32) NOT NULL DEFAULT '%s'", authenticationLogsTableName, models.AuthenticationEventFirstFactor),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN actor VARCHAR(100) NOT NULL DEFAULT ''", authenticationLogsTableName),
+		},
+		down: []string{
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN actor", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN event", authenticationLogsTableName),
+		},
+	},
+	{
+		Version:     4,
+		Description: "Create the sessions table",
+		tables: []migrationTable{
+			{sessionsTableName, "CREATE TABLE %s (id VARCHAR(128) PRIMARY KEY, data TEXT NOT NULL, expires_at BIGINT NOT NULL)"},
+		},
+		indexes: []migrationIndex{
+			{"sessions_expires_at_idx", sessionsTableName, "expires_at"},
+		},
+	},
+	{
+		Version:     5,
+		Description: "Widen the TOTP secrets column for the encrypted secrets",
+		// SQLite doesn't enforce the length of the VARCHAR columns.
+		upDialect: map[string][]string{
+			"mysql":    {fmt.Sprintf("ALTER TABLE %s MODIFY secret TEXT", totpSecretsTableName)},
+			"postgres": {fmt.Sprintf("ALTER TABLE %s ALTER COLUMN secret TYPE TEXT", totpSecretsTableName)},
+		},
+		downDialect: map[string][]string{
+			"mysql":    {fmt.Sprintf("ALTER TABLE %s MODIFY secret VARCHAR(64)", totpSecretsTableName)},
+			"postgres": {fmt.Sprintf("ALTER TABLE %s ALTER COLUMN secret TYPE VARCHAR(64)", totpSecretsTableName)},
+		},
+	},
+	{
+		Version:     6,
+		Description: "Add the client, the request and the factor of the authentication logs",
+		up: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN auth_type VARCHAR(16) NOT NULL DEFAULT ''", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN remote_ip VARCHAR(47) NOT NULL DEFAULT ''", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN user_agent VARCHAR(%d) NOT NULL DEFAULT ''", authenticationLogsTableName, authenticationLogUserAgentMaxLength),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN request_method VARCHAR(8) NOT NULL DEFAULT ''", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN request_url VARCHAR(%d) NOT NULL DEFAULT ''", authenticationLogsTableName, authenticationLogRequestURLMaxLength),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN banned BOOL NOT NULL DEFAULT FALSE", authenticationLogsTableName),
+			// The attempts logged before the factor was recorded are all first factor attempts.
+			fmt.Sprintf("UPDATE %s SET auth_type='%s' WHERE event='%s'", authenticationLogsTableName, models.AuthenticationType1FA, models.AuthenticationEventFirstFactor),
+			fmt.Sprintf("CREATE INDEX remote_ip_time_idx ON %s (remote_ip, time)", authenticationLogsTableName),
+		},
+		// The index is dropped before the columns it indexes, MySQL names the table of the index.
+		downDialect: map[string][]string{
+			"mysql":    {fmt.Sprintf("DROP INDEX remote_ip_time_idx ON %s", authenticationLogsTableName)},
+			"postgres": {"DROP INDEX remote_ip_time_idx"},
+			"sqlite":   {"DROP INDEX remote_ip_time_idx"},
+		},
+		down: []string{
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN banned", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN request_url", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN request_method", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN user_agent", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN remote_ip", authenticationLogsTableName),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN auth_type", authenticationLogsTableName),
+		},
 	},
 }
 
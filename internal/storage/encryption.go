@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// ChangeEncryptionKey re-encrypts the TOTP secrets and U2F devices of the database with the new encryption key in a
+// single transaction. The database is encrypted for the first time when it isn't encrypted yet.
+func (p *SQLProvider) ChangeEncryptionKey(encryptionKey string) error {
+	key := newEncryptionKey(encryptionKey)
+	if key == nil {
+		return errors.New("the new storage encryption key must not be empty")
+	}
+
+	value, err := p.getEncryptionCheckValue()
+	if err != nil {
+		return err
+	}
+
+	var previous *[32]byte
+
+	if value != "" {
+		if err = checkEncryptionKey(p.key, value); err != nil {
+			return err
+		}
+
+		previous = p.key
+	}
+
+	if err = p.reencrypt(previous, key); err != nil {
+		return err
+	}
+
+	p.key = key
+
+	return nil
+}
+
+// checkEncryption checks the encryption key is the key the database was encrypted with, and encrypts the database
+// when the encryption key is configured for the first time.
+func (p *SQLProvider) checkEncryption() error {
+	value, err := p.getEncryptionCheckValue()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case value == "" && p.key == nil:
+		return nil
+	case value == "":
+		p.log.Info("Storage encryption key is configured for the first time, the TOTP secrets and U2F devices are being encrypted")
+
+		if err = p.reencrypt(nil, p.key); err == nil {
+			return nil
+		}
+
+		// The database may have been encrypted by another instance started at the same time, in which case the
+		// check value it inserted made the transaction fail.
+		if value, _ = p.getEncryptionCheckValue(); value == "" {
+			return err
+		}
+
+		p.log.Info("Storage encryption was done by another instance, the encryption key is checked instead")
+
+		return checkEncryptionKey(p.key, value)
+	default:
+		return checkEncryptionKey(p.key, value)
+	}
+}
+
+func (p *SQLProvider) getEncryptionCheckValue() (value string, err error) {
+	err = p.db.QueryRow(p.sqlConfigGetValue, storageEncryptionCategory, storageEncryptionCheckKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return value, err
+}
+
+// reencrypt decrypts the TOTP secrets and U2F devices with the previous key and encrypts them with the key, a nil key
+// means the values aren't encrypted.
+func (p *SQLProvider) reencrypt(previous, key *[32]byte) (err error) {
+	value, err := encryptValue(key, []byte(storageEncryptionCheckValue))
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err = p.saveEncryptionCheckValue(tx, previous, value); err != nil {
+		return p.handleReencryptionFailure(tx, err)
+	}
+
+	if err = p.reencryptTOTPSecrets(tx, previous, key); err != nil {
+		return p.handleReencryptionFailure(tx, err)
+	}
+
+	if err = p.reencryptU2FDeviceHandles(tx, previous, key); err != nil {
+		return p.handleReencryptionFailure(tx, err)
+	}
+
+	return tx.Commit()
+}
+
+// saveEncryptionCheckValue saves the check value of the new key before the values are re-encrypted. The database is
+// encrypted for the first time only if the check value can be inserted, i.e. by a single instance when several are
+// started at the same time. Otherwise the check value is locked until the end of the transaction and must still be
+// the one of the previous key.
+func (p *SQLProvider) saveEncryptionCheckValue(tx *sql.Tx, previous *[32]byte, value string) (err error) {
+	if previous == nil {
+		_, err = tx.Exec(p.sqlConfigInsertValue, storageEncryptionCategory, storageEncryptionCheckKey, value)
+
+		return err
+	}
+
+	var current string
+
+	if err = tx.QueryRow(p.sqlConfigGetValueForUpdate, storageEncryptionCategory, storageEncryptionCheckKey).Scan(&current); err != nil {
+		return err
+	}
+
+	if err = checkEncryptionKey(previous, current); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(p.sqlConfigSetValue, storageEncryptionCategory, storageEncryptionCheckKey, value)
+
+	return err
+}
+
+func (p *SQLProvider) reencryptTOTPSecrets(tx *sql.Tx, previous, key *[32]byte) error {
+	rows, err := tx.Query(p.sqlGetTOTPSecrets)
+	if err != nil {
+		return err
+	}
+
+	var secrets [][2]string
+
+	for rows.Next() {
+		var secret [2]string
+
+		if err = rows.Scan(&secret[0], &secret[1]); err != nil {
+			_ = rows.Close()
+
+			return err
+		}
+
+		secrets = append(secrets, secret)
+	}
+
+	// The rows must be closed before the next statement of the transaction on MySQL.
+	if err = rows.Close(); err != nil {
+		return err
+	}
+
+	for _, row := range secrets {
+		username, secret := row[0], row[1]
+
+		if secret, err = decryptTOTPSecret(previous, secret); err != nil {
+			return fmt.Errorf("unable to decrypt the TOTP secret of user %s: %w", username, err)
+		}
+
+		if secret, err = encryptTOTPSecret(key, secret); err != nil {
+			return fmt.Errorf("unable to encrypt the TOTP secret of user %s: %w", username, err)
+		}
+
+		if _, err = tx.Exec(p.sqlUpsertTOTPSecret, username, secret); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *SQLProvider) reencryptU2FDeviceHandles(tx *sql.Tx, previous, key *[32]byte) error {
+	rows, err := tx.Query(p.sqlGetU2FDeviceHandles)
+	if err != nil {
+		return err
+	}
+
+	var devices [][3]string
+
+	for rows.Next() {
+		var device [3]string
+
+		if err = rows.Scan(&device[0], &device[1], &device[2]); err != nil {
+			_ = rows.Close()
+
+			return err
+		}
+
+		devices = append(devices, device)
+	}
+
+	if err = rows.Close(); err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		username := device[0]
+
+		for i := 1; i < len(device); i++ {
+			data, err := decryptValue(previous, device[i])
+			if err != nil {
+				return fmt.Errorf("unable to decrypt the U2F device of user %s: %w", username, err)
+			}
+
+			if device[i], err = encryptValue(key, data); err != nil {
+				return fmt.Errorf("unable to encrypt the U2F device of user %s: %w", username, err)
+			}
+		}
+
+		if _, err = tx.Exec(p.sqlUpsertU2FDeviceHandle, username, device[1], device[2]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *SQLProvider) handleReencryptionFailure(tx *sql.Tx, err error) error {
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("rollback error occurred: %v (inner error %v)", rollbackErr, err)
+	}
+
+	return err
+}
+
+// newEncryptionKey derives the key of the encrypted columns from the encryption key, nil is returned when the
+// encryption key isn't configured in which case the columns aren't encrypted.
+func newEncryptionKey(encryptionKey string) *[32]byte {
+	if encryptionKey == "" {
+		return nil
+	}
+
+	key := sha256.Sum256([]byte(encryptionKey))
+
+	return &key
+}
+
+func checkEncryptionKey(key *[32]byte, value string) error {
+	if key == nil {
+		return ErrEncryptionKeyMissing
+	}
+
+	plaintext, err := decryptValue(key, value)
+	if err != nil || string(plaintext) != storageEncryptionCheckValue {
+		return ErrEncryptionKeyMismatch
+	}
+
+	return nil
+}
+
+// encryptValue encrypts the data with the key when it's not nil and encodes it in base64.
+func encryptValue(key *[32]byte, data []byte) (value string, err error) {
+	if key != nil {
+		if data, err = utils.Encrypt(data, key); err != nil {
+			return "", err
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decryptValue decodes the value from base64 and decrypts it with the key when it's not nil.
+func decryptValue(key *[32]byte, value string) (data []byte, err error) {
+	if data, err = base64.StdEncoding.DecodeString(value); err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return data, nil
+	}
+
+	return utils.Decrypt(data, key)
+}
+
+// encryptTOTPSecret encrypts the TOTP secret with the key, the secret is saved as is when the key is nil.
+func encryptTOTPSecret(key *[32]byte, secret string) (string, error) {
+	if key == nil {
+		return secret, nil
+	}
+
+	return encryptValue(key, []byte(secret))
+}
+
+func decryptTOTPSecret(key *[32]byte, value string) (string, error) {
+	if key == nil {
+		return value, nil
+	}
+
+	secret, err := decryptValue(key, value)
+
+	return string(secret), err
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testEncryptionKey      = "a_very_important_secret_key"
+	testOtherEncryptionKey = "another_very_important_secret_key"
+)
+
+// capturedArg matches any argument and captures its value.
+type capturedArg struct {
+	value string
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	value, ok := v.(string)
+	a.value = value
+
+	return ok
+}
+
+func mustEncryptValue(t *testing.T, encryptionKey string, data string) string {
+	value, err := encryptValue(newEncryptionKey(encryptionKey), []byte(data))
+	require.NoError(t, err)
+
+	return value
+}
+
+func mustDecryptValue(t *testing.T, encryptionKey string, value string) string {
+	data, err := decryptValue(newEncryptionKey(encryptionKey), value)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+// expectReencryption expects the re-encryption of the database which was encrypted with the check value, an empty
+// check value means the database wasn't encrypted.
+func expectReencryption(mock sqlmock.Sqlmock, check string, secrets, devices *sqlmock.Rows, args ...*capturedArg) {
+	mock.ExpectBegin()
+
+	if check == "" {
+		mock.ExpectExec(
+			fmt.Sprintf("INSERT INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+			WithArgs(storageEncryptionCategory, storageEncryptionCheckKey, args[3]).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	} else {
+		mock.ExpectQuery(
+			fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\? FOR UPDATE", configTableName)).
+			WithArgs(storageEncryptionCategory, storageEncryptionCheckKey).
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(check))
+
+		mock.ExpectExec(
+			fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+			WithArgs(storageEncryptionCategory, storageEncryptionCheckKey, args[3]).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, secret FROM %s ORDER BY username", totpSecretsTableName)).
+		WillReturnRows(secrets)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, secret\\) VALUES \\(\\?, \\?\\)", totpSecretsTableName)).
+		WithArgs(unitTestUser, args[0]).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, keyHandle, publicKey FROM %s ORDER BY username", u2fDeviceHandlesTableName)).
+		WillReturnRows(devices)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, keyHandle, publicKey\\) VALUES \\(\\?, \\?, \\?\\)", u2fDeviceHandlesTableName)).
+		WithArgs(unitTestUser, args[1], args[2]).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectCommit()
+}
+
+func TestSQLShouldEncryptDatabaseWhenEncryptionKeyIsConfigured(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.key = newEncryptionKey(testEncryptionKey)
+
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+	expectEncryptionCheck(mock, "")
+
+	args := []*capturedArg{{}, {}, {}, {}}
+
+	expectReencryption(mock, "",
+		sqlmock.NewRows([]string{"username", "secret"}).AddRow(unitTestUser, "abc123"),
+		sqlmock.NewRows([]string{"username", "keyHandle", "publicKey"}).AddRow(unitTestUser, "YWJj", "MTIz"),
+		args...)
+
+	require.NoError(t, provider.initialize(provider.db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, "abc123", mustDecryptValue(t, testEncryptionKey, args[0].value))
+	assert.Equal(t, "abc", mustDecryptValue(t, testEncryptionKey, args[1].value))
+	assert.Equal(t, "123", mustDecryptValue(t, testEncryptionKey, args[2].value))
+	assert.Equal(t, storageEncryptionCheckValue, mustDecryptValue(t, testEncryptionKey, args[3].value))
+}
+
+func TestSQLShouldCheckEncryptionKeyWhenAnotherInstanceEncryptedDatabase(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.key = newEncryptionKey(testEncryptionKey)
+
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+	expectEncryptionCheck(mock, "")
+
+	mock.ExpectBegin()
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs(storageEncryptionCategory, storageEncryptionCheckKey, sqlmock.AnyArg()).
+		WillReturnError(errors.New("duplicate key value violates unique constraint"))
+
+	mock.ExpectRollback()
+
+	expectEncryptionCheck(mock, mustEncryptValue(t, testEncryptionKey, storageEncryptionCheckValue))
+
+	assert.NoError(t, provider.initialize(provider.db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLShouldNotChangeEncryptionKeyChangedByAnotherInstance(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.key = newEncryptionKey(testEncryptionKey)
+
+	expectEncryptionCheck(mock, mustEncryptValue(t, testEncryptionKey, storageEncryptionCheckValue))
+
+	mock.ExpectBegin()
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\? FOR UPDATE", configTableName)).
+		WithArgs(storageEncryptionCategory, storageEncryptionCheckKey).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(mustEncryptValue(t, testOtherEncryptionKey, storageEncryptionCheckValue)))
+
+	mock.ExpectRollback()
+
+	assert.Equal(t, ErrEncryptionKeyMismatch, provider.ChangeEncryptionKey(testOtherEncryptionKey))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLShouldRejectWrongEncryptionKey(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.key = newEncryptionKey(testOtherEncryptionKey)
+
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+	expectEncryptionCheck(mock, mustEncryptValue(t, testEncryptionKey, storageEncryptionCheckValue))
+
+	assert.Equal(t, ErrEncryptionKeyMismatch, provider.initialize(provider.db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLShouldRejectMissingEncryptionKey(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+	expectEncryptionCheck(mock, mustEncryptValue(t, testEncryptionKey, storageEncryptionCheckValue))
+
+	assert.Equal(t, ErrEncryptionKeyMissing, provider.initialize(provider.db))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLShouldChangeEncryptionKey(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.key = newEncryptionKey(testEncryptionKey)
+
+	expectEncryptionCheck(mock, mustEncryptValue(t, testEncryptionKey, storageEncryptionCheckValue))
+
+	args := []*capturedArg{{}, {}, {}, {}}
+
+	expectReencryption(mock, mustEncryptValue(t, testEncryptionKey, storageEncryptionCheckValue),
+		sqlmock.NewRows([]string{"username", "secret"}).
+			AddRow(unitTestUser, mustEncryptValue(t, testEncryptionKey, "abc123")),
+		sqlmock.NewRows([]string{"username", "keyHandle", "publicKey"}).
+			AddRow(unitTestUser, mustEncryptValue(t, testEncryptionKey, "abc"), mustEncryptValue(t, testEncryptionKey, "123")),
+		args...)
+
+	require.NoError(t, provider.ChangeEncryptionKey(testOtherEncryptionKey))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, "abc123", mustDecryptValue(t, testOtherEncryptionKey, args[0].value))
+	assert.Equal(t, "abc", mustDecryptValue(t, testOtherEncryptionKey, args[1].value))
+	assert.Equal(t, "123", mustDecryptValue(t, testOtherEncryptionKey, args[2].value))
+	assert.Equal(t, storageEncryptionCheckValue, mustDecryptValue(t, testOtherEncryptionKey, args[3].value))
+
+	// The TOTP secrets are saved and loaded with the new key.
+	secret := &capturedArg{}
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, secret\\) VALUES \\(\\?, \\?\\)", totpSecretsTableName)).
+		WithArgs(unitTestUser, secret).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, provider.SaveTOTPSecret(unitTestUser, "def456"))
+	assert.NotEqual(t, "def456", secret.value)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT secret FROM %s WHERE username=\\?", totpSecretsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"secret"}).AddRow(secret.value))
+
+	loaded, err := provider.LoadTOTPSecret(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, "def456", loaded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLShouldNotChangeEncryptionKeyWithWrongKey(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.key = newEncryptionKey(testOtherEncryptionKey)
+
+	expectEncryptionCheck(mock, mustEncryptValue(t, testEncryptionKey, storageEncryptionCheckValue))
+
+	assert.Equal(t, ErrEncryptionKeyMismatch, provider.ChangeEncryptionKey("yet_another_very_important_secret_key"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLShouldRunDialectMigrationStatements(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	provider.name = "mysql"
+
+	expectCurrentSchema(mock, "4")
+
+	statements, err := provider.MigrateSchema(5, true)
+	require.NoError(t, err)
+	require.Len(t, statements, 3)
+	assert.Equal(t, "ALTER TABLE totp_secrets MODIFY secret TEXT", statements[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -8,4 +8,13 @@ var (
 
 	// ErrNoTOTPSecret error thrown when no TOTP secret has been found in DB.
 	ErrNoTOTPSecret = errors.New("no TOTP secret registered")
+
+	// ErrNoPersonalAccessToken error thrown when no personal access token has been found in DB.
+	ErrNoPersonalAccessToken = errors.New("no personal access token found")
+
+	// ErrEncryptionKeyMismatch error thrown when the encryption key isn't the key the database was encrypted with.
+	ErrEncryptionKeyMismatch = errors.New("the storage encryption key doesn't match the key the database was encrypted with")
+
+	// ErrEncryptionKeyMissing error thrown when the database is encrypted and no encryption key is configured.
+	ErrEncryptionKeyMissing = errors.New("the TOTP secrets and U2F devices of the database are encrypted but no storage encryption key is configured")
 )
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ExportVersion is the version of the format of the exports of the second factors of the users.
+const ExportVersion = 1
+
+// Import modes deciding what's done with the users of an import who already have second factors in the storage.
+const (
+	ImportModeAbort     = "abort"
+	ImportModeSkip      = "skip"
+	ImportModeOverwrite = "overwrite"
+)
+
+// ExportKeyDerivationArgon2id is the algorithm deriving the key encrypting an export from its encryption key.
+const ExportKeyDerivationArgon2id = "argon2id"
+
+const (
+	exportSaltLength          = 16
+	exportArgon2idIterations  = 3
+	exportArgon2idMemory      = 64 * 1024
+	exportArgon2idParallelism = 4
+
+	// exportArgon2idMaxMemory bounds the memory of the key derivation of the exports which are imported.
+	exportArgon2idMaxMemory = 1024 * 1024
+)
+
+// ErrImportConflicts error thrown when users of an import already have second factors in the storage in the abort
+// import mode.
+var ErrImportConflicts = errors.New("users of the import already have second factors registered")
+
+// Export is an export of the second factors and the preferences of the users. The TOTP secrets and the U2F devices are
+// encrypted with a key derived from the encryption key of the export when Encryption isn't nil.
+type Export struct {
+	Version    int               `yaml:"version" json:"version"`
+	Encryption *ExportEncryption `yaml:"encryption,omitempty" json:"encryption,omitempty"`
+	Users      []ExportUser      `yaml:"users" json:"users"`
+}
+
+// ExportEncryption is the key derivation of an encrypted export, the salt is encoded in base64 and the memory is in
+// KiB.
+type ExportEncryption struct {
+	KeyDerivation string `yaml:"key_derivation" json:"key_derivation"`
+	Salt          string `yaml:"salt" json:"salt"`
+	Iterations    uint32 `yaml:"iterations" json:"iterations"`
+	Memory        uint32 `yaml:"memory" json:"memory"`
+	Parallelism   uint8  `yaml:"parallelism" json:"parallelism"`
+}
+
+// ExportUser is the export of the second factors and the preferences of a user.
+type ExportUser struct {
+	Username           string           `yaml:"username" json:"username"`
+	Preferred2FAMethod string           `yaml:"preferred_2fa_method,omitempty" json:"preferred_2fa_method,omitempty"`
+	TOTPSecret         string           `yaml:"totp_secret,omitempty" json:"totp_secret,omitempty"`
+	U2FDevice          *ExportU2FDevice `yaml:"u2f_device,omitempty" json:"u2f_device,omitempty"`
+}
+
+// ExportU2FDevice is the export of the U2F device of a user, the key handle and the public key are encoded in base64.
+type ExportU2FDevice struct {
+	KeyHandle string `yaml:"key_handle" json:"key_handle"`
+	PublicKey string `yaml:"public_key" json:"public_key"`
+}
+
+// ImportResult is the result of an import, it lists the usernames of the users imported, skipped or conflicting with
+// the users of the storage.
+type ImportResult struct {
+	Imported  []string
+	Skipped   []string
+	Conflicts []string
+}
+
+// ExportSecondFactors exports the second factors and the preferences of the users of the provider. The TOTP secrets
+// and the U2F devices are encrypted in the export when the encryption key isn't empty.
+func ExportSecondFactors(provider Provider, encryptionKey string) (export *Export, err error) {
+	users, err := provider.LoadSecondFactors()
+	if err != nil {
+		return nil, err
+	}
+
+	export = &Export{
+		Version: ExportVersion,
+		Users:   make([]ExportUser, 0, len(users)),
+	}
+
+	var key *[32]byte
+
+	if encryptionKey != "" {
+		if export.Encryption, err = newExportEncryption(); err != nil {
+			return nil, err
+		}
+
+		if key, err = export.Encryption.key(encryptionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var user ExportUser
+
+	for _, factors := range users {
+		if user, err = encryptExportUser(key, factors); err != nil {
+			return nil, fmt.Errorf("unable to export the second factors of user %s: %w", factors.Username, err)
+		}
+
+		export.Users = append(export.Users, user)
+	}
+
+	return export, nil
+}
+
+// ImportSecondFactors imports the second factors and the preferences of the users of the export into the provider in
+// a single transaction. The users who already have a second factor or a preferred 2FA method are conflicting, they
+// abort the import, are skipped or have all their second factors replaced depending on the mode. The export is
+// entirely decrypted and checked before any user is imported.
+func ImportSecondFactors(provider Provider, export *Export, encryptionKey, mode string) (result ImportResult, err error) {
+	if mode != ImportModeAbort && mode != ImportModeSkip && mode != ImportModeOverwrite {
+		return result, fmt.Errorf("unknown import mode '%s', it must be '%s', '%s' or '%s'", mode, ImportModeAbort, ImportModeSkip, ImportModeOverwrite)
+	}
+
+	if export.Version != ExportVersion {
+		return result, fmt.Errorf("unsupported export version %d, the supported version is %d", export.Version, ExportVersion)
+	}
+
+	var key *[32]byte
+
+	if export.Encryption != nil {
+		if encryptionKey == "" {
+			return result, errors.New("the export is encrypted, its encryption key must be provided")
+		}
+
+		if key, err = export.Encryption.key(encryptionKey); err != nil {
+			return result, err
+		}
+	}
+
+	users := make([]SecondFactors, len(export.Users))
+	usernames := make(map[string]bool, len(export.Users))
+
+	for i, user := range export.Users {
+		switch {
+		case user.Username == "":
+			return result, fmt.Errorf("the user at index %d of the export has no username", i)
+		case usernames[user.Username]:
+			return result, fmt.Errorf("the user %s is exported more than once", user.Username)
+		}
+
+		usernames[user.Username] = true
+
+		if users[i], err = decryptExportUser(key, user); err != nil {
+			return result, fmt.Errorf("unable to decrypt the second factors of user %s, the encryption key may be wrong: %w", user.Username, err)
+		}
+	}
+
+	return provider.SaveSecondFactors(users, mode)
+}
+
+func newExportEncryption() (encryption *ExportEncryption, err error) {
+	salt := make([]byte, exportSaltLength)
+
+	if _, err = rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("unable to generate the salt of the export: %w", err)
+	}
+
+	return &ExportEncryption{
+		KeyDerivation: ExportKeyDerivationArgon2id,
+		Salt:          base64.StdEncoding.EncodeToString(salt),
+		Iterations:    exportArgon2idIterations,
+		Memory:        exportArgon2idMemory,
+		Parallelism:   exportArgon2idParallelism,
+	}, nil
+}
+
+// key derives the key encrypting the export from the encryption key.
+func (e ExportEncryption) key(encryptionKey string) (key *[32]byte, err error) {
+	if e.KeyDerivation != ExportKeyDerivationArgon2id {
+		return nil, fmt.Errorf("unsupported key derivation '%s' of the export, the supported key derivation is '%s'", e.KeyDerivation, ExportKeyDerivationArgon2id)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("unable to decode the salt of the export: %w", err)
+	case len(salt) < exportSaltLength:
+		return nil, fmt.Errorf("the salt of the export must be at least %d bytes long", exportSaltLength)
+	case e.Iterations == 0 || e.Parallelism == 0 || e.Memory == 0 || e.Memory > exportArgon2idMaxMemory:
+		return nil, fmt.Errorf("invalid key derivation parameters of the export, the iterations and the parallelism must be positive and the memory between 1 and %d KiB", exportArgon2idMaxMemory)
+	}
+
+	key = new([32]byte)
+
+	copy(key[:], argon2.IDKey([]byte(encryptionKey), salt, e.Iterations, e.Memory, e.Parallelism, uint32(len(key))))
+
+	return key, nil
+}
+
+func encryptExportUser(key *[32]byte, factors SecondFactors) (user ExportUser, err error) {
+	user.Username = factors.Username
+	user.Preferred2FAMethod = factors.Preferred2FAMethod
+
+	if factors.TOTPSecret != "" {
+		if user.TOTPSecret, err = encryptTOTPSecret(key, factors.TOTPSecret); err != nil {
+			return user, err
+		}
+	}
+
+	if factors.KeyHandle != nil {
+		user.U2FDevice = &ExportU2FDevice{}
+
+		if user.U2FDevice.KeyHandle, err = encryptValue(key, factors.KeyHandle); err != nil {
+			return user, err
+		}
+
+		if user.U2FDevice.PublicKey, err = encryptValue(key, factors.PublicKey); err != nil {
+			return user, err
+		}
+	}
+
+	return user, nil
+}
+
+func decryptExportUser(key *[32]byte, user ExportUser) (factors SecondFactors, err error) {
+	factors.Username = user.Username
+	factors.Preferred2FAMethod = user.Preferred2FAMethod
+
+	if user.TOTPSecret != "" {
+		if factors.TOTPSecret, err = decryptTOTPSecret(key, user.TOTPSecret); err != nil {
+			return factors, err
+		}
+	}
+
+	if user.U2FDevice != nil {
+		if factors.KeyHandle, err = decryptValue(key, user.U2FDevice.KeyHandle); err != nil {
+			return factors, err
+		}
+
+		if factors.PublicKey, err = decryptValue(key, user.U2FDevice.PublicKey); err != nil {
+			return factors, err
+		}
+	}
+
+	return factors, nil
+}
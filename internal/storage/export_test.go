@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecondFactors = []SecondFactors{
+	{Username: "harry", KeyHandle: []byte("abc"), PublicKey: []byte("123")},
+	{Username: "john", Preferred2FAMethod: "totp", TOTPSecret: "JBSWY3DPEHPK3PXP"},
+}
+
+func TestShouldExportSecondFactors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+	provider.EXPECT().LoadSecondFactors().Return(testSecondFactors, nil)
+
+	export, err := ExportSecondFactors(provider, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, &Export{
+		Version: ExportVersion,
+		Users: []ExportUser{
+			{Username: "harry", U2FDevice: &ExportU2FDevice{KeyHandle: "YWJj", PublicKey: "MTIz"}},
+			{Username: "john", Preferred2FAMethod: "totp", TOTPSecret: "JBSWY3DPEHPK3PXP"},
+		},
+	}, export)
+}
+
+func TestShouldImportEncryptedExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	source := NewMockProvider(ctrl)
+	source.EXPECT().LoadSecondFactors().Return(testSecondFactors, nil).Times(2)
+
+	export, err := ExportSecondFactors(source, testEncryptionKey)
+	require.NoError(t, err)
+
+	require.NotNil(t, export.Encryption)
+	assert.Equal(t, ExportKeyDerivationArgon2id, export.Encryption.KeyDerivation)
+	assert.NotEqual(t, "JBSWY3DPEHPK3PXP", export.Users[1].TOTPSecret)
+	assert.NotEqual(t, "YWJj", export.Users[0].U2FDevice.KeyHandle)
+
+	// Each export is encrypted with a key derived with its own salt.
+	other, err := ExportSecondFactors(source, testEncryptionKey)
+	require.NoError(t, err)
+	assert.NotEqual(t, export.Encryption.Salt, other.Encryption.Salt)
+
+	_, err = ImportSecondFactors(NewMockProvider(ctrl), export, "", ImportModeAbort)
+	assert.EqualError(t, err, "the export is encrypted, its encryption key must be provided")
+
+	_, err = ImportSecondFactors(NewMockProvider(ctrl), export, testOtherEncryptionKey, ImportModeAbort)
+	assert.EqualError(t, err, "unable to decrypt the second factors of user harry, the encryption key may be wrong: cipher: message authentication failed")
+
+	destination := NewMockProvider(ctrl)
+	destination.EXPECT().
+		SaveSecondFactors(testSecondFactors, ImportModeAbort).
+		Return(ImportResult{Imported: []string{"harry", "john"}}, nil)
+
+	result, err := ImportSecondFactors(destination, export, testEncryptionKey, ImportModeAbort)
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{Imported: []string{"harry", "john"}}, result)
+}
+
+func TestShouldRejectInvalidImports(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := NewMockProvider(ctrl)
+
+	_, err := ImportSecondFactors(provider, &Export{Version: ExportVersion}, "", "replace")
+	assert.EqualError(t, err, "unknown import mode 'replace', it must be 'abort', 'skip' or 'overwrite'")
+
+	_, err = ImportSecondFactors(provider, &Export{Version: 2}, "", ImportModeAbort)
+	assert.EqualError(t, err, "unsupported export version 2, the supported version is 1")
+
+	_, err = ImportSecondFactors(provider, &Export{Version: ExportVersion, Users: []ExportUser{{}}}, "", ImportModeAbort)
+	assert.EqualError(t, err, "the user at index 0 of the export has no username")
+
+	_, err = ImportSecondFactors(provider, &Export{Version: ExportVersion, Users: []ExportUser{{Username: "john"}, {Username: "john"}}}, "", ImportModeAbort)
+	assert.EqualError(t, err, "the user john is exported more than once")
+
+	encryption := &ExportEncryption{KeyDerivation: "sha256", Salt: "c2FsdHNhbHRzYWx0c2FsdA==", Iterations: 1, Memory: 1024, Parallelism: 1}
+
+	_, err = ImportSecondFactors(provider, &Export{Version: ExportVersion, Encryption: encryption}, testEncryptionKey, ImportModeAbort)
+	assert.EqualError(t, err, "unsupported key derivation 'sha256' of the export, the supported key derivation is 'argon2id'")
+
+	encryption.KeyDerivation, encryption.Salt = ExportKeyDerivationArgon2id, "c2FsdA=="
+
+	_, err = ImportSecondFactors(provider, &Export{Version: ExportVersion, Encryption: encryption}, testEncryptionKey, ImportModeAbort)
+	assert.EqualError(t, err, "the salt of the export must be at least 16 bytes long")
+
+	encryption.Salt, encryption.Memory = "c2FsdHNhbHRzYWx0c2FsdA==", 4*1024*1024
+
+	_, err = ImportSecondFactors(provider, &Export{Version: ExportVersion, Encryption: encryption}, testEncryptionKey, ImportModeAbort)
+	assert.EqualError(t, err, "invalid key derivation parameters of the export, the iterations and the parallelism must be positive and the memory between 1 and 1048576 KiB")
+}
@@ -0,0 +1,368 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/authelia/authelia/v4/internal/utils"
+)
+
+// Migrations returns the migrations of the schema of the SQL storage providers ordered by version.
+func Migrations() []Migration {
+	return sqlMigrations
+}
+
+// LatestSchemaVersion returns the version of the schema this version of Authelia migrates to.
+func LatestSchemaVersion() SchemaVersion {
+	return storageSchemaCurrentVersion
+}
+
+// SchemaVersion returns the version of the schema of the database, 0 if the schema isn't created.
+func (p *SQLProvider) SchemaVersion() (version SchemaVersion, err error) {
+	version, _, err = p.getSchemaBasicDetails()
+
+	return version, err
+}
+
+// MigrateSchema migrates the schema up or down to the target version in a single transaction and returns the
+// statements of the migrations. The statements are only returned and not run when dryRun is true. The MySQL
+// statements altering the schema can't be rolled back when a migration fails since MySQL commits them implicitly.
+func (p *SQLProvider) MigrateSchema(target SchemaVersion, dryRun bool) (statements []string, err error) {
+	if target < 0 || target > storageSchemaCurrentVersion {
+		return nil, fmt.Errorf("storage schema v%d doesn't exist, the latest version is v%d", target, storageSchemaCurrentVersion)
+	}
+
+	version, tables, err := p.getSchemaBasicDetails()
+	if err != nil {
+		return nil, err
+	}
+
+	if version > storageSchemaCurrentVersion {
+		return nil, fmt.Errorf("storage schema v%d is newer than the latest version v%d known to this version of Authelia", version, storageSchemaCurrentVersion)
+	}
+
+	return p.migrate(version, target, tables, dryRun)
+}
+
+func (p *SQLProvider) migrate(version, target SchemaVersion, tables []string, dryRun bool) (statements []string, err error) {
+	if version == target {
+		return nil, nil
+	}
+
+	mtx := &migrationTransaction{dryRun: dryRun, tx: p.db}
+
+	var tx *sql.Tx
+
+	if !dryRun {
+		if tx, err = p.db.Begin(); err != nil {
+			return nil, err
+		}
+
+		mtx.tx = tx
+	}
+
+	now := time.Now()
+
+	if target > version {
+		for i, migration := range sqlMigrations[version:target] {
+			if err = p.migrateUp(mtx, migration, tables); err != nil {
+				return nil, p.handleMigrationFailure(tx, storageSchemaUpgradeErrorText, migration.Version, err)
+			}
+
+			if err = p.migrationFinalize(mtx, now, i, migration.Version-1, migration.Version); err != nil {
+				return nil, p.handleMigrationFailure(tx, storageSchemaUpgradeErrorText, migration.Version, err)
+			}
+		}
+	} else {
+		// The encryption is checked before any migration is reverted since MySQL can't roll back the reverted ones.
+		if version >= storageSchemaEncryptionVersion && target < storageSchemaEncryptionVersion {
+			if err = p.checkUnencrypted(mtx); err != nil {
+				return nil, p.handleMigrationFailure(tx, storageSchemaDowngradeErrorText, storageSchemaEncryptionVersion, err)
+			}
+		}
+
+		for i := 0; version-SchemaVersion(i) > target; i++ {
+			migration := sqlMigrations[version-SchemaVersion(i)-1]
+
+			if err = p.migrateDown(mtx, migration); err != nil {
+				return nil, p.handleMigrationFailure(tx, storageSchemaDowngradeErrorText, migration.Version, err)
+			}
+
+			// The config table is dropped by the migration to v1.
+			if migration.Version == 1 {
+				continue
+			}
+
+			if err = p.migrationFinalize(mtx, now, i, migration.Version, migration.Version-1); err != nil {
+				return nil, p.handleMigrationFailure(tx, storageSchemaDowngradeErrorText, migration.Version, err)
+			}
+		}
+	}
+
+	if dryRun {
+		return mtx.statements, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	p.log.Infof("Storage schema migration from v%d to v%d completed", version, target)
+
+	return mtx.statements, nil
+}
+
+// SchemaMigrationHistory returns the history of the migrations of the schema, the oldest first.
+func (p *SQLProvider) SchemaMigrationHistory() (records []MigrationRecord, err error) {
+	version, _, err := p.getSchemaBasicDetails()
+	if err != nil || version == 0 {
+		return nil, err
+	}
+
+	rows, err := p.db.Query(p.sqlConfigGetValuesByCategory, storageSchemaMigrationsCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	type entry struct {
+		record MigrationRecord
+		step   int
+	}
+
+	var (
+		key, value string
+		entries    []entry
+	)
+
+	for rows.Next() {
+		if err = rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+
+		record, step, err := parseMigrationRecord(key, value)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry{record, step})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].record.Time.Equal(entries[j].record.Time) {
+			return entries[i].step < entries[j].step
+		}
+
+		return entries[i].record.Time.Before(entries[j].record.Time)
+	})
+
+	for _, e := range entries {
+		records = append(records, e.record)
+	}
+
+	return records, rows.Err()
+}
+
+// Close closes the connection to the database.
+func (p *SQLProvider) Close() error {
+	return p.db.Close()
+}
+
+func (p *SQLProvider) migrateUp(tx transaction, migration Migration, tables []string) (err error) {
+	for _, table := range migration.tables {
+		if utils.IsStringInSlice(table.name, tables) {
+			continue
+		}
+
+		if _, err = tx.Exec(fmt.Sprintf(table.statement, table.name)); err != nil {
+			return fmt.Errorf("unable to create table %s: %v", table.name, err)
+		}
+	}
+
+	for _, index := range migration.indexes {
+		// The indexes of the tables created by the migration can't exist yet.
+		if utils.IsStringInSlice(index.table, tables) {
+			var count int
+
+			if err = tx.QueryRow(p.sqlGetIndexExistence, index.table, index.name).Scan(&count); err != nil {
+				return fmt.Errorf("unable to check the existence of index %s: %v", index.name, err)
+			}
+
+			if count != 0 {
+				continue
+			}
+		}
+
+		if _, err = tx.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (%s)", index.name, index.table, index.columns)); err != nil {
+			return fmt.Errorf("unable to create index %s: %v", index.name, err)
+		}
+	}
+
+	for _, statement := range append(migration.up, migration.upDialect[p.name]...) {
+		if _, err = tx.Exec(statement); err != nil {
+			return fmt.Errorf("unable to alter table: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *SQLProvider) migrateDown(tx transaction, migration Migration) (err error) {
+	for _, statement := range append(migration.downDialect[p.name], migration.down...) {
+		if _, err = tx.Exec(statement); err != nil {
+			return fmt.Errorf("unable to alter table: %v", err)
+		}
+	}
+
+	for i := len(migration.indexes) - 1; i >= 0; i-- {
+		index := migration.indexes[i]
+
+		// The indexes of the tables of the migration are dropped with the tables.
+		if migration.hasTable(index.table) {
+			continue
+		}
+
+		if _, err = tx.Exec(fmt.Sprintf(p.sqlDropIndex, index.name, index.table)); err != nil {
+			return fmt.Errorf("unable to drop index %s: %v", index.name, err)
+		}
+	}
+
+	for i := len(migration.tables) - 1; i >= 0; i-- {
+		if _, err = tx.Exec(fmt.Sprintf("DROP TABLE %s", migration.tables[i].name)); err != nil {
+			return fmt.Errorf("unable to drop table %s: %v", migration.tables[i].name, err)
+		}
+	}
+
+	return nil
+}
+
+// migrationFinalize sets the schema version and records the migration in the history of the migrations.
+func (p *SQLProvider) migrationFinalize(tx transaction, start time.Time, step int, before, after SchemaVersion) (err error) {
+	if _, err = tx.Exec(p.sqlConfigSetValue, "schema", "version", after.ToString()); err != nil {
+		return err
+	}
+
+	// The key orders the migrations of a single run which share the time of the run.
+	key := fmt.Sprintf("%d.%d", start.UnixNano(), step)
+	value := fmt.Sprintf("%d,%d,%s", before, after, utils.Version())
+
+	if _, err = tx.Exec(p.sqlConfigSetValue, storageSchemaMigrationsCategory, key, value); err != nil {
+		return err
+	}
+
+	p.log.Debugf("Storage schema migrated from v%d to v%d", before, after)
+
+	return nil
+}
+
+// checkUnencrypted refuses to revert the migration widening the column of the TOTP secrets while the database is
+// encrypted, the encrypted secrets don't fit in the narrower column and MySQL may truncate them depending on its SQL
+// mode. The older versions of Authelia can't read the encrypted data anyway.
+func (p *SQLProvider) checkUnencrypted(tx transaction) (err error) {
+	var value string
+
+	err = tx.QueryRow(p.sqlConfigGetValue, storageEncryptionCategory, storageEncryptionCheckKey).Scan(&value)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return nil
+	case err != nil:
+		return fmt.Errorf("unable to check the encryption of the database: %v", err)
+	default:
+		return errors.New("the TOTP secrets and U2F devices are encrypted and can't be read by the older versions of Authelia")
+	}
+}
+
+func (p *SQLProvider) handleMigrationFailure(tx *sql.Tx, text string, version SchemaVersion, err error) error {
+	formattedErr := fmt.Errorf("%s%d: %v", text, version, err)
+
+	if tx == nil {
+		return formattedErr
+	}
+
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("rollback error occurred: %v (inner error %v)", rollbackErr, formattedErr)
+	}
+
+	return formattedErr
+}
+
+func (m Migration) hasTable(name string) bool {
+	for _, table := range m.tables {
+		if table.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseMigrationRecord(key, value string) (record MigrationRecord, step int, err error) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 {
+		return record, 0, fmt.Errorf("unable to parse the schema migration %s: the key is malformed", key)
+	}
+
+	nanoseconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return record, 0, fmt.Errorf("unable to parse the schema migration %s: %w", key, err)
+	}
+
+	if step, err = strconv.Atoi(parts[1]); err != nil {
+		return record, 0, fmt.Errorf("unable to parse the schema migration %s: %w", key, err)
+	}
+
+	if parts = strings.SplitN(value, ",", 3); len(parts) != 3 {
+		return record, 0, fmt.Errorf("unable to parse the schema migration %s: the value %s is malformed", key, value)
+	}
+
+	before, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return record, 0, fmt.Errorf("unable to parse the schema migration %s: %w", key, err)
+	}
+
+	after, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return record, 0, fmt.Errorf("unable to parse the schema migration %s: %w", key, err)
+	}
+
+	return MigrationRecord{
+		Time:            time.Unix(0, nanoseconds),
+		Before:          SchemaVersion(before),
+		After:           SchemaVersion(after),
+		AutheliaVersion: parts[2],
+	}, step, nil
+}
+
+// migrationTransaction records the statements of the migrations, the statements are only recorded and not run in dry
+// run mode while the queries checking the schema are still run.
+type migrationTransaction struct {
+	tx         transaction
+	dryRun     bool
+	statements []string
+}
+
+func (t *migrationTransaction) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if len(args) == 0 {
+		t.statements = append(t.statements, query)
+	} else {
+		t.statements = append(t.statements, fmt.Sprintf("%s %v", query, args))
+	}
+
+	if t.dryRun {
+		return driver.RowsAffected(0), nil
+	}
+
+	return t.tx.Exec(query, args...)
+}
+
+func (t *migrationTransaction) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRow(query, args...)
+}
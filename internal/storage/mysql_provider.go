@@ -8,6 +8,7 @@ import (
 	_ "github.com/go-sql-driver/mysql" // Load the MySQL Driver used in the connection string.
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/logging"
 )
 
 // MySQLProvider is a MySQL provider.
@@ -16,39 +17,77 @@ type MySQLProvider struct {
 }
 
 // NewMySQLProvider a MySQL provider.
-func NewMySQLProvider(configuration schema.MySQLStorageConfiguration) *MySQLProvider {
+func NewMySQLProvider(configuration schema.MySQLStorageConfiguration, encryptionKey string) *MySQLProvider {
+	provider := newMySQLProvider(configuration, encryptionKey)
+
+	if err := provider.initialize(provider.db); err != nil {
+		provider.log.Fatalf("Unable to initialize SQL database: %v", err)
+	}
+
+	return provider
+}
+
+func newMySQLProvider(configuration schema.MySQLStorageConfiguration, encryptionKey string) *MySQLProvider {
 	provider := MySQLProvider{
 		SQLProvider{
 			name: "mysql",
-
-			sqlUpgradesCreateTableStatements: sqlUpgradeCreateTableStatements,
+			log:  logging.Logger(),
+			key:  newEncryptionKey(encryptionKey),
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=?", userPreferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("REPLACE INTO %s (username, second_factor_method) VALUES (?, ?)", userPreferencesTableName),
+			sqlDeleteSecondFactorPreference: fmt.Sprintf("DELETE FROM %s WHERE username=?", userPreferencesTableName),
 
 			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=?)", identityVerificationTokensTableName),
 			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES (?)", identityVerificationTokensTableName),
 			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
 
 			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=?", totpSecretsTableName),
+			sqlGetTOTPSecrets:          fmt.Sprintf("SELECT username, secret FROM %s ORDER BY username", totpSecretsTableName),
 			sqlUpsertTOTPSecret:        fmt.Sprintf("REPLACE INTO %s (username, secret) VALUES (?, ?)", totpSecretsTableName),
 			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpSecretsTableName),
 
 			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=?", u2fDeviceHandlesTableName),
+			sqlGetU2FDeviceHandles:          fmt.Sprintf("SELECT username, keyHandle, publicKey FROM %s ORDER BY username", u2fDeviceHandlesTableName),
 			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("REPLACE INTO %s (username, keyHandle, publicKey) VALUES (?, ?, ?)", u2fDeviceHandlesTableName),
-
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES (?, ?, ?)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlDeleteU2FDeviceHandle:        fmt.Sprintf("DELETE FROM %s WHERE username=?", u2fDeviceHandlesTableName),
+
+			sqlGetSecondFactorUsernames: fmt.Sprintf("SELECT username FROM %s UNION SELECT username FROM %s UNION SELECT username FROM %s ORDER BY username", totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName),
+			sqlGetSecondFactors:         fmt.Sprintf("SELECT u.username, p.second_factor_method, t.secret, d.keyHandle, d.publicKey FROM (SELECT username FROM %[1]s UNION SELECT username FROM %[2]s UNION SELECT username FROM %[3]s) AS u LEFT JOIN %[3]s AS p ON p.username=u.username LEFT JOIN %[1]s AS t ON t.username=u.username LEFT JOIN %[2]s AS d ON d.username=u.username ORDER BY u.username", totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName),
+
+			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? AND auth_type IN (?, ?) AND banned=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetAuthenticationLogs:       fmt.Sprintf("SELECT username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned FROM %s WHERE (?='' OR username=?) AND (?='' OR remote_ip=?) AND (?=0 OR time>=?) AND (?=0 OR time<=?) ORDER BY time DESC LIMIT ? OFFSET ?", authenticationLogsTableName),
+
+			sqlInsertPersonalAccessToken:         fmt.Sprintf("INSERT INTO %s (id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", personalAccessTokensTableName),
+			sqlGetPersonalAccessTokenByHash:      fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE token_hash=?", personalAccessTokensTableName),
+			sqlGetPersonalAccessTokensByUsername: fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE username=? ORDER BY created_at", personalAccessTokensTableName),
+			sqlRevokePersonalAccessToken:         fmt.Sprintf("UPDATE %s SET revoked=TRUE WHERE id=? AND username=?", personalAccessTokensTableName),
+			sqlUpdatePersonalAccessTokenLastUsed: fmt.Sprintf("UPDATE %s SET last_used=? WHERE id=?", personalAccessTokensTableName),
+
+			sqlUpsertSession:            fmt.Sprintf("REPLACE INTO %s (id, data, expires_at) VALUES (?, ?, ?)", sessionsTableName),
+			sqlGetSessionByID:           fmt.Sprintf("SELECT data FROM %s WHERE id=? AND (expires_at=0 OR expires_at>?)", sessionsTableName),
+			sqlUpdateSessionID:          fmt.Sprintf("UPDATE %s SET id=?, expires_at=? WHERE id=?", sessionsTableName),
+			sqlDeleteSession:            fmt.Sprintf("DELETE FROM %s WHERE id=?", sessionsTableName),
+			sqlCountSessions:            fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE expires_at=0 OR expires_at>?", sessionsTableName),
+			sqlDeleteExpiredSessions:    fmt.Sprintf("DELETE FROM %s WHERE expires_at<>0 AND expires_at<=?", sessionsTableName),
+			sqlInsertSessionIfNotExists: fmt.Sprintf("INSERT IGNORE INTO %s (id, data, expires_at) VALUES (?, ?, ?)", sessionsTableName),
+			sqlDeleteExpiredSessionByID: fmt.Sprintf("DELETE FROM %s WHERE id=? AND expires_at<>0 AND expires_at<=?", sessionsTableName),
+			sqlDeleteSessionByData:      fmt.Sprintf("DELETE FROM %s WHERE id=? AND data=?", sessionsTableName),
 
 			sqlGetExistingTables: "SELECT table_name FROM information_schema.tables WHERE table_type='BASE TABLE' AND table_schema=database()",
 
-			sqlConfigSetValue: fmt.Sprintf("REPLACE INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
-			sqlConfigGetValue: fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=?", configTableName),
+			sqlGetIndexExistence: "SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema=database() AND table_name=? AND index_name=?",
+			sqlDropIndex:         "DROP INDEX %[1]s ON %[2]s",
+
+			sqlConfigSetValue:            fmt.Sprintf("REPLACE INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
+			sqlConfigInsertValue:         fmt.Sprintf("INSERT INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
+			sqlConfigGetValue:            fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=?", configTableName),
+			sqlConfigGetValueForUpdate:   fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=? FOR UPDATE", configTableName),
+			sqlConfigGetValuesByCategory: fmt.Sprintf("SELECT key_name, value FROM %s WHERE category=?", configTableName),
 		},
 	}
 
-	provider.sqlUpgradesCreateTableStatements[SchemaVersion(1)][authenticationLogsTableName] = "CREATE TABLE %s (username VARCHAR(100), successful BOOL, time INTEGER, INDEX usr_time_idx (username, time))"
-
 	connectionString := configuration.Username
 
 	if configuration.Password != "" {
@@ -77,9 +116,7 @@ func NewMySQLProvider(configuration schema.MySQLStorageConfiguration) *MySQLProv
 		provider.log.Fatalf("Unable to connect to SQL database: %v", err)
 	}
 
-	if err := provider.initialize(db); err != nil {
-		provider.log.Fatalf("Unable to initialize SQL database: %v", err)
-	}
+	provider.db = db
 
 	return &provider
 }
@@ -9,6 +9,7 @@ import (
 	_ "github.com/jackc/pgx/v4/stdlib" // Load the PostgreSQL Driver used in the connection string.
 
 	"github.com/authelia/authelia/v4/internal/configuration/schema"
+	"github.com/authelia/authelia/v4/internal/logging"
 )
 
 // PostgreSQLProvider is a PostgreSQL provider.
@@ -17,35 +18,74 @@ type PostgreSQLProvider struct {
 }
 
 // NewPostgreSQLProvider a PostgreSQL provider.
-func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration) *PostgreSQLProvider {
+func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration, encryptionKey string) *PostgreSQLProvider {
+	provider := newPostgreSQLProvider(configuration, encryptionKey)
+
+	if err := provider.initialize(provider.db); err != nil {
+		provider.log.Fatalf("Unable to initialize SQL database: %v", err)
+	}
+
+	return provider
+}
+
+func newPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration, encryptionKey string) *PostgreSQLProvider {
 	provider := PostgreSQLProvider{
 		SQLProvider{
 			name: "postgres",
-
-			sqlUpgradesCreateTableStatements:        sqlUpgradeCreateTableStatements,
-			sqlUpgradesCreateTableIndexesStatements: sqlUpgradesCreateTableIndexesStatements,
+			log:  logging.Logger(),
+			key:  newEncryptionKey(encryptionKey),
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=$1", userPreferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("INSERT INTO %s (username, second_factor_method) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET second_factor_method=$2", userPreferencesTableName),
+			sqlDeleteSecondFactorPreference: fmt.Sprintf("DELETE FROM %s WHERE username=$1", userPreferencesTableName),
 
 			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=$1)", identityVerificationTokensTableName),
 			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES ($1)", identityVerificationTokensTableName),
 			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=$1", identityVerificationTokensTableName),
 
 			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=$1", totpSecretsTableName),
+			sqlGetTOTPSecrets:          fmt.Sprintf("SELECT username, secret FROM %s ORDER BY username", totpSecretsTableName),
 			sqlUpsertTOTPSecret:        fmt.Sprintf("INSERT INTO %s (username, secret) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET secret=$2", totpSecretsTableName),
 			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=$1", totpSecretsTableName),
 
 			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=$1", u2fDeviceHandlesTableName),
+			sqlGetU2FDeviceHandles:          fmt.Sprintf("SELECT username, keyHandle, publicKey FROM %s ORDER BY username", u2fDeviceHandlesTableName),
 			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("INSERT INTO %s (username, keyHandle, publicKey) VALUES ($1, $2, $3) ON CONFLICT (username) DO UPDATE SET keyHandle=$2, publicKey=$3", u2fDeviceHandlesTableName),
-
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES ($1, $2, $3)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", authenticationLogsTableName),
+			sqlDeleteU2FDeviceHandle:        fmt.Sprintf("DELETE FROM %s WHERE username=$1", u2fDeviceHandlesTableName),
+
+			sqlGetSecondFactorUsernames: fmt.Sprintf("SELECT username FROM %s UNION SELECT username FROM %s UNION SELECT username FROM %s ORDER BY username", totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName),
+			sqlGetSecondFactors:         fmt.Sprintf("SELECT u.username, p.second_factor_method, t.secret, d.keyHandle, d.publicKey FROM (SELECT username FROM %[1]s UNION SELECT username FROM %[2]s UNION SELECT username FROM %[3]s) AS u LEFT JOIN %[3]s AS p ON p.username=u.username LEFT JOIN %[1]s AS t ON t.username=u.username LEFT JOIN %[2]s AS d ON d.username=u.username ORDER BY u.username", totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName),
+
+			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>$1 AND username=$2 AND auth_type IN ($3, $4) AND banned=$5 ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetAuthenticationLogs:       fmt.Sprintf("SELECT username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned FROM %s WHERE ($1='' OR username=$2) AND ($3='' OR remote_ip=$4) AND ($5=0 OR time>=$6) AND ($7=0 OR time<=$8) ORDER BY time DESC LIMIT $9 OFFSET $10", authenticationLogsTableName),
+
+			sqlInsertPersonalAccessToken:         fmt.Sprintf("INSERT INTO %s (id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", personalAccessTokensTableName),
+			sqlGetPersonalAccessTokenByHash:      fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE token_hash=$1", personalAccessTokensTableName),
+			sqlGetPersonalAccessTokensByUsername: fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE username=$1 ORDER BY created_at", personalAccessTokensTableName),
+			sqlRevokePersonalAccessToken:         fmt.Sprintf("UPDATE %s SET revoked=TRUE WHERE id=$1 AND username=$2", personalAccessTokensTableName),
+			sqlUpdatePersonalAccessTokenLastUsed: fmt.Sprintf("UPDATE %s SET last_used=$1 WHERE id=$2", personalAccessTokensTableName),
+
+			sqlUpsertSession:            fmt.Sprintf("INSERT INTO %s (id, data, expires_at) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET data=$2, expires_at=$3", sessionsTableName),
+			sqlGetSessionByID:           fmt.Sprintf("SELECT data FROM %s WHERE id=$1 AND (expires_at=0 OR expires_at>$2)", sessionsTableName),
+			sqlUpdateSessionID:          fmt.Sprintf("UPDATE %s SET id=$1, expires_at=$2 WHERE id=$3", sessionsTableName),
+			sqlDeleteSession:            fmt.Sprintf("DELETE FROM %s WHERE id=$1", sessionsTableName),
+			sqlCountSessions:            fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE expires_at=0 OR expires_at>$1", sessionsTableName),
+			sqlDeleteExpiredSessions:    fmt.Sprintf("DELETE FROM %s WHERE expires_at<>0 AND expires_at<=$1", sessionsTableName),
+			sqlInsertSessionIfNotExists: fmt.Sprintf("INSERT INTO %s (id, data, expires_at) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING", sessionsTableName),
+			sqlDeleteExpiredSessionByID: fmt.Sprintf("DELETE FROM %s WHERE id=$1 AND expires_at<>0 AND expires_at<=$2", sessionsTableName),
+			sqlDeleteSessionByData:      fmt.Sprintf("DELETE FROM %s WHERE id=$1 AND data=$2", sessionsTableName),
 
 			sqlGetExistingTables: "SELECT table_name FROM information_schema.tables WHERE table_type='BASE TABLE' AND table_schema='public'",
 
-			sqlConfigSetValue: fmt.Sprintf("INSERT INTO %s (category, key_name, value) VALUES ($1, $2, $3) ON CONFLICT (category, key_name) DO UPDATE SET value=$3", configTableName),
-			sqlConfigGetValue: fmt.Sprintf("SELECT value FROM %s WHERE category=$1 AND key_name=$2", configTableName),
+			sqlGetIndexExistence: "SELECT COUNT(*) FROM pg_indexes WHERE schemaname='public' AND tablename=$1 AND indexname=$2",
+			sqlDropIndex:         "DROP INDEX %[1]s",
+
+			sqlConfigSetValue:            fmt.Sprintf("INSERT INTO %s (category, key_name, value) VALUES ($1, $2, $3) ON CONFLICT (category, key_name) DO UPDATE SET value=$3", configTableName),
+			sqlConfigInsertValue:         fmt.Sprintf("INSERT INTO %s (category, key_name, value) VALUES ($1, $2, $3)", configTableName),
+			sqlConfigGetValue:            fmt.Sprintf("SELECT value FROM %s WHERE category=$1 AND key_name=$2", configTableName),
+			sqlConfigGetValueForUpdate:   fmt.Sprintf("SELECT value FROM %s WHERE category=$1 AND key_name=$2 FOR UPDATE", configTableName),
+			sqlConfigGetValuesByCategory: fmt.Sprintf("SELECT key_name, value FROM %s WHERE category=$1", configTableName),
 		},
 	}
 
@@ -82,9 +122,7 @@ func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration)
 		provider.log.Fatalf("Unable to connect to SQL database: %v", err)
 	}
 
-	if err := provider.initialize(db); err != nil {
-		provider.log.Fatalf("Unable to initialize SQL database: %v", err)
-	}
+	provider.db = db
 
 	return &provider
 }
@@ -23,6 +23,25 @@ type Provider interface {
 	SaveU2FDeviceHandle(username string, keyHandle []byte, publicKey []byte) error
 	LoadU2FDeviceHandle(username string) (keyHandle []byte, publicKey []byte, err error)
 
+	LoadSecondFactors() ([]SecondFactors, error)
+	SaveSecondFactors(users []SecondFactors, mode string) (ImportResult, error)
+
 	AppendAuthenticationLog(attempt models.AuthenticationAttempt) error
 	LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error)
+	LoadAuthenticationLogs(filter AuthenticationLogFilter) ([]models.AuthenticationAttempt, error)
+
+	SavePersonalAccessToken(token models.PersonalAccessToken) error
+	LoadPersonalAccessTokenByHash(hash string) (*models.PersonalAccessToken, error)
+	LoadPersonalAccessTokens(username string) ([]models.PersonalAccessToken, error)
+	RevokePersonalAccessToken(username, id string) error
+	UpdatePersonalAccessTokenLastUsed(id string, lastUsed time.Time) error
+
+	SaveSession(id string, data []byte, expiresAt time.Time) error
+	LoadSession(id string, now time.Time) ([]byte, error)
+	RegenerateSession(id, newID string, expiresAt time.Time) error
+	DestroySession(id string) error
+	CountSessions(now time.Time) (int, error)
+	DeleteExpiredSessions(now time.Time) (int64, error)
+	LockSession(id string, token []byte, expiresAt, now time.Time) (bool, error)
+	UnlockSession(id string, token []byte) error
 }
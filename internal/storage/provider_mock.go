@@ -181,6 +181,36 @@ func (mr *MockProviderMockRecorder) LoadU2FDeviceHandle(username interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadU2FDeviceHandle", reflect.TypeOf((*MockProvider)(nil).LoadU2FDeviceHandle), username)
 }
 
+// LoadSecondFactors mocks base method
+func (m *MockProvider) LoadSecondFactors() ([]SecondFactors, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadSecondFactors")
+	ret0, _ := ret[0].([]SecondFactors)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadSecondFactors indicates an expected call of LoadSecondFactors
+func (mr *MockProviderMockRecorder) LoadSecondFactors() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadSecondFactors", reflect.TypeOf((*MockProvider)(nil).LoadSecondFactors))
+}
+
+// SaveSecondFactors mocks base method
+func (m *MockProvider) SaveSecondFactors(users []SecondFactors, mode string) (ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSecondFactors", users, mode)
+	ret0, _ := ret[0].(ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveSecondFactors indicates an expected call of SaveSecondFactors
+func (mr *MockProviderMockRecorder) SaveSecondFactors(users, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSecondFactors", reflect.TypeOf((*MockProvider)(nil).SaveSecondFactors), users, mode)
+}
+
 // AppendAuthenticationLog mocks base method
 func (m *MockProvider) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
 	m.ctrl.T.Helper()
@@ -209,3 +239,206 @@ func (mr *MockProviderMockRecorder) LoadLatestAuthenticationLogs(username, fromD
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).LoadLatestAuthenticationLogs), username, fromDate)
 }
+
+// LoadAuthenticationLogs mocks base method
+func (m *MockProvider) LoadAuthenticationLogs(filter AuthenticationLogFilter) ([]models.AuthenticationAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadAuthenticationLogs", filter)
+	ret0, _ := ret[0].([]models.AuthenticationAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadAuthenticationLogs indicates an expected call of LoadAuthenticationLogs
+func (mr *MockProviderMockRecorder) LoadAuthenticationLogs(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).LoadAuthenticationLogs), filter)
+}
+
+// SavePersonalAccessToken mocks base method
+func (m *MockProvider) SavePersonalAccessToken(token models.PersonalAccessToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePersonalAccessToken", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePersonalAccessToken indicates an expected call of SavePersonalAccessToken
+func (mr *MockProviderMockRecorder) SavePersonalAccessToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePersonalAccessToken", reflect.TypeOf((*MockProvider)(nil).SavePersonalAccessToken), token)
+}
+
+// LoadPersonalAccessTokenByHash mocks base method
+func (m *MockProvider) LoadPersonalAccessTokenByHash(hash string) (*models.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadPersonalAccessTokenByHash", hash)
+	ret0, _ := ret[0].(*models.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadPersonalAccessTokenByHash indicates an expected call of LoadPersonalAccessTokenByHash
+func (mr *MockProviderMockRecorder) LoadPersonalAccessTokenByHash(hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadPersonalAccessTokenByHash", reflect.TypeOf((*MockProvider)(nil).LoadPersonalAccessTokenByHash), hash)
+}
+
+// LoadPersonalAccessTokens mocks base method
+func (m *MockProvider) LoadPersonalAccessTokens(username string) ([]models.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadPersonalAccessTokens", username)
+	ret0, _ := ret[0].([]models.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadPersonalAccessTokens indicates an expected call of LoadPersonalAccessTokens
+func (mr *MockProviderMockRecorder) LoadPersonalAccessTokens(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadPersonalAccessTokens", reflect.TypeOf((*MockProvider)(nil).LoadPersonalAccessTokens), username)
+}
+
+// RevokePersonalAccessToken mocks base method
+func (m *MockProvider) RevokePersonalAccessToken(username, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokePersonalAccessToken", username, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokePersonalAccessToken indicates an expected call of RevokePersonalAccessToken
+func (mr *MockProviderMockRecorder) RevokePersonalAccessToken(username, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokePersonalAccessToken", reflect.TypeOf((*MockProvider)(nil).RevokePersonalAccessToken), username, id)
+}
+
+// UpdatePersonalAccessTokenLastUsed mocks base method
+func (m *MockProvider) UpdatePersonalAccessTokenLastUsed(id string, lastUsed time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePersonalAccessTokenLastUsed", id, lastUsed)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePersonalAccessTokenLastUsed indicates an expected call of UpdatePersonalAccessTokenLastUsed
+func (mr *MockProviderMockRecorder) UpdatePersonalAccessTokenLastUsed(id, lastUsed interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePersonalAccessTokenLastUsed", reflect.TypeOf((*MockProvider)(nil).UpdatePersonalAccessTokenLastUsed), id, lastUsed)
+}
+
+// SaveSession mocks base method
+func (m *MockProvider) SaveSession(id string, data []byte, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSession", id, data, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSession indicates an expected call of SaveSession
+func (mr *MockProviderMockRecorder) SaveSession(id, data, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSession", reflect.TypeOf((*MockProvider)(nil).SaveSession), id, data, expiresAt)
+}
+
+// LoadSession mocks base method
+func (m *MockProvider) LoadSession(id string, now time.Time) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadSession", id, now)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadSession indicates an expected call of LoadSession
+func (mr *MockProviderMockRecorder) LoadSession(id, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadSession", reflect.TypeOf((*MockProvider)(nil).LoadSession), id, now)
+}
+
+// RegenerateSession mocks base method
+func (m *MockProvider) RegenerateSession(id, newID string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegenerateSession", id, newID, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegenerateSession indicates an expected call of RegenerateSession
+func (mr *MockProviderMockRecorder) RegenerateSession(id, newID, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegenerateSession", reflect.TypeOf((*MockProvider)(nil).RegenerateSession), id, newID, expiresAt)
+}
+
+// DestroySession mocks base method
+func (m *MockProvider) DestroySession(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroySession", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DestroySession indicates an expected call of DestroySession
+func (mr *MockProviderMockRecorder) DestroySession(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroySession", reflect.TypeOf((*MockProvider)(nil).DestroySession), id)
+}
+
+// CountSessions mocks base method
+func (m *MockProvider) CountSessions(now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountSessions", now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountSessions indicates an expected call of CountSessions
+func (mr *MockProviderMockRecorder) CountSessions(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountSessions", reflect.TypeOf((*MockProvider)(nil).CountSessions), now)
+}
+
+// DeleteExpiredSessions mocks base method
+func (m *MockProvider) DeleteExpiredSessions(now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredSessions", now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredSessions indicates an expected call of DeleteExpiredSessions
+func (mr *MockProviderMockRecorder) DeleteExpiredSessions(now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredSessions", reflect.TypeOf((*MockProvider)(nil).DeleteExpiredSessions), now)
+}
+
+// LockSession mocks base method
+func (m *MockProvider) LockSession(id string, token []byte, expiresAt, now time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockSession", id, token, expiresAt, now)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockSession indicates an expected call of LockSession
+func (mr *MockProviderMockRecorder) LockSession(id, token, expiresAt, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockSession", reflect.TypeOf((*MockProvider)(nil).LockSession), id, token, expiresAt, now)
+}
+
+// UnlockSession mocks base method
+func (m *MockProvider) UnlockSession(id string, token []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockSession", id, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockSession indicates an expected call of UnlockSession
+func (mr *MockProviderMockRecorder) UnlockSession(id, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockSession", reflect.TypeOf((*MockProvider)(nil).UnlockSession), id, token)
+}
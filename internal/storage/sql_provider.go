@@ -4,10 +4,14 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/authelia/authelia/v4/internal/authentication"
+	"github.com/authelia/authelia/v4/internal/configuration/schema"
 	"github.com/authelia/authelia/v4/internal/logging"
 	"github.com/authelia/authelia/v4/internal/models"
 	"github.com/authelia/authelia/v4/internal/utils"
@@ -19,37 +23,86 @@ type SQLProvider struct {
 	log  *logrus.Logger
 	name string
 
-	sqlUpgradesCreateTableStatements        map[SchemaVersion]map[string]string
-	sqlUpgradesCreateTableIndexesStatements map[SchemaVersion][]string
+	// key encrypts the TOTP secrets and U2F devices, they aren't encrypted when it's nil.
+	key *[32]byte
 
 	sqlGetPreferencesByUsername     string
 	sqlUpsertSecondFactorPreference string
+	sqlDeleteSecondFactorPreference string
 
 	sqlTestIdentityVerificationTokenExistence string
 	sqlInsertIdentityVerificationToken        string
 	sqlDeleteIdentityVerificationToken        string
 
 	sqlGetTOTPSecretByUsername string
+	sqlGetTOTPSecrets          string
 	sqlUpsertTOTPSecret        string
 	sqlDeleteTOTPSecret        string
 
 	sqlGetU2FDeviceHandleByUsername string
+	sqlGetU2FDeviceHandles          string
 	sqlUpsertU2FDeviceHandle        string
+	sqlDeleteU2FDeviceHandle        string
+
+	sqlGetSecondFactorUsernames string
+	sqlGetSecondFactors         string
 
 	sqlInsertAuthenticationLog     string
 	sqlGetLatestAuthenticationLogs string
+	sqlGetAuthenticationLogs       string
+
+	sqlInsertPersonalAccessToken         string
+	sqlGetPersonalAccessTokenByHash      string
+	sqlGetPersonalAccessTokensByUsername string
+	sqlRevokePersonalAccessToken         string
+	sqlUpdatePersonalAccessTokenLastUsed string
+
+	sqlUpsertSession         string
+	sqlGetSessionByID        string
+	sqlUpdateSessionID       string
+	sqlDeleteSession         string
+	sqlCountSessions         string
+	sqlDeleteExpiredSessions string
+
+	sqlInsertSessionIfNotExists string
+	sqlDeleteExpiredSessionByID string
+	sqlDeleteSessionByData      string
 
 	sqlGetExistingTables string
+	sqlGetIndexExistence string
+	sqlDropIndex         string
+
+	sqlConfigSetValue            string
+	sqlConfigInsertValue         string
+	sqlConfigGetValue            string
+	sqlConfigGetValueForUpdate   string
+	sqlConfigGetValuesByCategory string
+}
 
-	sqlConfigSetValue string
-	sqlConfigGetValue string
+// NewSQLProviderWithoutUpgrade returns the SQL provider of the storage configuration without upgrading the schema of
+// the database so the schema can be migrated explicitly. It returns nil if no storage provider is configured.
+func NewSQLProviderWithoutUpgrade(configuration schema.StorageConfiguration) *SQLProvider {
+	switch {
+	case configuration.PostgreSQL != nil:
+		return &newPostgreSQLProvider(*configuration.PostgreSQL, configuration.EncryptionKey).SQLProvider
+	case configuration.MySQL != nil:
+		return &newMySQLProvider(*configuration.MySQL, configuration.EncryptionKey).SQLProvider
+	case configuration.Local != nil:
+		return &newSQLiteProvider(configuration.Local.Path, configuration.EncryptionKey).SQLProvider
+	default:
+		return nil
+	}
 }
 
 func (p *SQLProvider) initialize(db *sql.DB) error {
 	p.db = db
 	p.log = logging.Logger()
 
-	return p.upgrade()
+	if err := p.upgrade(); err != nil {
+		return err
+	}
+
+	return p.checkEncryption()
 }
 
 func (p *SQLProvider) getSchemaBasicDetails() (version SchemaVersion, tables []string, err error) {
@@ -96,46 +149,17 @@ func (p *SQLProvider) upgrade() error {
 		return err
 	}
 
-	if version < storageSchemaCurrentVersion {
-		p.log.Debugf("Storage schema is v%d, latest is v%d", version, storageSchemaCurrentVersion)
-
-		tx, err := p.db.Begin()
-		if err != nil {
-			return err
-		}
-
-		switch version {
-		case 0:
-			err := p.upgradeSchemaToVersion001(tx, tables)
-			if err != nil {
-				return p.handleUpgradeFailure(tx, 1, err)
-			}
-
-			fallthrough
-		default:
-			err := tx.Commit()
-			if err != nil {
-				return err
-			}
-
-			p.log.Infof("Storage schema upgrade to v%d completed", storageSchemaCurrentVersion)
-		}
-	} else {
+	if version >= storageSchemaCurrentVersion {
 		p.log.Debug("Storage schema is up to date")
-	}
 
-	return nil
-}
+		return nil
+	}
 
-func (p *SQLProvider) handleUpgradeFailure(tx *sql.Tx, version SchemaVersion, err error) error {
-	rollbackErr := tx.Rollback()
-	formattedErr := fmt.Errorf("%s%d: %v", storageSchemaUpgradeErrorText, version, err)
+	p.log.Debugf("Storage schema is v%d, latest is v%d", version, storageSchemaCurrentVersion)
 
-	if rollbackErr != nil {
-		return fmt.Errorf("rollback error occurred: %v (inner error %v)", rollbackErr, formattedErr)
-	}
+	_, err = p.migrate(version, storageSchemaCurrentVersion, tables, false)
 
-	return formattedErr
+	return err
 }
 
 // LoadPreferred2FAMethod load the preferred method for 2FA from the database.
@@ -189,7 +213,13 @@ func (p *SQLProvider) RemoveIdentityVerificationToken(token string) error {
 
 // SaveTOTPSecret save a TOTP secret of a given user in the database.
 func (p *SQLProvider) SaveTOTPSecret(username string, secret string) error {
-	_, err := p.db.Exec(p.sqlUpsertTOTPSecret, username, secret)
+	value, err := encryptTOTPSecret(p.key, secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(p.sqlUpsertTOTPSecret, username, value)
+
 	return err
 }
 
@@ -204,7 +234,7 @@ func (p *SQLProvider) LoadTOTPSecret(username string) (string, error) {
 		return "", err
 	}
 
-	return secret, nil
+	return decryptTOTPSecret(p.key, secret)
 }
 
 // DeleteTOTPSecret delete a TOTP secret from the database given a username.
@@ -215,10 +245,17 @@ func (p *SQLProvider) DeleteTOTPSecret(username string) error {
 
 // SaveU2FDeviceHandle save a registered U2F device registration blob.
 func (p *SQLProvider) SaveU2FDeviceHandle(username string, keyHandle []byte, publicKey []byte) error {
-	_, err := p.db.Exec(p.sqlUpsertU2FDeviceHandle,
-		username,
-		base64.StdEncoding.EncodeToString(keyHandle),
-		base64.StdEncoding.EncodeToString(publicKey))
+	keyHandleValue, err := encryptValue(p.key, keyHandle)
+	if err != nil {
+		return err
+	}
+
+	publicKeyValue, err := encryptValue(p.key, publicKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(p.sqlUpsertU2FDeviceHandle, username, keyHandleValue, publicKeyValue)
 
 	return err
 }
@@ -234,13 +271,13 @@ func (p *SQLProvider) LoadU2FDeviceHandle(username string) ([]byte, []byte, erro
 		return nil, nil, err
 	}
 
-	keyHandle, err := base64.StdEncoding.DecodeString(keyHandleBase64)
+	keyHandle, err := decryptValue(p.key, keyHandleBase64)
 
 	if err != nil {
 		return nil, nil, err
 	}
 
-	publicKey, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+	publicKey, err := decryptValue(p.key, publicKeyBase64)
 
 	if err != nil {
 		return nil, nil, err
@@ -249,27 +286,217 @@ func (p *SQLProvider) LoadU2FDeviceHandle(username string) ([]byte, []byte, erro
 	return keyHandle, publicKey, nil
 }
 
+// LoadSecondFactors load the second factors and the preferences of the users with a TOTP secret, a U2F device or a
+// preferred 2FA method from the database in a single query.
+func (p *SQLProvider) LoadSecondFactors() ([]SecondFactors, error) {
+	rows, err := p.db.Query(p.sqlGetSecondFactors)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := make([]SecondFactors, 0)
+
+	var method, secret, keyHandle, publicKey sql.NullString
+
+	for rows.Next() {
+		var user SecondFactors
+
+		if err = rows.Scan(&user.Username, &method, &secret, &keyHandle, &publicKey); err != nil {
+			return nil, err
+		}
+
+		user.Preferred2FAMethod = method.String
+
+		if secret.Valid {
+			if user.TOTPSecret, err = decryptTOTPSecret(p.key, secret.String); err != nil {
+				return nil, fmt.Errorf("unable to decrypt the TOTP secret of user %s: %w", user.Username, err)
+			}
+		}
+
+		if keyHandle.Valid {
+			if user.KeyHandle, err = decryptValue(p.key, keyHandle.String); err != nil {
+				return nil, fmt.Errorf("unable to decrypt the U2F device of user %s: %w", user.Username, err)
+			}
+
+			if user.PublicKey, err = decryptValue(p.key, publicKey.String); err != nil {
+				return nil, fmt.Errorf("unable to decrypt the U2F device of user %s: %w", user.Username, err)
+			}
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SaveSecondFactors save the second factors and the preferences of the users to the database in a single transaction.
+// The users who already have a second factor or a preferred 2FA method are conflicting, they abort the save, are
+// skipped or have all their second factors replaced depending on the mode.
+func (p *SQLProvider) SaveSecondFactors(users []SecondFactors, mode string) (result ImportResult, err error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return result, err
+	}
+
+	existing, err := p.loadSecondFactorUsernames(tx)
+	if err != nil {
+		return result, p.handleImportFailure(tx, err)
+	}
+
+	for _, user := range users {
+		if existing[user.Username] {
+			result.Conflicts = append(result.Conflicts, user.Username)
+		}
+	}
+
+	if mode == ImportModeAbort && len(result.Conflicts) != 0 {
+		return result, p.handleImportFailure(tx, ErrImportConflicts)
+	}
+
+	for _, user := range users {
+		if existing[user.Username] {
+			if mode == ImportModeSkip {
+				result.Skipped = append(result.Skipped, user.Username)
+
+				continue
+			}
+
+			if err = p.deleteSecondFactors(tx, user.Username); err != nil {
+				return result, p.handleImportFailure(tx, fmt.Errorf("unable to delete the second factors of user %s: %w", user.Username, err))
+			}
+		}
+
+		if err = p.saveSecondFactors(tx, user); err != nil {
+			return result, p.handleImportFailure(tx, fmt.Errorf("unable to save the second factors of user %s: %w", user.Username, err))
+		}
+
+		result.Imported = append(result.Imported, user.Username)
+	}
+
+	return result, tx.Commit()
+}
+
+func (p *SQLProvider) handleImportFailure(tx *sql.Tx, err error) error {
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("rollback error occurred: %v (inner error %v)", rollbackErr, err)
+	}
+
+	return err
+}
+
+func (p *SQLProvider) loadSecondFactorUsernames(tx *sql.Tx) (usernames map[string]bool, err error) {
+	rows, err := tx.Query(p.sqlGetSecondFactorUsernames)
+	if err != nil {
+		return nil, err
+	}
+
+	usernames = make(map[string]bool)
+
+	var username string
+
+	for rows.Next() {
+		if err = rows.Scan(&username); err != nil {
+			_ = rows.Close()
+
+			return nil, err
+		}
+
+		usernames[username] = true
+	}
+
+	// The rows must be closed before the next statement of the transaction on MySQL.
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return usernames, rows.Err()
+}
+
+func (p *SQLProvider) deleteSecondFactors(tx *sql.Tx, username string) (err error) {
+	for _, query := range []string{p.sqlDeleteSecondFactorPreference, p.sqlDeleteTOTPSecret, p.sqlDeleteU2FDeviceHandle} {
+		if _, err = tx.Exec(query, username); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *SQLProvider) saveSecondFactors(tx *sql.Tx, user SecondFactors) (err error) {
+	if user.Preferred2FAMethod != "" {
+		if _, err = tx.Exec(p.sqlUpsertSecondFactorPreference, user.Username, user.Preferred2FAMethod); err != nil {
+			return err
+		}
+	}
+
+	if user.TOTPSecret != "" {
+		secret, err := encryptTOTPSecret(p.key, user.TOTPSecret)
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(p.sqlUpsertTOTPSecret, user.Username, secret); err != nil {
+			return err
+		}
+	}
+
+	if user.KeyHandle != nil {
+		keyHandle, err := encryptValue(p.key, user.KeyHandle)
+		if err != nil {
+			return err
+		}
+
+		publicKey, err := encryptValue(p.key, user.PublicKey)
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(p.sqlUpsertU2FDeviceHandle, user.Username, keyHandle, publicKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // AppendAuthenticationLog append a mark to the authentication log.
 func (p *SQLProvider) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
-	_, err := p.db.Exec(p.sqlInsertAuthenticationLog, attempt.Username, attempt.Successful, attempt.Time.Unix())
+	var remoteIP string
+
+	if attempt.RemoteIP != nil {
+		remoteIP = attempt.RemoteIP.String()
+	}
+
+	_, err := p.db.Exec(p.sqlInsertAuthenticationLog,
+		attempt.Username, attempt.Successful, attempt.Time.Unix(), attempt.Event, attempt.Actor, attempt.Type, remoteIP,
+		truncate(attempt.UserAgent, authenticationLogUserAgentMaxLength), attempt.RequestMethod,
+		truncate(attempt.RequestURL, authenticationLogRequestURLMaxLength), attempt.Banned)
+
 	return err
 }
 
-// LoadLatestAuthenticationLogs retrieve the latest marks from the authentication log.
+// LoadLatestAuthenticationLogs retrieve the latest first factor marks from the authentication log, made on the portal
+// or with basic auth. The attempts rejected because the user was banned aren't returned so they don't extend the ban.
 func (p *SQLProvider) LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
 	var t int64
 
-	rows, err := p.db.Query(p.sqlGetLatestAuthenticationLogs, fromDate.Unix(), username)
+	rows, err := p.db.Query(p.sqlGetLatestAuthenticationLogs, fromDate.Unix(), username, models.AuthenticationType1FA, models.AuthenticationTypeBasic, false)
 
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	attempts := make([]models.AuthenticationAttempt, 0, 10)
 
 	for rows.Next() {
 		attempt := models.AuthenticationAttempt{
 			Username: username,
+			Event:    models.AuthenticationEventFirstFactor,
+			Type:     models.AuthenticationType1FA,
 		}
 		err = rows.Scan(&attempt.Successful, &t)
 		attempt.Time = time.Unix(t, 0)
@@ -283,3 +510,244 @@ func (p *SQLProvider) LoadLatestAuthenticationLogs(username string, fromDate tim
 
 	return attempts, nil
 }
+
+// LoadAuthenticationLogs retrieve the marks of the authentication log matching the filter, the latest first.
+func (p *SQLProvider) LoadAuthenticationLogs(filter AuthenticationLogFilter) ([]models.AuthenticationAttempt, error) {
+	filter = filter.Bounded()
+
+	// The zero times don't bound the range of the attempts, an unbounded range can't be passed as the maximum time
+	// since the time column is a 32 bits integer on PostgreSQL.
+	var from, to int64
+
+	if !filter.From.IsZero() {
+		from = filter.From.Unix()
+	}
+
+	if !filter.To.IsZero() {
+		to = filter.To.Unix()
+	}
+
+	rows, err := p.db.Query(p.sqlGetAuthenticationLogs,
+		filter.Username, filter.Username, filter.RemoteIP, filter.RemoteIP, from, from, to, to, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	attempts := make([]models.AuthenticationAttempt, 0, filter.Limit)
+
+	var (
+		t        int64
+		remoteIP string
+	)
+
+	for rows.Next() {
+		var attempt models.AuthenticationAttempt
+
+		if err = rows.Scan(&attempt.Username, &attempt.Successful, &t, &attempt.Event, &attempt.Actor, &attempt.Type,
+			&remoteIP, &attempt.UserAgent, &attempt.RequestMethod, &attempt.RequestURL, &attempt.Banned); err != nil {
+			return nil, err
+		}
+
+		attempt.Time = time.Unix(t, 0)
+		attempt.RemoteIP = net.ParseIP(remoteIP)
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// SavePersonalAccessToken save a personal access token in the database.
+func (p *SQLProvider) SavePersonalAccessToken(token models.PersonalAccessToken) error {
+	_, err := p.db.Exec(p.sqlInsertPersonalAccessToken,
+		token.ID, token.Username, token.Name, token.Hash, int(token.Level), strings.Join(token.Domains, ","),
+		token.Created.Unix(), token.Expires.Unix(), unixOrZero(token.LastUsed), token.Revoked)
+
+	return err
+}
+
+// LoadPersonalAccessTokenByHash load a personal access token given the hash of the token from the database.
+func (p *SQLProvider) LoadPersonalAccessTokenByHash(hash string) (*models.PersonalAccessToken, error) {
+	token, err := scanPersonalAccessToken(p.db.QueryRow(p.sqlGetPersonalAccessTokenByHash, hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoPersonalAccessToken
+		}
+
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// LoadPersonalAccessTokens load the personal access tokens of a given user from the database.
+func (p *SQLProvider) LoadPersonalAccessTokens(username string) ([]models.PersonalAccessToken, error) {
+	rows, err := p.db.Query(p.sqlGetPersonalAccessTokensByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tokens := make([]models.PersonalAccessToken, 0)
+
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// RevokePersonalAccessToken revoke a personal access token of a given user in the database.
+func (p *SQLProvider) RevokePersonalAccessToken(username, id string) error {
+	result, err := p.db.Exec(p.sqlRevokePersonalAccessToken, id, username)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoPersonalAccessToken
+	}
+
+	return nil
+}
+
+// UpdatePersonalAccessTokenLastUsed update the last time a personal access token was used in the database.
+func (p *SQLProvider) UpdatePersonalAccessTokenLastUsed(id string, lastUsed time.Time) error {
+	_, err := p.db.Exec(p.sqlUpdatePersonalAccessTokenLastUsed, lastUsed.Unix(), id)
+	return err
+}
+
+// SaveSession save the data of a session in the database, a zero expiresAt means the session never expires.
+func (p *SQLProvider) SaveSession(id string, data []byte, expiresAt time.Time) error {
+	_, err := p.db.Exec(p.sqlUpsertSession, id, base64.StdEncoding.EncodeToString(data), unixOrZero(expiresAt))
+	return err
+}
+
+// LoadSession load the data of a session which hasn't expired from the database, nil is returned when there is no
+// such session.
+func (p *SQLProvider) LoadSession(id string, now time.Time) ([]byte, error) {
+	var dataBase64 string
+	if err := p.db.QueryRow(p.sqlGetSessionByID, id, now.Unix()).Scan(&dataBase64); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(dataBase64)
+}
+
+// RegenerateSession replace the ID and the expiration of a session in the database.
+func (p *SQLProvider) RegenerateSession(id, newID string, expiresAt time.Time) error {
+	_, err := p.db.Exec(p.sqlUpdateSessionID, newID, unixOrZero(expiresAt), id)
+	return err
+}
+
+// DestroySession delete a session from the database.
+func (p *SQLProvider) DestroySession(id string) error {
+	_, err := p.db.Exec(p.sqlDeleteSession, id)
+	return err
+}
+
+// CountSessions count the sessions which haven't expired in the database.
+func (p *SQLProvider) CountSessions(now time.Time) (count int, err error) {
+	err = p.db.QueryRow(p.sqlCountSessions, now.Unix()).Scan(&count)
+	return count, err
+}
+
+// DeleteExpiredSessions delete the expired sessions from the database and return the number of sessions deleted.
+func (p *SQLProvider) DeleteExpiredSessions(now time.Time) (int64, error) {
+	result, err := p.db.Exec(p.sqlDeleteExpiredSessions, now.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// LockSession saves the token in the session with the ID if the session doesn't exist or has expired, and returns true
+// if it was saved. The session is used as a lock shared by the instances of Authelia using the database.
+func (p *SQLProvider) LockSession(id string, token []byte, expiresAt, now time.Time) (bool, error) {
+	if _, err := p.db.Exec(p.sqlDeleteExpiredSessionByID, id, now.Unix()); err != nil {
+		return false, err
+	}
+
+	result, err := p.db.Exec(p.sqlInsertSessionIfNotExists, id, base64.StdEncoding.EncodeToString(token), unixOrZero(expiresAt))
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+
+	return rows == 1, err
+}
+
+// UnlockSession deletes the session with the ID if it still holds the token saved by LockSession.
+func (p *SQLProvider) UnlockSession(id string, token []byte) error {
+	_, err := p.db.Exec(p.sqlDeleteSessionByData, id, base64.StdEncoding.EncodeToString(token))
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPersonalAccessToken(row scanner) (*models.PersonalAccessToken, error) {
+	var (
+		token                      models.PersonalAccessToken
+		level                      int
+		domains                    string
+		created, expires, lastUsed int64
+	)
+
+	err := row.Scan(&token.ID, &token.Username, &token.Name, &token.Hash, &level, &domains, &created, &expires, &lastUsed, &token.Revoked)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Level = authentication.Level(level)
+	token.Created = time.Unix(created, 0)
+	token.Expires = time.Unix(expires, 0)
+
+	if domains != "" {
+		token.Domains = strings.Split(domains, ",")
+	}
+
+	if lastUsed != 0 {
+		token.LastUsed = time.Unix(lastUsed, 0)
+	}
+
+	return &token, nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// truncate truncates the value to the maximum number of characters of its column.
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+
+	return string(runes[:length])
+}
@@ -3,8 +3,12 @@ package storage
 import (
 	"database/sql/driver"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"sort"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,9 +18,106 @@ import (
 
 	"github.com/authelia/authelia/v4/internal/authentication"
 	"github.com/authelia/authelia/v4/internal/models"
+	"github.com/authelia/authelia/v4/internal/utils"
 )
 
-const currentSchemaMockSchemaVersion = "1"
+const currentSchemaMockSchemaVersion = "6"
+
+func expectSchemaMigrationFinalize(mock sqlmock.Sqlmock, before, after int) {
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", strconv.Itoa(after)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs(storageSchemaMigrationsCategory, sqlmock.AnyArg(), fmt.Sprintf("%d,%d,%s", before, after, utils.Version())).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSchemaMigrationsToVersion2(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", personalAccessTokensTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSchemaMigrationFinalize(mock, 1, 2)
+}
+
+func expectSchemaMigrationsToVersion3(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN event VARCHAR\\(32\\) NOT NULL DEFAULT 'first_factor'", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN actor VARCHAR\\(100\\) NOT NULL DEFAULT ''", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSchemaMigrationFinalize(mock, 2, 3)
+}
+
+func expectSchemaMigrationsToVersion4(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s \\(id VARCHAR\\(128\\) PRIMARY KEY, data TEXT NOT NULL, expires_at BIGINT NOT NULL\\)", sessionsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX sessions_expires_at_idx ON %s \\(expires_at\\)", sessionsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSchemaMigrationFinalize(mock, 3, 4)
+}
+
+func expectSchemaMigrationsToVersion5(mock sqlmock.Sqlmock) {
+	expectSchemaMigrationFinalize(mock, 4, 5)
+}
+
+func expectSchemaMigrationsToVersion6(mock sqlmock.Sqlmock) {
+	for _, column := range []string{
+		"auth_type VARCHAR\\(16\\) NOT NULL DEFAULT ''",
+		"remote_ip VARCHAR\\(47\\) NOT NULL DEFAULT ''",
+		"user_agent VARCHAR\\(512\\) NOT NULL DEFAULT ''",
+		"request_method VARCHAR\\(8\\) NOT NULL DEFAULT ''",
+		"request_url VARCHAR\\(2048\\) NOT NULL DEFAULT ''",
+		"banned BOOL NOT NULL DEFAULT FALSE",
+	} {
+		mock.ExpectExec(
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", authenticationLogsTableName, column)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET auth_type='1fa' WHERE event='first_factor'", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX remote_ip_time_idx ON %s \\(remote_ip, time\\)", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSchemaMigrationFinalize(mock, 5, 6)
+}
+
+func expectSchemaDowngradeToVersion5(mock sqlmock.Sqlmock) {
+	for _, column := range []string{"banned", "request_url", "request_method", "user_agent", "remote_ip", "auth_type"} {
+		mock.ExpectExec(
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", authenticationLogsTableName, column)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	expectSchemaMigrationFinalize(mock, 6, 5)
+}
+
+func expectEncryptionCheck(mock sqlmock.Sqlmock, value string) {
+	rows := sqlmock.NewRows([]string{"value"})
+
+	if value != "" {
+		rows.AddRow(value)
+	}
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(storageEncryptionCategory, storageEncryptionCheckKey).
+		WillReturnRows(rows)
+}
 
 func TestSQLInitializeDatabase(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
@@ -28,32 +129,33 @@ func TestSQLInitializeDatabase(t *testing.T) {
 
 	mock.ExpectBegin()
 
-	keys := make([]string, 0, len(sqlUpgradeCreateTableStatements[1]))
-	for k := range sqlUpgradeCreateTableStatements[1] {
-		keys = append(keys, k)
-	}
-
-	sort.Strings(keys)
-
-	for _, table := range keys {
+	for _, table := range []string{
+		authenticationLogsTableName, configTableName, identityVerificationTokensTableName,
+		totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName,
+	} {
 		mock.ExpectExec(
 			fmt.Sprintf("CREATE TABLE %s .*", table)).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 	}
 
 	mock.ExpectExec(
-		fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s .*", authenticationLogsTableName)).
+		fmt.Sprintf("CREATE INDEX usr_time_idx ON %s \\(username, time\\)", authenticationLogsTableName)).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	mock.ExpectExec(
-		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
-		WithArgs("schema", "version", "1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectSchemaMigrationFinalize(mock, 0, 1)
+	expectSchemaMigrationsToVersion2(mock)
+	expectSchemaMigrationsToVersion3(mock)
+	expectSchemaMigrationsToVersion4(mock)
+	expectSchemaMigrationsToVersion5(mock)
+	expectSchemaMigrationsToVersion6(mock)
 
 	mock.ExpectCommit()
 
+	expectEncryptionCheck(mock, "")
+
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestSQLUpgradeDatabase(t *testing.T) {
@@ -74,19 +176,348 @@ func TestSQLUpgradeDatabase(t *testing.T) {
 		fmt.Sprintf("CREATE TABLE %s .*", configTableName)).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
+	mock.ExpectQuery(
+		"SELECT COUNT\\(\\*\\) FROM sqlite_master WHERE type='index' AND tbl_name=\\? AND name=\\?").
+		WithArgs(authenticationLogsTableName, "usr_time_idx").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
 	mock.ExpectExec(
-		fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s .*", authenticationLogsTableName)).
+		fmt.Sprintf("CREATE INDEX usr_time_idx ON %s \\(username, time\\)", authenticationLogsTableName)).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
+	expectSchemaMigrationFinalize(mock, 0, 1)
+	expectSchemaMigrationsToVersion2(mock)
+	expectSchemaMigrationsToVersion3(mock)
+	expectSchemaMigrationsToVersion4(mock)
+	expectSchemaMigrationsToVersion5(mock)
+	expectSchemaMigrationsToVersion6(mock)
+
+	mock.ExpectCommit()
+
+	expectEncryptionCheck(mock, "")
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabaseWithExistingIndex(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName))
+
+	mock.ExpectBegin()
+
 	mock.ExpectExec(
-		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
-		WithArgs("schema", "version", "1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+		fmt.Sprintf("CREATE TABLE %s .*", configTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectQuery(
+		"SELECT COUNT\\(\\*\\) FROM sqlite_master WHERE type='index' AND tbl_name=\\? AND name=\\?").
+		WithArgs(authenticationLogsTableName, "usr_time_idx").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	expectSchemaMigrationFinalize(mock, 0, 1)
+
+	mock.ExpectCommit()
+
+	statements, err := provider.MigrateSchema(1, false)
+	require.NoError(t, err)
+	assert.Len(t, statements, 3)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabaseFromVersion1(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow("1"))
+
+	mock.ExpectBegin()
+
+	expectSchemaMigrationsToVersion2(mock)
+	expectSchemaMigrationsToVersion3(mock)
+	expectSchemaMigrationsToVersion4(mock)
+	expectSchemaMigrationsToVersion5(mock)
+	expectSchemaMigrationsToVersion6(mock)
 
 	mock.ExpectCommit()
 
+	expectEncryptionCheck(mock, "")
+
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabaseFromVersion2(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName).
+			AddRow(personalAccessTokensTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow("2"))
+
+	mock.ExpectBegin()
+
+	expectSchemaMigrationsToVersion3(mock)
+	expectSchemaMigrationsToVersion4(mock)
+	expectSchemaMigrationsToVersion5(mock)
+	expectSchemaMigrationsToVersion6(mock)
+
+	mock.ExpectCommit()
+
+	expectEncryptionCheck(mock, "")
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabaseFromVersion3(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName).
+			AddRow(personalAccessTokensTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow("3"))
+
+	mock.ExpectBegin()
+
+	expectSchemaMigrationsToVersion4(mock)
+	expectSchemaMigrationsToVersion5(mock)
+	expectSchemaMigrationsToVersion6(mock)
+
+	mock.ExpectCommit()
+
+	expectEncryptionCheck(mock, "")
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectCurrentSchema(mock sqlmock.Sqlmock, version string) {
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName).
+			AddRow(personalAccessTokensTableName).
+			AddRow(sessionsTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(version))
+}
+
+func TestSQLDowngradeDatabase(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+
+	mock.ExpectBegin()
+
+	expectEncryptionCheck(mock, "")
+	expectSchemaDowngradeToVersion5(mock)
+	expectSchemaMigrationFinalize(mock, 5, 4)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", sessionsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSchemaMigrationFinalize(mock, 4, 3)
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s DROP COLUMN actor", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s DROP COLUMN event", authenticationLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSchemaMigrationFinalize(mock, 3, 2)
+
+	mock.ExpectCommit()
+
+	statements, err := provider.MigrateSchema(2, false)
+	require.NoError(t, err)
+	assert.Len(t, statements, 17)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLDowngradeDatabaseToVersion0(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	expectCurrentSchema(mock, "1")
+
+	mock.ExpectBegin()
+
+	for _, table := range []string{
+		userPreferencesTableName, u2fDeviceHandlesTableName, totpSecretsTableName,
+		identityVerificationTokensTableName, configTableName, authenticationLogsTableName,
+	} {
+		mock.ExpectExec(
+			fmt.Sprintf("DROP TABLE %s", table)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	mock.ExpectCommit()
+
+	_, err := provider.MigrateSchema(0, false)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLDowngradeDatabaseShouldRollbackOnFailure(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+
+	mock.ExpectBegin()
+
+	expectEncryptionCheck(mock, "")
+	expectSchemaDowngradeToVersion5(mock)
+	expectSchemaMigrationFinalize(mock, 5, 4)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", sessionsTableName)).
+		WillReturnError(errors.New("table is locked"))
+
+	mock.ExpectRollback()
+
+	_, err := provider.MigrateSchema(3, false)
+	assert.EqualError(t, err, "storage schema downgrade failed at v4: unable to drop table sessions: table is locked")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLDowngradeDatabaseShouldRefuseToNarrowEncryptedTOTPSecrets(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+
+	mock.ExpectBegin()
+
+	expectEncryptionCheck(mock, "encrypted")
+
+	mock.ExpectRollback()
+
+	_, err := provider.MigrateSchema(4, false)
+	assert.EqualError(t, err, "storage schema downgrade failed at v5: the TOTP secrets and U2F devices are encrypted and can't be read by the older versions of Authelia")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLMigrateDatabaseDryRun(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName).
+			AddRow(personalAccessTokensTableName))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs("schema", "version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow("3"))
+
+	statements, err := provider.MigrateSchema(4, true)
+	require.NoError(t, err)
+	require.Len(t, statements, 4)
+
+	assert.Equal(t, "CREATE TABLE sessions (id VARCHAR(128) PRIMARY KEY, data TEXT NOT NULL, expires_at BIGINT NOT NULL)", statements[0])
+	assert.Equal(t, "CREATE INDEX sessions_expires_at_idx ON sessions (expires_at)", statements[1])
+	assert.Equal(t, "REPLACE INTO config (category, key_name, value) VALUES (?, ?, ?) [schema version 4]", statements[2])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLMigrateDatabaseShouldRejectUnknownVersion(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	_, err := provider.MigrateSchema(7, false)
+	assert.EqualError(t, err, "storage schema v7 doesn't exist, the latest version is v6")
+
+	expectCurrentSchema(mock, "7")
+
+	_, err = provider.MigrateSchema(1, false)
+	assert.EqualError(t, err, "storage schema v7 is newer than the latest version v6 known to this version of Authelia")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLSchemaMigrationHistory(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT key_name, value FROM %s WHERE category=\\?", configTableName)).
+		WithArgs(storageSchemaMigrationsCategory).
+		WillReturnRows(sqlmock.NewRows([]string{"key_name", "value"}).
+			AddRow("1577880060000000000.0", "3,4,v4.31.0").
+			AddRow("1577880000000000000.1", "1,2,v4.30.0").
+			AddRow("1577880000000000000.0", "0,1,v4.30.0"))
+
+	records, err := provider.SchemaMigrationHistory()
+	require.NoError(t, err)
+
+	assert.Equal(t, []MigrationRecord{
+		{Time: time.Unix(1577880000, 0), Before: 0, After: 1, AutheliaVersion: "v4.30.0"},
+		{Time: time.Unix(1577880000, 0), Before: 1, After: 2, AutheliaVersion: "v4.30.0"},
+		{Time: time.Unix(1577880060, 0), Before: 3, After: 4, AutheliaVersion: "v4.31.0"},
+	}, records)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
@@ -107,23 +538,25 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
 		WithArgs(args...).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).
-			AddRow("1"))
+			AddRow(currentSchemaMockSchemaVersion))
+
+	expectEncryptionCheck(mock, "")
 
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
 	attempts := []models.AuthenticationAttempt{
-		{Username: unitTestUser, Successful: true, Time: time.Unix(1577880001, 0)},
-		{Username: unitTestUser, Successful: true, Time: time.Unix(1577880002, 0)},
-		{Username: unitTestUser, Successful: false, Time: time.Unix(1577880003, 0)},
+		{Username: unitTestUser, Successful: true, Time: time.Unix(1577880001, 0), Event: models.AuthenticationEventFirstFactor, Type: models.AuthenticationType1FA},
+		{Username: unitTestUser, Successful: true, Time: time.Unix(1577880002, 0), Event: models.AuthenticationEventFirstFactor, Type: models.AuthenticationType1FA},
+		{Username: unitTestUser, Successful: false, Time: time.Unix(1577880003, 0), Event: models.AuthenticationEventFirstFactor, Type: models.AuthenticationType1FA},
 	}
 
 	rows := sqlmock.NewRows([]string{"successful", "time"})
 
 	for id, attempt := range attempts {
-		args = []driver.Value{attempt.Username, attempt.Successful, attempt.Time.Unix()}
+		args = []driver.Value{attempt.Username, attempt.Successful, attempt.Time.Unix(), attempt.Event, attempt.Actor, attempt.Type, "", "", "", "", false}
 		mock.ExpectExec(
-			fmt.Sprintf("INSERT INTO %s \\(username, successful, time\\) VALUES \\(\\?, \\?, \\?\\)", authenticationLogsTableName)).
+			fmt.Sprintf("INSERT INTO %s \\(username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)", authenticationLogsTableName)).
 			WithArgs(args...).
 			WillReturnResult(sqlmock.NewResult(int64(id), 1))
 
@@ -132,9 +565,9 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 		rows.AddRow(attempt.Successful, attempt.Time.Unix())
 	}
 
-	args = []driver.Value{1577880000, unitTestUser}
+	args = []driver.Value{1577880000, unitTestUser, models.AuthenticationType1FA, models.AuthenticationTypeBasic, false}
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
+		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? AND auth_type IN \\(\\?, \\?\\) AND banned=\\? ORDER BY time DESC", authenticationLogsTableName)).
 		WithArgs(args...).
 		WillReturnRows(rows)
 
@@ -154,7 +587,7 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 
 	// Test Blank Rows.
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
+		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? AND auth_type IN \\(\\?, \\?\\) AND banned=\\? ORDER BY time DESC", authenticationLogsTableName)).
 		WithArgs(args...).
 		WillReturnRows(sqlmock.NewRows([]string{"successful", "time"}))
 
@@ -163,6 +596,73 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 	assert.Len(t, results, 0)
 }
 
+func TestSQLProviderMethodsAuthenticationLogsWithClient(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	attempt := models.AuthenticationAttempt{
+		Username:      unitTestUser,
+		Successful:    false,
+		Time:          time.Unix(1577880001, 0),
+		Event:         models.AuthenticationEventFirstFactor,
+		Type:          models.AuthenticationTypeBasic,
+		RemoteIP:      net.ParseIP("192.168.1.10"),
+		UserAgent:     strings.Repeat("a", 600),
+		RequestMethod: "GET",
+		RequestURL:    "https://secure.example.com/",
+	}
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned\\) VALUES .*", authenticationLogsTableName)).
+		WithArgs(unitTestUser, false, int64(1577880001), models.AuthenticationEventFirstFactor, "", models.AuthenticationTypeBasic,
+			"192.168.1.10", strings.Repeat("a", 512), "GET", "https://secure.example.com/", false).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, provider.AppendAuthenticationLog(attempt))
+
+	columns := []string{"username", "successful", "time", "event", "actor", "auth_type", "remote_ip", "user_agent", "request_method", "request_url", "banned"}
+	query := fmt.Sprintf("SELECT username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned FROM %s WHERE \\(\\?='' OR username=\\?\\) AND \\(\\?='' OR remote_ip=\\?\\) AND \\(\\?=0 OR time>=\\?\\) AND \\(\\?=0 OR time<=\\?\\) ORDER BY time DESC LIMIT \\? OFFSET \\?", authenticationLogsTableName)
+
+	mock.ExpectQuery(query).
+		WithArgs("", "", "192.168.1.10", "192.168.1.10", int64(1577880000), int64(1577880000), int64(1577880060), int64(1577880060), 10, 20).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(unitTestUser, false, 1577880001, models.AuthenticationEventFirstFactor, "", models.AuthenticationTypeBasic,
+				"192.168.1.10", "curl/7.79.1", "GET", "https://secure.example.com/", false).
+			AddRow("harry", false, 1577880000, models.AuthenticationEventFirstFactor, "", models.AuthenticationType1FA,
+				"192.168.1.10", "", "", "", true))
+
+	results, err := provider.LoadAuthenticationLogs(AuthenticationLogFilter{
+		RemoteIP: "192.168.1.10",
+		From:     time.Unix(1577880000, 0),
+		To:       time.Unix(1577880060, 0),
+		Limit:    10,
+		Offset:   20,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, models.AuthenticationAttempt{
+		Username:      unitTestUser,
+		Time:          time.Unix(1577880001, 0),
+		Event:         models.AuthenticationEventFirstFactor,
+		Type:          models.AuthenticationTypeBasic,
+		RemoteIP:      net.ParseIP("192.168.1.10"),
+		UserAgent:     "curl/7.79.1",
+		RequestMethod: "GET",
+		RequestURL:    "https://secure.example.com/",
+	}, results[0])
+	assert.Equal(t, "harry", results[1].Username)
+	assert.True(t, results[1].Banned)
+
+	// The limit is bounded and the time range is unbounded when the times are zero.
+	mock.ExpectQuery(query).
+		WithArgs(unitTestUser, unitTestUser, "", "", int64(0), int64(0), int64(0), int64(0), AuthenticationLogsMaxLimit, 0).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	results, err = provider.LoadAuthenticationLogs(AuthenticationLogFilter{Username: unitTestUser, Limit: 1000})
+	require.NoError(t, err)
+	assert.Len(t, results, 0)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestSQLProviderMethodsPreferred(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
@@ -183,6 +683,8 @@ func TestSQLProviderMethodsPreferred(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).
 			AddRow(currentSchemaMockSchemaVersion))
 
+	expectEncryptionCheck(mock, "")
+
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
@@ -234,6 +736,8 @@ func TestSQLProviderMethodsTOTP(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).
 			AddRow(currentSchemaMockSchemaVersion))
 
+	expectEncryptionCheck(mock, "")
+
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
@@ -296,6 +800,8 @@ func TestSQLProviderMethodsU2F(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).
 			AddRow(currentSchemaMockSchemaVersion))
 
+	expectEncryptionCheck(mock, "")
+
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
@@ -337,6 +843,125 @@ func TestSQLProviderMethodsU2F(t *testing.T) {
 	assert.Equal(t, []byte(nil), publicKey)
 }
 
+func TestSQLProviderMethodsLoadSecondFactors(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+	expectEncryptionCheck(mock, "")
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(provider.sqlGetSecondFactors)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "second_factor_method", "secret", "keyHandle", "publicKey"}).
+			AddRow("harry", nil, nil, "YWJj", "MTIz").
+			AddRow(unitTestUser, "totp", "JBSWY3DPEHPK3PXP", nil, nil))
+
+	users, err := provider.LoadSecondFactors()
+	assert.NoError(t, err)
+	assert.Equal(t, []SecondFactors{
+		{Username: "harry", KeyHandle: []byte("abc"), PublicKey: []byte("123")},
+		{Username: unitTestUser, Preferred2FAMethod: "totp", TOTPSecret: "JBSWY3DPEHPK3PXP"},
+	}, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectSecondFactorUsernames(mock sqlmock.Sqlmock, usernames ...string) {
+	rows := sqlmock.NewRows([]string{"username"})
+
+	for _, username := range usernames {
+		rows.AddRow(username)
+	}
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username FROM %s UNION SELECT username FROM %s UNION SELECT username FROM %s ORDER BY username",
+			totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName)).
+		WillReturnRows(rows)
+}
+
+func TestSQLProviderMethodsSaveSecondFactors(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectCurrentSchema(mock, currentSchemaMockSchemaVersion)
+	expectEncryptionCheck(mock, "")
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	users := []SecondFactors{
+		{Username: "harry", Preferred2FAMethod: "u2f", KeyHandle: []byte("abc"), PublicKey: []byte("123")},
+		{Username: unitTestUser, TOTPSecret: "JBSWY3DPEHPK3PXP"},
+	}
+
+	expectSaveHarry := func() {
+		mock.ExpectExec(
+			fmt.Sprintf("REPLACE INTO %s \\(username, second_factor_method\\) VALUES \\(\\?, \\?\\)", userPreferencesTableName)).
+			WithArgs("harry", "u2f").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectExec(
+			fmt.Sprintf("REPLACE INTO %s \\(username, keyHandle, publicKey\\) VALUES \\(\\?, \\?, \\?\\)", u2fDeviceHandlesTableName)).
+			WithArgs("harry", "YWJj", "MTIz").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	// The users who already have second factors abort the import.
+	mock.ExpectBegin()
+	expectSecondFactorUsernames(mock, unitTestUser)
+	mock.ExpectRollback()
+
+	result, err := provider.SaveSecondFactors(users, ImportModeAbort)
+	assert.Equal(t, ErrImportConflicts, err)
+	assert.Equal(t, ImportResult{Conflicts: []string{unitTestUser}}, result)
+
+	// They're skipped.
+	mock.ExpectBegin()
+	expectSecondFactorUsernames(mock, unitTestUser)
+	expectSaveHarry()
+	mock.ExpectCommit()
+
+	result, err = provider.SaveSecondFactors(users, ImportModeSkip)
+	assert.NoError(t, err)
+	assert.Equal(t, ImportResult{Imported: []string{"harry"}, Skipped: []string{unitTestUser}, Conflicts: []string{unitTestUser}}, result)
+
+	// Or all their second factors are replaced by the ones of the import, including the ones missing from it.
+	mock.ExpectBegin()
+	expectSecondFactorUsernames(mock, unitTestUser)
+	expectSaveHarry()
+
+	for _, table := range []string{userPreferencesTableName, totpSecretsTableName, u2fDeviceHandlesTableName} {
+		mock.ExpectExec(fmt.Sprintf("DELETE FROM %s WHERE username=\\?", table)).
+			WithArgs(unitTestUser).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, secret\\) VALUES \\(\\?, \\?\\)", totpSecretsTableName)).
+		WithArgs(unitTestUser, "JBSWY3DPEHPK3PXP").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectCommit()
+
+	result, err = provider.SaveSecondFactors(users, ImportModeOverwrite)
+	assert.NoError(t, err)
+	assert.Equal(t, ImportResult{Imported: []string{"harry", unitTestUser}, Conflicts: []string{unitTestUser}}, result)
+
+	// The whole import is rolled back when a user can't be saved.
+	mock.ExpectBegin()
+	expectSecondFactorUsernames(mock)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, second_factor_method\\) VALUES \\(\\?, \\?\\)", userPreferencesTableName)).
+		WithArgs("harry", "u2f").
+		WillReturnError(errors.New("database is locked"))
+
+	mock.ExpectRollback()
+
+	_, err = provider.SaveSecondFactors(users, ImportModeAbort)
+	assert.EqualError(t, err, "unable to save the second factors of user harry: database is locked")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
@@ -357,6 +982,8 @@ func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).
 			AddRow(currentSchemaMockSchemaVersion))
 
+	expectEncryptionCheck(mock, "")
+
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
@@ -398,3 +1025,227 @@ func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, valid)
 }
+
+func TestSQLProviderMethodsPersonalAccessTokens(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName).
+			AddRow(personalAccessTokensTableName))
+
+	args := []driver.Value{"schema", "version"}
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	expectEncryptionCheck(mock, "")
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	token := models.PersonalAccessToken{
+		ID:       "4f1c8a6e-0b5e-4d6c-9e0a-2a8f7d3c1b2a",
+		Username: unitTestUser,
+		Name:     "ci",
+		Hash:     "hash",
+		Level:    authentication.TwoFactor,
+		Domains:  []string{"api.example.com", "*.example.org"},
+		Created:  time.Unix(1577880000, 0),
+		Expires:  time.Unix(1609502400, 0),
+	}
+
+	columns := []string{"id", "username", "name", "token_hash", "auth_level", "domains", "created_at", "expires_at", "last_used", "revoked"}
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)", personalAccessTokensTableName)).
+		WithArgs(token.ID, unitTestUser, "ci", "hash", 2, "api.example.com,*.example.org", int64(1577880000), int64(1609502400), int64(0), false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.SavePersonalAccessToken(token)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE token_hash=\\?", personalAccessTokensTableName)).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(token.ID, unitTestUser, "ci", "hash", 2, "api.example.com,*.example.org", 1577880000, 1609502400, 0, false))
+
+	loaded, err := provider.LoadPersonalAccessTokenByHash("hash")
+	require.NoError(t, err)
+	assert.Equal(t, token, *loaded)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE token_hash=\\?", personalAccessTokensTableName)).
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	loaded, err = provider.LoadPersonalAccessTokenByHash("unknown")
+	assert.Nil(t, loaded)
+	assert.EqualError(t, err, "no personal access token found")
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE username=\\? ORDER BY created_at", personalAccessTokensTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(token.ID, unitTestUser, "ci", "hash", 2, "api.example.com,*.example.org", 1577880000, 1609502400, 0, false).
+			AddRow("other", unitTestUser, "cli", "hash2", 1, "", 1577880000, 1609502400, 1577890000, true))
+
+	tokens, err := provider.LoadPersonalAccessTokens(unitTestUser)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, token, tokens[0])
+	assert.Equal(t, authentication.OneFactor, tokens[1].Level)
+	assert.Nil(t, tokens[1].Domains)
+	assert.Equal(t, time.Unix(1577890000, 0), tokens[1].LastUsed)
+	assert.True(t, tokens[1].Revoked)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET revoked=TRUE WHERE id=\\? AND username=\\?", personalAccessTokensTableName)).
+		WithArgs(token.ID, unitTestUser).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.RevokePersonalAccessToken(unitTestUser, token.ID))
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET revoked=TRUE WHERE id=\\? AND username=\\?", personalAccessTokensTableName)).
+		WithArgs(token.ID, "harry").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.EqualError(t, provider.RevokePersonalAccessToken("harry", token.ID), "no personal access token found")
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET last_used=\\? WHERE id=\\?", personalAccessTokensTableName)).
+		WithArgs(int64(1577890000), token.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.UpdatePersonalAccessTokenLastUsed(token.ID, time.Unix(1577890000, 0)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderMethodsSessions(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName).
+			AddRow(personalAccessTokensTableName).
+			AddRow(sessionsTableName))
+
+	args := []driver.Value{"schema", "version"}
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	expectEncryptionCheck(mock, "")
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	now := time.Unix(1577880000, 0)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(id, data, expires_at\\) VALUES \\(\\?, \\?, \\?\\)", sessionsTableName)).
+		WithArgs("abc", "ZGF0YQ==", int64(1577883600)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.SaveSession("abc", []byte("data"), now.Add(time.Hour)))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(id, data, expires_at\\) VALUES \\(\\?, \\?, \\?\\)", sessionsTableName)).
+		WithArgs("def", "ZGF0YQ==", int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.SaveSession("def", []byte("data"), time.Time{}))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT data FROM %s WHERE id=\\? AND \\(expires_at=0 OR expires_at>\\?\\)", sessionsTableName)).
+		WithArgs("abc", int64(1577880000)).
+		WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow("ZGF0YQ=="))
+
+	data, err := provider.LoadSession("abc", now)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT data FROM %s WHERE id=\\? AND \\(expires_at=0 OR expires_at>\\?\\)", sessionsTableName)).
+		WithArgs("unknown", int64(1577880000)).
+		WillReturnRows(sqlmock.NewRows([]string{"data"}))
+
+	data, err = provider.LoadSession("unknown", now)
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET id=\\?, expires_at=\\? WHERE id=\\?", sessionsTableName)).
+		WithArgs("ghi", int64(1577883600), "abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.RegenerateSession("abc", "ghi", now.Add(time.Hour)))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE id=\\?", sessionsTableName)).
+		WithArgs("ghi").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.DestroySession("ghi"))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT COUNT\\(\\*\\) FROM %s WHERE expires_at=0 OR expires_at>\\?", sessionsTableName)).
+		WithArgs(int64(1577880000)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	count, err := provider.CountSessions(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE expires_at<>0 AND expires_at<=\\?", sessionsTableName)).
+		WithArgs(int64(1577880000)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := provider.DeleteExpiredSessions(now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	for _, rows := range []int64{1, 0} {
+		mock.ExpectExec(
+			fmt.Sprintf("DELETE FROM %s WHERE id=\\? AND expires_at<>0 AND expires_at<=\\?", sessionsTableName)).
+			WithArgs("lock:index:john", int64(1577880000)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mock.ExpectExec(
+			fmt.Sprintf("INSERT IGNORE INTO %s \\(id, data, expires_at\\) VALUES \\(\\?, \\?, \\?\\)", sessionsTableName)).
+			WithArgs("lock:index:john", "dG9rZW4=", int64(1577880005)).
+			WillReturnResult(sqlmock.NewResult(0, rows))
+
+		locked, err := provider.LockSession("lock:index:john", []byte("token"), now.Add(5*time.Second), now)
+		require.NoError(t, err)
+		assert.Equal(t, rows == 1, locked)
+	}
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE id=\\? AND data=\\?", sessionsTableName)).
+		WithArgs("lock:index:john", "dG9rZW4=").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, provider.UnlockSession("lock:index:john", []byte("token")))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
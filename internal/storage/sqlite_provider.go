@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	_ "github.com/mattn/go-sqlite3" // Load the SQLite Driver used in the connection string.
+
+	"github.com/authelia/authelia/v4/internal/logging"
 )
 
 // SQLiteProvider is a SQLite3 provider.
@@ -13,35 +15,75 @@ type SQLiteProvider struct {
 }
 
 // NewSQLiteProvider constructs a SQLite provider.
-func NewSQLiteProvider(path string) *SQLiteProvider {
+func NewSQLiteProvider(path, encryptionKey string) *SQLiteProvider {
+	provider := newSQLiteProvider(path, encryptionKey)
+
+	if err := provider.initialize(provider.db); err != nil {
+		provider.log.Fatalf("Unable to initialize SQL database %s: %s", path, err)
+	}
+
+	return provider
+}
+
+func newSQLiteProvider(path, encryptionKey string) *SQLiteProvider {
 	provider := SQLiteProvider{
 		SQLProvider{
 			name: "sqlite",
-
-			sqlUpgradesCreateTableStatements:        sqlUpgradeCreateTableStatements,
-			sqlUpgradesCreateTableIndexesStatements: sqlUpgradesCreateTableIndexesStatements,
+			log:  logging.Logger(),
+			key:  newEncryptionKey(encryptionKey),
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=?", userPreferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("REPLACE INTO %s (username, second_factor_method) VALUES (?, ?)", userPreferencesTableName),
+			sqlDeleteSecondFactorPreference: fmt.Sprintf("DELETE FROM %s WHERE username=?", userPreferencesTableName),
 
 			sqlTestIdentityVerificationTokenExistence: fmt.Sprintf("SELECT EXISTS (SELECT * FROM %s WHERE token=?)", identityVerificationTokensTableName),
 			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES (?)", identityVerificationTokensTableName),
 			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
 
 			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=?", totpSecretsTableName),
+			sqlGetTOTPSecrets:          fmt.Sprintf("SELECT username, secret FROM %s ORDER BY username", totpSecretsTableName),
 			sqlUpsertTOTPSecret:        fmt.Sprintf("REPLACE INTO %s (username, secret) VALUES (?, ?)", totpSecretsTableName),
 			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpSecretsTableName),
 
 			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=?", u2fDeviceHandlesTableName),
+			sqlGetU2FDeviceHandles:          fmt.Sprintf("SELECT username, keyHandle, publicKey FROM %s ORDER BY username", u2fDeviceHandlesTableName),
 			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("REPLACE INTO %s (username, keyHandle, publicKey) VALUES (?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlDeleteU2FDeviceHandle:        fmt.Sprintf("DELETE FROM %s WHERE username=?", u2fDeviceHandlesTableName),
+
+			sqlGetSecondFactorUsernames: fmt.Sprintf("SELECT username FROM %s UNION SELECT username FROM %s UNION SELECT username FROM %s ORDER BY username", totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName),
+			sqlGetSecondFactors:         fmt.Sprintf("SELECT u.username, p.second_factor_method, t.secret, d.keyHandle, d.publicKey FROM (SELECT username FROM %[1]s UNION SELECT username FROM %[2]s UNION SELECT username FROM %[3]s) AS u LEFT JOIN %[3]s AS p ON p.username=u.username LEFT JOIN %[1]s AS t ON t.username=u.username LEFT JOIN %[2]s AS d ON d.username=u.username ORDER BY u.username", totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName),
+
+			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? AND auth_type IN (?, ?) AND banned=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlGetAuthenticationLogs:       fmt.Sprintf("SELECT username, successful, time, event, actor, auth_type, remote_ip, user_agent, request_method, request_url, banned FROM %s WHERE (?='' OR username=?) AND (?='' OR remote_ip=?) AND (?=0 OR time>=?) AND (?=0 OR time<=?) ORDER BY time DESC LIMIT ? OFFSET ?", authenticationLogsTableName),
+
+			sqlInsertPersonalAccessToken:         fmt.Sprintf("INSERT INTO %s (id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", personalAccessTokensTableName),
+			sqlGetPersonalAccessTokenByHash:      fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE token_hash=?", personalAccessTokensTableName),
+			sqlGetPersonalAccessTokensByUsername: fmt.Sprintf("SELECT id, username, name, token_hash, auth_level, domains, created_at, expires_at, last_used, revoked FROM %s WHERE username=? ORDER BY created_at", personalAccessTokensTableName),
+			sqlRevokePersonalAccessToken:         fmt.Sprintf("UPDATE %s SET revoked=TRUE WHERE id=? AND username=?", personalAccessTokensTableName),
+			sqlUpdatePersonalAccessTokenLastUsed: fmt.Sprintf("UPDATE %s SET last_used=? WHERE id=?", personalAccessTokensTableName),
 
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES (?, ?, ?)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlUpsertSession:            fmt.Sprintf("REPLACE INTO %s (id, data, expires_at) VALUES (?, ?, ?)", sessionsTableName),
+			sqlGetSessionByID:           fmt.Sprintf("SELECT data FROM %s WHERE id=? AND (expires_at=0 OR expires_at>?)", sessionsTableName),
+			sqlUpdateSessionID:          fmt.Sprintf("UPDATE %s SET id=?, expires_at=? WHERE id=?", sessionsTableName),
+			sqlDeleteSession:            fmt.Sprintf("DELETE FROM %s WHERE id=?", sessionsTableName),
+			sqlCountSessions:            fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE expires_at=0 OR expires_at>?", sessionsTableName),
+			sqlDeleteExpiredSessions:    fmt.Sprintf("DELETE FROM %s WHERE expires_at<>0 AND expires_at<=?", sessionsTableName),
+			sqlInsertSessionIfNotExists: fmt.Sprintf("INSERT OR IGNORE INTO %s (id, data, expires_at) VALUES (?, ?, ?)", sessionsTableName),
+			sqlDeleteExpiredSessionByID: fmt.Sprintf("DELETE FROM %s WHERE id=? AND expires_at<>0 AND expires_at<=?", sessionsTableName),
+			sqlDeleteSessionByData:      fmt.Sprintf("DELETE FROM %s WHERE id=? AND data=?", sessionsTableName),
 
 			sqlGetExistingTables: "SELECT name FROM sqlite_master WHERE type='table'",
 
-			sqlConfigSetValue: fmt.Sprintf("REPLACE INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
-			sqlConfigGetValue: fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=?", configTableName),
+			sqlGetIndexExistence: "SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND tbl_name=? AND name=?",
+			sqlDropIndex:         "DROP INDEX %[1]s",
+
+			// SQLite doesn't support FOR UPDATE, the database is locked by the first write of a transaction instead.
+			sqlConfigSetValue:            fmt.Sprintf("REPLACE INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
+			sqlConfigInsertValue:         fmt.Sprintf("INSERT INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
+			sqlConfigGetValue:            fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=?", configTableName),
+			sqlConfigGetValueForUpdate:   fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=?", configTableName),
+			sqlConfigGetValuesByCategory: fmt.Sprintf("SELECT key_name, value FROM %s WHERE category=?", configTableName),
 		},
 	}
 
@@ -50,9 +92,7 @@ func NewSQLiteProvider(path string) *SQLiteProvider {
 		provider.log.Fatalf("Unable to create SQL database %s: %s", path, err)
 	}
 
-	if err := provider.initialize(db); err != nil {
-		provider.log.Fatalf("Unable to initialize SQL database %s: %s", path, err)
-	}
+	provider.db = db
 
 	return &provider
 }
@@ -3,8 +3,48 @@ package storage
 import (
 	"database/sql"
 	"strconv"
+	"time"
 )
 
+// AuthenticationLogFilter filters the authentication logs loaded by LoadAuthenticationLogs. The empty username and
+// remote IP match any user and any client, the zero times don't bound the range of the attempts.
+type AuthenticationLogFilter struct {
+	Username string
+	RemoteIP string
+	From     time.Time
+	To       time.Time
+	// Limit is the maximum number of attempts returned, AuthenticationLogsDefaultLimit if zero.
+	Limit  int
+	Offset int
+}
+
+// Bounded returns the filter with the limit bounded to AuthenticationLogsMaxLimit, AuthenticationLogsDefaultLimit
+// when not set, and with an offset which isn't negative.
+func (f AuthenticationLogFilter) Bounded() AuthenticationLogFilter {
+	switch {
+	case f.Limit <= 0:
+		f.Limit = AuthenticationLogsDefaultLimit
+	case f.Limit > AuthenticationLogsMaxLimit:
+		f.Limit = AuthenticationLogsMaxLimit
+	}
+
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	return f
+}
+
+// SecondFactors are the second factors and the preferences of a user, the TOTP secret and the U2F device are empty
+// when the user doesn't have them.
+type SecondFactors struct {
+	Username           string
+	Preferred2FAMethod string
+	TOTPSecret         string
+	KeyHandle          []byte
+	PublicKey          []byte
+}
+
 // SchemaVersion is a simple int representation of the schema version.
 type SchemaVersion int
 
@@ -13,6 +53,47 @@ func (s SchemaVersion) ToString() string {
 	return strconv.Itoa(int(s))
 }
 
+// Migration is a reversible migration of the schema of the SQL storage providers to its version. Migrating up creates
+// the tables which don't exist, then the indexes which don't exist and runs the up statements. Migrating down runs
+// the down statements and drops the tables of the migration. The statements which differ between the dialects are
+// run after the up statements and before the down statements, keyed by the name of the provider.
+type Migration struct {
+	Version     SchemaVersion
+	Description string
+
+	tables  []migrationTable
+	indexes []migrationIndex
+	up      []string
+	down    []string
+
+	upDialect   map[string][]string
+	downDialect map[string][]string
+}
+
+// MigrationRecord is an entry of the history of the migrations of the schema recorded in the config table.
+type MigrationRecord struct {
+	Time time.Time
+
+	// Before and After are the versions of the schema before and after the migration.
+	Before SchemaVersion
+	After  SchemaVersion
+
+	// AutheliaVersion is the version of Authelia which applied the migration.
+	AutheliaVersion string
+}
+
+type migrationTable struct {
+	name      string
+	statement string
+}
+
+type migrationIndex struct {
+	name    string
+	table   string
+	columns string
+}
+
 type transaction interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
@@ -5,11 +5,11 @@ MODE=$1
 cp /templates/${MODE}.conf /data/redis.conf
 chown -R redis:redis /data
 
-if [ "${MODE}" == "master" ] || [ "${MODE}" == "slave" ]; then
+if [ "${MODE}" == "master" ] || [ "${MODE}" == "slave" ] || [ "${MODE}" == "cluster" ]; then
   redis-server /data/redis.conf
 elif [ "${MODE}" == "sentinel" ]; then
   redis-server /data/redis.conf --sentinel
 else
-  echo "invalid argument: entrypoint.sh [master|slave|sentinel]"
+  echo "invalid argument: entrypoint.sh [master|slave|sentinel|cluster]"
   exit 1
 fi
@@ -0,0 +1,155 @@
+package suites
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type HighAvailabilityClusterWebDriverSuite struct {
+	*SeleniumSuite
+}
+
+func NewHighAvailabilityClusterWebDriverSuite() *HighAvailabilityClusterWebDriverSuite {
+	return &HighAvailabilityClusterWebDriverSuite{SeleniumSuite: new(SeleniumSuite)}
+}
+
+func (s *HighAvailabilityClusterWebDriverSuite) SetupSuite() {
+	wds, err := StartWebDriver()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.WebDriverSession = wds
+}
+
+func (s *HighAvailabilityClusterWebDriverSuite) TearDownSuite() {
+	err := s.WebDriverSession.Stop()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (s *HighAvailabilityClusterWebDriverSuite) SetupTest() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s.doLogout(ctx, s.T())
+	s.doVisit(s.T(), HomeBaseURL)
+	s.verifyIsHome(ctx, s.T())
+}
+
+func (s *HighAvailabilityClusterWebDriverSuite) TestShouldKeepUserSessionActiveWithRedisMasterFailure() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
+	defer cancel()
+
+	secret := s.doRegisterThenLogout(ctx, s.T(), "john", "password")
+
+	s.doLoginTwoFactor(ctx, s.T(), "john", "password", false, secret, "")
+	s.verifyIsSecondFactorPage(ctx, s.T())
+
+	err := haClusterDockerEnvironment.Stop("redis-cluster-node-0")
+	s.Require().NoError(err)
+
+	defer func() {
+		err = haClusterDockerEnvironment.Start("redis-cluster-node-0")
+		s.Require().NoError(err)
+	}()
+
+	// Allow the replica to be promoted, the node timeout of the cluster is 5 seconds.
+	time.Sleep(10 * time.Second)
+
+	s.doVisit(s.T(), HomeBaseURL)
+	s.verifyIsHome(ctx, s.T())
+
+	// Verify the user is still authenticated
+	s.doVisit(s.T(), GetLoginBaseURL())
+	s.verifyIsSecondFactorPage(ctx, s.T())
+
+	// Then logout and login again to check we can see the secret.
+	s.doLogout(ctx, s.T())
+	s.verifyIsFirstFactorPage(ctx, s.T())
+
+	s.doLoginTwoFactor(ctx, s.T(), "john", "password", false, secret, fmt.Sprintf("%s/secret.html", SecureBaseURL))
+	s.verifySecretAuthorized(ctx, s.T())
+}
+
+func (s *HighAvailabilityClusterWebDriverSuite) TestShouldKeepSessionAfterAutheliaRestart() {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	secret := s.doRegisterAndLogin2FA(ctx, s.T(), "john", "password", false, "")
+	s.verifyIsSecondFactorPage(ctx, s.T())
+
+	err := haClusterDockerEnvironment.Restart("authelia-backend")
+	s.Require().NoError(err)
+
+	err = waitUntilAutheliaBackendIsReady(haClusterDockerEnvironment)
+	s.Require().NoError(err)
+
+	s.doVisit(s.T(), HomeBaseURL)
+	s.verifyIsHome(ctx, s.T())
+
+	// Verify the user is still authenticated
+	s.doVisit(s.T(), GetLoginBaseURL())
+	s.verifyIsSecondFactorPage(ctx, s.T())
+
+	// Then logout and login again to check the secret is still there
+	s.doLogout(ctx, s.T())
+	s.verifyIsFirstFactorPage(ctx, s.T())
+
+	s.doLoginTwoFactor(ctx, s.T(), "john", "password", false, secret, fmt.Sprintf("%s/secret.html", SecureBaseURL))
+	s.verifySecretAuthorized(ctx, s.T())
+}
+
+type HighAvailabilityClusterSuite struct {
+	suite.Suite
+}
+
+func NewHighAvailabilityClusterSuite() *HighAvailabilityClusterSuite {
+	return &HighAvailabilityClusterSuite{}
+}
+
+func (s *HighAvailabilityClusterSuite) TestBasicAuth() {
+	s.Assert().Equal(DoGetWithAuth(s.T(), "john", "password"), 200)
+	s.Assert().Equal(DoGetWithAuth(s.T(), "john", "bad-password"), 302)
+	s.Assert().Equal(DoGetWithAuth(s.T(), "dontexist", "password"), 302)
+}
+
+func (s *HighAvailabilityClusterSuite) TestOneFactorScenario() {
+	suite.Run(s.T(), NewOneFactorScenario())
+}
+
+func (s *HighAvailabilityClusterSuite) TestTwoFactorScenario() {
+	suite.Run(s.T(), NewTwoFactorScenario())
+}
+
+func (s *HighAvailabilityClusterSuite) TestRegulationScenario() {
+	suite.Run(s.T(), NewRegulationScenario())
+}
+
+func (s *HighAvailabilityClusterSuite) TestHighAvailabilityClusterWebDriverSuite() {
+	suite.Run(s.T(), NewHighAvailabilityClusterWebDriverSuite())
+}
+
+func TestHighAvailabilityClusterWebDriverSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping suite test in short mode")
+	}
+
+	suite.Run(t, NewHighAvailabilityClusterWebDriverSuite())
+}
+
+func TestHighAvailabilityClusterSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping suite test in short mode")
+	}
+
+	suite.Run(t, NewHighAvailabilityClusterSuite())
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"net"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// GetRemoteIP returns the remote IP of the request taking the X-Forwarded-For header into account if provided.
+func GetRemoteIP(ctx *fasthttp.RequestCtx) net.IP {
+	XForwardedFor := ctx.Request.Header.Peek("X-Forwarded-For")
+	if XForwardedFor != nil {
+		ips := strings.Split(string(XForwardedFor), ",")
+
+		if len(ips) > 0 {
+			return net.ParseIP(strings.Trim(ips[0], " "))
+		}
+	}
+
+	return ctx.RemoteIP()
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -49,3 +50,84 @@ func ParseDurationString(input string) (time.Duration, error) {
 
 	return duration, nil
 }
+
+// ParseWeekday parses a string into a time.Weekday. Both the full English name and the three letter abbreviation are
+// accepted, case-insensitively. Example: Monday, monday, and mon are all time.Monday.
+func ParseWeekday(input string) (weekday time.Weekday, err error) {
+	value := strings.ToLower(strings.TrimSpace(input))
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+
+		if value == name || value == name[:3] {
+			return day, nil
+		}
+	}
+
+	return time.Sunday, fmt.Errorf("could not parse '%s' as a day of the week", input)
+}
+
+// ParseTimeOfDayRange parses a string in the format of HH:MM-HH:MM into the start and end offsets since midnight.
+// The end of the range may be before the start of the range, which indicates the range spans midnight.
+// Example: 09:00-17:00 returns 9h and 17h, 22:00-06:00 returns 22h and 6h.
+func ParseTimeOfDayRange(input string) (start, end time.Duration, err error) {
+	parts := strings.Split(strings.TrimSpace(input), "-")
+
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("could not parse '%s' as a time range, it must be in the format of HH:MM-HH:MM", input)
+	}
+
+	if start, err = parseTimeOfDay(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("could not parse '%s' as a time range: %w", input, err)
+	}
+
+	if end, err = parseTimeOfDay(parts[1]); err != nil {
+		return 0, 0, fmt.Errorf("could not parse '%s' as a time range: %w", input, err)
+	}
+
+	if start == end {
+		return 0, 0, fmt.Errorf("could not parse '%s' as a time range: the start and end of the range are the same", input)
+	}
+
+	return start, end, nil
+}
+
+func parseTimeOfDay(input string) (offset time.Duration, err error) {
+	value := strings.TrimSpace(input)
+
+	if value == "24:00" {
+		return Day, nil
+	}
+
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("the time '%s' must be in the format of HH:MM", value)
+	}
+
+	return time.Duration(t.Hour())*Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseDateTime parses a string as either a RFC3339 timestamp, a date and time in the format of YYYY-MM-DD HH:MM, or
+// a date in the format of YYYY-MM-DD. Values without an explicit offset are interpreted in the provided location, and
+// dateOnly is true when the value had no time component.
+func ParseDateTime(input string, location *time.Location) (t time.Time, dateOnly bool, err error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	value := strings.TrimSpace(input)
+
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+
+	if t, err = time.ParseInLocation("2006-01-02 15:04", value, location); err == nil {
+		return t, false, nil
+	}
+
+	if t, err = time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return t, true, nil
+	}
+
+	return time.Time{}, false, fmt.Errorf("could not parse '%s' as a date, it must be in the format of YYYY-MM-DD, YYYY-MM-DD HH:MM, or RFC3339", input)
+}
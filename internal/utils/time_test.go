@@ -76,3 +76,71 @@ func TestShouldTimeIntervalsMakeSense(t *testing.T) {
 	assert.Equal(t, Year, Day*365)
 	assert.Equal(t, Month, Year/12)
 }
+
+func TestShouldParseWeekday(t *testing.T) {
+	weekday, err := ParseWeekday("Monday")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Monday, weekday)
+
+	weekday, err = ParseWeekday("sun")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Sunday, weekday)
+
+	weekday, err = ParseWeekday(" SATURDAY ")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Saturday, weekday)
+
+	_, err = ParseWeekday("someday")
+	assert.EqualError(t, err, "could not parse 'someday' as a day of the week")
+}
+
+func TestShouldParseTimeOfDayRange(t *testing.T) {
+	start, end, err := ParseTimeOfDayRange("09:00-17:30")
+	assert.NoError(t, err)
+	assert.Equal(t, 9*time.Hour, start)
+	assert.Equal(t, 17*time.Hour+30*time.Minute, end)
+
+	start, end, err = ParseTimeOfDayRange("22:00-06:00")
+	assert.NoError(t, err)
+	assert.Equal(t, 22*time.Hour, start)
+	assert.Equal(t, 6*time.Hour, end)
+
+	start, end, err = ParseTimeOfDayRange("00:00-24:00")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), start)
+	assert.Equal(t, Day, end)
+}
+
+func TestShouldNotParseInvalidTimeOfDayRange(t *testing.T) {
+	_, _, err := ParseTimeOfDayRange("09:00")
+	assert.EqualError(t, err, "could not parse '09:00' as a time range, it must be in the format of HH:MM-HH:MM")
+
+	_, _, err = ParseTimeOfDayRange("09:00-25:00")
+	assert.EqualError(t, err, "could not parse '09:00-25:00' as a time range: the time '25:00' must be in the format of HH:MM")
+
+	_, _, err = ParseTimeOfDayRange("09:00-09:00")
+	assert.EqualError(t, err, "could not parse '09:00-09:00' as a time range: the start and end of the range are the same")
+}
+
+func TestShouldParseDateTime(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+
+	value, dateOnly, err := ParseDateTime("2021-07-01", location)
+	assert.NoError(t, err)
+	assert.True(t, dateOnly)
+	assert.Equal(t, time.Date(2021, time.July, 1, 0, 0, 0, 0, location), value)
+
+	value, dateOnly, err = ParseDateTime("2021-07-01 13:30", location)
+	assert.NoError(t, err)
+	assert.False(t, dateOnly)
+	assert.Equal(t, time.Date(2021, time.July, 1, 13, 30, 0, 0, location), value)
+
+	value, dateOnly, err = ParseDateTime("2021-07-01T13:30:00Z", location)
+	assert.NoError(t, err)
+	assert.False(t, dateOnly)
+	assert.True(t, time.Date(2021, time.July, 1, 13, 30, 0, 0, time.UTC).Equal(value))
+
+	_, _, err = ParseDateTime("01/07/2021", location)
+	assert.EqualError(t, err, "could not parse '01/07/2021' as a date, it must be in the format of YYYY-MM-DD, YYYY-MM-DD HH:MM, or RFC3339")
+}